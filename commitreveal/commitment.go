@@ -0,0 +1,184 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+// Package commitreveal lets several parties agree on a shared random seed,
+// for example for shuffle.SeededShuffle, without any of them being able to
+// choose it.
+//
+// Each party draws a random contribution and publishes a salted, optionally
+// signed commitment to it. Once every commitment is in, the parties reveal
+// their contributions and salts, which are checked against the commitments
+// and combined into the seed. A Round collects both phases and reports the
+// parties that never committed, committed but did not reveal, or committed
+// to two different values.
+//
+// A party that withholds its reveal after seeing the others can still choose
+// between the seed with and without its contribution. Callers must treat
+// every party reported by Result as faulty, for example by excluding it and
+// running a new round, rather than silently using the partial seed.
+package commitreveal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash"
+	"io"
+
+	"github.com/pkg/errors"
+	"gitlab.com/xx_network/crypto/hasher"
+)
+
+const (
+	// ContributionSize is the size of a random contribution in bytes.
+	ContributionSize = 32
+
+	// SaltSize is the size of the commitment salt in bytes.
+	SaltSize = 32
+
+	// Domain separation strings for the commitment, signature and seed
+	// hashes
+	commitmentDomain = "xx_network/crypto commitreveal commitment v1"
+	signatureDomain  = "xx_network/crypto commitreveal signature v1"
+	seedDomain       = "xx_network/crypto commitreveal seed v1"
+)
+
+// Commitment is a party's published commitment to its contribution.
+type Commitment struct {
+	// RoundID identifies the round the commitment belongs to.
+	RoundID []byte
+	// Party identifies the committing party.
+	Party string
+	// Hash is the hash used for the commitment digest.
+	Hash hasher.HashType
+	// Digest is the hash of the round, party, salt and contribution.
+	Digest []byte
+	// Signature is the party's signature over the commitment, if signed.
+	Signature []byte
+}
+
+// Reveal opens a Commitment.
+type Reveal struct {
+	// Party identifies the revealing party.
+	Party string
+	// Contribution is the party's random contribution to the seed.
+	Contribution []byte
+	// Salt is the random salt used in the commitment.
+	Salt []byte
+}
+
+// Commit draws a random contribution and salt from rng and returns the
+// commitment to publish and the reveal to keep until the reveal phase. If
+// signer is not nil the commitment is signed.
+func Commit(rng io.Reader, h hasher.HashType, roundID []byte, party string,
+	signer Signer) (*Commitment, *Reveal, error) {
+	hash, err := h.New()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reveal := &Reveal{
+		Party:        party,
+		Contribution: make([]byte, ContributionSize),
+		Salt:         make([]byte, SaltSize),
+	}
+	if _, err = io.ReadFull(rng, reveal.Contribution); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate contribution")
+	}
+	if _, err = io.ReadFull(rng, reveal.Salt); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate salt")
+	}
+
+	c := &Commitment{
+		RoundID: roundID,
+		Party:   party,
+		Hash:    h,
+		Digest: commitmentDigest(hash, roundID, party, reveal.Salt,
+			reveal.Contribution),
+	}
+
+	if signer != nil {
+		sig, err := signer.Sign(c.signedMessage())
+		if err != nil {
+			return nil, nil, errors.WithMessage(err,
+				"failed to sign commitment")
+		}
+		c.Signature = sig
+	}
+
+	return c, reveal, nil
+}
+
+// VerifyReveal checks that the reveal opens the commitment.
+func VerifyReveal(c *Commitment, r *Reveal) error {
+	if c.Party != r.Party {
+		return errors.Errorf("reveal from %q does not match commitment "+
+			"from %q", r.Party, c.Party)
+	}
+	h, err := c.Hash.New()
+	if err != nil {
+		return err
+	}
+	if len(r.Contribution) != ContributionSize || len(r.Salt) != SaltSize {
+		return errors.Errorf("reveal from %q has a %d byte contribution "+
+			"and %d byte salt, expected %d and %d", r.Party,
+			len(r.Contribution), len(r.Salt), ContributionSize, SaltSize)
+	}
+
+	digest := commitmentDigest(h, c.RoundID, c.Party, r.Salt, r.Contribution)
+	if !bytes.Equal(digest, c.Digest) {
+		return errors.Errorf("reveal from %q does not open its commitment",
+			r.Party)
+	}
+	return nil
+}
+
+// VerifySignature checks the commitment signature with the verifier.
+func (c *Commitment) VerifySignature(v Verifier) error {
+	if len(c.Signature) == 0 {
+		return errors.Errorf("commitment from %q is not signed", c.Party)
+	}
+	return v.Verify(c.signedMessage(), c.Signature)
+}
+
+// Equal returns true if both commitments are for the same round, party, hash
+// and digest. Signatures are not compared, as randomized signatures differ
+// even over the same commitment.
+func (c *Commitment) Equal(other *Commitment) bool {
+	return bytes.Equal(c.RoundID, other.RoundID) && c.Party == other.Party &&
+		c.Hash == other.Hash && bytes.Equal(c.Digest, other.Digest)
+}
+
+// signedMessage returns the message covered by the commitment signature.
+func (c *Commitment) signedMessage() []byte {
+	var buf bytes.Buffer
+	writeField(&buf, []byte(signatureDomain))
+	writeField(&buf, c.RoundID)
+	writeField(&buf, []byte(c.Party))
+	buf.WriteByte(byte(c.Hash))
+	writeField(&buf, c.Digest)
+	return buf.Bytes()
+}
+
+// commitmentDigest hashes the commitment inputs, each prefixed with its
+// length so that no two inputs share an encoding.
+func commitmentDigest(h hash.Hash, roundID []byte, party string, salt,
+	contribution []byte) []byte {
+	writeField(h, []byte(commitmentDomain))
+	writeField(h, roundID)
+	writeField(h, []byte(party))
+	writeField(h, salt)
+	writeField(h, contribution)
+	return h.Sum(nil)
+}
+
+// writeField writes the field prefixed with its 8 byte big-endian length.
+func writeField(w io.Writer, field []byte) {
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], uint64(len(field)))
+	_, _ = w.Write(length[:])
+	_, _ = w.Write(field)
+}
@@ -0,0 +1,99 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package commitreveal
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"gitlab.com/xx_network/crypto/csprng"
+	"gitlab.com/xx_network/crypto/hasher"
+)
+
+// Happy path: a reveal opens its own commitment for every hash type
+func TestCommit_VerifyReveal(t *testing.T) {
+	hashes := []hasher.HashType{hasher.SHA2_256, hasher.SHA3_256,
+		hasher.BLAKE2, hasher.BLAKE3}
+	for _, h := range hashes {
+		c, r, err := Commit(rand.Reader, h, []byte("round"), "alice", nil)
+		if err != nil {
+			t.Fatalf("Commit with %s failed: %+v", h, err)
+		}
+		if len(c.Digest) != h.Size() {
+			t.Errorf("Digest with %s has %d bytes", h, len(c.Digest))
+		}
+		if c.Signature != nil {
+			t.Errorf("Unsigned commitment has a signature")
+		}
+		if err = VerifyReveal(c, r); err != nil {
+			t.Errorf("Reveal with %s did not verify: %+v", h, err)
+		}
+	}
+}
+
+// Error path: tampered reveals and commitments do not verify
+func TestVerifyReveal_Tampered(t *testing.T) {
+	c, r, err := Commit(rand.Reader, hasher.SHA3_256, []byte("round"),
+		"alice", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tamper := []func(c *Commitment, r *Reveal){
+		func(c *Commitment, r *Reveal) { r.Contribution[0] ^= 1 },
+		func(c *Commitment, r *Reveal) { r.Salt[SaltSize-1] ^= 1 },
+		func(c *Commitment, r *Reveal) { r.Contribution = r.Contribution[1:] },
+		func(c *Commitment, r *Reveal) { r.Party = "bob" },
+		func(c *Commitment, r *Reveal) { c.Party = "bob"; r.Party = "bob" },
+		func(c *Commitment, r *Reveal) { c.RoundID = []byte("other") },
+		func(c *Commitment, r *Reveal) { c.Hash = hasher.SHA2_256 },
+	}
+	for i, f := range tamper {
+		cc := *c
+		rr := &Reveal{
+			Party:        r.Party,
+			Contribution: append([]byte{}, r.Contribution...),
+			Salt:         append([]byte{}, r.Salt...),
+		}
+		f(&cc, rr)
+		if VerifyReveal(&cc, rr) == nil {
+			t.Errorf("Tampered reveal %d verified", i)
+		}
+	}
+}
+
+// Error path: Commit fails on an unknown hash or a failing source
+func TestCommit_Errors(t *testing.T) {
+	if _, _, err := Commit(rand.Reader, hasher.HashType(200), nil, "a",
+		nil); err == nil {
+		t.Errorf("Commit with an unknown hash succeeded")
+	}
+	rng := csprng.NewFailingSource(csprng.NewSystemRNG(), 40, nil)
+	if _, _, err := Commit(rng, hasher.SHA3_256, nil, "a", nil); err == nil {
+		t.Errorf("Commit with a failing source succeeded")
+	}
+}
+
+// Tests that Equal ignores the signature but not the digest
+func TestCommitment_Equal(t *testing.T) {
+	c, _, err := Commit(rand.Reader, hasher.SHA3_256, []byte("round"),
+		"alice", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := *c
+	other.Signature = []byte("sig")
+	if !c.Equal(&other) {
+		t.Errorf("Commitments differing only in signature are not equal")
+	}
+	other.Digest = bytes.Repeat([]byte{1}, len(c.Digest))
+	if c.Equal(&other) {
+		t.Errorf("Commitments with different digests are equal")
+	}
+}
@@ -0,0 +1,256 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package commitreveal
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	jww "github.com/spf13/jwalterweatherman"
+	"gitlab.com/xx_network/crypto/hasher"
+)
+
+// ErrEquivocation is returned when a party sends a second, different
+// commitment.
+var ErrEquivocation = errors.New("party equivocated")
+
+// ErrInvalidReveal is returned when a reveal does not open the named party's
+// commitment. Reveals are not signed, so the party is not blamed for it.
+var ErrInvalidReveal = errors.New("reveal does not open the commitment")
+
+// ErrIncomplete is returned by Round.Result when some parties did not take
+// part honestly. The result is still returned so they can be identified.
+var ErrIncomplete = errors.New("not every party committed and revealed")
+
+// Phase is the current phase of a Round.
+type Phase uint8
+
+const (
+	// CommitPhase accepts commitments.
+	CommitPhase Phase = iota
+	// RevealPhase accepts reveals.
+	RevealPhase
+)
+
+// Round collects the commitments and reveals of a fixed set of parties for
+// one seed agreement. It is safe for concurrent use.
+type Round struct {
+	id        []byte
+	hash      hasher.HashType
+	parties   map[string]bool
+	verifiers map[string]Verifier
+
+	phase        Phase
+	commitments  map[string]*Commitment
+	reveals      map[string]*Reveal
+	equivocators map[string]bool
+
+	mux sync.Mutex
+}
+
+// Result is the outcome of a Round.
+type Result struct {
+	// Seed is the combination of every valid reveal.
+	Seed []byte
+	// Contributors are the parties whose reveals make up the seed, sorted.
+	Contributors []string
+	// Missing are the parties that never committed, sorted.
+	Missing []string
+	// Aborted are the parties that committed but did not reveal, sorted.
+	Aborted []string
+	// Equivocated are the parties that sent conflicting commitments, sorted.
+	Equivocated []string
+}
+
+// NewRound starts a round with the given identifier among the given parties.
+// Every commitment must use the given hash. If verifiers is not nil, every
+// party must have a verifier and every commitment must be signed.
+func NewRound(roundID []byte, h hasher.HashType, parties []string,
+	verifiers map[string]Verifier) (*Round, error) {
+	if !h.Available() {
+		return nil, errors.Errorf("unknown hash type %s", h)
+	}
+	if len(parties) == 0 {
+		return nil, errors.New("a round needs at least one party")
+	}
+
+	r := &Round{
+		id:           roundID,
+		hash:         h,
+		parties:      make(map[string]bool, len(parties)),
+		verifiers:    verifiers,
+		commitments:  make(map[string]*Commitment),
+		reveals:      make(map[string]*Reveal),
+		equivocators: make(map[string]bool),
+	}
+	for _, p := range parties {
+		if r.parties[p] {
+			return nil, errors.Errorf("party %q listed twice", p)
+		}
+		if verifiers != nil && verifiers[p] == nil {
+			return nil, errors.Errorf("no verifier for party %q", p)
+		}
+		r.parties[p] = true
+	}
+	return r, nil
+}
+
+// Phase returns the current phase of the round.
+func (r *Round) Phase() Phase {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return r.phase
+}
+
+// AddCommitment records a party's commitment. A repeated identical
+// commitment is ignored; a different second commitment marks the party as
+// an equivocator and returns ErrEquivocation.
+func (r *Round) AddCommitment(c *Commitment) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.phase != CommitPhase {
+		return errors.Errorf("commitment from %q received after the "+
+			"commit phase closed", c.Party)
+	}
+	if !r.parties[c.Party] {
+		return errors.Errorf("commitment from unknown party %q", c.Party)
+	}
+	if !bytes.Equal(c.RoundID, r.id) {
+		return errors.Errorf("commitment from %q is for another round",
+			c.Party)
+	}
+	if c.Hash != r.hash {
+		return errors.Errorf("commitment from %q uses %s instead of %s",
+			c.Party, c.Hash, r.hash)
+	}
+	if len(c.Digest) != r.hash.Size() {
+		return errors.Errorf("commitment from %q has a %d byte digest",
+			c.Party, len(c.Digest))
+	}
+	if r.verifiers != nil {
+		if err := c.VerifySignature(r.verifiers[c.Party]); err != nil {
+			return err
+		}
+	}
+
+	if prev, ok := r.commitments[c.Party]; ok {
+		if prev.Equal(c) {
+			return nil
+		}
+		r.equivocators[c.Party] = true
+		return errors.Wrapf(ErrEquivocation, "second commitment from %q",
+			c.Party)
+	}
+	r.commitments[c.Party] = c
+	return nil
+}
+
+// CloseCommitments ends the commit phase. No further commitments are
+// accepted and reveals may be added. Reveals should only be released once
+// every party has seen that the commit phase is closed.
+func (r *Round) CloseCommitments() {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.phase = RevealPhase
+}
+
+// AddReveal checks a reveal against the party's commitment and records it.
+// Reveals are not signed, so anyone can send one in a party's name. A reveal
+// that does not open the commitment is rejected with ErrInvalidReveal without
+// blaming the party, and once a valid reveal is recorded any later one is
+// ignored.
+func (r *Round) AddReveal(rv *Reveal) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.phase != RevealPhase {
+		return errors.Errorf("reveal from %q received before the commit "+
+			"phase closed", rv.Party)
+	}
+	c, ok := r.commitments[rv.Party]
+	if !ok {
+		return errors.Errorf("reveal from %q without a commitment", rv.Party)
+	}
+	if _, ok = r.reveals[rv.Party]; ok {
+		return nil
+	}
+	if err := VerifyReveal(c, rv); err != nil {
+		return errors.Wrap(ErrInvalidReveal, err.Error())
+	}
+	r.reveals[rv.Party] = rv
+	return nil
+}
+
+// Result combines the valid reveals of every party that did not equivocate
+// into the seed and reports the parties that did not take part honestly. If
+// any did, the result is returned together with ErrIncomplete.
+func (r *Round) Result() (*Result, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.phase != RevealPhase {
+		return nil, errors.New("the commit phase has not closed")
+	}
+
+	res := &Result{}
+	var reveals []*Reveal
+	for p := range r.parties {
+		switch {
+		case r.equivocators[p]:
+			res.Equivocated = append(res.Equivocated, p)
+		case r.commitments[p] == nil:
+			res.Missing = append(res.Missing, p)
+		case r.reveals[p] == nil:
+			res.Aborted = append(res.Aborted, p)
+		default:
+			res.Contributors = append(res.Contributors, p)
+			reveals = append(reveals, r.reveals[p])
+		}
+	}
+	sort.Strings(res.Contributors)
+	sort.Strings(res.Missing)
+	sort.Strings(res.Aborted)
+	sort.Strings(res.Equivocated)
+
+	if len(reveals) == 0 {
+		return res, errors.Wrap(ErrIncomplete, "no valid reveals")
+	}
+	res.Seed = Combine(r.hash, r.id, reveals)
+
+	if len(res.Missing)+len(res.Aborted)+len(res.Equivocated) > 0 {
+		return res, ErrIncomplete
+	}
+	return res, nil
+}
+
+// Combine hashes the round identifier and every contribution, ordered by
+// party, into a seed of the hash's output size. The order of reveals does
+// not matter. The reveals must already have been checked. It panics if h is
+// not a registered hash type.
+func Combine(h hasher.HashType, roundID []byte, reveals []*Reveal) []byte {
+	sorted := make([]*Reveal, len(reveals))
+	copy(sorted, reveals)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Party < sorted[j].Party
+	})
+
+	hash, err := h.New()
+	if err != nil {
+		jww.FATAL.Panicf("Failed to combine reveals: %+v", err)
+	}
+	writeField(hash, []byte(seedDomain))
+	writeField(hash, roundID)
+	for _, rv := range sorted {
+		writeField(hash, []byte(rv.Party))
+		writeField(hash, rv.Contribution)
+	}
+	return hash.Sum(nil)
+}
@@ -0,0 +1,310 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package commitreveal
+
+import (
+	"bytes"
+	"crypto/rand"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+	"gitlab.com/xx_network/crypto/hasher"
+	"gitlab.com/xx_network/crypto/signature/ec"
+)
+
+var testRoundID = []byte("test round")
+
+// commitAll has every party commit and returns their commitments and reveals
+func commitAll(t *testing.T, parties []string,
+	signers map[string]Signer) ([]*Commitment, []*Reveal) {
+	var commitments []*Commitment
+	var reveals []*Reveal
+	for _, p := range parties {
+		c, r, err := Commit(rand.Reader, hasher.SHA3_256, testRoundID, p,
+			signers[p])
+		if err != nil {
+			t.Fatal(err)
+		}
+		commitments = append(commitments, c)
+		reveals = append(reveals, r)
+	}
+	return commitments, reveals
+}
+
+// Happy path: every party commits and reveals and the seed is the
+// combination of every contribution
+func TestRound_Honest(t *testing.T) {
+	parties := []string{"carol", "alice", "bob"}
+	round, err := NewRound(testRoundID, hasher.SHA3_256, parties, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitments, reveals := commitAll(t, parties, nil)
+
+	for _, c := range commitments {
+		if err = round.AddCommitment(c); err != nil {
+			t.Fatalf("AddCommitment failed: %+v", err)
+		}
+	}
+	if err = round.AddReveal(reveals[0]); err == nil {
+		t.Errorf("Reveal accepted during the commit phase")
+	}
+	round.CloseCommitments()
+	if round.Phase() != RevealPhase {
+		t.Errorf("Round is not in the reveal phase")
+	}
+	for _, r := range reveals {
+		if err = round.AddReveal(r); err != nil {
+			t.Fatalf("AddReveal failed: %+v", err)
+		}
+	}
+
+	res, err := round.Result()
+	if err != nil {
+		t.Fatalf("Result failed: %+v", err)
+	}
+	if !reflect.DeepEqual(res.Contributors, []string{"alice", "bob", "carol"}) {
+		t.Errorf("Unexpected contributors %v", res.Contributors)
+	}
+	expected := Combine(hasher.SHA3_256, testRoundID,
+		[]*Reveal{reveals[2], reveals[0], reveals[1]})
+	if !bytes.Equal(res.Seed, expected) {
+		t.Errorf("Seed does not match Combine")
+	}
+	if len(res.Seed) != 32 {
+		t.Errorf("Seed has %d bytes", len(res.Seed))
+	}
+}
+
+// Tests that a party that commits but does not reveal is reported as aborted
+// and one that never commits as missing
+func TestRound_AbortAndMissing(t *testing.T) {
+	parties := []string{"alice", "bob", "carol"}
+	round, err := NewRound(testRoundID, hasher.SHA3_256, parties, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitments, reveals := commitAll(t, parties, nil)
+	for _, c := range commitments[:2] {
+		if err = round.AddCommitment(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	round.CloseCommitments()
+	if err = round.AddCommitment(commitments[2]); err == nil {
+		t.Errorf("Commitment accepted after the commit phase closed")
+	}
+	if err = round.AddReveal(reveals[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err = round.AddReveal(reveals[2]); err == nil {
+		t.Errorf("Reveal without a commitment accepted")
+	}
+
+	res, err := round.Result()
+	if errors.Cause(err) != ErrIncomplete {
+		t.Errorf("Expected ErrIncomplete, got %v", err)
+	}
+	if !reflect.DeepEqual(res.Contributors, []string{"alice"}) ||
+		!reflect.DeepEqual(res.Aborted, []string{"bob"}) ||
+		!reflect.DeepEqual(res.Missing, []string{"carol"}) {
+		t.Errorf("Unexpected result %+v", res)
+	}
+	if !bytes.Equal(res.Seed,
+		Combine(hasher.SHA3_256, testRoundID, reveals[:1])) {
+		t.Errorf("Seed does not combine the valid reveals")
+	}
+}
+
+// Tests that conflicting commitments are reported as equivocation and
+// excluded from the seed, while a bad reveal is rejected without blaming the
+// named party
+func TestRound_Equivocation(t *testing.T) {
+	parties := []string{"alice", "bob", "carol"}
+	round, err := NewRound(testRoundID, hasher.SHA3_256, parties, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitments, reveals := commitAll(t, parties, nil)
+	for _, c := range commitments {
+		if err = round.AddCommitment(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Resending the same commitment is not equivocation
+	if err = round.AddCommitment(commitments[0]); err != nil {
+		t.Errorf("Repeated commitment rejected: %+v", err)
+	}
+	second, _, err := Commit(rand.Reader, hasher.SHA3_256, testRoundID, "bob",
+		nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = round.AddCommitment(second); errors.Cause(err) != ErrEquivocation {
+		t.Errorf("Expected ErrEquivocation, got %v", err)
+	}
+
+	round.CloseCommitments()
+	bad := *reveals[2]
+	bad.Contribution = make([]byte, ContributionSize)
+	if err = round.AddReveal(&bad); errors.Cause(err) != ErrInvalidReveal {
+		t.Errorf("Expected ErrInvalidReveal, got %v", err)
+	}
+	for _, r := range reveals {
+		_ = round.AddReveal(r)
+	}
+
+	res, err := round.Result()
+	if errors.Cause(err) != ErrIncomplete {
+		t.Errorf("Expected ErrIncomplete, got %v", err)
+	}
+	if !reflect.DeepEqual(res.Contributors, []string{"alice", "carol"}) ||
+		!reflect.DeepEqual(res.Equivocated, []string{"bob"}) {
+		t.Errorf("Unexpected result %+v", res)
+	}
+}
+
+// Tests that a forged reveal sent after a party's valid reveal neither blames
+// the party nor changes the seed
+func TestRound_AddReveal_ForgedAfterValid(t *testing.T) {
+	parties := []string{"alice", "bob", "carol"}
+	round, err := NewRound(testRoundID, hasher.SHA3_256, parties, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitments, reveals := commitAll(t, parties, nil)
+	for _, c := range commitments {
+		if err = round.AddCommitment(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	round.CloseCommitments()
+	for _, r := range reveals {
+		if err = round.AddReveal(r); err != nil {
+			t.Fatalf("AddReveal failed: %+v", err)
+		}
+	}
+	expected, err := round.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forged := &Reveal{Party: "bob", Contribution: make([]byte, ContributionSize),
+		Salt: make([]byte, SaltSize)}
+	_ = round.AddReveal(forged)
+	_ = round.AddReveal(&Reveal{Party: "carol"})
+
+	res, err := round.Result()
+	if err != nil {
+		t.Fatalf("Result failed after forged reveals: %+v", err)
+	}
+	if !bytes.Equal(res.Seed, expected.Seed) {
+		t.Errorf("Forged reveal changed the seed")
+	}
+	if !reflect.DeepEqual(res.Contributors, parties) ||
+		len(res.Equivocated) != 0 {
+		t.Errorf("Unexpected result %+v", res)
+	}
+}
+
+// Error path: commitments for the wrong round, hash or party, or without a
+// valid signature, are rejected
+func TestRound_AddCommitment_Invalid(t *testing.T) {
+	alice, err := ec.NewKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mallory, err := ec.NewKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifiers := map[string]Verifier{"alice": NewECVerifier(alice.GetPublic())}
+	round, err := NewRound(testRoundID, hasher.SHA3_256, []string{"alice"},
+		verifiers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commit := func(h hasher.HashType, id []byte, party string,
+		s Signer) *Commitment {
+		c, _, err := Commit(rand.Reader, h, id, party, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	invalid := []*Commitment{
+		commit(hasher.SHA3_256, testRoundID, "alice", nil),
+		commit(hasher.SHA3_256, testRoundID, "alice", NewECSigner(mallory)),
+		commit(hasher.SHA3_256, []byte("other"), "alice", NewECSigner(alice)),
+		commit(hasher.BLAKE2, testRoundID, "alice", NewECSigner(alice)),
+		commit(hasher.SHA3_256, testRoundID, "bob", NewECSigner(alice)),
+	}
+	for i, c := range invalid {
+		if round.AddCommitment(c) == nil {
+			t.Errorf("Invalid commitment %d accepted", i)
+		}
+	}
+	valid := commit(hasher.SHA3_256, testRoundID, "alice", NewECSigner(alice))
+	if err = round.AddCommitment(valid); err != nil {
+		t.Errorf("Valid signed commitment rejected: %+v", err)
+	}
+}
+
+// Error path: NewRound rejects bad parameters
+func TestNewRound_Errors(t *testing.T) {
+	if _, err := NewRound(testRoundID, hasher.SHA3_256, nil, nil); err == nil {
+		t.Errorf("Round without parties created")
+	}
+	if _, err := NewRound(testRoundID, hasher.SHA3_256,
+		[]string{"a", "a"}, nil); err == nil {
+		t.Errorf("Round with a duplicate party created")
+	}
+	if _, err := NewRound(testRoundID, hasher.HashType(200),
+		[]string{"a"}, nil); err == nil {
+		t.Errorf("Round with an unknown hash created")
+	}
+	if _, err := NewRound(testRoundID, hasher.SHA3_256, []string{"a", "b"},
+		map[string]Verifier{"a": NewECVerifier(nil)}); err == nil {
+		t.Errorf("Round with a missing verifier created")
+	}
+}
+
+// Tests that Result before closing commitments errors and that a round
+// with no valid reveals has no seed
+func TestRound_Result_Early(t *testing.T) {
+	round, err := NewRound(testRoundID, hasher.SHA3_256, []string{"a"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = round.Result(); err == nil {
+		t.Errorf("Result returned before the commit phase closed")
+	}
+	round.CloseCommitments()
+	res, err := round.Result()
+	if errors.Cause(err) != ErrIncomplete || res.Seed != nil {
+		t.Errorf("Expected no seed and ErrIncomplete, got %v", err)
+	}
+}
+
+// Tests that Combine depends on the round and every contribution
+func TestCombine(t *testing.T) {
+	_, reveals := commitAll(t, []string{"a", "b"}, nil)
+	seed := Combine(hasher.SHA3_256, testRoundID, reveals)
+	if bytes.Equal(seed, Combine(hasher.SHA3_256, []byte("x"), reveals)) {
+		t.Errorf("Seed does not depend on the round")
+	}
+	if bytes.Equal(seed, Combine(hasher.SHA3_256, testRoundID, reveals[:1])) {
+		t.Errorf("Seed does not depend on every contribution")
+	}
+	if !bytes.Equal(seed, Combine(hasher.SHA3_256, testRoundID,
+		[]*Reveal{reveals[1], reveals[0]})) {
+		t.Errorf("Seed depends on reveal order")
+	}
+}
@@ -0,0 +1,97 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package commitreveal
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"io"
+
+	"github.com/pkg/errors"
+	"gitlab.com/xx_network/crypto/signature/ec"
+	"gitlab.com/xx_network/crypto/signature/rsa"
+)
+
+// Signer signs commitments on behalf of a party.
+type Signer interface {
+	Sign(msg []byte) ([]byte, error)
+}
+
+// Verifier checks commitment signatures from a party. It returns nil if the
+// signature is valid.
+type Verifier interface {
+	Verify(msg, sig []byte) error
+}
+
+// ecSigner signs with an ed25519 key.
+type ecSigner struct {
+	priv *ec.PrivateKey
+}
+
+// NewECSigner returns a Signer using the ed25519 private key.
+func NewECSigner(priv *ec.PrivateKey) Signer {
+	return &ecSigner{priv: priv}
+}
+
+// Sign returns the ed25519 signature of msg.
+func (s *ecSigner) Sign(msg []byte) ([]byte, error) {
+	return ec.Sign(s.priv, msg), nil
+}
+
+// ecVerifier verifies with an ed25519 key.
+type ecVerifier struct {
+	pub *ec.PublicKey
+}
+
+// NewECVerifier returns a Verifier using the ed25519 public key.
+func NewECVerifier(pub *ec.PublicKey) Verifier {
+	return &ecVerifier{pub: pub}
+}
+
+// Verify checks the ed25519 signature of msg.
+func (v *ecVerifier) Verify(msg, sig []byte) error {
+	if !ec.Verify(v.pub, msg, sig) {
+		return errors.New("invalid ed25519 commitment signature")
+	}
+	return nil
+}
+
+// rsaSigner signs with an RSA key using RSASSA-PSS over SHA-256.
+type rsaSigner struct {
+	rng  io.Reader
+	priv *rsa.PrivateKey
+}
+
+// NewRSASigner returns a Signer using the RSA private key. The randomness
+// source is used for the PSS salt.
+func NewRSASigner(rng io.Reader, priv *rsa.PrivateKey) Signer {
+	return &rsaSigner{rng: rng, priv: priv}
+}
+
+// Sign returns the RSASSA-PSS signature of the SHA-256 hash of msg.
+func (s *rsaSigner) Sign(msg []byte) ([]byte, error) {
+	hashed := sha256.Sum256(msg)
+	return rsa.Sign(s.rng, s.priv, crypto.SHA256, hashed[:], nil)
+}
+
+// rsaVerifier verifies with an RSA key.
+type rsaVerifier struct {
+	pub *rsa.PublicKey
+}
+
+// NewRSAVerifier returns a Verifier using the RSA public key.
+func NewRSAVerifier(pub *rsa.PublicKey) Verifier {
+	return &rsaVerifier{pub: pub}
+}
+
+// Verify checks the RSASSA-PSS signature of the SHA-256 hash of msg.
+func (v *rsaVerifier) Verify(msg, sig []byte) error {
+	hashed := sha256.Sum256(msg)
+	err := rsa.Verify(v.pub, crypto.SHA256, hashed[:], sig, nil)
+	return errors.Wrap(err, "invalid RSA commitment signature")
+}
@@ -0,0 +1,73 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package commitreveal
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"gitlab.com/xx_network/crypto/hasher"
+	"gitlab.com/xx_network/crypto/signature/ec"
+	"gitlab.com/xx_network/crypto/signature/rsa"
+)
+
+// Tests that signed commitments verify with the matching key only, for
+// both ed25519 and RSA keys
+func TestSigners(t *testing.T) {
+	ecKey, err := ec.NewKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecOther, err := ec.NewKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaOther, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		signer        Signer
+		verifier, bad Verifier
+	}{
+		{"ec", NewECSigner(ecKey), NewECVerifier(ecKey.GetPublic()),
+			NewECVerifier(ecOther.GetPublic())},
+		{"rsa", NewRSASigner(rand.Reader, rsaKey),
+			NewRSAVerifier(rsaKey.GetPublic()),
+			NewRSAVerifier(rsaOther.GetPublic())},
+	}
+	for _, tt := range tests {
+		c, _, err := Commit(rand.Reader, hasher.SHA3_256, []byte("round"),
+			"alice", tt.signer)
+		if err != nil {
+			t.Fatalf("%s: Commit failed: %+v", tt.name, err)
+		}
+		if err = c.VerifySignature(tt.verifier); err != nil {
+			t.Errorf("%s: signature did not verify: %+v", tt.name, err)
+		}
+		if c.VerifySignature(tt.bad) == nil {
+			t.Errorf("%s: signature verified with the wrong key", tt.name)
+		}
+
+		c.Digest[0] ^= 1
+		if c.VerifySignature(tt.verifier) == nil {
+			t.Errorf("%s: signature verified over a changed digest",
+				tt.name)
+		}
+		c.Signature = nil
+		if c.VerifySignature(tt.verifier) == nil {
+			t.Errorf("%s: unsigned commitment verified", tt.name)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package csprng
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20"
+)
+
+const (
+	// DefaultChaChaReseedBytes is the default number of bytes a ChaChaRNG
+	// outputs before it reseeds from its entropy source.
+	DefaultChaChaReseedBytes = 1 << 30
+
+	// DefaultChaChaReseedPeriod is the default amount of time after which a
+	// ChaChaRNG reseeds from its entropy source.
+	DefaultChaChaReseedPeriod = 5 * time.Minute
+
+	// chachaMaxChunk is the most keystream generated under a single key
+	// before the generator rekeys, well below the ChaCha20 counter limit.
+	chachaMaxChunk = 1 << 20
+
+	// chachaBufferSize is the size of the keystream buffer small reads are
+	// served from. The first chacha20.KeySize bytes of every buffer become
+	// the next key.
+	chachaBufferSize = 768
+)
+
+// ChaChaRNGParams configures the reseeding behaviour of a ChaChaRNG.
+type ChaChaRNGParams struct {
+	// ReseedBytes is the number of output bytes after which the generator
+	// reseeds. Zero disables byte based reseeding.
+	ReseedBytes uint64
+
+	// ReseedPeriod is the amount of time after which the generator reseeds.
+	// Zero disables time based reseeding.
+	ReseedPeriod time.Duration
+
+	// Entropy is the source the generator is seeded from. Defaults to
+	// SystemRNG when nil.
+	Entropy io.Reader
+}
+
+// DefaultChaChaRNGParams returns the default ChaChaRNG parameters.
+func DefaultChaChaRNGParams() ChaChaRNGParams {
+	return ChaChaRNGParams{
+		ReseedBytes:  DefaultChaChaReseedBytes,
+		ReseedPeriod: DefaultChaChaReseedPeriod,
+		Entropy:      NewSystemRNG(),
+	}
+}
+
+// ChaChaRNG is a userspace generator built on the ChaCha20 keystream using
+// the fast-key-erasure construction: every block of keystream begins with 32
+// bytes that replace the key before any of the block is returned, and bytes
+// are erased from the buffer as soon as they are read, so that a compromise
+// of the current state does not reveal earlier output. Small reads are served
+// from a buffered block; large reads rekey before and after. The key is seeded
+// from SystemRNG (or the configured entropy source) on first use and reseeded
+// after a configurable number of bytes or amount of time.
+//
+// A ChaChaRNG is safe for concurrent use, but concurrent readers contend on a
+// single lock; use a SourcePool to give each goroutine its own generator.
+type ChaChaRNG struct {
+	params ChaChaRNGParams
+
+	key       [chacha20.KeySize]byte
+	buf       [chachaBufferSize]byte
+	bufIdx    int
+	seeded    bool
+	generated uint64
+	seededAt  time.Time
+
+	mux sync.Mutex
+}
+
+// NewChaChaRNG returns a ChaChaRNG with the default parameters under the
+// Source interface. It is a SourceConstructor.
+func NewChaChaRNG() Source {
+	return NewChaChaRNGWithParams(DefaultChaChaRNGParams())
+}
+
+// NewChaChaRNGWithParams returns a ChaChaRNG with the given parameters. The
+// generator is seeded lazily on the first call to Read.
+func NewChaChaRNGWithParams(params ChaChaRNGParams) *ChaChaRNG {
+	if params.Entropy == nil {
+		params.Entropy = NewSystemRNG()
+	}
+	return &ChaChaRNG{params: params}
+}
+
+// Read fills b with keystream output, reseeding from the entropy source first
+// when the reseed budget is spent. On error, the returned count is the number
+// of bytes of b that were filled before the failure.
+func (c *ChaChaRNG) Read(b []byte) (int, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if !c.seeded || c.reseedDue() {
+		if err := c.reseed(nil); err != nil {
+			return 0, err
+		}
+	}
+
+	if len(b) <= len(c.buf)-chacha20.KeySize {
+		// Serve small reads from the buffer, erasing what is handed out
+		if len(c.buf)-c.bufIdx < len(b) {
+			if _, err := c.fill(c.buf[:]); err != nil {
+				return 0, err
+			}
+			copy(c.key[:], c.buf[:chacha20.KeySize])
+			for i := 0; i < chacha20.KeySize; i++ {
+				c.buf[i] = 0
+			}
+			c.bufIdx = chacha20.KeySize
+		}
+		served := c.buf[c.bufIdx : c.bufIdx+len(b)]
+		copy(b, served)
+		for i := range served {
+			served[i] = 0
+		}
+		c.bufIdx += len(b)
+	} else {
+		for n := 0; n < len(b); {
+			end := n + chachaMaxChunk
+			if end > len(b) {
+				end = len(b)
+			}
+			if err := c.rekeyAndFill(b[n:end]); err != nil {
+				c.generated += uint64(n)
+				return n, err
+			}
+			n = end
+		}
+	}
+
+	c.generated += uint64(len(b))
+	return len(b), nil
+}
+
+// SetSeed mixes the seed into the generator together with fresh entropy from
+// the entropy source. The seed supplements the entropy source; it does not
+// make the output deterministic.
+func (c *ChaChaRNG) SetSeed(seed []byte) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.reseed(seed)
+}
+
+// Reseed immediately mixes fresh entropy from the entropy source into the
+// generator.
+func (c *ChaChaRNG) Reseed() error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.reseed(nil)
+}
+
+// rekeyAndFill writes keystream into b after replacing the key with the
+// first 32 bytes of keystream. The caller must hold the lock.
+func (c *ChaChaRNG) rekeyAndFill(b []byte) error {
+	var newKey [chacha20.KeySize]byte
+	cipher, err := c.fill(newKey[:])
+	if err != nil {
+		return err
+	}
+	c.key = newKey
+
+	for i := range b {
+		b[i] = 0
+	}
+	cipher.XORKeyStream(b, b)
+	return nil
+}
+
+// fill writes keystream under the current key into b and returns the cipher
+// so the caller can continue the stream. The caller must hold the lock.
+func (c *ChaChaRNG) fill(b []byte) (*chacha20.Cipher, error) {
+	var nonce [chacha20.NonceSize]byte
+	cipher, err := chacha20.NewUnauthenticatedCipher(c.key[:], nonce[:])
+	if err != nil {
+		// Only possible with invalid key or nonce sizes
+		return nil, errors.Wrap(err, "failed to create ChaCha20 cipher")
+	}
+
+	for i := range b {
+		b[i] = 0
+	}
+	cipher.XORKeyStream(b, b)
+	return cipher, nil
+}
+
+// reseed hashes the current key, fresh entropy and any extra seed material
+// into a new key. The caller must hold the lock.
+func (c *ChaChaRNG) reseed(extra []byte) error {
+	var fresh [chacha20.KeySize]byte
+	n, err := io.ReadFull(c.params.Entropy, fresh[:])
+	if err != nil {
+		return errors.Wrapf(err, "failed to reseed ChaChaRNG, read %d of "+
+			"%d bytes", n, len(fresh))
+	}
+
+	h, _ := blake2b.New256(nil)
+	h.Write(c.key[:])
+	h.Write(fresh[:])
+	h.Write(extra)
+	h.Sum(c.key[:0])
+
+	// Discard any buffered output produced under the previous key
+	for i := range c.buf {
+		c.buf[i] = 0
+	}
+	c.bufIdx = len(c.buf)
+	c.seeded = true
+	c.generated = 0
+	c.seededAt = time.Now()
+	return nil
+}
+
+// reseedDue returns true if the byte or time budget since the last reseed
+// has been spent. The caller must hold the lock.
+func (c *ChaChaRNG) reseedDue() bool {
+	if c.params.ReseedBytes != 0 && c.generated >= c.params.ReseedBytes {
+		return true
+	}
+	return c.params.ReseedPeriod != 0 &&
+		time.Since(c.seededAt) >= c.params.ReseedPeriod
+}
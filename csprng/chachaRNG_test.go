@@ -0,0 +1,208 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package csprng
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+// countingEntropy counts the reads made against it and returns a fixed
+// pattern.
+type countingEntropy struct {
+	reads int
+	err   error
+}
+
+func (c *countingEntropy) Read(b []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	c.reads++
+	for i := range b {
+		b[i] = byte(c.reads)
+	}
+	return len(b), nil
+}
+
+// Tests that NewChaChaRNG meets the source constructor and returns a
+// ChaChaRNG under the interface.
+func TestNewChaChaRNG(t *testing.T) {
+	var sc SourceConstructor = NewChaChaRNG
+	if _, ok := sc().(*ChaChaRNG); !ok {
+		t.Errorf("NewChaChaRNG did not return a ChaChaRNG pointer under " +
+			"the interface.")
+	}
+}
+
+// Tests that consecutive reads differ, that served bytes are erased from the
+// buffer and that the key is replaced whenever the buffer is refilled.
+func TestChaChaRNG_Read(t *testing.T) {
+	c := NewChaChaRNGWithParams(DefaultChaChaRNGParams())
+
+	seen := make(map[string]bool)
+	keys := make(map[[32]byte]bool)
+	for i := 0; i < 100; i++ {
+		out := make([]byte, 64)
+		n, err := c.Read(out)
+		if err != nil || n != len(out) {
+			t.Fatalf("Read failed.\nexpected: %d bytes\nreceived: %d bytes, %v",
+				len(out), n, err)
+		}
+		if seen[string(out)] {
+			t.Errorf("Read %d repeated earlier output: %x", i, out)
+		}
+		seen[string(out)] = true
+		keys[c.key] = true
+
+		for j, v := range c.buf[:c.bufIdx] {
+			if v != 0 {
+				t.Fatalf("Read %d left byte %d of the buffer unerased.", i, j)
+			}
+		}
+	}
+
+	// 100 reads of 64 bytes span at least 8 buffers of 736 bytes
+	if len(keys) < 8 {
+		t.Errorf("The key was not replaced on every refill."+
+			"\nexpected: at least %d keys\nreceived: %d", 8, len(keys))
+	}
+}
+
+// Tests that reads larger than a single chunk are filled completely.
+func TestChaChaRNG_Read_Large(t *testing.T) {
+	c := NewChaChaRNG()
+	out := make([]byte, 2*chachaMaxChunk+7)
+	n, err := c.Read(out)
+	if err != nil || n != len(out) {
+		t.Fatalf("Read failed.\nexpected: %d bytes\nreceived: %d bytes, %v",
+			len(out), n, err)
+	}
+	if bytes.Equal(out[:chachaMaxChunk], out[chachaMaxChunk:2*chachaMaxChunk]) {
+		t.Errorf("Consecutive chunks were identical.")
+	}
+}
+
+// Tests that the generator reseeds once the byte budget is spent.
+func TestChaChaRNG_ReseedBytes(t *testing.T) {
+	entropy := &countingEntropy{}
+	c := NewChaChaRNGWithParams(
+		ChaChaRNGParams{ReseedBytes: 100, Entropy: entropy})
+
+	out := make([]byte, 60)
+	for i := 0; i < 4; i++ {
+		if _, err := c.Read(out); err != nil {
+			t.Fatalf("Read %d returned an error: %+v", i, err)
+		}
+	}
+
+	// Seeded on the first read and reseeded on the third
+	if entropy.reads != 2 {
+		t.Errorf("Unexpected number of reseeds.\nexpected: %d\nreceived: %d",
+			2, entropy.reads)
+	}
+}
+
+// Tests that the generator reseeds once the time budget is spent.
+func TestChaChaRNG_ReseedPeriod(t *testing.T) {
+	entropy := &countingEntropy{}
+	c := NewChaChaRNGWithParams(
+		ChaChaRNGParams{ReseedPeriod: time.Millisecond, Entropy: entropy})
+
+	out := make([]byte, 16)
+	if _, err := c.Read(out); err != nil {
+		t.Fatalf("Read returned an error: %+v", err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if _, err := c.Read(out); err != nil {
+		t.Fatalf("Read returned an error: %+v", err)
+	}
+
+	if entropy.reads != 2 {
+		t.Errorf("Unexpected number of reseeds.\nexpected: %d\nreceived: %d",
+			2, entropy.reads)
+	}
+}
+
+// Tests that SetSeed changes the key.
+func TestChaChaRNG_SetSeed(t *testing.T) {
+	c := NewChaChaRNGWithParams(DefaultChaChaRNGParams())
+	key := c.key
+	if err := c.SetSeed([]byte("operator seed")); err != nil {
+		t.Fatalf("SetSeed returned an error: %+v", err)
+	}
+	if c.key == key {
+		t.Errorf("SetSeed did not change the key.")
+	}
+}
+
+// Error path: tests that entropy source errors are returned from Read.
+func TestChaChaRNG_Read_EntropyError(t *testing.T) {
+	expectedErr := errors.New("entropy failure")
+	c := NewChaChaRNGWithParams(
+		ChaChaRNGParams{Entropy: &countingEntropy{err: expectedErr}})
+
+	n, err := c.Read(make([]byte, 16))
+	if n != 0 || !errors.Is(err, expectedErr) {
+		t.Errorf("Read should fail when the entropy source fails."+
+			"\nexpected: %v\nreceived: %d bytes, %v", expectedErr, n, err)
+	}
+}
+
+// Tests that GenerateInGroup works with a ChaChaRNG.
+func TestChaChaRNG_GenerateInGroup(t *testing.T) {
+	b, err := GenerateInGroup(modp4096, len(modp4096), NewChaChaRNG())
+	if err != nil {
+		t.Fatalf("GenerateInGroup failed: %+v", err)
+	}
+	if !InGroup(b, modp4096) {
+		t.Errorf("Generated value is not in the group: %x", b)
+	}
+}
+
+// Benchmark for GenerateInGroup with a 4096-bit prime using SystemRNG.
+func BenchmarkGenerateInGroup_SystemRNG(b *testing.B) {
+	rng := NewSystemRNG()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = GenerateInGroup(modp4096, len(modp4096), rng)
+	}
+}
+
+// Benchmark for GenerateInGroup with a 4096-bit prime using ChaChaRNG.
+func BenchmarkGenerateInGroup_ChaChaRNG(b *testing.B) {
+	rng := NewChaChaRNG()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = GenerateInGroup(modp4096, len(modp4096), rng)
+	}
+}
+
+// Benchmark for parallel GenerateInGroup with a 4096-bit prime using
+// SystemRNG.
+func BenchmarkGenerateInGroup_SystemRNG_Parallel(b *testing.B) {
+	rng := NewSystemRNG()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _ = GenerateInGroup(modp4096, len(modp4096), rng)
+		}
+	})
+}
+
+// Benchmark for parallel GenerateInGroup with a 4096-bit prime using a pool
+// of ChaChaRNG.
+func BenchmarkGenerateInGroup_ChaChaRNGPool_Parallel(b *testing.B) {
+	pool := NewSourcePool(NewChaChaRNG)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _ = GenerateInGroup(modp4096, len(modp4096), pool)
+		}
+	})
+}
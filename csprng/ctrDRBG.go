@@ -0,0 +1,329 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package csprng
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ctrKeyLen is the AES-256 key length in bytes.
+	ctrKeyLen = 32
+
+	// ctrSeedLen is the CTR_DRBG seed length for AES-256 in bytes.
+	ctrSeedLen = ctrKeyLen + aes.BlockSize
+)
+
+// CtrDRBG is a deterministic random bit generator following the CTR_DRBG
+// construction in NIST SP 800-90A Rev. 1 section 10.2 with AES-256.
+//
+// When the derivation function is enabled, entropy inputs of any length of at
+// least 32 bytes are accepted and condensed with Block_Cipher_df. Without it,
+// entropy inputs must be exactly 48 bytes and personalization strings and
+// additional inputs may not exceed 48 bytes.
+//
+// The generator starts out uninstantiated. The first call to SetSeed (or
+// Instantiate) instantiates it and every subsequent call to SetSeed reseeds
+// it. Once the reseed interval is exhausted, Read returns ErrReseedRequired
+// until the generator is reseeded.
+type CtrDRBG struct {
+	useDF           bool
+	personalization []byte
+	reseedInterval  uint64
+
+	block         cipher.Block
+	v             [aes.BlockSize]byte
+	reseedCounter uint64
+	instantiated  bool
+
+	mux sync.Mutex
+}
+
+// NewCtrDRBG returns an uninstantiated AES-256 CTR_DRBG. useDF selects
+// whether the derivation function is used. The personalization string is
+// used when the generator is instantiated through SetSeed and may be nil.
+func NewCtrDRBG(useDF bool, personalization []byte) *CtrDRBG {
+	return &CtrDRBG{
+		useDF:           useDF,
+		personalization: append([]byte{}, personalization...),
+		reseedInterval:  MaxReseedInterval,
+	}
+}
+
+// NewCtrDRBGFromSeed returns an AES-256 CTR_DRBG instantiated with the given
+// entropy input, nonce and personalization string. The nonce is ignored when
+// the derivation function is disabled.
+func NewCtrDRBGFromSeed(useDF bool, entropy, nonce,
+	personalization []byte) (*CtrDRBG, error) {
+	d := NewCtrDRBG(useDF, personalization)
+	if err := d.Instantiate(entropy, nonce, personalization); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// SetReseedInterval sets the number of generate requests allowed between
+// reseeds. The interval must be between 1 and MaxReseedInterval.
+func (d *CtrDRBG) SetReseedInterval(interval uint64) error {
+	if interval == 0 || interval > MaxReseedInterval {
+		return errors.Errorf("reseed interval must be between 1 and %d, "+
+			"received %d", MaxReseedInterval, interval)
+	}
+	d.mux.Lock()
+	d.reseedInterval = interval
+	d.mux.Unlock()
+	return nil
+}
+
+// Instantiate seeds the generator from the entropy input, nonce and
+// personalization string as described in SP 800-90A sections 10.2.1.3.1 and
+// 10.2.1.3.2. Any previous state is discarded.
+func (d *CtrDRBG) Instantiate(entropy, nonce, personalization []byte) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return d.instantiate(entropy, nonce, personalization)
+}
+
+// Reseed mixes fresh entropy and optional additional input into the state of
+// an instantiated generator and resets the reseed counter.
+func (d *CtrDRBG) Reseed(entropy, additionalInput []byte) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return d.reseed(entropy, additionalInput)
+}
+
+// Generate fills b with pseudorandom bytes, mixing in the optional additional
+// input. A single request may not exceed MaxBytesPerRequest.
+func (d *CtrDRBG) Generate(b, additionalInput []byte) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return d.generate(b, additionalInput)
+}
+
+// Read fills b with pseudorandom bytes. Requests larger than
+// MaxBytesPerRequest are split into several generate calls.
+func (d *CtrDRBG) Read(b []byte) (int, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	n := 0
+	for n < len(b) {
+		end := n + MaxBytesPerRequest
+		if end > len(b) {
+			end = len(b)
+		}
+		if err := d.generate(b[n:end], nil); err != nil {
+			return n, err
+		}
+		n = end
+	}
+	return n, nil
+}
+
+// SetSeed instantiates the generator with the seed as entropy input on first
+// use, and reseeds it with the seed on every call after that.
+func (d *CtrDRBG) SetSeed(seed []byte) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	if !d.instantiated {
+		return d.instantiate(seed, nil, d.personalization)
+	}
+	return d.reseed(seed, nil)
+}
+
+// instantiate implements the CTR_DRBG instantiate process. The caller must
+// hold the lock.
+func (d *CtrDRBG) instantiate(entropy, nonce, personalization []byte) error {
+	seedMaterial, err := d.seedMaterial(entropy, nonce, personalization)
+	if err != nil {
+		return err
+	}
+
+	d.block, _ = aes.NewCipher(make([]byte, ctrKeyLen))
+	d.v = [aes.BlockSize]byte{}
+	d.update(seedMaterial)
+	d.reseedCounter = 1
+	d.instantiated = true
+	return nil
+}
+
+// reseed implements the CTR_DRBG reseed process. The caller must hold the
+// lock.
+func (d *CtrDRBG) reseed(entropy, additionalInput []byte) error {
+	if !d.instantiated {
+		return ErrNotInstantiated
+	}
+
+	seedMaterial, err := d.seedMaterial(entropy, nil, additionalInput)
+	if err != nil {
+		return err
+	}
+
+	d.update(seedMaterial)
+	d.reseedCounter = 1
+	return nil
+}
+
+// generate implements the CTR_DRBG generate process. The caller must hold the
+// lock.
+func (d *CtrDRBG) generate(b, additionalInput []byte) error {
+	if !d.instantiated {
+		return ErrNotInstantiated
+	}
+	if len(b) > MaxBytesPerRequest {
+		return errors.Errorf(requestTooLargeErr, len(b),
+			MaxBytesPerRequest)
+	}
+	if d.reseedCounter > d.reseedInterval {
+		return ErrReseedRequired
+	}
+
+	additional := make([]byte, ctrSeedLen)
+	if len(additionalInput) > 0 {
+		if d.useDF {
+			additional = blockCipherDF(additionalInput, ctrSeedLen)
+		} else if len(additionalInput) > ctrSeedLen {
+			return errors.Errorf("additional input of %d bytes exceeds "+
+				"the seed length of %d bytes", len(additionalInput),
+				ctrSeedLen)
+		} else {
+			copy(additional, additionalInput)
+		}
+		d.update(additional)
+	}
+
+	var block [aes.BlockSize]byte
+	for n := 0; n < len(b); {
+		incrementCounter(&d.v)
+		d.block.Encrypt(block[:], d.v[:])
+		n += copy(b[n:], block[:])
+	}
+
+	d.update(additional)
+	d.reseedCounter++
+	return nil
+}
+
+// seedMaterial builds the seed material for instantiation and reseeding. With
+// the derivation function the inputs are concatenated and condensed,
+// otherwise the entropy input is XORed with the zero padded extra input.
+func (d *CtrDRBG) seedMaterial(entropy, nonce, extra []byte) ([]byte, error) {
+	if d.useDF {
+		if len(entropy) < ctrKeyLen {
+			return nil, errors.Errorf(entropyTooShortErr, len(entropy),
+				ctrKeyLen)
+		}
+		input := make([]byte, 0, len(entropy)+len(nonce)+len(extra))
+		input = append(input, entropy...)
+		input = append(input, nonce...)
+		input = append(input, extra...)
+		return blockCipherDF(input, ctrSeedLen), nil
+	}
+
+	if len(entropy) != ctrSeedLen {
+		return nil, errors.Errorf("entropy input must be exactly %d bytes "+
+			"without a derivation function, received %d", ctrSeedLen,
+			len(entropy))
+	}
+	if len(extra) > ctrSeedLen {
+		return nil, errors.Errorf("personalization string or additional "+
+			"input of %d bytes exceeds the seed length of %d bytes",
+			len(extra), ctrSeedLen)
+	}
+
+	seedMaterial := make([]byte, ctrSeedLen)
+	copy(seedMaterial, extra)
+	for i := range seedMaterial {
+		seedMaterial[i] ^= entropy[i]
+	}
+	return seedMaterial, nil
+}
+
+// update implements the CTR_DRBG_Update function. providedData must be
+// exactly ctrSeedLen bytes.
+func (d *CtrDRBG) update(providedData []byte) {
+	temp := make([]byte, ctrSeedLen)
+	for n := 0; n < ctrSeedLen; n += aes.BlockSize {
+		incrementCounter(&d.v)
+		d.block.Encrypt(temp[n:], d.v[:])
+	}
+	for i := range temp {
+		temp[i] ^= providedData[i]
+	}
+
+	d.block, _ = aes.NewCipher(temp[:ctrKeyLen])
+	copy(d.v[:], temp[ctrKeyLen:])
+}
+
+// incrementCounter increments the big-endian block counter modulo 2^128.
+func incrementCounter(v *[aes.BlockSize]byte) {
+	for i := len(v) - 1; i >= 0; i-- {
+		v[i]++
+		if v[i] != 0 {
+			return
+		}
+	}
+}
+
+// blockCipherDF implements Block_Cipher_df from SP 800-90A section 10.3.2
+// with AES-256, returning outLen bytes derived from input.
+func blockCipherDF(input []byte, outLen int) []byte {
+	// S = L || N || input || 0x80, zero padded to a multiple of the block size
+	s := make([]byte, 8, 8+len(input)+1+aes.BlockSize)
+	binary.BigEndian.PutUint32(s[0:4], uint32(len(input)))
+	binary.BigEndian.PutUint32(s[4:8], uint32(outLen))
+	s = append(s, input...)
+	s = append(s, 0x80)
+	for len(s)%aes.BlockSize != 0 {
+		s = append(s, 0x00)
+	}
+
+	key := make([]byte, ctrKeyLen)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	block, _ := aes.NewCipher(key)
+
+	temp := make([]byte, 0, ctrSeedLen)
+	iv := make([]byte, aes.BlockSize)
+	for i := uint32(0); len(temp) < ctrSeedLen; i++ {
+		binary.BigEndian.PutUint32(iv[0:4], i)
+		temp = append(temp, bcc(block, iv, s)...)
+	}
+
+	block, _ = aes.NewCipher(temp[:ctrKeyLen])
+	x := temp[ctrKeyLen:ctrSeedLen]
+	out := make([]byte, 0, outLen+aes.BlockSize)
+	for len(out) < outLen {
+		block.Encrypt(x, x)
+		out = append(out, x...)
+	}
+	return out[:outLen]
+}
+
+// bcc implements the BCC function from SP 800-90A section 10.3.3 over the
+// concatenation of iv and data, which must both be multiples of the block
+// size.
+func bcc(block cipher.Block, iv, data []byte) []byte {
+	chain := make([]byte, aes.BlockSize)
+	for _, in := range [][]byte{iv, data} {
+		for n := 0; n < len(in); n += aes.BlockSize {
+			for i := 0; i < aes.BlockSize; i++ {
+				chain[i] ^= in[n+i]
+			}
+			block.Encrypt(chain, chain)
+		}
+	}
+	return chain
+}
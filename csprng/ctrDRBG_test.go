@@ -0,0 +1,293 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package csprng
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+// ctrDRBGVector is a known-answer test. The expected output is the result of
+// the second generate call, as in the NIST CAVP CTR_DRBG.rsp vectors.
+type ctrDRBGVector struct {
+	name             string
+	useDF            bool
+	entropy          string
+	nonce            string
+	personalization  string
+	reseedEntropy    string
+	reseedAdditional string
+	additional1      string
+	additional2      string
+	returned         string
+}
+
+var ctrDRBGVectors = []ctrDRBGVector{
+	{
+		// NIST CAVP CTR_DRBG.rsp [AES-256 use df] no reseed, COUNT = 0
+		name:    "CAVP AES-256 use df",
+		useDF:   true,
+		entropy: "36401940fa8b1fba91a1661f211d78a0b9389a74e5bccfece8d766af1a6d3b14",
+		nonce:   "496f25b0f1301b4f501be30380a137eb",
+		returned: "5862eb38bd558dd978a696e6df164782ddd887e7e9a6c9f3f1fbafb78941b535" +
+			"a64912dfd224c6dc7454e5250b3d97165e16260c2faf1cc7735cb75fb4f07e1d",
+	},
+	{
+		// NIST ACVP ctrDRBG-1.0 AES-256 no df, with personalization string,
+		// reseed and additional input
+		name:  "ACVP AES-256 no df",
+		useDF: false,
+		entropy: "9fcbb4ccc0135c484bded061da9fd70748682fe84166b97ff53f9aa1909b2e95" +
+			"d3d529c0f453b3ac575d12aa441cc5cd",
+		personalization: "2c9fed0b39556cdbe699ebca2a0ec7eecb287e8744475050" +
+			"c572fa8ae9ed0a4a7d6f1cabf1c4278532fb20af7d64bd32",
+		reseedEntropy: "913c0da19b010eddd55a7a4f3f713eef5b1534d34360a7ec" +
+			"376ae71a6b340043cc7726f762cb853453f399b3a645062a",
+		reseedAdditional: "2d9d4ec141a22e6cd2f6ee4f6719cf6bdf95cfe50b8d5ea6" +
+			"c87d38b4b872706fff80b0380bb90e9c42d11d6526e56c29",
+		additional1: "a642f06d327828f3e84564a3e37d60c157073b95864ca079" +
+			"81b0189668a0d978cd5dc68f06801ceff0dc839a312b028e",
+		additional2: "9db14babfa9107c88ba92073c0b4a65e89147ea06d74b894" +
+			"142979482f452915b35b5636f9b8a951759735ade7c8d5d1",
+		returned: "f10c645683ff0131254052ed4c698122b46b563654c29d728ac191ca4aaefe64" +
+			"9eefe4c6fc33b25bb739294dd5cf578099f856c98d98000cbf971f1e6ea90082" +
+			"2ff8c110118f6520471744d3f8a3f5c7d568494240e57f5488af9c9f9f4e7322" +
+			"f56ccd843c0dbfce9170c02e205389420527f23edb3369d9fcc5e34901b5ba4e" +
+			"b71b973fc7982ffe0899ff7fe53ee0c4f51a3ef93ef9c6d4d279dd7536f8776b" +
+			"e94aaa05e89ef6e6aee8832b4b42ffca5fb91ec0273f9ef945865512889b0c5e" +
+			"e141d1b38df827d2a694835561628c6f9b093a01a835f07adbb9e03febf93389" +
+			"e8f3b86e1e0abf1f9958fa286ad995289c2f606d1a9043a166c1afe8d00769c7" +
+			"12650819c9068a4bd22717c98338395a7ba6e95b5178bfbf4efb0f05a91713ba" +
+			"8bf2127a6ba1edfa6d1cab05c03ee0d2afe1da4eb8f2c579ec872ff4b602027e" +
+			"f4bdcf2f4b01423f8e600a13d7cacb6ab83263ba58f907694af614a6724fd0e4" +
+			"c627a0d91ddc6716c697face6f4808a4f37b731de4e0cd4766ceadaaaf479925" +
+			"05299c72ac1a6e9a8335b8d7e501b3841188d0da4de5267674444dc2b0cf9f01" +
+			"0756fa865a25ca3f1b24c34e845b2259926b6a867a7684de68a6137c4fb0f47a" +
+			"2e54ae9e6455beba0b0a9629644fe9e378ee95386443ba977124ffd1192e9f46" +
+			"0684c7b09fa99f5f93f04f56fd7955e042187887ce696f1934017e458b16b5c9",
+	},
+}
+
+// Tests that CtrDRBG reproduces the known-answer vectors.
+func TestCtrDRBG_KnownAnswer(t *testing.T) {
+	for _, v := range ctrDRBGVectors {
+		d, err := NewCtrDRBGFromSeed(v.useDF, decodeHexString(t, v.entropy),
+			decodeHexString(t, v.nonce), decodeHexString(t, v.personalization))
+		if err != nil {
+			t.Fatalf("%s: failed to instantiate: %+v", v.name, err)
+		}
+
+		if v.reseedEntropy != "" {
+			err = d.Reseed(decodeHexString(t, v.reseedEntropy),
+				decodeHexString(t, v.reseedAdditional))
+			if err != nil {
+				t.Fatalf("%s: failed to reseed: %+v", v.name, err)
+			}
+		}
+
+		expected := decodeHexString(t, v.returned)
+		received := make([]byte, len(expected))
+		if err = d.Generate(received, decodeHexString(t, v.additional1)); err != nil {
+			t.Fatalf("%s: first generate failed: %+v", v.name, err)
+		}
+		if err = d.Generate(received, decodeHexString(t, v.additional2)); err != nil {
+			t.Fatalf("%s: second generate failed: %+v", v.name, err)
+		}
+
+		if !bytes.Equal(expected, received) {
+			t.Errorf("%s: unexpected output.\nexpected: %x\nreceived: %x",
+				v.name, expected, received)
+		}
+	}
+}
+
+// Tests that SetSeed instantiates the generator on first use so that two
+// generators seeded identically produce identical output.
+func TestCtrDRBG_SetSeed(t *testing.T) {
+	seed := bytes.Repeat([]byte{0x42}, 40)
+	var sc SourceConstructor = func() Source {
+		return NewCtrDRBG(true, []byte("personalization"))
+	}
+
+	a, b := sc(), sc()
+	for _, s := range []Source{a, b} {
+		if err := s.SetSeed(seed); err != nil {
+			t.Fatalf("SetSeed returned an error: %+v", err)
+		}
+	}
+
+	outA, outB := make([]byte, 100), make([]byte, 100)
+	if _, err := a.Read(outA); err != nil {
+		t.Fatalf("Read returned an error: %+v", err)
+	}
+	if _, err := b.Read(outB); err != nil {
+		t.Fatalf("Read returned an error: %+v", err)
+	}
+	if !bytes.Equal(outA, outB) {
+		t.Errorf("Identically seeded generators diverged."+
+			"\nexpected: %x\nreceived: %x", outA, outB)
+	}
+
+	if err := a.SetSeed(seed); err != nil {
+		t.Fatalf("SetSeed returned an error: %+v", err)
+	}
+	fresh := sc()
+	if err := fresh.SetSeed(seed); err != nil {
+		t.Fatalf("SetSeed returned an error: %+v", err)
+	}
+	_, _ = a.Read(outA)
+	_, _ = fresh.Read(outB)
+	if bytes.Equal(outA, outB) {
+		t.Errorf("Second SetSeed reinstantiated instead of reseeding.")
+	}
+}
+
+// Tests that concurrent first calls to SetSeed do not lose a seed.
+func TestCtrDRBG_SetSeed_Concurrent(t *testing.T) {
+	checkConcurrentSetSeed(t, func() Source {
+		return NewCtrDRBG(true, nil)
+	})
+}
+
+// checkConcurrentSetSeed tests that when two goroutines call SetSeed on a new
+// generator, one call instantiates it and the other reseeds it, so that the
+// output is that of one of the two orders and neither seed is lost.
+func checkConcurrentSetSeed(t *testing.T, sc SourceConstructor) {
+	seeds := [][]byte{bytes.Repeat([]byte{1}, 48), bytes.Repeat([]byte{2}, 48)}
+	expected := make(map[string]bool)
+	for _, order := range [][]int{{0, 1}, {1, 0}} {
+		s := sc()
+		for _, i := range order {
+			if err := s.SetSeed(seeds[i]); err != nil {
+				t.Fatalf("SetSeed returned an error: %+v", err)
+			}
+		}
+		out := make([]byte, 32)
+		_, _ = s.Read(out)
+		expected[string(out)] = true
+	}
+
+	for i := 0; i < 1000; i++ {
+		s := sc()
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		for _, seed := range seeds {
+			wg.Add(1)
+			go func(seed []byte) {
+				defer wg.Done()
+				<-start
+				if err := s.SetSeed(seed); err != nil {
+					t.Errorf("SetSeed returned an error: %+v", err)
+				}
+			}(seed)
+		}
+		close(start)
+		wg.Wait()
+
+		out := make([]byte, 32)
+		if _, err := s.Read(out); err != nil {
+			t.Fatalf("Read returned an error: %+v", err)
+		}
+		if !expected[string(out)] {
+			t.Fatalf("Concurrent SetSeed calls lost a seed.")
+		}
+	}
+}
+
+// Tests that the output of an instantiated CtrDRBG can be used by Generate
+// and GenerateInGroup.
+func TestCtrDRBG_Generate(t *testing.T) {
+	d, err := NewCtrDRBGFromSeed(false, make([]byte, ctrSeedLen), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to instantiate: %+v", err)
+	}
+
+	b, err := Generate(32, d)
+	if err != nil || len(b) != 32 {
+		t.Errorf("Generate failed: %+v", err)
+	}
+
+	b, err = GenerateInGroup(modp4096, len(modp4096), d)
+	if err != nil {
+		t.Fatalf("GenerateInGroup failed: %+v", err)
+	}
+	if !InGroup(b, modp4096) {
+		t.Errorf("Generated value is not in the group: %x", b)
+	}
+}
+
+// Tests that Read splits requests larger than MaxBytesPerRequest.
+func TestCtrDRBG_Read_Large(t *testing.T) {
+	d, err := NewCtrDRBGFromSeed(true, make([]byte, 32), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to instantiate: %+v", err)
+	}
+
+	b := make([]byte, 2*MaxBytesPerRequest+5)
+	n, err := d.Read(b)
+	if err != nil || n != len(b) {
+		t.Errorf("Read failed.\nexpected: %d bytes\nreceived: %d bytes, %v",
+			len(b), n, err)
+	}
+
+	if err = d.Generate(b, nil); err == nil {
+		t.Errorf("Generate should reject requests over MaxBytesPerRequest.")
+	}
+}
+
+// Tests that the reseed interval is enforced and that reseeding resets it.
+func TestCtrDRBG_ReseedInterval(t *testing.T) {
+	d, err := NewCtrDRBGFromSeed(true, make([]byte, 32), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to instantiate: %+v", err)
+	}
+	if err = d.SetReseedInterval(1); err != nil {
+		t.Fatalf("SetReseedInterval returned an error: %+v", err)
+	}
+
+	b := make([]byte, 16)
+	if _, err = d.Read(b); err != nil {
+		t.Fatalf("Read returned an error: %+v", err)
+	}
+	if _, err = d.Read(b); err != ErrReseedRequired {
+		t.Errorf("Read should require a reseed.\nexpected: %v\nreceived: %v",
+			ErrReseedRequired, err)
+	}
+
+	if err = d.SetSeed(make([]byte, 32)); err != nil {
+		t.Fatalf("SetSeed returned an error: %+v", err)
+	}
+	if _, err = d.Read(b); err != nil {
+		t.Errorf("Read after reseed returned an error: %+v", err)
+	}
+}
+
+// Error path: tests that invalid inputs are rejected.
+func TestCtrDRBG_Errors(t *testing.T) {
+	if _, err := NewCtrDRBG(true, nil).Read(make([]byte, 1)); err != ErrNotInstantiated {
+		t.Errorf("Read should fail before instantiation."+
+			"\nexpected: %v\nreceived: %v", ErrNotInstantiated, err)
+	}
+	if err := NewCtrDRBG(true, nil).SetSeed(make([]byte, 16)); err == nil {
+		t.Errorf("SetSeed should reject entropy shorter than the key.")
+	}
+	if err := NewCtrDRBG(false, nil).SetSeed(make([]byte, 32)); err == nil {
+		t.Errorf("SetSeed without a derivation function should reject " +
+			"entropy that is not exactly the seed length.")
+	}
+
+	d, err := NewCtrDRBGFromSeed(false, make([]byte, ctrSeedLen), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to instantiate: %+v", err)
+	}
+	if err = d.Generate(make([]byte, 16), make([]byte, ctrSeedLen+1)); err == nil {
+		t.Errorf("Generate without a derivation function should reject " +
+			"additional input longer than the seed length.")
+	}
+}
@@ -0,0 +1,42 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package csprng
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Limits shared by the NIST SP 800-90A generators (see table 2 and table 3 in
+// section 10).
+const (
+	// MaxReseedInterval is the maximum number of generate requests allowed
+	// between reseeds, and the default interval of every DRBG.
+	MaxReseedInterval uint64 = 1 << 48
+
+	// MaxBytesPerRequest is the largest output of a single generate request
+	// (2^19 bits).
+	MaxBytesPerRequest = 1 << 16
+)
+
+// Error messages shared by the DRBG implementations.
+const (
+	entropyTooShortErr = "entropy input of %d bytes is shorter than the " +
+		"required %d bytes"
+	requestTooLargeErr = "requested %d bytes exceeds the maximum of %d " +
+		"bytes per request"
+)
+
+var (
+	// ErrNotInstantiated is returned when a DRBG is used before it is seeded.
+	ErrNotInstantiated = errors.New("DRBG has not been instantiated")
+
+	// ErrReseedRequired is returned when a DRBG has exhausted its reseed
+	// interval and must be reseeded before generating more output.
+	ErrReseedRequired = errors.New("DRBG reseed interval exhausted; " +
+		"reseed required")
+)
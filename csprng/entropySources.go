@@ -0,0 +1,114 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package csprng
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ReaderSource is an EntropySource that reads fixed-size events from an
+// io.Reader, such as SystemRNG or an already open hardware RNG device.
+type ReaderSource struct {
+	r    io.Reader
+	size int
+}
+
+// NewReaderSource returns an EntropySource that reads events of size bytes
+// from r. The size must be between 1 and MaxEventSize.
+func NewReaderSource(r io.Reader, size int) (*ReaderSource, error) {
+	if size < 1 || size > MaxEventSize {
+		return nil, errors.Errorf("event size must be between 1 and %d, "+
+			"received %d", MaxEventSize, size)
+	}
+	return &ReaderSource{r: r, size: size}, nil
+}
+
+// Event reads a single event from the reader.
+func (s *ReaderSource) Event() ([]byte, error) {
+	event := make([]byte, s.size)
+	if _, err := io.ReadFull(s.r, event); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return event, nil
+}
+
+// FileSource is an EntropySource that reads events from a file, for example
+// a hardware RNG device such as /dev/hwrng. The file is opened for every
+// event so that devices that come and go are handled gracefully.
+type FileSource struct {
+	path string
+	size int
+}
+
+// NewFileSource returns an EntropySource that reads events of size bytes
+// from the file at path. The size must be between 1 and MaxEventSize.
+func NewFileSource(path string, size int) (*FileSource, error) {
+	if size < 1 || size > MaxEventSize {
+		return nil, errors.Errorf("event size must be between 1 and %d, "+
+			"received %d", MaxEventSize, size)
+	}
+	return &FileSource{path: path, size: size}, nil
+}
+
+// Event reads a single event from the file.
+func (s *FileSource) Event() ([]byte, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer f.Close()
+
+	event := make([]byte, s.size)
+	if _, err = io.ReadFull(f, event); err != nil {
+		return nil, errors.Wrapf(err, "failed to read %d bytes from %s",
+			s.size, s.path)
+	}
+	return event, nil
+}
+
+// TimingJitterSource is an EntropySource that samples the high resolution
+// clock around a small amount of work. Each event holds the low bits of
+// several consecutive timing deltas; the entropy per event is small, which is
+// accounted for by Fortuna's pool schedule.
+type TimingJitterSource struct {
+	samples int
+}
+
+// NewTimingJitterSource returns an EntropySource that takes the given number
+// of timing samples per event. Each sample contributes two bytes, so samples
+// must be between 1 and MaxEventSize/2.
+func NewTimingJitterSource(samples int) (*TimingJitterSource, error) {
+	if samples < 1 || samples > MaxEventSize/2 {
+		return nil, errors.Errorf("samples must be between 1 and %d, "+
+			"received %d", MaxEventSize/2, samples)
+	}
+	return &TimingJitterSource{samples: samples}, nil
+}
+
+// Event samples the clock and returns the timing deltas.
+func (s *TimingJitterSource) Event() ([]byte, error) {
+	event := make([]byte, 2*s.samples)
+	acc := uint64(0)
+	last := time.Now().UnixNano()
+	for i := 0; i < s.samples; i++ {
+		// Busy work whose duration varies with caches, interrupts and
+		// scheduling
+		for j := 0; j < 64; j++ {
+			acc = acc*6364136223846793005 + uint64(last)
+		}
+		now := time.Now().UnixNano()
+		binary.BigEndian.PutUint16(event[2*i:], uint16(now-last)^uint16(acc))
+		last = now
+	}
+	return event, nil
+}
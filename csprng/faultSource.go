@@ -0,0 +1,183 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package csprng
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInjectedFault is the default error returned by a FaultSource.
+var ErrInjectedFault = errors.New("injected RNG fault")
+
+// ErrFaultScriptExhausted is returned by a scripted FaultSource once every
+// step has been served.
+var ErrFaultScriptExhausted = errors.New("fault script exhausted")
+
+// FaultStep is the result of a single Read from a scripted FaultSource.
+type FaultStep struct {
+	// Data is copied into the read buffer. A step with less data than the
+	// buffer produces a short read.
+	Data []byte
+	// Err is returned alongside the data.
+	Err error
+}
+
+// FaultSource is a Source for testing failure handling. It can fail after a
+// number of bytes, return short reads, return constant output or follow a
+// script of reads. Each mode has its own constructor; modes are combined by
+// wrapping one FaultSource in another.
+//
+// FaultSource is not random and must never be used outside of tests.
+type FaultSource struct {
+	// Output comes from src if set and is the constant byte otherwise
+	src      Source
+	constant byte
+
+	// failAfter is the number of bytes served before failing, or -1
+	failAfter int
+	err       error
+
+	// maxRead limits the bytes returned per Read when greater than 0
+	maxRead int
+
+	// script is served in order when scripted is set
+	script   []FaultStep
+	scripted bool
+
+	seedErr error
+	served  int
+	reads   int
+	mux     sync.Mutex
+}
+
+// NewConstantSource returns a FaultSource that fills every read with c.
+func NewConstantSource(c byte) *FaultSource {
+	return &FaultSource{constant: c, failAfter: -1}
+}
+
+// NewZeroSource returns a FaultSource that returns all-zero output.
+func NewZeroSource() *FaultSource {
+	return NewConstantSource(0)
+}
+
+// NewFailingSource returns a FaultSource that serves n bytes from src and then
+// fails with err. The read that crosses the limit returns the bytes up to the
+// limit together with err. If err is nil, ErrInjectedFault is used.
+func NewFailingSource(src Source, n int, err error) *FaultSource {
+	if err == nil {
+		err = ErrInjectedFault
+	}
+	if n < 0 {
+		n = 0
+	}
+	return &FaultSource{src: src, failAfter: n, err: err}
+}
+
+// NewShortReadSource returns a FaultSource that serves at most max bytes from
+// src per Read without returning an error.
+func NewShortReadSource(src Source, max int) *FaultSource {
+	if max < 0 {
+		max = 0
+	}
+	return &FaultSource{src: src, failAfter: -1, maxRead: max}
+}
+
+// NewScriptedSource returns a FaultSource that serves one step per Read and
+// fails with ErrFaultScriptExhausted once the script runs out.
+func NewScriptedSource(steps ...FaultStep) *FaultSource {
+	return &FaultSource{script: steps, scripted: true, failAfter: -1}
+}
+
+// Read serves the next bytes according to the configured fault.
+func (f *FaultSource) Read(b []byte) (int, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.reads++
+
+	if f.scripted {
+		if len(f.script) == 0 {
+			return 0, ErrFaultScriptExhausted
+		}
+		step := f.script[0]
+		f.script = f.script[1:]
+		n := copy(b, step.Data)
+		f.served += n
+		return n, step.Err
+	}
+
+	want := len(b)
+	var err error
+	if f.maxRead > 0 && want > f.maxRead {
+		want = f.maxRead
+	}
+	if f.failAfter >= 0 && f.served+want > f.failAfter {
+		want = f.failAfter - f.served
+		err = f.err
+	}
+
+	n, srcErr := f.fill(b[:want])
+	f.served += n
+	if srcErr != nil {
+		return n, srcErr
+	}
+	return n, err
+}
+
+// SetSeed passes the seed to the underlying source, or returns the error set
+// by FailSetSeed.
+func (f *FaultSource) SetSeed(seed []byte) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if f.seedErr != nil {
+		return f.seedErr
+	}
+	if f.src != nil {
+		return f.src.SetSeed(seed)
+	}
+	return nil
+}
+
+// FailSetSeed makes every later SetSeed call fail with err.
+func (f *FaultSource) FailSetSeed(err error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.seedErr = err
+}
+
+// Served returns the total number of bytes returned by Read.
+func (f *FaultSource) Served() int {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.served
+}
+
+// Reads returns the number of calls made to Read.
+func (f *FaultSource) Reads() int {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.reads
+}
+
+// fill fills b from the underlying source or with the constant byte. The
+// caller must hold the lock.
+func (f *FaultSource) fill(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	if f.src != nil {
+		return f.src.Read(b)
+	}
+	for i := range b {
+		b[i] = f.constant
+	}
+	return len(b), nil
+}
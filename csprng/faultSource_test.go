@@ -0,0 +1,148 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package csprng
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// Tests that a constant source fills every read with its byte.
+func TestNewConstantSource(t *testing.T) {
+	for _, src := range []*FaultSource{NewZeroSource(), NewConstantSource(7)} {
+		b := make([]byte, 40)
+		n, err := src.Read(b)
+		if err != nil || n != len(b) {
+			t.Fatalf("Read failed: %d bytes, %v", n, err)
+		}
+		if !bytes.Equal(b, bytes.Repeat(b[:1], len(b))) {
+			t.Errorf("Output is not constant: %x", b)
+		}
+	}
+}
+
+// Tests that a failing source serves exactly n bytes before failing.
+func TestNewFailingSource(t *testing.T) {
+	src := NewFailingSource(NewConstantSource(1), 20, nil)
+
+	if n, err := src.Read(make([]byte, 16)); err != nil || n != 16 {
+		t.Errorf("First read should succeed: %d bytes, %v", n, err)
+	}
+	if n, err := src.Read(make([]byte, 16)); err != ErrInjectedFault || n != 4 {
+		t.Errorf("Read crossing the limit should return the remaining "+
+			"bytes and the error: %d bytes, %v", n, err)
+	}
+	if n, err := src.Read(make([]byte, 16)); err != ErrInjectedFault || n != 0 {
+		t.Errorf("Reads after the limit should fail: %d bytes, %v", n, err)
+	}
+	if src.Served() != 20 || src.Reads() != 3 {
+		t.Errorf("Unexpected counters: %d served, %d reads",
+			src.Served(), src.Reads())
+	}
+
+	// Reads ending exactly on the limit succeed
+	expectedErr := errors.New("custom")
+	src = NewFailingSource(NewZeroSource(), 8, expectedErr)
+	if n, err := src.Read(make([]byte, 8)); err != nil || n != 8 {
+		t.Errorf("Read up to the limit should succeed: %d bytes, %v", n, err)
+	}
+	if _, err := src.Read(make([]byte, 1)); err != expectedErr {
+		t.Errorf("Unexpected error.\nexpected: %v\nreceived: %v",
+			expectedErr, err)
+	}
+}
+
+// Tests that a short read source caps each read.
+func TestNewShortReadSource(t *testing.T) {
+	src := NewShortReadSource(NewConstantSource(3), 5)
+	b := make([]byte, 16)
+	n, err := src.Read(b)
+	if err != nil || n != 5 {
+		t.Errorf("Read should be short: %d bytes, %v", n, err)
+	}
+	if b[5] != 0 {
+		t.Errorf("Bytes past the short read were written: %x", b)
+	}
+}
+
+// Tests that a scripted source serves its steps in order.
+func TestNewScriptedSource(t *testing.T) {
+	stepErr := errors.New("step error")
+	src := NewScriptedSource(
+		FaultStep{Data: []byte{1, 2, 3, 4}},
+		FaultStep{Data: []byte{5}, Err: stepErr},
+	)
+
+	b := make([]byte, 4)
+	if n, err := src.Read(b); err != nil || n != 4 ||
+		!bytes.Equal(b, []byte{1, 2, 3, 4}) {
+		t.Errorf("First step not served: %d bytes, %v, %x", n, err, b)
+	}
+	if n, err := src.Read(b); err != stepErr || n != 1 || b[0] != 5 {
+		t.Errorf("Second step not served: %d bytes, %v, %x", n, err, b)
+	}
+	if _, err := src.Read(b); err != ErrFaultScriptExhausted {
+		t.Errorf("Unexpected error.\nexpected: %v\nreceived: %v",
+			ErrFaultScriptExhausted, err)
+	}
+}
+
+// Tests that SetSeed failures can be injected.
+func TestFaultSource_SetSeed(t *testing.T) {
+	src := NewFailingSource(NewSystemRNG(), 10, nil)
+	if err := src.SetSeed([]byte("seed")); err != nil {
+		t.Errorf("SetSeed returned an error: %+v", err)
+	}
+	src.FailSetSeed(ErrInjectedFault)
+	if err := src.SetSeed([]byte("seed")); err != ErrInjectedFault {
+		t.Errorf("Unexpected error.\nexpected: %v\nreceived: %v",
+			ErrInjectedFault, err)
+	}
+}
+
+// Error path: tests that Generate returns an error and no output on read
+// errors and short reads.
+func TestGenerate_Faults(t *testing.T) {
+	sources := map[string]Source{
+		"error":       NewFailingSource(NewSystemRNG(), 0, nil),
+		"error after": NewFailingSource(NewSystemRNG(), 10, nil),
+		"short read":  NewShortReadSource(NewSystemRNG(), 10),
+		"short error": NewScriptedSource(
+			FaultStep{Data: make([]byte, 10), Err: ErrInjectedFault}),
+	}
+	for name, src := range sources {
+		b, err := Generate(32, src)
+		if err == nil || b != nil {
+			t.Errorf("Generate should fail on %s: %x, %v", name, b, err)
+		}
+	}
+}
+
+// Error path: tests that GenerateInGroup returns source errors and gives up
+// on stuck sources instead of looping forever.
+func TestGenerateInGroup_Faults(t *testing.T) {
+	sources := map[string]Source{
+		"error":          NewFailingSource(NewSystemRNG(), 100, nil),
+		"short read":     NewShortReadSource(NewSystemRNG(), 8),
+		"zero":           NewZeroSource(),
+		"constant above": NewConstantSource(0xff),
+	}
+	for name, src := range sources {
+		b, err := GenerateInGroup(modp4096, len(modp4096), src)
+		if err == nil || b != nil {
+			t.Errorf("GenerateInGroup should fail on %s: %v", name, err)
+		}
+
+		b, err = GenerateInGroup(modp4096, 32, src)
+		if name != "constant above" && (err == nil || b != nil) {
+			t.Errorf("GenerateInGroup should fail on %s for short "+
+				"values: %v", name, err)
+		}
+	}
+}
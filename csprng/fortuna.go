@@ -0,0 +1,305 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package csprng
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"hash"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	jww "github.com/spf13/jwalterweatherman"
+)
+
+// Fortuna construction parameters from Ferguson, Schneier and Kohno,
+// "Cryptography Engineering", chapter 9.
+const (
+	// FortunaPools is the number of entropy pools.
+	FortunaPools = 32
+
+	// MaxEventSize is the largest entropy event accepted by AddRandomEvent.
+	MaxEventSize = 32
+
+	// FortunaSeedFileSize is the size of a Fortuna seed file in bytes.
+	FortunaSeedFileSize = 64
+
+	// fortunaMinPoolSize is the number of bytes pool 0 must collect before
+	// a reseed is allowed.
+	fortunaMinPoolSize = 64
+
+	// fortunaMaxRequest is the largest output generated under a single key.
+	fortunaMaxRequest = 1 << 20
+
+	// reservedSource is never assigned by RegisterSource, so callers can
+	// add their own events under it with AddRandomEvent.
+	reservedSource = 255
+)
+
+// fortunaMinReseedInterval is the minimum time between two reseeds.
+var fortunaMinReseedInterval = 100 * time.Millisecond
+
+// ErrFortunaNotSeeded is returned when Fortuna is read from before it has
+// gathered enough entropy to seed its generator.
+var ErrFortunaNotSeeded = errors.New("Fortuna has not been seeded")
+
+// EntropySource supplies entropy events to a Fortuna accumulator.
+type EntropySource interface {
+	// Event returns a single entropy event of 1 to MaxEventSize bytes.
+	Event() ([]byte, error)
+}
+
+// Fortuna is a Fortuna style entropy accumulator. Entropy events from
+// registered sources and from AddRandomEvent are spread over 32 pools; the
+// generator, AES-256 in counter mode, is reseeded from an increasing subset of
+// the pools whenever pool 0 has collected enough entropy. SetSeed and seed
+// files reseed the generator directly. The generator state can be persisted
+// across restarts with a seed file.
+type Fortuna struct {
+	// Generator state
+	block   cipher.Block
+	key     [sha256.Size]byte
+	counter [aes.BlockSize]byte
+
+	// Accumulator state
+	pools        [FortunaPools]hash.Hash
+	pool0Size    int
+	reseedCount  uint64
+	lastReseed   time.Time
+	nextPool     map[uint8]int
+	nextSourceID uint8
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+	mux  sync.Mutex
+}
+
+// NewFortuna returns an unseeded Fortuna accumulator. It must collect entropy
+// through registered sources, AddRandomEvent, SetSeed or a seed file before
+// it can be read from.
+func NewFortuna() *Fortuna {
+	f := &Fortuna{
+		nextPool: make(map[uint8]int),
+		quit:     make(chan struct{}),
+	}
+	for i := range f.pools {
+		f.pools[i] = sha256.New()
+	}
+	return f
+}
+
+// Read fills b with output from the generator, reseeding it first if enough
+// entropy has accumulated.
+func (f *Fortuna) Read(b []byte) (int, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if f.pool0Size >= fortunaMinPoolSize &&
+		time.Since(f.lastReseed) >= fortunaMinReseedInterval {
+		f.reseedFromPools()
+	}
+
+	if f.block == nil {
+		return 0, ErrFortunaNotSeeded
+	}
+
+	for n := 0; n < len(b); {
+		end := n + fortunaMaxRequest
+		if end > len(b) {
+			end = len(b)
+		}
+		f.pseudoRandomData(b[n:end])
+		n = end
+	}
+	return len(b), nil
+}
+
+// SetSeed reseeds the generator directly with the seed, as a seed file does,
+// so that it can be read from immediately. The seed should hold at least 32
+// bytes of entropy.
+func (f *Fortuna) SetSeed(seed []byte) error {
+	if len(seed) == 0 {
+		return errors.New("Fortuna seed must not be empty")
+	}
+
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.reseed(seed)
+	return nil
+}
+
+// AddRandomEvent adds an entropy event from the given source to the next
+// pool for that source. Each source cycles through the pools in turn. Events
+// must be between 1 and MaxEventSize bytes.
+func (f *Fortuna) AddRandomEvent(source uint8, data []byte) error {
+	if len(data) < 1 || len(data) > MaxEventSize {
+		return errors.Errorf("entropy event must be between 1 and %d "+
+			"bytes, received %d", MaxEventSize, len(data))
+	}
+
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	i := f.nextPool[source]
+	f.nextPool[source] = (i + 1) % FortunaPools
+
+	f.pools[i].Write([]byte{source, byte(len(data))})
+	f.pools[i].Write(data)
+	if i == 0 {
+		f.pool0Size += 2 + len(data)
+	}
+	return nil
+}
+
+// RegisterSource polls the entropy source every interval and adds its events
+// to the pools until Close is called. It returns the source number assigned
+// to the source.
+func (f *Fortuna) RegisterSource(src EntropySource,
+	interval time.Duration) (uint8, error) {
+	if interval <= 0 {
+		return 0, errors.Errorf("invalid polling interval %s", interval)
+	}
+
+	f.mux.Lock()
+	if f.nextSourceID == reservedSource {
+		f.mux.Unlock()
+		return 0, errors.New("too many entropy sources registered")
+	}
+	id := f.nextSourceID
+	f.nextSourceID++
+	f.mux.Unlock()
+
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-f.quit:
+				return
+			case <-ticker.C:
+				event, err := src.Event()
+				if err == nil {
+					err = f.AddRandomEvent(id, event)
+				}
+				if err != nil {
+					jww.WARN.Printf("Fortuna entropy source %d failed: %+v",
+						id, err)
+				}
+			}
+		}
+	}()
+
+	return id, nil
+}
+
+// Close stops polling all registered entropy sources.
+func (f *Fortuna) Close() {
+	f.mux.Lock()
+	select {
+	case <-f.quit:
+	default:
+		close(f.quit)
+	}
+	f.mux.Unlock()
+	f.wg.Wait()
+}
+
+// WriteSeedFile writes FortunaSeedFileSize bytes of generator output to the
+// file at path so that the generator can be seeded on the next start.
+func (f *Fortuna) WriteSeedFile(path string) error {
+	seed := make([]byte, FortunaSeedFileSize)
+	if _, err := f.Read(seed); err != nil {
+		return err
+	}
+	return errors.WithStack(os.WriteFile(path, seed, 0600))
+}
+
+// UpdateSeedFile reseeds the generator with the contents of the seed file at
+// path and immediately overwrites the file, so that the same seed is never
+// used twice.
+func (f *Fortuna) UpdateSeedFile(path string) error {
+	seed, err := os.ReadFile(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(seed) != FortunaSeedFileSize {
+		return errors.Errorf("seed file must be %d bytes, found %d",
+			FortunaSeedFileSize, len(seed))
+	}
+
+	f.mux.Lock()
+	f.reseed(seed)
+	f.mux.Unlock()
+
+	return f.WriteSeedFile(path)
+}
+
+// reseedFromPools reseeds the generator from every pool i for which 2^i
+// divides the reseed count, then empties those pools. The caller must hold
+// the lock.
+func (f *Fortuna) reseedFromPools() {
+	f.reseedCount++
+
+	var s []byte
+	for i := 0; i < FortunaPools; i++ {
+		if f.reseedCount%(1<<uint(i)) != 0 {
+			break
+		}
+		digest := sha256.Sum256(f.pools[i].Sum(nil))
+		s = append(s, digest[:]...)
+		f.pools[i].Reset()
+	}
+
+	f.pool0Size = 0
+	f.lastReseed = time.Now()
+	f.reseed(s)
+}
+
+// reseed replaces the generator key with SHA-256d(key || seed). The caller
+// must hold the lock.
+func (f *Fortuna) reseed(seed []byte) {
+	h := sha256.New()
+	h.Write(f.key[:])
+	h.Write(seed)
+	f.key = sha256.Sum256(h.Sum(nil))
+	f.block, _ = aes.NewCipher(f.key[:])
+	f.incrementCounter()
+}
+
+// pseudoRandomData fills b with generator output and then replaces the key
+// with two further blocks of output. The caller must hold the lock.
+func (f *Fortuna) pseudoRandomData(b []byte) {
+	var block [aes.BlockSize]byte
+	for n := 0; n < len(b); {
+		f.block.Encrypt(block[:], f.counter[:])
+		f.incrementCounter()
+		n += copy(b[n:], block[:])
+	}
+
+	for n := 0; n < len(f.key); n += aes.BlockSize {
+		f.block.Encrypt(f.key[n:], f.counter[:])
+		f.incrementCounter()
+	}
+	f.block, _ = aes.NewCipher(f.key[:])
+}
+
+// incrementCounter increments the 128-bit little-endian generator counter.
+// The caller must hold the lock.
+func (f *Fortuna) incrementCounter() {
+	for i := range f.counter {
+		f.counter[i]++
+		if f.counter[i] != 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,242 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package csprng
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// seedFortuna fills pool 0 and performs the first reseed.
+func seedFortuna(t *testing.T, f *Fortuna) {
+	for i := 0; i < 2*FortunaPools; i++ {
+		if err := f.AddRandomEvent(0, bytes.Repeat([]byte{byte(i)}, 32)); err != nil {
+			t.Fatalf("AddRandomEvent returned an error: %+v", err)
+		}
+	}
+	if _, err := f.Read(make([]byte, 1)); err != nil {
+		t.Fatalf("Read returned an error: %+v", err)
+	}
+}
+
+// Error path: tests that an unseeded Fortuna cannot be read from.
+func TestFortuna_Read_NotSeeded(t *testing.T) {
+	f := NewFortuna()
+	if _, err := f.Read(make([]byte, 16)); err != ErrFortunaNotSeeded {
+		t.Errorf("Read should fail before seeding."+
+			"\nexpected: %v\nreceived: %v", ErrFortunaNotSeeded, err)
+	}
+}
+
+// Tests that identical event streams give identical output and that
+// consecutive reads differ.
+func TestFortuna_Read(t *testing.T) {
+	a, b := NewFortuna(), NewFortuna()
+	seedFortuna(t, a)
+	seedFortuna(t, b)
+
+	outA, outB := make([]byte, 100), make([]byte, 100)
+	_, _ = a.Read(outA)
+	_, _ = b.Read(outB)
+	if !bytes.Equal(outA, outB) {
+		t.Errorf("Identically seeded accumulators diverged."+
+			"\nexpected: %x\nreceived: %x", outA, outB)
+	}
+
+	_, _ = a.Read(outB)
+	if bytes.Equal(outA, outB) {
+		t.Errorf("Consecutive reads returned the same output.")
+	}
+
+	large := make([]byte, 2*fortunaMaxRequest+3)
+	if n, err := a.Read(large); err != nil || n != len(large) {
+		t.Errorf("Read failed.\nexpected: %d bytes\nreceived: %d bytes, %v",
+			len(large), n, err)
+	}
+}
+
+// Tests that reseeds use pool i only every 2^i reseeds.
+func TestFortuna_ReseedSchedule(t *testing.T) {
+	interval := fortunaMinReseedInterval
+	fortunaMinReseedInterval = 0
+	defer func() { fortunaMinReseedInterval = interval }()
+
+	f := NewFortuna()
+	event := bytes.Repeat([]byte{0xAA}, 32)
+	for reseed := 1; reseed <= 4; reseed++ {
+		// Two full rounds over the pools puts 68 bytes into pool 0
+		for i := 0; i < 2*FortunaPools; i++ {
+			_ = f.AddRandomEvent(1, event)
+		}
+		_, _ = f.Read(make([]byte, 1))
+
+		if f.reseedCount != uint64(reseed) {
+			t.Fatalf("Unexpected reseed count.\nexpected: %d\nreceived: %d",
+				reseed, f.reseedCount)
+		}
+
+		empty := sha256Empty()
+		for i := 0; i < 3; i++ {
+			used := reseed%(1<<uint(i)) == 0
+			isEmpty := bytes.Equal(f.pools[i].Sum(nil), empty)
+			if used != isEmpty {
+				t.Errorf("Reseed %d: pool %d used=%t but empty=%t",
+					reseed, i, used, isEmpty)
+			}
+		}
+	}
+}
+
+// Tests that SetSeed with a 32 byte seed makes the generator usable and that
+// the output depends on the seed.
+func TestFortuna_SetSeed(t *testing.T) {
+	f := NewFortuna()
+	var src Source = f
+	if err := src.SetSeed(bytes.Repeat([]byte{0x42}, 32)); err != nil {
+		t.Fatalf("SetSeed returned an error: %+v", err)
+	}
+	out := make([]byte, 16)
+	if _, err := src.Read(out); err != nil {
+		t.Fatalf("Read after SetSeed returned an error: %+v", err)
+	}
+
+	other := NewFortuna()
+	if err := other.SetSeed(bytes.Repeat([]byte{0x43}, 32)); err != nil {
+		t.Fatal(err)
+	}
+	otherOut := make([]byte, 16)
+	if _, err := other.Read(otherOut); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(out, otherOut) {
+		t.Errorf("Different seeds produced the same output")
+	}
+
+	if err := NewFortuna().SetSeed(nil); err == nil {
+		t.Errorf("SetSeed accepted an empty seed")
+	}
+}
+
+// Error path: tests that events of invalid sizes are rejected.
+func TestFortuna_AddRandomEvent_Size(t *testing.T) {
+	f := NewFortuna()
+	if err := f.AddRandomEvent(0, nil); err == nil {
+		t.Errorf("AddRandomEvent should reject empty events.")
+	}
+	if err := f.AddRandomEvent(0, make([]byte, MaxEventSize+1)); err == nil {
+		t.Errorf("AddRandomEvent should reject oversized events.")
+	}
+}
+
+// Tests that registered sources feed the pools until Close is called.
+func TestFortuna_RegisterSource(t *testing.T) {
+	f := NewFortuna()
+	jitter, err := NewTimingJitterSource(16)
+	if err != nil {
+		t.Fatalf("NewTimingJitterSource returned an error: %+v", err)
+	}
+	system, err := NewReaderSource(NewSystemRNG(), 32)
+	if err != nil {
+		t.Fatalf("NewReaderSource returned an error: %+v", err)
+	}
+
+	for _, src := range []EntropySource{jitter, system} {
+		if _, err = f.RegisterSource(src, time.Millisecond); err != nil {
+			t.Fatalf("RegisterSource returned an error: %+v", err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err = f.Read(make([]byte, 16)); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Registered sources never seeded the generator: %+v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	f.Close()
+
+	if _, err = f.RegisterSource(jitter, 0); err == nil {
+		t.Errorf("RegisterSource should reject a zero interval.")
+	}
+}
+
+// Tests that a seed file can be written, loaded and is replaced on load.
+func TestFortuna_SeedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fortuna.seed")
+
+	f := NewFortuna()
+	seedFortuna(t, f)
+	if err := f.WriteSeedFile(path); err != nil {
+		t.Fatalf("WriteSeedFile returned an error: %+v", err)
+	}
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read seed file: %+v", err)
+	}
+	if len(original) != FortunaSeedFileSize {
+		t.Errorf("Unexpected seed file size.\nexpected: %d\nreceived: %d",
+			FortunaSeedFileSize, len(original))
+	}
+
+	restarted := NewFortuna()
+	if err = restarted.UpdateSeedFile(path); err != nil {
+		t.Fatalf("UpdateSeedFile returned an error: %+v", err)
+	}
+	if _, err = restarted.Read(make([]byte, 16)); err != nil {
+		t.Errorf("Read after loading the seed file returned an error: %+v",
+			err)
+	}
+
+	updated, _ := os.ReadFile(path)
+	if bytes.Equal(original, updated) {
+		t.Errorf("UpdateSeedFile did not replace the seed file.")
+	}
+
+	if err = os.WriteFile(path, []byte("short"), 0600); err != nil {
+		t.Fatalf("Failed to write seed file: %+v", err)
+	}
+	if err = NewFortuna().UpdateSeedFile(path); err == nil {
+		t.Errorf("UpdateSeedFile should reject a seed file of the wrong size.")
+	}
+}
+
+// Tests that FileSource reads events from a file.
+func TestFileSource_Event(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hwrng")
+	if err := os.WriteFile(path, bytes.Repeat([]byte{7}, 64), 0600); err != nil {
+		t.Fatalf("Failed to write file: %+v", err)
+	}
+
+	src, err := NewFileSource(path, 32)
+	if err != nil {
+		t.Fatalf("NewFileSource returned an error: %+v", err)
+	}
+	event, err := src.Event()
+	if err != nil || !bytes.Equal(event, bytes.Repeat([]byte{7}, 32)) {
+		t.Errorf("Unexpected event: %x, %v", event, err)
+	}
+
+	missing, _ := NewFileSource(path+".missing", 32)
+	if _, err = missing.Event(); err == nil {
+		t.Errorf("Event should fail for a missing file.")
+	}
+	if _, err = NewFileSource(path, MaxEventSize+1); err == nil {
+		t.Errorf("NewFileSource should reject oversized events.")
+	}
+}
+
+// sha256Empty returns the digest of an empty SHA-256 pool.
+func sha256Empty() []byte {
+	return NewFortuna().pools[0].Sum(nil)
+}
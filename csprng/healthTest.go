@@ -0,0 +1,301 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package csprng
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultHealthMinEntropy is the default assessed min-entropy per output
+	// byte used to derive the health test cutoffs. It is deliberately lower
+	// than the 8 bits of a working generator so that false alarms are
+	// negligible even on very long streams.
+	DefaultHealthMinEntropy = 4.0
+
+	// DefaultHealthAlphaExponent is the default false positive probability
+	// exponent; the probability of a false alarm per sample is 2^-40.
+	DefaultHealthAlphaExponent = 40
+
+	// aptWindowSize is the adaptive proportion test window for non-binary
+	// samples (SP 800-90B section 4.4.2).
+	aptWindowSize = 512
+
+	// startupSamples is the number of samples run through the health tests
+	// and discarded before any output is returned (SP 800-90B section 4.3).
+	startupSamples = 1024
+)
+
+// HealthTest identifies one of the continuous health tests.
+type HealthTest uint8
+
+const (
+	// RepetitionCountTest detects a source stuck on a single value.
+	RepetitionCountTest HealthTest = iota
+	// AdaptiveProportionTest detects a large loss of entropy.
+	AdaptiveProportionTest
+)
+
+// String returns the name of the health test.
+func (t HealthTest) String() string {
+	switch t {
+	case RepetitionCountTest:
+		return "repetition count test"
+	case AdaptiveProportionTest:
+		return "adaptive proportion test"
+	default:
+		return "unknown health test"
+	}
+}
+
+// HealthTestError is returned when a health test trips.
+type HealthTestError struct {
+	// Test is the health test that failed.
+	Test HealthTest
+	// Startup is true if the failure happened during the startup self-test.
+	Startup bool
+	// Count is the repetition or proportion count that reached the cutoff.
+	Count int
+	// Cutoff is the cutoff of the failed test.
+	Cutoff int
+}
+
+// Error returns a description of the failure.
+func (e *HealthTestError) Error() string {
+	stage := "continuous"
+	if e.Startup {
+		stage = "startup"
+	}
+	return fmt.Sprintf("RNG %s %s failed: count %d reached cutoff %d",
+		stage, e.Test, e.Count, e.Cutoff)
+}
+
+// HealthTestParams configures a HealthTestedSource.
+type HealthTestParams struct {
+	// MinEntropy is the assessed min-entropy of the source per byte, in bits.
+	// It must be greater than 0 and at most 8.
+	MinEntropy float64
+
+	// AlphaExponent sets the false positive probability per sample to
+	// 2^-AlphaExponent. SP 800-90B recommends values between 20 and 40.
+	AlphaExponent int
+
+	// Latch keeps the source in a failed state after a health test trips,
+	// so that every later Read fails with the same error.
+	Latch bool
+}
+
+// DefaultHealthTestParams returns the default health test parameters with
+// latching enabled.
+func DefaultHealthTestParams() HealthTestParams {
+	return HealthTestParams{
+		MinEntropy:    DefaultHealthMinEntropy,
+		AlphaExponent: DefaultHealthAlphaExponent,
+		Latch:         true,
+	}
+}
+
+// HealthTestedSource wraps a Source and runs the SP 800-90B repetition count
+// test and adaptive proportion test over every byte it outputs. If a test
+// trips, the read fails with a *HealthTestError and no output is returned.
+type HealthTestedSource struct {
+	src       Source
+	params    HealthTestParams
+	rctCutoff int
+	aptCutoff int
+
+	// Repetition count test state
+	rctLast  byte
+	rctCount int
+
+	// Adaptive proportion test state
+	aptFirst byte
+	aptCount int
+	aptIdx   int
+
+	failed error
+	mux    sync.Mutex
+}
+
+// NewHealthTestedSource wraps src with continuous health tests and runs the
+// startup self-test, returning an error if it fails.
+func NewHealthTestedSource(src Source,
+	params HealthTestParams) (*HealthTestedSource, error) {
+	if params.MinEntropy <= 0 || params.MinEntropy > 8 {
+		return nil, errors.Errorf("min-entropy must be in (0, 8], "+
+			"received %f", params.MinEntropy)
+	}
+	if params.AlphaExponent < 1 || params.AlphaExponent > 64 {
+		return nil, errors.Errorf("alpha exponent must be between 1 and 64, "+
+			"received %d", params.AlphaExponent)
+	}
+
+	alpha := math.Exp2(-float64(params.AlphaExponent))
+	h := &HealthTestedSource{
+		src:       src,
+		params:    params,
+		rctCutoff: RepetitionCountCutoff(params.MinEntropy, alpha),
+		aptCutoff: AdaptiveProportionCutoff(params.MinEntropy, alpha),
+	}
+
+	if err := h.StartupTest(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// StartupTest reads 1024 bytes from the wrapped source, runs them through
+// both health tests and discards them. A failure latches regardless of the
+// Latch parameter.
+func (h *HealthTestedSource) StartupTest() error {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	h.resetTests()
+	samples := make([]byte, startupSamples)
+	if err := h.readAndTest(samples); err != nil {
+		if hte, ok := err.(*HealthTestError); ok {
+			hte.Startup = true
+			h.failed = hte
+		}
+		return err
+	}
+	return nil
+}
+
+// Read fills b from the wrapped source after running each byte through the
+// health tests. On failure b is zeroed and the error is returned.
+func (h *HealthTestedSource) Read(b []byte) (int, error) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	if h.failed != nil {
+		return 0, h.failed
+	}
+
+	if err := h.readAndTest(b); err != nil {
+		for i := range b {
+			b[i] = 0
+		}
+		if _, ok := err.(*HealthTestError); ok {
+			if h.params.Latch {
+				h.failed = err
+			} else {
+				h.resetTests()
+			}
+		}
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// SetSeed passes the seed to the wrapped source.
+func (h *HealthTestedSource) SetSeed(seed []byte) error {
+	return h.src.SetSeed(seed)
+}
+
+// Failed returns the latched health test failure, or nil if the source is
+// healthy.
+func (h *HealthTestedSource) Failed() error {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return h.failed
+}
+
+// Cutoffs returns the repetition count and adaptive proportion test cutoffs.
+func (h *HealthTestedSource) Cutoffs() (rct, apt int) {
+	return h.rctCutoff, h.aptCutoff
+}
+
+// readAndTest fills b from the wrapped source and runs the health tests. The
+// caller must hold the lock.
+func (h *HealthTestedSource) readAndTest(b []byte) error {
+	n, err := h.src.Read(b)
+	if err != nil {
+		return err
+	}
+	if n != len(b) {
+		return errors.Errorf("bad read count, %d read != %d needed",
+			n, len(b))
+	}
+
+	for _, sample := range b {
+		if err = h.testSample(sample); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// testSample runs a single sample through both tests (SP 800-90B sections
+// 4.4.1 and 4.4.2). The caller must hold the lock.
+func (h *HealthTestedSource) testSample(sample byte) error {
+	if h.rctCount > 0 && sample == h.rctLast {
+		h.rctCount++
+		if h.rctCount >= h.rctCutoff {
+			return &HealthTestError{Test: RepetitionCountTest,
+				Count: h.rctCount, Cutoff: h.rctCutoff}
+		}
+	} else {
+		h.rctLast = sample
+		h.rctCount = 1
+	}
+
+	if h.aptIdx == 0 {
+		h.aptFirst = sample
+		h.aptCount = 1
+	} else if sample == h.aptFirst {
+		h.aptCount++
+		if h.aptCount >= h.aptCutoff {
+			return &HealthTestError{Test: AdaptiveProportionTest,
+				Count: h.aptCount, Cutoff: h.aptCutoff}
+		}
+	}
+	h.aptIdx = (h.aptIdx + 1) % aptWindowSize
+	return nil
+}
+
+// resetTests clears the state of both tests. The caller must hold the lock.
+func (h *HealthTestedSource) resetTests() {
+	h.rctCount = 0
+	h.aptCount = 0
+	h.aptIdx = 0
+}
+
+// RepetitionCountCutoff returns the repetition count test cutoff
+// C = 1 + ceil(-log2(alpha) / H) for min-entropy H bits per sample.
+func RepetitionCountCutoff(minEntropy, alpha float64) int {
+	return 1 + int(math.Ceil(-math.Log2(alpha)/minEntropy))
+}
+
+// AdaptiveProportionCutoff returns the adaptive proportion test cutoff
+// C = 1 + CRITBINOM(W, 2^-H, 1 - alpha) for a window of 512 samples with
+// min-entropy H bits per sample.
+func AdaptiveProportionCutoff(minEntropy, alpha float64) int {
+	p := math.Exp2(-minEntropy)
+	logP, logQ := math.Log(p), math.Log1p(-p)
+	lgW, _ := math.Lgamma(aptWindowSize + 1)
+
+	// Find the smallest k with P(X > k) <= alpha by summing the upper tail
+	tail := 0.0
+	for k := aptWindowSize; k >= 0; k-- {
+		lgK, _ := math.Lgamma(float64(k + 1))
+		lgWK, _ := math.Lgamma(float64(aptWindowSize - k + 1))
+		pmf := math.Exp(lgW - lgK - lgWK + float64(k)*logP +
+			float64(aptWindowSize-k)*logQ)
+		if tail+pmf > alpha {
+			return 1 + k
+		}
+		tail += pmf
+	}
+	return 1
+}
@@ -0,0 +1,164 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package csprng
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+)
+
+// patternSource returns random bytes until healthy bytes have been read and
+// then repeats the pattern.
+type patternSource struct {
+	healthy int
+	pattern []byte
+	idx     int
+}
+
+func (p *patternSource) Read(b []byte) (int, error) {
+	for i := range b {
+		if p.healthy > 0 {
+			_, _ = NewSystemRNG().Read(b[i : i+1])
+			p.healthy--
+			continue
+		}
+		b[i] = p.pattern[p.idx%len(p.pattern)]
+		p.idx++
+	}
+	return len(b), nil
+}
+
+func (p *patternSource) SetSeed([]byte) error { return nil }
+
+// Tests that the cutoffs match SP 800-90B table 2 (alpha = 2^-20, W = 512).
+func TestCutoffs(t *testing.T) {
+	alpha := math.Exp2(-20)
+	expectedAPT := map[float64]int{0.5: 410, 1: 311, 2: 177, 4: 62, 8: 13}
+	for minEntropy, expected := range expectedAPT {
+		if received := AdaptiveProportionCutoff(minEntropy, alpha); received != expected {
+			t.Errorf("Unexpected APT cutoff for H=%.1f."+
+				"\nexpected: %d\nreceived: %d", minEntropy, expected, received)
+		}
+	}
+
+	expectedRCT := map[float64]int{0.5: 41, 1: 21, 2: 11, 4: 6, 8: 4}
+	for minEntropy, expected := range expectedRCT {
+		if received := RepetitionCountCutoff(minEntropy, alpha); received != expected {
+			t.Errorf("Unexpected RCT cutoff for H=%.1f."+
+				"\nexpected: %d\nreceived: %d", minEntropy, expected, received)
+		}
+	}
+}
+
+// Tests that a healthy SystemRNG passes the startup and continuous tests.
+func TestHealthTestedSource_SystemRNG(t *testing.T) {
+	h, err := NewHealthTestedSource(NewSystemRNG(), DefaultHealthTestParams())
+	if err != nil {
+		t.Fatalf("NewHealthTestedSource returned an error: %+v", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		b, err := GenerateInGroup(modp4096, len(modp4096), h)
+		if err != nil || !InGroup(b, modp4096) {
+			t.Fatalf("GenerateInGroup failed: %+v", err)
+		}
+	}
+	if h.Failed() != nil {
+		t.Errorf("Healthy source latched a failure: %+v", h.Failed())
+	}
+}
+
+// Error path: tests that a stuck source fails the startup self-test.
+func TestHealthTestedSource_StartupFailure(t *testing.T) {
+	_, err := NewHealthTestedSource(&patternSource{pattern: []byte{0}},
+		DefaultHealthTestParams())
+
+	var hte *HealthTestError
+	if !errors.As(err, &hte) || hte.Test != RepetitionCountTest || !hte.Startup {
+		t.Errorf("Stuck source should fail the startup repetition count "+
+			"test.\nreceived: %v", err)
+	}
+}
+
+// Error path: tests that a source which gets stuck after startup trips the
+// repetition count test, zeroes the output and latches.
+func TestHealthTestedSource_RepetitionCount(t *testing.T) {
+	src := &patternSource{healthy: startupSamples + 100, pattern: []byte{0xAB}}
+	h, err := NewHealthTestedSource(src, DefaultHealthTestParams())
+	if err != nil {
+		t.Fatalf("NewHealthTestedSource returned an error: %+v", err)
+	}
+
+	b := make([]byte, 200)
+	n, err := h.Read(b)
+	var hte *HealthTestError
+	if n != 0 || !errors.As(err, &hte) || hte.Test != RepetitionCountTest {
+		t.Fatalf("Read should trip the repetition count test."+
+			"\nreceived: %d bytes, %v", n, err)
+	}
+	if !bytes.Equal(b, make([]byte, len(b))) {
+		t.Errorf("Output was not zeroed after a failure.")
+	}
+
+	// The failure is latched even if the source recovers
+	src.healthy = 1 << 20
+	if _, err = h.Read(b); err != hte {
+		t.Errorf("Failure was not latched.\nexpected: %v\nreceived: %v",
+			hte, err)
+	}
+	if h.Failed() != hte {
+		t.Errorf("Failed did not return the latched error.")
+	}
+}
+
+// Error path: tests that a biased source without long runs trips the
+// adaptive proportion test, and that without latching the source recovers.
+func TestHealthTestedSource_AdaptiveProportion(t *testing.T) {
+	// Every other byte is 0, so runs are never longer than 1
+	pattern := make([]byte, 0, 510)
+	for i := 1; i < 256; i++ {
+		pattern = append(pattern, 0, byte(i))
+	}
+
+	params := DefaultHealthTestParams()
+	params.Latch = false
+	src := &patternSource{healthy: startupSamples, pattern: pattern}
+	h, err := NewHealthTestedSource(src, params)
+	if err != nil {
+		t.Fatalf("NewHealthTestedSource returned an error: %+v", err)
+	}
+
+	_, err = h.Read(make([]byte, 4*aptWindowSize))
+	var hte *HealthTestError
+	if !errors.As(err, &hte) || hte.Test != AdaptiveProportionTest {
+		t.Fatalf("Read should trip the adaptive proportion test."+
+			"\nreceived: %v", err)
+	}
+
+	src.healthy = 1 << 20
+	if _, err = h.Read(make([]byte, 64)); err != nil {
+		t.Errorf("Unlatched source should recover: %+v", err)
+	}
+}
+
+// Error path: tests that invalid parameters are rejected.
+func TestNewHealthTestedSource_InvalidParams(t *testing.T) {
+	params := DefaultHealthTestParams()
+	params.MinEntropy = 9
+	if _, err := NewHealthTestedSource(NewSystemRNG(), params); err == nil {
+		t.Errorf("NewHealthTestedSource should reject a min-entropy over 8.")
+	}
+
+	params = DefaultHealthTestParams()
+	params.AlphaExponent = 0
+	if _, err := NewHealthTestedSource(NewSystemRNG(), params); err == nil {
+		t.Errorf("NewHealthTestedSource should reject an alpha exponent of 0.")
+	}
+}
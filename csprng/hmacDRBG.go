@@ -0,0 +1,254 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package csprng
+
+import (
+	"crypto/hmac"
+	"hash"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gitlab.com/xx_network/crypto/hasher"
+)
+
+// HmacDRBG is a deterministic random bit generator following the HMAC_DRBG
+// construction in NIST SP 800-90A Rev. 1 section 10.1.2. The underlying hash
+// function is selected with a hasher.HashType.
+//
+// The generator starts out uninstantiated. The first call to SetSeed (or
+// Instantiate) instantiates it and every subsequent call to SetSeed reseeds
+// it. Once the reseed interval is exhausted, Read returns ErrReseedRequired
+// until the generator is reseeded.
+type HmacDRBG struct {
+	hashType        hasher.HashType
+	personalization []byte
+	reseedInterval  uint64
+
+	key           []byte
+	v             []byte
+	reseedCounter uint64
+	instantiated  bool
+
+	mux sync.Mutex
+}
+
+// NewHmacDRBG returns an uninstantiated HMAC_DRBG using the given hash. The
+// personalization string is used when the generator is instantiated through
+// SetSeed and may be nil.
+func NewHmacDRBG(h hasher.HashType, personalization []byte) *HmacDRBG {
+	return &HmacDRBG{
+		hashType:        h,
+		personalization: append([]byte{}, personalization...),
+		reseedInterval:  MaxReseedInterval,
+	}
+}
+
+// NewHmacDRBGFromSeed returns an HMAC_DRBG instantiated with the given
+// entropy input, nonce and personalization string.
+func NewHmacDRBGFromSeed(h hasher.HashType, entropy, nonce,
+	personalization []byte) (*HmacDRBG, error) {
+	d := NewHmacDRBG(h, personalization)
+	if err := d.Instantiate(entropy, nonce, personalization); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// SetReseedInterval sets the number of generate requests allowed between
+// reseeds. The interval must be between 1 and MaxReseedInterval.
+func (d *HmacDRBG) SetReseedInterval(interval uint64) error {
+	if interval == 0 || interval > MaxReseedInterval {
+		return errors.Errorf("reseed interval must be between 1 and %d, "+
+			"received %d", MaxReseedInterval, interval)
+	}
+	d.mux.Lock()
+	d.reseedInterval = interval
+	d.mux.Unlock()
+	return nil
+}
+
+// Instantiate seeds the generator from the entropy input, nonce and
+// personalization string as described in SP 800-90A section 10.1.2.3. Any
+// previous state is discarded.
+func (d *HmacDRBG) Instantiate(entropy, nonce, personalization []byte) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return d.instantiate(entropy, nonce, personalization)
+}
+
+// Reseed mixes fresh entropy and optional additional input into the state of
+// an instantiated generator and resets the reseed counter.
+func (d *HmacDRBG) Reseed(entropy, additionalInput []byte) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return d.reseed(entropy, additionalInput)
+}
+
+// Generate fills b with pseudorandom bytes, mixing in the optional additional
+// input. A single request may not exceed MaxBytesPerRequest.
+func (d *HmacDRBG) Generate(b, additionalInput []byte) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return d.generate(b, additionalInput)
+}
+
+// Read fills b with pseudorandom bytes. Requests larger than
+// MaxBytesPerRequest are split into several generate calls.
+func (d *HmacDRBG) Read(b []byte) (int, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	n := 0
+	for n < len(b) {
+		end := n + MaxBytesPerRequest
+		if end > len(b) {
+			end = len(b)
+		}
+		if err := d.generate(b[n:end], nil); err != nil {
+			return n, err
+		}
+		n = end
+	}
+	return n, nil
+}
+
+// SetSeed instantiates the generator with the seed as entropy input on first
+// use, and reseeds it with the seed on every call after that.
+func (d *HmacDRBG) SetSeed(seed []byte) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	if !d.instantiated {
+		return d.instantiate(seed, nil, d.personalization)
+	}
+	return d.reseed(seed, nil)
+}
+
+// instantiate implements the HMAC_DRBG instantiate process. The caller must
+// hold the lock.
+func (d *HmacDRBG) instantiate(entropy, nonce, personalization []byte) error {
+	if err := d.checkHash(); err != nil {
+		return err
+	}
+	if len(entropy) < d.securityStrength() {
+		return errors.Errorf(entropyTooShortErr, len(entropy),
+			d.securityStrength())
+	}
+
+	size := d.hashType.Size()
+	d.key = make([]byte, size)
+	d.v = make([]byte, size)
+	for i := range d.v {
+		d.v[i] = 0x01
+	}
+
+	d.update(entropy, nonce, personalization)
+	d.reseedCounter = 1
+	d.instantiated = true
+	return nil
+}
+
+// reseed implements the HMAC_DRBG reseed process. The caller must hold the
+// lock.
+func (d *HmacDRBG) reseed(entropy, additionalInput []byte) error {
+	if !d.instantiated {
+		return ErrNotInstantiated
+	}
+	if len(entropy) < d.securityStrength() {
+		return errors.Errorf(entropyTooShortErr, len(entropy),
+			d.securityStrength())
+	}
+
+	d.update(entropy, additionalInput)
+	d.reseedCounter = 1
+	return nil
+}
+
+// generate implements the HMAC_DRBG generate process. The caller must hold
+// the lock.
+func (d *HmacDRBG) generate(b, additionalInput []byte) error {
+	if !d.instantiated {
+		return ErrNotInstantiated
+	}
+	if len(b) > MaxBytesPerRequest {
+		return errors.Errorf(requestTooLargeErr, len(b),
+			MaxBytesPerRequest)
+	}
+	if d.reseedCounter > d.reseedInterval {
+		return ErrReseedRequired
+	}
+
+	if len(additionalInput) > 0 {
+		d.update(additionalInput)
+	}
+
+	mac := hmac.New(d.newHash, d.key)
+	for n := 0; n < len(b); {
+		mac.Reset()
+		mac.Write(d.v)
+		d.v = mac.Sum(d.v[:0])
+		n += copy(b[n:], d.v)
+	}
+
+	d.update(additionalInput)
+	d.reseedCounter++
+	return nil
+}
+
+// update implements the HMAC_DRBG_Update function over the concatenation of
+// the provided data.
+func (d *HmacDRBG) update(providedData ...[]byte) {
+	empty := true
+	for _, data := range providedData {
+		if len(data) > 0 {
+			empty = false
+		}
+	}
+
+	for _, round := range []byte{0x00, 0x01} {
+		if round == 0x01 && empty {
+			return
+		}
+
+		mac := hmac.New(d.newHash, d.key)
+		mac.Write(d.v)
+		mac.Write([]byte{round})
+		for _, data := range providedData {
+			mac.Write(data)
+		}
+		d.key = mac.Sum(d.key[:0])
+
+		mac = hmac.New(d.newHash, d.key)
+		mac.Write(d.v)
+		d.v = mac.Sum(d.v[:0])
+	}
+}
+
+// newHash returns a new instance of the configured hash for use with HMAC.
+// The hash type has already been checked.
+func (d *HmacDRBG) newHash() hash.Hash {
+	h, _ := d.hashType.New()
+	return h
+}
+
+// checkHash returns an error if the configured hash type is unknown.
+func (d *HmacDRBG) checkHash() error {
+	if !d.hashType.Available() {
+		return errors.Errorf("unsupported hash type %s", d.hashType)
+	}
+	return nil
+}
+
+// securityStrength returns the minimum entropy input length in bytes, which
+// is the security strength of the hash function (SP 800-57 table 3).
+func (d *HmacDRBG) securityStrength() int {
+	if d.hashType.Size() < 32 {
+		return 24
+	}
+	return 32
+}
@@ -0,0 +1,261 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package csprng
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"gitlab.com/xx_network/crypto/hasher"
+)
+
+// hmacDRBGVector is a known-answer test. The expected output is the result of
+// the second generate call, as in the NIST CAVP HMAC_DRBG.rsp vectors.
+type hmacDRBGVector struct {
+	name             string
+	hashType         hasher.HashType
+	entropy          string
+	nonce            string
+	personalization  string
+	reseedEntropy    string
+	reseedAdditional string
+	additional1      string
+	additional2      string
+	returned         string
+}
+
+var hmacDRBGVectors = []hmacDRBGVector{
+	{
+		// NIST CAVP HMAC_DRBG.rsp [SHA-256] no reseed, COUNT = 0
+		name:     "CAVP SHA-256",
+		hashType: hasher.SHA2_256,
+		entropy:  "ca851911349384bffe89de1cbdc46e6831e44d34a4fb935ee285dd14b71a7488",
+		nonce:    "659ba96c601dc69fc902940805ec0ca8",
+		returned: "e528e9abf2dece54d47c7e75e5fe302149f817ea9fb4bee6f4199697d04d5b89" +
+			"d54fbb978a15b5c443c9ec21036d2460b6f73ebad0dc2aba6e624abf07745bc1" +
+			"07694bb7547bb0995f70de25d6b29e2d3011bb19d27676c07162c8b5ccde0668" +
+			"961df86803482cb37ed6d5c0bb8d50cf1f50d476aa0458bdaba806f48be9dcb8",
+	},
+	{
+		// NIST CAVP HMAC_DRBG.rsp [SHA-256] personalization string,
+		// COUNT = 0
+		name:            "CAVP SHA-256 personalization",
+		hashType:        hasher.SHA2_256,
+		entropy:         "5cacc68165a2e2ee20812f35ec73a79dbf30fd475476ac0c44fc6174cdac2b55",
+		nonce:           "6f885496c1e63af620becd9e71ecb824",
+		personalization: "e72dd8590d4ed5295515c35ed6199e9d211b8f069b3058caa6670b96ef1208d0",
+		returned: "f1012cf543f94533df27fedfbf58e5b79a3dc517a9c402bdbfc9a0c0f721f9d5" +
+			"3faf4aafdc4b8f7a1b580fcaa52338d4bd95f58966a243cdcd3f446ed4bc546d" +
+			"9f607b190dd69954450d16cd0e2d6437067d8b44d19a6af7a7cfa8794e5fbd72" +
+			"8e8fb2f2e8db5dd4ff1aa275f35886098e80ff844886060da8b1e7137846b23b",
+	},
+	{
+		// Consistency vector exercising reseed and additional input
+		name:     "SHA-256 reseed and additional input",
+		hashType: hasher.SHA2_256,
+		entropy: "000102030405060708090a0b0c0d0e0f" +
+			"101112131415161718191a1b1c1d1e1f",
+		nonce:           "202122232425262728292a2b2c2d2e2f",
+		personalization: hex.EncodeToString([]byte("xx network personalization")),
+		reseedEntropy: "303132333435363738393a3b3c3d3e3f" +
+			"404142434445464748494a4b4c4d4e4f",
+		reseedAdditional: hex.EncodeToString([]byte("reseed additional")),
+		additional1:      hex.EncodeToString([]byte("additional 1")),
+		additional2:      hex.EncodeToString([]byte("additional 2")),
+		returned: "dac156cbd51985f6cf2fc62145c1afc806df209f7808480bde6e42dd3b54785b" +
+			"575ac4684894eaea5b4774e0a375b62d1b9fea46623de5eab5b5b5d0c5b2a74b",
+	},
+}
+
+// Tests that HmacDRBG reproduces the known-answer vectors.
+func TestHmacDRBG_KnownAnswer(t *testing.T) {
+	for _, v := range hmacDRBGVectors {
+		d, err := NewHmacDRBGFromSeed(v.hashType, decodeHexString(t, v.entropy),
+			decodeHexString(t, v.nonce), decodeHexString(t, v.personalization))
+		if err != nil {
+			t.Fatalf("%s: failed to instantiate: %+v", v.name, err)
+		}
+
+		if v.reseedEntropy != "" {
+			err = d.Reseed(decodeHexString(t, v.reseedEntropy),
+				decodeHexString(t, v.reseedAdditional))
+			if err != nil {
+				t.Fatalf("%s: failed to reseed: %+v", v.name, err)
+			}
+		}
+
+		expected := decodeHexString(t, v.returned)
+		received := make([]byte, len(expected))
+		if err = d.Generate(received, decodeHexString(t, v.additional1)); err != nil {
+			t.Fatalf("%s: first generate failed: %+v", v.name, err)
+		}
+		if err = d.Generate(received, decodeHexString(t, v.additional2)); err != nil {
+			t.Fatalf("%s: second generate failed: %+v", v.name, err)
+		}
+
+		if !bytes.Equal(expected, received) {
+			t.Errorf("%s: unexpected output.\nexpected: %x\nreceived: %x",
+				v.name, expected, received)
+		}
+	}
+}
+
+// Tests that SetSeed instantiates the generator on first use so that two
+// generators seeded identically produce identical output, and that a second
+// SetSeed reseeds rather than reinstantiates.
+func TestHmacDRBG_SetSeed(t *testing.T) {
+	seed := bytes.Repeat([]byte{0x42}, 32)
+	var sc SourceConstructor = func() Source {
+		return NewHmacDRBG(hasher.SHA3_256, []byte("personalization"))
+	}
+
+	a, b := sc(), sc()
+	for _, s := range []Source{a, b} {
+		if err := s.SetSeed(seed); err != nil {
+			t.Fatalf("SetSeed returned an error: %+v", err)
+		}
+	}
+
+	outA, outB := make([]byte, 100), make([]byte, 100)
+	if _, err := a.Read(outA); err != nil {
+		t.Fatalf("Read returned an error: %+v", err)
+	}
+	if _, err := b.Read(outB); err != nil {
+		t.Fatalf("Read returned an error: %+v", err)
+	}
+	if !bytes.Equal(outA, outB) {
+		t.Errorf("Identically seeded generators diverged."+
+			"\nexpected: %x\nreceived: %x", outA, outB)
+	}
+
+	if err := a.SetSeed(seed); err != nil {
+		t.Fatalf("SetSeed returned an error: %+v", err)
+	}
+	fresh := sc()
+	if err := fresh.SetSeed(seed); err != nil {
+		t.Fatalf("SetSeed returned an error: %+v", err)
+	}
+	_, _ = a.Read(outA)
+	_, _ = fresh.Read(outB)
+	if bytes.Equal(outA, outB) {
+		t.Errorf("Second SetSeed reinstantiated instead of reseeding.")
+	}
+}
+
+// Tests that concurrent first calls to SetSeed do not lose a seed.
+func TestHmacDRBG_SetSeed_Concurrent(t *testing.T) {
+	checkConcurrentSetSeed(t, func() Source {
+		return NewHmacDRBG(hasher.SHA2_256, nil)
+	})
+}
+
+// Tests that the output of an instantiated HmacDRBG can be used by Generate
+// and GenerateInGroup.
+func TestHmacDRBG_Generate(t *testing.T) {
+	d, err := NewHmacDRBGFromSeed(hasher.BLAKE2, make([]byte, 32), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to instantiate: %+v", err)
+	}
+
+	b, err := Generate(32, d)
+	if err != nil || len(b) != 32 {
+		t.Errorf("Generate failed: %+v", err)
+	}
+
+	b, err = GenerateInGroup(modp4096, len(modp4096), d)
+	if err != nil {
+		t.Fatalf("GenerateInGroup failed: %+v", err)
+	}
+	if !InGroup(b, modp4096) {
+		t.Errorf("Generated value is not in the group: %x", b)
+	}
+}
+
+// Tests that Read splits requests larger than MaxBytesPerRequest.
+func TestHmacDRBG_Read_Large(t *testing.T) {
+	d, err := NewHmacDRBGFromSeed(hasher.SHA2_256, make([]byte, 32), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to instantiate: %+v", err)
+	}
+
+	b := make([]byte, 2*MaxBytesPerRequest+5)
+	n, err := d.Read(b)
+	if err != nil || n != len(b) {
+		t.Errorf("Read failed.\nexpected: %d bytes\nreceived: %d bytes, %v",
+			len(b), n, err)
+	}
+
+	if err = d.Generate(b, nil); err == nil {
+		t.Errorf("Generate should reject requests over MaxBytesPerRequest.")
+	}
+}
+
+// Tests that the reseed interval is enforced and that reseeding resets it.
+func TestHmacDRBG_ReseedInterval(t *testing.T) {
+	d, err := NewHmacDRBGFromSeed(hasher.SHA2_256, make([]byte, 32), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to instantiate: %+v", err)
+	}
+	if err = d.SetReseedInterval(2); err != nil {
+		t.Fatalf("SetReseedInterval returned an error: %+v", err)
+	}
+
+	b := make([]byte, 16)
+	for i := 0; i < 2; i++ {
+		if _, err = d.Read(b); err != nil {
+			t.Fatalf("Read %d returned an error: %+v", i, err)
+		}
+	}
+	if _, err = d.Read(b); err != ErrReseedRequired {
+		t.Errorf("Read should require a reseed.\nexpected: %v\nreceived: %v",
+			ErrReseedRequired, err)
+	}
+
+	if err = d.SetSeed(make([]byte, 32)); err != nil {
+		t.Fatalf("SetSeed returned an error: %+v", err)
+	}
+	if _, err = d.Read(b); err != nil {
+		t.Errorf("Read after reseed returned an error: %+v", err)
+	}
+
+	if err = d.SetReseedInterval(0); err == nil {
+		t.Errorf("SetReseedInterval should reject an interval of 0.")
+	}
+}
+
+// Error path: tests that an uninstantiated generator and short entropy
+// inputs are rejected.
+func TestHmacDRBG_Errors(t *testing.T) {
+	d := NewHmacDRBG(hasher.SHA2_256, nil)
+	if _, err := d.Read(make([]byte, 1)); err != ErrNotInstantiated {
+		t.Errorf("Read should fail before instantiation."+
+			"\nexpected: %v\nreceived: %v", ErrNotInstantiated, err)
+	}
+	if err := d.Reseed(make([]byte, 32), nil); err != ErrNotInstantiated {
+		t.Errorf("Reseed should fail before instantiation."+
+			"\nexpected: %v\nreceived: %v", ErrNotInstantiated, err)
+	}
+	if err := d.SetSeed(make([]byte, 16)); err == nil {
+		t.Errorf("SetSeed should reject entropy shorter than the " +
+			"security strength.")
+	}
+	if err := NewHmacDRBG(hasher.HashType(200), nil).SetSeed(
+		make([]byte, 32)); err == nil {
+		t.Errorf("SetSeed should reject an unknown hash type.")
+	}
+}
+
+// decodeHexString decodes the hex string or fails the test.
+func decodeHexString(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("Failed to decode hex string %q: %+v", s, err)
+	}
+	return b
+}
@@ -0,0 +1,45 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package csprng
+
+import (
+	"sync"
+)
+
+// SourcePool holds a pool of Sources built by a SourceConstructor so that
+// many goroutines can draw randomness without contending on a single
+// generator. A SourcePool implements io.Reader and can be passed directly to
+// Generate and GenerateInGroup.
+type SourcePool struct {
+	pool sync.Pool
+}
+
+// NewSourcePool returns a SourcePool that builds new Sources with sc.
+func NewSourcePool(sc SourceConstructor) *SourcePool {
+	return &SourcePool{
+		pool: sync.Pool{New: func() interface{} { return sc() }},
+	}
+}
+
+// Get takes a Source from the pool, building a new one if the pool is empty.
+// The Source should be returned with Put once the caller is done with it.
+func (p *SourcePool) Get() Source {
+	return p.pool.Get().(Source)
+}
+
+// Put returns a Source to the pool.
+func (p *SourcePool) Put(s Source) {
+	p.pool.Put(s)
+}
+
+// Read fills b from a pooled Source.
+func (p *SourcePool) Read(b []byte) (int, error) {
+	s := p.Get()
+	defer p.Put(s)
+	return s.Read(b)
+}
@@ -0,0 +1,50 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package csprng
+
+import (
+	"sync"
+	"testing"
+)
+
+// Tests that a SourcePool builds Sources with its constructor and can be read
+// from concurrently.
+func TestSourcePool(t *testing.T) {
+	built := 0
+	var mux sync.Mutex
+	pool := NewSourcePool(func() Source {
+		mux.Lock()
+		built++
+		mux.Unlock()
+		return NewChaChaRNG()
+	})
+
+	if _, ok := pool.Get().(*ChaChaRNG); !ok {
+		t.Errorf("Get did not return a Source built by the constructor.")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				b, err := Generate(32, pool)
+				if err != nil || len(b) != 32 {
+					t.Errorf("Generate from the pool failed: %+v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if built == 0 {
+		t.Errorf("The pool never called its constructor.")
+	}
+}
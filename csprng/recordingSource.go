@@ -0,0 +1,138 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package csprng
+
+import (
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// RecordingSource wraps a Source and writes every call made against it to a
+// transcript, so that the exact sequence of random values used by a protocol
+// run can later be served back by a ReplaySource. Records are written in the
+// order the calls complete; concurrent callers therefore produce a transcript
+// whose order is only reproducible if they are replayed in the same order.
+//
+// A transcript contains every random byte handed out and must be treated as
+// secret. It is intended for debugging and tests, never for production keys.
+type RecordingSource struct {
+	src Source
+	w   io.Writer
+	err error
+	mux sync.Mutex
+}
+
+// NewRecordingSource wraps src and writes the transcript header to w.
+func NewRecordingSource(src Source, w io.Writer) (*RecordingSource, error) {
+	if err := writeTranscriptHeader(w); err != nil {
+		return nil, errors.WithMessage(err, "failed to write transcript header")
+	}
+	return &RecordingSource{src: src, w: w}, nil
+}
+
+// Read reads from the wrapped source and records the call, labelled with the
+// calling function.
+func (r *RecordingSource) Read(b []byte) (int, error) {
+	return r.read(callerLabel(), b)
+}
+
+// SetSeed passes the seed to the wrapped source and records the call,
+// labelled with the calling function.
+func (r *RecordingSource) SetSeed(seed []byte) error {
+	return r.setSeed(callerLabel(), seed)
+}
+
+// Labeled returns a Source that records its calls to the same transcript
+// under the given label instead of the calling function.
+func (r *RecordingSource) Labeled(label string) Source {
+	return &labeledRecordingSource{r: r, label: label}
+}
+
+// Err returns the first error encountered writing the transcript, if any.
+// Once writing fails, all later calls fail with the same error.
+func (r *RecordingSource) Err() error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return r.err
+}
+
+// read performs and records a Read.
+func (r *RecordingSource) read(label string, b []byte) (int, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	n, readErr := r.src.Read(b)
+	if n < 0 || n > len(b) {
+		n = 0
+	}
+	rec := &TranscriptRecord{
+		Kind:      ReadRecord,
+		Label:     label,
+		Requested: len(b),
+		Data:      b[:n],
+	}
+	if readErr != nil {
+		rec.Err = readErr.Error()
+	}
+
+	if err := r.write(rec); err != nil {
+		return 0, err
+	}
+	return n, readErr
+}
+
+// setSeed performs and records a SetSeed.
+func (r *RecordingSource) setSeed(label string, seed []byte) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.err != nil {
+		return r.err
+	}
+
+	rec := &TranscriptRecord{Kind: SetSeedRecord, Label: label, Data: seed}
+	seedErr := r.src.SetSeed(seed)
+	if seedErr != nil {
+		rec.Err = seedErr.Error()
+	}
+
+	if err := r.write(rec); err != nil {
+		return err
+	}
+	return seedErr
+}
+
+// write appends the record to the transcript. The caller must hold the lock.
+func (r *RecordingSource) write(rec *TranscriptRecord) error {
+	if _, err := r.w.Write(rec.Marshal()); err != nil {
+		r.err = errors.Wrap(err, "failed to write transcript record")
+	}
+	return r.err
+}
+
+// labeledRecordingSource records calls under a fixed label.
+type labeledRecordingSource struct {
+	r     *RecordingSource
+	label string
+}
+
+// Read reads from the wrapped source and records the call.
+func (l *labeledRecordingSource) Read(b []byte) (int, error) {
+	return l.r.read(l.label, b)
+}
+
+// SetSeed passes the seed to the wrapped source and records the call.
+func (l *labeledRecordingSource) SetSeed(seed []byte) error {
+	return l.r.setSeed(l.label, seed)
+}
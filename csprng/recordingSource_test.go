@@ -0,0 +1,142 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package csprng
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// errorSource fails every call with the stored error.
+type errorSource struct {
+	err error
+}
+
+func (e *errorSource) Read([]byte) (int, error) { return 0, e.err }
+func (e *errorSource) SetSeed([]byte) error     { return e.err }
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+// Tests that every call is recorded with its size, data and caller label.
+func TestRecordingSource(t *testing.T) {
+	var transcript bytes.Buffer
+	r, err := NewRecordingSource(NewSystemRNG(), &transcript)
+	if err != nil {
+		t.Fatalf("NewRecordingSource returned an error: %+v", err)
+	}
+
+	out := make([]byte, 24)
+	if _, err = r.Read(out); err != nil {
+		t.Fatalf("Read returned an error: %+v", err)
+	}
+	if err = r.Labeled("keygen").SetSeed([]byte("seed")); err != nil {
+		t.Fatalf("SetSeed returned an error: %+v", err)
+	}
+	group, err := GenerateInGroup(modp4096, 32, r)
+	if err != nil {
+		t.Fatalf("GenerateInGroup returned an error: %+v", err)
+	}
+
+	records, err := ReadTranscript(&transcript)
+	if err != nil {
+		t.Fatalf("ReadTranscript returned an error: %+v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Unexpected number of records.\nexpected: %d\nreceived: %d",
+			3, len(records))
+	}
+
+	if records[0].Kind != ReadRecord || records[0].Requested != len(out) ||
+		!bytes.Equal(records[0].Data, out) {
+		t.Errorf("First record does not match the read: %+v", records[0])
+	}
+	if !strings.HasSuffix(records[0].Label, "csprng.TestRecordingSource") {
+		t.Errorf("Unexpected caller label.\nexpected: %s\nreceived: %s",
+			"csprng.TestRecordingSource", records[0].Label)
+	}
+
+	if records[1].Kind != SetSeedRecord || records[1].Label != "keygen" ||
+		string(records[1].Data) != "seed" {
+		t.Errorf("Second record does not match the SetSeed: %+v", records[1])
+	}
+
+	// Reads made through Generate are labelled with the caller outside the
+	// package
+	if !bytes.Equal(records[2].Data, group) ||
+		!strings.HasSuffix(records[2].Label, "csprng.TestRecordingSource") {
+		t.Errorf("Third record does not match GenerateInGroup: %+v", records[2])
+	}
+}
+
+// Tests that errors from the wrapped source are recorded and returned.
+func TestRecordingSource_SourceError(t *testing.T) {
+	expectedErr := errors.New("source failure")
+	var transcript bytes.Buffer
+	r, _ := NewRecordingSource(&errorSource{err: expectedErr}, &transcript)
+
+	if _, err := r.Read(make([]byte, 8)); err != expectedErr {
+		t.Errorf("Read did not return the source error.\nexpected: %v"+
+			"\nreceived: %v", expectedErr, err)
+	}
+
+	records, err := ReadTranscript(&transcript)
+	if err != nil {
+		t.Fatalf("ReadTranscript returned an error: %+v", err)
+	}
+	if len(records) != 1 || records[0].Err != expectedErr.Error() ||
+		len(records[0].Data) != 0 {
+		t.Errorf("Error was not recorded: %+v", records)
+	}
+}
+
+// Error path: tests that a transcript write failure latches.
+func TestRecordingSource_WriteError(t *testing.T) {
+	if _, err := NewRecordingSource(NewSystemRNG(), failingWriter{}); err == nil {
+		t.Errorf("NewRecordingSource should fail when the header cannot " +
+			"be written.")
+	}
+
+	r := &RecordingSource{src: NewSystemRNG(), w: failingWriter{}}
+	if _, err := r.Read(make([]byte, 8)); err == nil {
+		t.Errorf("Read should fail when the record cannot be written.")
+	}
+	if r.Err() == nil {
+		t.Errorf("Write failure was not latched.")
+	}
+	if err := r.SetSeed([]byte("seed")); err != r.Err() {
+		t.Errorf("SetSeed did not return the latched error.\nexpected: %v"+
+			"\nreceived: %v", r.Err(), err)
+	}
+}
+
+// Error path: tests that corrupt transcripts are rejected.
+func TestReadTranscript_Invalid(t *testing.T) {
+	var transcript bytes.Buffer
+	r, _ := NewRecordingSource(NewSystemRNG(), &transcript)
+	_, _ = r.Read(make([]byte, 16))
+	valid := transcript.Bytes()
+
+	tests := map[string][]byte{
+		"empty":     {},
+		"bad magic": append([]byte("XXRX"), valid[4:]...),
+		"version":   append(append([]byte(transcriptMagic), 2), valid[5:]...),
+		"truncated": valid[:len(valid)-3],
+	}
+	for name, data := range tests {
+		if _, err := ReadTranscript(bytes.NewReader(data)); err == nil {
+			t.Errorf("ReadTranscript accepted a %s transcript.", name)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package csprng
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrTranscriptExhausted is returned when a ReplaySource is called after every
+// record in its transcript has been served.
+var ErrTranscriptExhausted = errors.New("replay transcript exhausted")
+
+// ReplayDivergenceError is returned when a call made against a ReplaySource
+// does not match the next record in the transcript.
+type ReplayDivergenceError struct {
+	// Index is the position of the mismatched record in the transcript.
+	Index int
+	// Expected is the record at that position.
+	Expected *TranscriptRecord
+	// Kind, Label and Size describe the call that was actually made. Size is
+	// the buffer length for a Read and the seed length for a SetSeed.
+	Kind  RecordKind
+	Label string
+	Size  int
+	// Seed is the seed passed to a SetSeed, which must equal the recorded
+	// one.
+	Seed []byte
+}
+
+// Error describes how the call diverged from the transcript.
+func (e *ReplayDivergenceError) Error() string {
+	expectedSize := e.Expected.Requested
+	if e.Expected.Kind == SetSeedRecord {
+		expectedSize = len(e.Expected.Data)
+	}
+	msg := fmt.Sprintf("replay diverged at record %d: expected %s of %d "+
+		"bytes from %q, received %s of %d bytes from %q", e.Index,
+		e.Expected.Kind, expectedSize, e.Expected.Label, e.Kind, e.Size,
+		e.Label)
+	if e.Kind == SetSeedRecord && e.Expected.Kind == SetSeedRecord &&
+		!bytes.Equal(e.Seed, e.Expected.Data) {
+		msg += " with a different seed"
+	}
+	return msg
+}
+
+// ReplaySource serves the bytes recorded in a transcript by a RecordingSource.
+// Every call must match the next record: same kind, same size and, if label
+// checking is enabled, the same caller label. SetSeed calls must also pass the
+// recorded seed. Recorded errors and short reads are reproduced.
+type ReplaySource struct {
+	records     []*TranscriptRecord
+	idx         int
+	checkLabels bool
+	mux         sync.Mutex
+}
+
+// NewReplaySource decodes the transcript from r. If checkLabels is set, the
+// caller label of every call must also match the recording.
+func NewReplaySource(r io.Reader, checkLabels bool) (*ReplaySource, error) {
+	records, err := ReadTranscript(r)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplaySource{records: records, checkLabels: checkLabels}, nil
+}
+
+// Read serves the next recorded Read.
+func (r *ReplaySource) Read(b []byte) (int, error) {
+	return r.read(callerLabel(), b)
+}
+
+// SetSeed checks the seed against the next recorded SetSeed.
+func (r *ReplaySource) SetSeed(seed []byte) error {
+	return r.setSeed(callerLabel(), seed)
+}
+
+// Labeled returns a Source that replays from the same transcript under the
+// given label, matching RecordingSource.Labeled.
+func (r *ReplaySource) Labeled(label string) Source {
+	return &labeledReplaySource{r: r, label: label}
+}
+
+// Remaining returns the number of records not yet replayed.
+func (r *ReplaySource) Remaining() int {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return len(r.records) - r.idx
+}
+
+// read serves the next record, which must be a Read of len(b) bytes.
+func (r *ReplaySource) read(label string, b []byte) (int, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	rec, err := r.next(ReadRecord, label, len(b), nil)
+	if err != nil {
+		return 0, err
+	}
+	n := copy(b, rec.Data)
+	if rec.Err != "" {
+		return n, errors.New(rec.Err)
+	}
+	return n, nil
+}
+
+// setSeed consumes the next record, which must be a SetSeed of seed.
+func (r *ReplaySource) setSeed(label string, seed []byte) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	rec, err := r.next(SetSeedRecord, label, len(seed), seed)
+	if err != nil {
+		return err
+	}
+	if rec.Err != "" {
+		return errors.New(rec.Err)
+	}
+	return nil
+}
+
+// next checks the call against the next record and advances past it. For a
+// SetSeed, seed must also equal the recorded seed. The position does not
+// advance on divergence. The caller must hold the lock.
+func (r *ReplaySource) next(kind RecordKind, label string, size int,
+	seed []byte) (*TranscriptRecord, error) {
+	if r.idx >= len(r.records) {
+		return nil, ErrTranscriptExhausted
+	}
+
+	rec := r.records[r.idx]
+	expectedSize := rec.Requested
+	if rec.Kind == SetSeedRecord {
+		expectedSize = len(rec.Data)
+	}
+	if rec.Kind != kind || expectedSize != size ||
+		(r.checkLabels && rec.Label != label) ||
+		(kind == SetSeedRecord && !bytes.Equal(rec.Data, seed)) {
+		return nil, &ReplayDivergenceError{Index: r.idx, Expected: rec,
+			Kind: kind, Label: label, Size: size, Seed: seed}
+	}
+
+	r.idx++
+	return rec, nil
+}
+
+// labeledReplaySource replays calls under a fixed label.
+type labeledReplaySource struct {
+	r     *ReplaySource
+	label string
+}
+
+// Read serves the next recorded Read.
+func (l *labeledReplaySource) Read(b []byte) (int, error) {
+	return l.r.read(l.label, b)
+}
+
+// SetSeed checks the seed against the next recorded SetSeed.
+func (l *labeledReplaySource) SetSeed(seed []byte) error {
+	return l.r.setSeed(l.label, seed)
+}
@@ -0,0 +1,139 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package csprng
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// runProtocol draws a few group elements from src and returns them.
+func runProtocol(t *testing.T, src Source) [][]byte {
+	var outputs [][]byte
+	for i := 0; i < 3; i++ {
+		b, err := GenerateInGroup(modp4096, 64, src)
+		if err != nil {
+			t.Fatalf("GenerateInGroup %d returned an error: %+v", i, err)
+		}
+		outputs = append(outputs, b)
+	}
+	return outputs
+}
+
+// recordRun records a short protocol run and returns its outputs and the
+// transcript.
+func recordRun(t *testing.T) ([][]byte, []byte) {
+	var transcript bytes.Buffer
+	r, err := NewRecordingSource(NewSystemRNG(), &transcript)
+	if err != nil {
+		t.Fatalf("NewRecordingSource returned an error: %+v", err)
+	}
+
+	outputs := runProtocol(t, r)
+	if err = r.Labeled("seed").SetSeed([]byte("seed")); err != nil {
+		t.Fatalf("SetSeed returned an error: %+v", err)
+	}
+	return outputs, transcript.Bytes()
+}
+
+// Tests that replaying a transcript reproduces the recorded run, including
+// the caller labels.
+func TestReplaySource(t *testing.T) {
+	outputs, transcript := recordRun(t)
+
+	r, err := NewReplaySource(bytes.NewReader(transcript), true)
+	if err != nil {
+		t.Fatalf("NewReplaySource returned an error: %+v", err)
+	}
+	for i, b := range runProtocol(t, r) {
+		if !bytes.Equal(b, outputs[i]) {
+			t.Errorf("Replayed output %d differs.\nexpected: %x\nreceived: %x",
+				i, outputs[i], b)
+		}
+	}
+	if err = r.Labeled("seed").SetSeed([]byte("seed")); err != nil {
+		t.Errorf("SetSeed returned an error: %+v", err)
+	}
+
+	if r.Remaining() != 0 {
+		t.Errorf("Records were left unreplayed: %d", r.Remaining())
+	}
+	if _, err = r.Read(make([]byte, 8)); err != ErrTranscriptExhausted {
+		t.Errorf("Read past the end did not fail.\nexpected: %v\nreceived: %v",
+			ErrTranscriptExhausted, err)
+	}
+}
+
+// Tests that recorded source errors are reproduced.
+func TestReplaySource_RecordedError(t *testing.T) {
+	var transcript bytes.Buffer
+	rec, _ := NewRecordingSource(
+		&errorSource{err: errors.New("source failure")}, &transcript)
+	_, _ = rec.Read(make([]byte, 8))
+
+	r, err := NewReplaySource(&transcript, false)
+	if err != nil {
+		t.Fatalf("NewReplaySource returned an error: %+v", err)
+	}
+	n, err := r.Read(make([]byte, 8))
+	if n != 0 || err == nil || err.Error() != "source failure" {
+		t.Errorf("Recorded error was not reproduced: %d bytes, %v", n, err)
+	}
+}
+
+// Error path: tests that diverging calls are rejected without advancing.
+func TestReplaySource_Diverged(t *testing.T) {
+	_, transcript := recordRun(t)
+
+	// Wrong size
+	r, _ := NewReplaySource(bytes.NewReader(transcript), false)
+	_, err := r.Read(make([]byte, 63))
+	var divErr *ReplayDivergenceError
+	if !errors.As(err, &divErr) || divErr.Index != 0 || divErr.Size != 63 {
+		t.Errorf("Read of the wrong size did not diverge: %v", err)
+	}
+	if r.Remaining() != 4 {
+		t.Errorf("Divergence advanced the transcript: %d remaining",
+			r.Remaining())
+	}
+
+	// Wrong kind
+	if err = r.SetSeed(make([]byte, 64)); !errors.As(err, &divErr) {
+		t.Errorf("SetSeed in place of Read did not diverge: %v", err)
+	}
+
+	// Wrong label
+	r, _ = NewReplaySource(bytes.NewReader(transcript), true)
+	_, err = r.Labeled("other").Read(make([]byte, 64))
+	if !errors.As(err, &divErr) {
+		t.Errorf("Read with the wrong label did not diverge: %v", err)
+	}
+
+	// Labels are ignored when checking is disabled
+	r, _ = NewReplaySource(bytes.NewReader(transcript), false)
+	if _, err = r.Labeled("other").Read(make([]byte, 64)); err != nil {
+		t.Errorf("Read with label checking disabled failed: %v", err)
+	}
+
+	// Wrong seed
+	for r.Remaining() > 1 {
+		_, _ = r.Read(make([]byte, 64))
+	}
+	err = r.SetSeed([]byte("deed"))
+	if !errors.As(err, &divErr) || divErr.Index != 3 {
+		t.Errorf("SetSeed with a different seed did not diverge: %v", err)
+	}
+	if r.Remaining() != 1 {
+		t.Errorf("Divergence advanced the transcript: %d remaining",
+			r.Remaining())
+	}
+	if err = r.SetSeed([]byte("seed")); err != nil {
+		t.Errorf("SetSeed with the recorded seed failed: %v", err)
+	}
+}
@@ -18,6 +18,10 @@ import (
 	jww "github.com/spf13/jwalterweatherman"
 )
 
+// maxGenerateAttempts bounds the number of reads GenerateInGroup makes before
+// concluding that the source is stuck, for example on constant output.
+const maxGenerateAttempts = 1 << 16
+
 // Defines the constructor of a source
 type SourceConstructor func() Source
 
@@ -72,18 +76,21 @@ func InGroup(sample, prime []byte) bool {
 // Generate a byte slice of size and return the result
 // Note use of io.Reader interface, as Source implements that, we only
 // require a Read function for these utilities.
+//
+// A read error or a short read is returned as an error; partially filled
+// output is never returned.
 func Generate(size int, rng io.Reader) ([]byte, error) {
 	key := make([]byte, size)
 	byteCount, err := rng.Read(key)
-	if err == nil && byteCount != size {
-		errMsg := fmt.Sprintf("bad read count, %d read != %d needed",
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read random bytes, "+
+			"%d read of %d needed", byteCount, size)
+	}
+	if byteCount != size {
+		return nil, errors.Errorf("bad read count, %d read != %d needed",
 			byteCount, size)
-		if err != nil {
-			return nil, errors.Wrapf(err, errMsg)
-		}
-		return nil, fmt.Errorf(errMsg)
 	}
-	return key, err
+	return key, nil
 }
 
 // GenerateInGroup creates a byte slice of at most size inside the given prime
@@ -116,6 +123,7 @@ func GenerateInGroup(prime []byte, size int, rng io.Reader) ([]byte,
 	// at the same position.
 	key := make([]byte, 0, size)
 	genSize := size
+	attempts := 0
 	if size == primeLen && primeLen > aes.BlockSize {
 		lessThan := false
 		var rngBuf []byte
@@ -136,6 +144,12 @@ func GenerateInGroup(prime []byte, size int, rng io.Reader) ([]byte,
 
 			// Generate entropy when the buffer is empty
 			if rngIdx >= len(rngBuf) {
+				attempts++
+				if attempts > maxGenerateAttempts {
+					return nil, errors.Errorf("could not generate a "+
+						"value in the group after %d reads",
+						maxGenerateAttempts)
+				}
 				newBuf, err := Generate(aes.BlockSize, rng)
 				if err != nil {
 					return nil, err
@@ -183,12 +197,18 @@ func GenerateInGroup(prime []byte, size int, rng io.Reader) ([]byte,
 	// Generate until we get something inside the prime group.
 	// Note that InGroup is really only testing for non-zero if the "slow"
 	// case above is triggered as len(rngValue) < primeLen
-	for {
+	if genSize == 0 {
+		return key, nil
+	}
+	for attempts = 0; attempts < maxGenerateAttempts; attempts++ {
 		rngValue, err := Generate(genSize, rng)
-		// return if we get an error OR if we are in the group
-		if err != nil || InGroup(rngValue, prime) {
-			key = append(key, rngValue...)
-			return key, err
+		if err != nil {
+			return nil, err
+		}
+		if InGroup(rngValue, prime) {
+			return append(key, rngValue...), nil
 		}
 	}
+	return nil, errors.Errorf("could not generate a value in the group "+
+		"after %d reads", maxGenerateAttempts)
 }
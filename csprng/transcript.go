@@ -0,0 +1,211 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package csprng
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Transcript file layout:
+//
+//	header: "XXRT" | version (1 byte)
+//	record: kind (1 byte) | label | requested size | data | error
+//
+// Label, data and error are each prefixed with their length as an unsigned
+// varint and the requested size is an unsigned varint.
+const (
+	transcriptMagic   = "XXRT"
+	transcriptVersion = 1
+
+	// maxTranscriptField bounds the length of a single field when decoding
+	// so that a corrupt transcript cannot trigger a huge allocation.
+	maxTranscriptField = 1 << 30
+)
+
+// RecordKind identifies the Source call a transcript record describes.
+type RecordKind uint8
+
+const (
+	// ReadRecord is a call to Read.
+	ReadRecord RecordKind = iota + 1
+	// SetSeedRecord is a call to SetSeed.
+	SetSeedRecord
+)
+
+// String returns the name of the record kind.
+func (k RecordKind) String() string {
+	switch k {
+	case ReadRecord:
+		return "Read"
+	case SetSeedRecord:
+		return "SetSeed"
+	default:
+		return "unknown record kind"
+	}
+}
+
+// TranscriptRecord is a single call to a Source as stored in a transcript.
+type TranscriptRecord struct {
+	Kind RecordKind
+	// Label identifies the caller, either set explicitly or taken from the
+	// first function outside this package on the call stack.
+	Label string
+	// Requested is the size of the buffer passed to Read.
+	Requested int
+	// Data holds the bytes returned by Read or passed to SetSeed.
+	Data []byte
+	// Err is the error message returned by the call, if any.
+	Err string
+}
+
+// writeTranscriptHeader writes the transcript magic and version.
+func writeTranscriptHeader(w io.Writer) error {
+	_, err := w.Write(append([]byte(transcriptMagic), transcriptVersion))
+	return errors.WithStack(err)
+}
+
+// readTranscriptHeader reads and checks the transcript magic and version.
+func readTranscriptHeader(r io.Reader) error {
+	header := make([]byte, len(transcriptMagic)+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return errors.Wrap(err, "failed to read transcript header")
+	}
+	if string(header[:len(transcriptMagic)]) != transcriptMagic {
+		return errors.New("not a transcript: bad magic")
+	}
+	if header[len(transcriptMagic)] != transcriptVersion {
+		return errors.Errorf("unsupported transcript version %d",
+			header[len(transcriptMagic)])
+	}
+	return nil
+}
+
+// Marshal encodes the record in the transcript record format.
+func (r *TranscriptRecord) Marshal() []byte {
+	var buf bytes.Buffer
+	var tmp [binary.MaxVarintLen64]byte
+
+	writeField := func(field []byte) {
+		buf.Write(tmp[:binary.PutUvarint(tmp[:], uint64(len(field)))])
+		buf.Write(field)
+	}
+
+	buf.WriteByte(byte(r.Kind))
+	writeField([]byte(r.Label))
+	buf.Write(tmp[:binary.PutUvarint(tmp[:], uint64(r.Requested))])
+	writeField(r.Data)
+	writeField([]byte(r.Err))
+	return buf.Bytes()
+}
+
+// readTranscriptRecord decodes the next record. It returns io.EOF if the
+// transcript ends cleanly before the record.
+func readTranscriptRecord(r *bufio.Reader) (*TranscriptRecord, error) {
+	kind, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	readField := func() ([]byte, error) {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if n > maxTranscriptField {
+			return nil, errors.Errorf("transcript field of %d bytes is "+
+				"too large", n)
+		}
+		field := make([]byte, n)
+		_, err = io.ReadFull(r, field)
+		return field, err
+	}
+
+	rec := &TranscriptRecord{Kind: RecordKind(kind)}
+	label, err := readField()
+	if err != nil {
+		return nil, errors.Wrap(unexpectedEOF(err), "failed to read label")
+	}
+	rec.Label = string(label)
+
+	requested, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, errors.Wrap(unexpectedEOF(err), "failed to read size")
+	}
+	rec.Requested = int(requested)
+
+	if rec.Data, err = readField(); err != nil {
+		return nil, errors.Wrap(unexpectedEOF(err), "failed to read data")
+	}
+	errMsg, err := readField()
+	if err != nil {
+		return nil, errors.Wrap(unexpectedEOF(err), "failed to read error")
+	}
+	rec.Err = string(errMsg)
+
+	return rec, nil
+}
+
+// ReadTranscript decodes every record in a transcript.
+func ReadTranscript(r io.Reader) ([]*TranscriptRecord, error) {
+	br := bufio.NewReader(r)
+	if err := readTranscriptHeader(br); err != nil {
+		return nil, err
+	}
+
+	var records []*TranscriptRecord
+	for {
+		rec, err := readTranscriptRecord(br)
+		if err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode record %d",
+				len(records))
+		}
+		records = append(records, rec)
+	}
+}
+
+// unexpectedEOF converts io.EOF inside a record to io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// csprngPkgPath is the import path of this package, used to skip its own
+// frames when labelling callers.
+var csprngPkgPath = reflect.TypeOf(TranscriptRecord{}).PkgPath()
+
+// callerLabel returns the name of the first function on the call stack that
+// is not in package io or in a non-test file of this package.
+func callerLabel() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		fn := frame.Function
+		inPkg := strings.HasPrefix(fn, csprngPkgPath+".") &&
+			!strings.HasSuffix(frame.File, "_test.go")
+		if !inPkg && !strings.HasPrefix(fn, "io.") {
+			return fn
+		}
+		if !more {
+			return ""
+		}
+	}
+}
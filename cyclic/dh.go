@@ -0,0 +1,107 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package cyclic
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+	"gitlab.com/xx_network/crypto/hasher"
+	"gitlab.com/xx_network/crypto/kdf"
+	"gitlab.com/xx_network/crypto/large"
+)
+
+// dhKDFSalt is the HKDF salt used by DeriveKey to separate Diffie-Hellman
+// keys from other uses of the shared secret.
+const dhKDFSalt = "xx_network/crypto cyclic DH v1"
+
+// PrivateKey is a Diffie-Hellman private key in a Group.
+type PrivateKey struct {
+	group  *Group
+	x      *large.Int
+	public *Element
+}
+
+// GenerateKey returns a new Diffie-Hellman key pair with a random exponent.
+func (g *Group) GenerateKey(rng io.Reader) (*PrivateKey, error) {
+	x, err := g.RandomExponent(rng)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to generate DH key")
+	}
+	return g.newPrivateKey(x), nil
+}
+
+// NewPrivateKey returns the private key with exponent 0 < x < q.
+func (g *Group) NewPrivateKey(x *large.Int) (*PrivateKey, error) {
+	if x.Cmp(large.NewInt(0)) <= 0 || x.Cmp(g.q) >= 0 {
+		return nil, errors.New("private exponent must be greater than 0 " +
+			"and less than q")
+	}
+	return g.newPrivateKey(x.DeepCopy()), nil
+}
+
+// NewPrivateKeyFromBytes decodes a private key serialized with
+// PrivateKey.Bytes.
+func (g *Group) NewPrivateKeyFromBytes(buf []byte) (*PrivateKey, error) {
+	if len(buf) != g.q.ByteLen() {
+		return nil, errors.Errorf("private key must be %d bytes, "+
+			"received %d", g.q.ByteLen(), len(buf))
+	}
+	return g.NewPrivateKey(large.NewIntFromBytes(buf))
+}
+
+// newPrivateKey computes the public key for the exponent.
+func (g *Group) newPrivateKey(x *large.Int) *PrivateKey {
+	return &PrivateKey{
+		group:  g,
+		x:      x,
+		public: g.ExpSecret(g.Generator(), x),
+	}
+}
+
+// Group returns the group of the key.
+func (k *PrivateKey) Group() *Group {
+	return k.group
+}
+
+// Public returns the public key g^x.
+func (k *PrivateKey) Public() *Element {
+	return k.public
+}
+
+// Bytes returns the private exponent as a big-endian byte slice the length of
+// q.
+func (k *PrivateKey) Bytes() []byte {
+	return k.x.LeftpadBytes(uint64(k.group.q.ByteLen()))
+}
+
+// SharedSecret returns peer^x, the Diffie-Hellman shared secret with the
+// owner of the peer public key.
+func (k *PrivateKey) SharedSecret(peer *Element) (*Element, error) {
+	if !k.group.Equal(peer.group) {
+		return nil, errors.New("peer public key is from a different group")
+	}
+	if peer.IsIdentity() {
+		return nil, errors.New("peer public key is the identity")
+	}
+	return k.group.ExpSecret(peer, k.x), nil
+}
+
+// DeriveKey returns size bytes of key material derived with HKDF over the
+// given hash from the shared secret with the peer and the context info.
+func (k *PrivateKey) DeriveKey(peer *Element, h hasher.HashType,
+	info []byte, size int) ([]byte, error) {
+	if !h.Available() {
+		return nil, errors.Errorf("unknown hash type %d", uint8(h))
+	}
+	secret, err := k.SharedSecret(peer)
+	if err != nil {
+		return nil, err
+	}
+	return kdf.Key(h, secret.Bytes(), []byte(dhKDFSalt), info, size)
+}
@@ -0,0 +1,109 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package cyclic
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"gitlab.com/xx_network/crypto/hasher"
+	"gitlab.com/xx_network/crypto/large"
+)
+
+// Happy path: both sides of a key agreement derive the same secret and key.
+func TestDiffieHellman(t *testing.T) {
+	for _, grp := range []*Group{testGroup(t), MODP2048, FFDHE3072} {
+		alice, err := grp.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bob, err := grp.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s1, err := alice.SharedSecret(bob.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		s2, err := bob.SharedSecret(alice.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !s1.Equal(s2) {
+			t.Errorf("Shared secrets differ")
+		}
+
+		info := []byte("test")
+		k1, err := alice.DeriveKey(bob.Public(), hasher.SHA3_256, info, 48)
+		if err != nil {
+			t.Fatal(err)
+		}
+		k2, err := bob.DeriveKey(alice.Public(), hasher.SHA3_256, info, 48)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(k1) != 48 || !bytes.Equal(k1, k2) {
+			t.Errorf("Derived keys differ")
+		}
+		k3, err := bob.DeriveKey(alice.Public(), hasher.SHA3_256,
+			[]byte("other"), 48)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(k1, k3) {
+			t.Errorf("Derived key does not depend on info")
+		}
+	}
+}
+
+// Tests that private keys survive serialization.
+func TestPrivateKey_Bytes(t *testing.T) {
+	grp := MODP2048
+	key, err := grp.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := grp.NewPrivateKeyFromBytes(key.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to decode private key: %+v", err)
+	}
+	if !decoded.Public().Equal(key.Public()) {
+		t.Errorf("Decoded key has a different public key")
+	}
+	if _, err = grp.NewPrivateKeyFromBytes(key.Bytes()[1:]); err == nil {
+		t.Errorf("Short private key decoded")
+	}
+}
+
+// Error path: invalid exponents, peers from another group, the identity and
+// unknown hashes are rejected.
+func TestDiffieHellman_Errors(t *testing.T) {
+	grp := testGroup(t)
+	for _, x := range []int64{0, -1, 1019, 2000} {
+		if _, err := grp.NewPrivateKey(large.NewInt(x)); err == nil {
+			t.Errorf("NewPrivateKey(%d) did not return an error", x)
+		}
+	}
+
+	key, err := grp.NewPrivateKey(large.NewInt(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = key.SharedSecret(grp.Identity()); err == nil {
+		t.Errorf("Shared secret with the identity succeeded")
+	}
+	if _, err = key.SharedSecret(MODP2048.Generator()); err == nil {
+		t.Errorf("Shared secret with another group succeeded")
+	}
+	if _, err = key.DeriveKey(grp.Generator(), hasher.HashType(200), nil,
+		32); err == nil {
+		t.Errorf("DeriveKey with an unknown hash succeeded")
+	}
+}
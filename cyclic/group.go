@@ -0,0 +1,334 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+// Package cyclic implements prime-order subgroups of the multiplicative group
+// modulo a prime p, and Diffie-Hellman key agreement in them.
+//
+// A Group is defined by a prime p, a prime subgroup order q dividing p-1 and a
+// generator g of that subgroup. Elements are only created through
+// constructors that check subgroup membership, so every Element is in the
+// order q subgroup; 0, 1, p-1 and members of other small subgroups are
+// rejected. Operations with secret exponents use the constant-time
+// Montgomery arithmetic in the large package.
+package cyclic
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+	jww "github.com/spf13/jwalterweatherman"
+	"gitlab.com/xx_network/crypto/csprng"
+	"gitlab.com/xx_network/crypto/large"
+)
+
+// maxExponentAttempts bounds the number of draws RandomExponent makes before
+// giving up on a source that keeps producing zero.
+const maxExponentAttempts = 128
+
+// Group is a prime-order subgroup of the integers modulo a prime. Its methods
+// other than UnmarshalJSON do not change it and are safe for concurrent use.
+// UnmarshalJSON replaces the group in place, so it must finish before the
+// group is shared.
+type Group struct {
+	name    string
+	p, q, g *large.Int
+	pMinus1 *large.Int
+
+	// modulus is the Montgomery context for p used by operations on secret
+	// values. It is built on first use because it is expensive for the
+	// larger built-in groups.
+	modulus     *large.Modulus
+	modulusOnce sync.Once
+}
+
+// NewGroup returns the subgroup of order q generated by g modulo p after
+// checking that p and q are prime, q divides p-1 and g generates the order q
+// subgroup.
+func NewGroup(p, q, g *large.Int) (*Group, error) {
+	if p == nil || q == nil || g == nil {
+		return nil, errors.New("group parameters must not be nil")
+	}
+	if p.Cmp(large.NewInt(5)) < 0 || !p.IsPrime() {
+		return nil, errors.New("modulus p is not an odd prime greater " +
+			"than 3")
+	}
+	if q.Cmp(large.NewInt(2)) <= 0 || !q.IsPrime() {
+		return nil, errors.New("subgroup order q is not an odd prime")
+	}
+
+	grp := newGroup("", p, q, g)
+	if large.NewInt(0).Mod(grp.pMinus1, q).Cmp(large.NewInt(0)) != 0 {
+		return nil, errors.New("subgroup order q does not divide p-1")
+	}
+	if err := grp.checkElement(g); err != nil {
+		return nil, errors.WithMessage(err, "invalid generator")
+	}
+	return grp, nil
+}
+
+// newGroup builds a Group without validating its parameters.
+func newGroup(name string, p, q, g *large.Int) *Group {
+	return &Group{
+		name:    name,
+		p:       p.DeepCopy(),
+		q:       q.DeepCopy(),
+		g:       g.DeepCopy(),
+		pMinus1: large.NewInt(0).Sub(p, large.NewInt(1)),
+	}
+}
+
+// newBuiltinGroup returns the safe-prime group with the given hexadecimal
+// prime p, subgroup order (p-1)/2 and generator 2.
+func newBuiltinGroup(name, pHex string) *Group {
+	p := large.NewIntFromString(pHex, 16)
+	if p == nil {
+		jww.FATAL.Panicf("cyclic: invalid prime for built-in group %s", name)
+	}
+	q := large.NewInt(0).RightShift(p, 1)
+	return newGroup(name, p, q, large.NewInt(2))
+}
+
+// Name returns the name of a built-in group or an empty string for groups
+// created with NewGroup.
+func (g *Group) Name() string {
+	return g.name
+}
+
+// P returns a copy of the modulus.
+func (g *Group) P() *large.Int {
+	return g.p.DeepCopy()
+}
+
+// Q returns a copy of the subgroup order.
+func (g *Group) Q() *large.Int {
+	return g.q.DeepCopy()
+}
+
+// G returns a copy of the generator.
+func (g *Group) G() *large.Int {
+	return g.g.DeepCopy()
+}
+
+// ByteLen returns the length in bytes of serialized elements.
+func (g *Group) ByteLen() int {
+	return g.p.ByteLen()
+}
+
+// Equal returns true if both groups have the same parameters.
+func (g *Group) Equal(other *Group) bool {
+	return g == other || (g.p.Cmp(other.p) == 0 && g.q.Cmp(other.q) == 0 &&
+		g.g.Cmp(other.g) == 0)
+}
+
+// getModulus returns the Montgomery context for p.
+func (g *Group) getModulus() *large.Modulus {
+	g.modulusOnce.Do(func() {
+		var err error
+		g.modulus, err = large.NewModulus(g.p)
+		if err != nil {
+			jww.FATAL.Panicf("cyclic: failed to create modulus: %+v", err)
+		}
+	})
+	return g.modulus
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Elements                                                                   //
+////////////////////////////////////////////////////////////////////////////////
+
+// Element is a member of the order q subgroup of a Group.
+type Element struct {
+	group *Group
+	value *large.Int
+}
+
+// NewElement returns x as an element of the group. It returns an error if x
+// is not in the order q subgroup or is 1 or p-1.
+func (g *Group) NewElement(x *large.Int) (*Element, error) {
+	if err := g.checkElement(x); err != nil {
+		return nil, err
+	}
+	return &Element{group: g, value: x.DeepCopy()}, nil
+}
+
+// NewElementFromBytes decodes an element serialized with Element.Bytes. The
+// buffer must be exactly ByteLen bytes long.
+func (g *Group) NewElementFromBytes(buf []byte) (*Element, error) {
+	if len(buf) != g.ByteLen() {
+		return nil, errors.Errorf("element must be %d bytes, received %d",
+			g.ByteLen(), len(buf))
+	}
+	return g.NewElement(large.NewIntFromBytes(buf))
+}
+
+// checkElement returns an error unless 1 < x < p-1 and x^q = 1 mod p.
+func (g *Group) checkElement(x *large.Int) error {
+	if x.Cmp(large.NewInt(1)) <= 0 || x.Cmp(g.pMinus1) >= 0 {
+		return errors.New("element must be greater than 1 and less than " +
+			"p-1")
+	}
+	if large.NewInt(0).Exp(x, g.q, g.p).Cmp(large.NewInt(1)) != 0 {
+		return errors.New("element is not in the prime-order subgroup")
+	}
+	return nil
+}
+
+// Generator returns the generator of the group.
+func (g *Group) Generator() *Element {
+	return &Element{group: g, value: g.g.DeepCopy()}
+}
+
+// Identity returns the identity element 1. It cannot be created with
+// NewElement but can result from arithmetic.
+func (g *Group) Identity() *Element {
+	return &Element{group: g, value: large.NewInt(1)}
+}
+
+// Group returns the group of the element.
+func (e *Element) Group() *Group {
+	return e.group
+}
+
+// Int returns a copy of the value of the element.
+func (e *Element) Int() *large.Int {
+	return e.value.DeepCopy()
+}
+
+// Bytes returns the element as a big-endian byte slice ByteLen bytes long.
+func (e *Element) Bytes() []byte {
+	return e.value.LeftpadBytes(uint64(e.group.ByteLen()))
+}
+
+// IsIdentity returns true if the element is 1.
+func (e *Element) IsIdentity() bool {
+	return e.value.Cmp(large.NewInt(1)) == 0
+}
+
+// Equal returns true if both elements are in the same group and have the same
+// value.
+func (e *Element) Equal(other *Element) bool {
+	return e.group.Equal(other.group) && e.value.Cmp(other.value) == 0
+}
+
+// String returns the element in hexadecimal, truncated like large.Int.Text.
+func (e *Element) String() string {
+	return e.value.Text(16)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Arithmetic                                                                 //
+////////////////////////////////////////////////////////////////////////////////
+
+// Mul returns x·y mod p.
+func (g *Group) Mul(x, y *Element) *Element {
+	g.checkGroup(x, y)
+	v := large.NewInt(0).Mul(x.value, y.value)
+	return &Element{group: g, value: v.Mod(v, g.p)}
+}
+
+// Inverse returns x⁻¹ mod p.
+func (g *Group) Inverse(x *Element) *Element {
+	g.checkGroup(x)
+	return &Element{group: g, value: large.NewInt(0).ModInverse(x.value, g.p)}
+}
+
+// Div returns x·y⁻¹ mod p.
+func (g *Group) Div(x, y *Element) *Element {
+	return g.Mul(x, g.Inverse(y))
+}
+
+// Exp returns x^k mod p for a public exponent k, which may be negative. It
+// is not constant time; use ExpSecret for secret exponents.
+func (g *Group) Exp(x *Element, k *large.Int) *Element {
+	g.checkGroup(x)
+	e := large.NewInt(0).Mod(k, g.q)
+	return &Element{group: g, value: large.NewInt(0).Exp(x.value, e, g.p)}
+}
+
+// ExpSecret returns x^k mod p in time independent of the secret exponent
+// 0 <= k < q.
+func (g *Group) ExpSecret(x *Element, k *large.Int) *Element {
+	g.checkGroup(x)
+	m := g.getModulus()
+	base := m.NewMontIntFromBytes(x.Bytes())
+	exp := k.LeftpadBytes(uint64(g.q.ByteLen()))
+	return &Element{group: g, value: m.Zero().ExpBytes(base, exp).Int()}
+}
+
+// RandomExponent returns a random exponent 0 < k < q drawn with
+// csprng.GenerateInGroup.
+func (g *Group) RandomExponent(rng io.Reader) (*large.Int, error) {
+	for i := 0; i < maxExponentAttempts; i++ {
+		buf, err := csprng.GenerateInGroup(g.q.Bytes(), g.q.ByteLen(), rng)
+		if err != nil {
+			return nil, errors.WithMessage(err,
+				"failed to generate exponent")
+		}
+		k := large.NewIntFromBytes(buf)
+		if k.Cmp(large.NewInt(0)) != 0 {
+			return k, nil
+		}
+	}
+	return nil, errors.Errorf("failed to generate a non-zero exponent "+
+		"after %d attempts", maxExponentAttempts)
+}
+
+// RandomElement returns g^k for a random exponent k.
+func (g *Group) RandomElement(rng io.Reader) (*Element, error) {
+	k, err := g.RandomExponent(rng)
+	if err != nil {
+		return nil, err
+	}
+	return g.ExpSecret(g.Generator(), k), nil
+}
+
+// checkGroup panics if any element is not from this group.
+func (g *Group) checkGroup(elements ...*Element) {
+	for _, e := range elements {
+		if !g.Equal(e.group) {
+			jww.FATAL.Panicf("cyclic: element is from a different group")
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Serialization                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+// groupJSON is the JSON representation of a Group.
+type groupJSON struct {
+	P *large.Int `json:"p"`
+	Q *large.Int `json:"q"`
+	G *large.Int `json:"g"`
+}
+
+// MarshalJSON encodes the group parameters as JSON. The name of a built-in
+// group is not included.
+func (g *Group) MarshalJSON() ([]byte, error) {
+	return json.Marshal(groupJSON{P: g.p, Q: g.q, G: g.g})
+}
+
+// UnmarshalJSON decodes group parameters from JSON and validates them as
+// NewGroup does. It replaces all state of g, including its name and the
+// Montgomery context built for a previous p. It must not be called while
+// other goroutines use g.
+func (g *Group) UnmarshalJSON(data []byte) error {
+	var gj groupJSON
+	if err := json.Unmarshal(data, &gj); err != nil {
+		return err
+	}
+	grp, err := NewGroup(gj.P, gj.Q, gj.G)
+	if err != nil {
+		return err
+	}
+	g.name = grp.name
+	g.p, g.q, g.g, g.pMinus1 = grp.p, grp.q, grp.g, grp.pMinus1
+	g.modulus, g.modulusOnce = nil, sync.Once{}
+	return nil
+}
@@ -0,0 +1,262 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package cyclic
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gitlab.com/xx_network/crypto/csprng"
+	"gitlab.com/xx_network/crypto/large"
+)
+
+// testGroup returns a small safe-prime group for fast tests: p = 2q + 1 with
+// q = 1019 and generator 4.
+func testGroup(t *testing.T) *Group {
+	grp, err := NewGroup(large.NewInt(2039), large.NewInt(1019),
+		large.NewInt(4))
+	if err != nil {
+		t.Fatalf("Failed to create test group: %+v", err)
+	}
+	return grp
+}
+
+// Tests that the built-in groups have the expected size and structure: p and
+// q = (p-1)/2 are prime and 2 generates the order q subgroup. The leading
+// words after the fixed 64 one bits are the binary expansions of pi (RFC
+// 3526) and e (RFC 7919).
+func TestBuiltinGroups(t *testing.T) {
+	tests := []struct {
+		grp    *Group
+		bits   int
+		prefix string
+	}{
+		{MODP1536, 1536, "ffffffffffffffffc90fdaa22168c234"},
+		{MODP2048, 2048, "ffffffffffffffffc90fdaa22168c234"},
+		{MODP3072, 3072, "ffffffffffffffffc90fdaa22168c234"},
+		{MODP4096, 4096, "ffffffffffffffffc90fdaa22168c234"},
+		{MODP6144, 6144, "ffffffffffffffffc90fdaa22168c234"},
+		{MODP8192, 8192, "ffffffffffffffffc90fdaa22168c234"},
+		{FFDHE2048, 2048, "ffffffffffffffffadf85458a2bb4a9a"},
+		{FFDHE3072, 3072, "ffffffffffffffffadf85458a2bb4a9a"},
+		{FFDHE4096, 4096, "ffffffffffffffffadf85458a2bb4a9a"},
+		{FFDHE6144, 6144, "ffffffffffffffffadf85458a2bb4a9a"},
+		{FFDHE8192, 8192, "ffffffffffffffffadf85458a2bb4a9a"},
+	}
+	for _, tt := range tests {
+		p := tt.grp.P()
+		hex := p.TextVerbose(16, 0)
+		if p.BitLen() != tt.bits || !strings.HasPrefix(hex, tt.prefix) ||
+			!strings.HasSuffix(hex, "ffffffffffffffff") {
+			t.Errorf("%s: unexpected prime %s", tt.grp.Name(), p.Text(16))
+		}
+		if testing.Short() && tt.bits > 4096 {
+			continue
+		}
+		if !p.BigInt().ProbablyPrime(1) ||
+			!tt.grp.Q().BigInt().ProbablyPrime(1) {
+			t.Errorf("%s: p or q is not prime", tt.grp.Name())
+		}
+		if err := tt.grp.checkElement(tt.grp.G()); err != nil {
+			t.Errorf("%s: invalid generator: %+v", tt.grp.Name(), err)
+		}
+	}
+}
+
+// Tests the commonly quoted tail of the 2048-bit primes.
+func TestBuiltinGroups_Tail(t *testing.T) {
+	if !strings.HasSuffix(MODP2048.P().TextVerbose(16, 0),
+		"15728e5a8aacaa68ffffffffffffffff") {
+		t.Errorf("MODP2048 prime does not match RFC 3526")
+	}
+	if !strings.HasSuffix(FFDHE2048.P().TextVerbose(16, 0),
+		"886b423861285c97ffffffffffffffff") {
+		t.Errorf("FFDHE2048 prime does not match RFC 7919")
+	}
+}
+
+// Error path: NewGroup rejects invalid parameters.
+func TestNewGroup_Invalid(t *testing.T) {
+	tests := []struct{ p, q, g int64 }{
+		{2040, 1019, 4},    // p not prime
+		{2039, 1018, 4},    // q not prime
+		{2039, 1013, 4},    // q does not divide p-1
+		{2039, 1019, 1},    // generator 1
+		{2039, 1019, 2038}, // generator p-1 of order 2
+		{2039, 1019, 7},    // generator not in the subgroup
+		{3, 2, 2},
+	}
+	for _, tt := range tests {
+		_, err := NewGroup(large.NewInt(tt.p), large.NewInt(tt.q),
+			large.NewInt(tt.g))
+		if err == nil {
+			t.Errorf("NewGroup(%d, %d, %d) did not return an error", tt.p,
+				tt.q, tt.g)
+		}
+	}
+	if _, err := NewGroup(nil, large.NewInt(1019), large.NewInt(4)); err == nil {
+		t.Errorf("NewGroup with a nil parameter did not return an error")
+	}
+}
+
+// Tests that NewElement rejects 0, 1, p-1, values outside the subgroup and
+// values out of range, and accepts subgroup members.
+func TestGroup_NewElement(t *testing.T) {
+	grp := testGroup(t)
+	for _, x := range []int64{0, 1, 2038, 2039, 5000, -4, 7} {
+		if _, err := grp.NewElement(large.NewInt(x)); err == nil {
+			t.Errorf("NewElement(%d) did not return an error", x)
+		}
+	}
+	for _, x := range []int64{4, 16, 9} {
+		if _, err := grp.NewElement(large.NewInt(x)); err != nil {
+			t.Errorf("NewElement(%d) returned an error: %+v", x, err)
+		}
+	}
+}
+
+// Tests that elements survive serialization and that wrongly sized buffers
+// are rejected.
+func TestElement_Bytes(t *testing.T) {
+	grp := MODP2048
+	e, err := grp.RandomElement(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := e.Bytes()
+	if len(buf) != 256 {
+		t.Errorf("Element is %d bytes", len(buf))
+	}
+	decoded, err := grp.NewElementFromBytes(buf)
+	if err != nil {
+		t.Fatalf("Failed to decode element: %+v", err)
+	}
+	if !decoded.Equal(e) {
+		t.Errorf("Decoded element differs")
+	}
+	if _, err = grp.NewElementFromBytes(buf[1:]); err == nil {
+		t.Errorf("Short buffer decoded")
+	}
+	if !bytes.Equal(grp.Generator().Bytes()[:255], make([]byte, 255)) {
+		t.Errorf("Element bytes are not left padded")
+	}
+}
+
+// Tests the group laws of Mul, Inverse, Div and Exp, and that ExpSecret
+// matches Exp.
+func TestGroup_Arithmetic(t *testing.T) {
+	for _, grp := range []*Group{testGroup(t), MODP2048} {
+		x, err := grp.RandomElement(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		y, err := grp.RandomElement(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !grp.Mul(x, grp.Inverse(x)).IsIdentity() {
+			t.Errorf("x·x⁻¹ is not the identity")
+		}
+		if !grp.Mul(grp.Div(x, y), y).Equal(x) {
+			t.Errorf("(x/y)·y is not x")
+		}
+		if !grp.Exp(x, grp.Q()).IsIdentity() {
+			t.Errorf("x^q is not the identity")
+		}
+		if !grp.Exp(x, large.NewInt(-1)).Equal(grp.Inverse(x)) {
+			t.Errorf("x^-1 is not the inverse")
+		}
+
+		k, err := grp.RandomExponent(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !grp.ExpSecret(x, k).Equal(grp.Exp(x, k)) {
+			t.Errorf("ExpSecret differs from Exp")
+		}
+		if !grp.ExpSecret(x, large.NewInt(0)).IsIdentity() {
+			t.Errorf("x^0 is not the identity")
+		}
+	}
+}
+
+// Tests that combining elements of different groups panics.
+func TestGroup_DifferentGroups(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Mixing groups did not panic")
+		}
+	}()
+	MODP2048.Mul(MODP2048.Generator(), FFDHE2048.Generator())
+}
+
+// Error path: RandomExponent fails when the source fails or only produces
+// zeros.
+func TestGroup_RandomExponent_Errors(t *testing.T) {
+	grp := MODP2048
+	if _, err := grp.RandomExponent(csprng.NewFailingSource(
+		csprng.NewSystemRNG(), 0, nil)); err == nil {
+		t.Errorf("RandomExponent with a failing source succeeded")
+	}
+	if _, err := grp.RandomExponent(csprng.NewZeroSource()); err == nil {
+		t.Errorf("RandomExponent with a zero source succeeded")
+	}
+}
+
+// Tests that groups survive JSON serialization and that invalid parameters
+// are rejected when decoding.
+func TestGroup_JSON(t *testing.T) {
+	data, err := json.Marshal(FFDHE2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grp := &Group{}
+	if err = json.Unmarshal(data, grp); err != nil {
+		t.Fatalf("Failed to decode group: %+v", err)
+	}
+	if !grp.Equal(FFDHE2048) {
+		t.Errorf("Decoded group differs")
+	}
+	if !grp.Exp(grp.Generator(), large.NewInt(5)).Equal(
+		FFDHE2048.Exp(FFDHE2048.Generator(), large.NewInt(5))) {
+		t.Errorf("Decoded group computes differently")
+	}
+
+	bad, _ := json.Marshal(groupJSON{P: large.NewInt(2039),
+		Q: large.NewInt(1019), G: large.NewInt(7)})
+	if err = json.Unmarshal(bad, &Group{}); err == nil {
+		t.Errorf("Invalid group decoded")
+	}
+}
+
+// Tests that decoding into a group that has already been used replaces its
+// name and Montgomery context along with its parameters.
+func TestGroup_JSON_Reuse(t *testing.T) {
+	grp := newBuiltinGroup("modp2048", modp2048Prime)
+	k := large.NewInt(12345)
+	grp.ExpSecret(grp.Generator(), k)
+
+	small := testGroup(t)
+	data, _ := json.Marshal(small)
+	if err := json.Unmarshal(data, grp); err != nil {
+		t.Fatalf("Failed to decode group: %+v", err)
+	}
+	if grp.Name() != "" {
+		t.Errorf("Decoded group kept the name %q", grp.Name())
+	}
+	expected := small.Exp(small.Generator(), k)
+	if received := grp.ExpSecret(grp.Generator(), k); !received.Equal(
+		expected) {
+		t.Errorf("ExpSecret() of the decoded group returned %s, expected %s",
+			received, expected)
+	}
+}
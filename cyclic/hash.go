@@ -0,0 +1,51 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package cyclic
+
+import (
+	"github.com/pkg/errors"
+	"gitlab.com/xx_network/crypto/hashtocurve"
+)
+
+// hashSecurityLevel is the security level k, in bits, at which HashToElement
+// hashes to the integers modulo p.
+const hashSecurityLevel = 128
+
+// HashToElement hashes msg to an element of the group whose discrete
+// logarithm nobody knows, for protocols such as OPRFs and DLEQ proofs. It
+// hashes to an integer u modulo p with hash_to_field of RFC 9380 section 5.2,
+// at a security level of 128 bits, and clears the cofactor by returning
+// u^((p-1)/q), which for the built-in safe-prime groups is u². The result is
+// uniform in the subgroup.
+//
+// It runs in time that depends only on the group and the length of msg, so
+// msg may be secret. An error is only returned for an invalid DST or
+// Expander, or if u is 0 or in the subgroup of order (p-1)/q, which is as
+// likely as guessing a random exponent.
+func (g *Group) HashToElement(e hashtocurve.Expander, msg,
+	dst []byte) (*Element, error) {
+	// hash_to_field with a count of one, reduced in constant time
+	length := (g.p.BitLen() + hashSecurityLevel + 7) / 8
+	uniform, err := e.Expand(msg, dst, length)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to hash to the group")
+	}
+	m := g.getModulus()
+	u := m.NewMontIntFromBytes(uniform)
+
+	cofactor := g.pMinus1.DeepCopy()
+	cofactor.Div(cofactor, g.q)
+	x := m.Zero().ExpBytes(u, cofactor.Bytes())
+
+	// Every power of u is in the subgroup, and p-1 is not as q is odd, so
+	// only 0 and 1 need to be rejected
+	if x.IsZero()|x.Equal(m.One()) == 1 {
+		return nil, errors.New("message hashed to the identity")
+	}
+	return &Element{group: g, value: x.Int()}, nil
+}
@@ -0,0 +1,100 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package cyclic
+
+import (
+	"strconv"
+	"testing"
+
+	"gitlab.com/xx_network/crypto/hasher"
+	"gitlab.com/xx_network/crypto/hashtocurve"
+	"gitlab.com/xx_network/crypto/large"
+)
+
+// Tests that HashToElement squares the output of hash_to_field for the
+// safe-prime groups and returns valid, distinct elements.
+func TestGroup_HashToElement(t *testing.T) {
+	dst := []byte("xx network test v1")
+	expanders := []hashtocurve.Expander{{Hash: hasher.SHA2_256},
+		{Hash: hasher.SHAKE256, XOF: true}}
+	for _, grp := range []*Group{MODP2048, FFDHE3072, testGroup(t)} {
+		for _, e := range expanders {
+			seen := make(map[string]bool)
+			for i := 0; i < 10; i++ {
+				msg := []byte("message " + strconv.Itoa(i))
+				x, err := grp.HashToElement(e, msg, dst)
+				if err != nil {
+					t.Fatalf("HashToElement() error: %+v", err)
+				}
+				if _, err = grp.NewElement(x.Int()); err != nil {
+					t.Errorf("%s: hashed to an invalid element: %+v",
+						grp.Name(), err)
+				}
+
+				u, err := hashtocurve.HashToField(e, msg, dst, grp.P(),
+					hashSecurityLevel, 1)
+				if err != nil {
+					t.Fatalf("HashToField() error: %+v", err)
+				}
+				expected := large.NewInt(0).Exp(u[0], large.NewInt(2), grp.P())
+				if x.Int().Cmp(expected) != 0 {
+					t.Errorf("%s: hashed to %s, expected u² = %s", grp.Name(),
+						x.Int().Text(16), expected.Text(16))
+				}
+
+				again, _ := grp.HashToElement(e, msg, dst)
+				if !x.Equal(again) {
+					t.Errorf("HashToElement() is not deterministic")
+				}
+				seen[x.String()] = true
+			}
+			if len(seen) != 10 {
+				t.Errorf("%s: 10 messages hashed to %d elements", grp.Name(),
+					len(seen))
+			}
+		}
+	}
+}
+
+// Tests that HashToElement clears a cofactor larger than two.
+func TestGroup_HashToElement_Cofactor(t *testing.T) {
+	// p = 32q + 1 for q = 1019, with the generator 2^32 mod p
+	grp, err := NewGroup(large.NewInt(32609), large.NewInt(1019),
+		large.NewInt(3297))
+	if err != nil {
+		t.Fatalf("NewGroup() error: %+v", err)
+	}
+	e := hashtocurve.Expander{Hash: hasher.BLAKE2}
+	for i := 0; i < 20; i++ {
+		x, err := grp.HashToElement(e, []byte{byte(i)}, []byte("DST"))
+		if err != nil {
+			t.Fatalf("HashToElement() error: %+v", err)
+		}
+		if _, err = grp.NewElement(x.Int()); err != nil {
+			t.Errorf("hashed to an invalid element: %+v", err)
+		}
+	}
+}
+
+// Tests that HashToElement separates DSTs and rejects invalid inputs.
+func TestGroup_HashToElement_Errors(t *testing.T) {
+	e := hashtocurve.Expander{Hash: hasher.SHA2_256}
+	x1, _ := MODP2048.HashToElement(e, []byte("msg"), []byte("DST 1"))
+	x2, _ := MODP2048.HashToElement(e, []byte("msg"), []byte("DST 2"))
+	if x1.Equal(x2) {
+		t.Errorf("different DSTs hashed to the same element")
+	}
+
+	if _, err := MODP2048.HashToElement(e, []byte("msg"), nil); err == nil {
+		t.Errorf("HashToElement() accepted an empty DST")
+	}
+	invalid := hashtocurve.Expander{Hash: hasher.SHA2_256, XOF: true}
+	if _, err := MODP2048.HashToElement(invalid, nil, []byte("DST")); err == nil {
+		t.Errorf("HashToElement() accepted SHA-256 as an XOF")
+	}
+}
@@ -0,0 +1,386 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package cyclic
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gitlab.com/xx_network/crypto/csprng"
+	"gitlab.com/xx_network/crypto/large"
+)
+
+const (
+	// MinPrimeBits is the smallest prime size the generators accept. It
+	// keeps every candidate larger than the sieving primes.
+	MinPrimeBits = 16
+
+	// sieveLimit bounds the small primes used to sieve candidates.
+	sieveLimit = 1 << 12
+
+	// sieveSpan is the number of consecutive odd candidates sieved after
+	// each random starting point.
+	sieveSpan = 1 << 12
+
+	// maxGeneratorAttempts bounds the number of random values
+	// SelectGenerator tries.
+	maxGeneratorAttempts = 1 << 10
+)
+
+// sievePrimes are the odd primes below sieveLimit.
+var sievePrimes = func() []uint64 {
+	composite := make([]bool, sieveLimit)
+	var primes []uint64
+	for i := 3; i < sieveLimit; i += 2 {
+		if composite[i] {
+			continue
+		}
+		primes = append(primes, uint64(i))
+		for j := i * i; j < sieveLimit; j += 2 * i {
+			composite[j] = true
+		}
+	}
+	return primes
+}()
+
+// GeneratePrime returns a random prime of exactly bits bits with its two top
+// bits set. Candidates are sieved and tested on workers goroutines, or
+// GOMAXPROCS goroutines if workers is not positive. It returns the context's
+// error if ctx is cancelled first.
+func GeneratePrime(ctx context.Context, rng csprng.Source, bits,
+	workers int) (*large.Int, error) {
+	p, _, err := searchPrime(ctx, rng, bits, workers, false)
+	return p, err
+}
+
+// GenerateSafePrime returns a random safe prime p = 2q + 1 of exactly bits
+// bits together with the prime q. Candidates are sieved and tested as in
+// GeneratePrime.
+func GenerateSafePrime(ctx context.Context, rng csprng.Source, bits,
+	workers int) (p, q *large.Int, err error) {
+	return searchPrime(ctx, rng, bits, workers, true)
+}
+
+// GenerateGroup returns a new group modulo a random safe prime of the given
+// size with a random generator of the order q subgroup.
+func GenerateGroup(ctx context.Context, rng csprng.Source, bits,
+	workers int) (*Group, error) {
+	p, q, err := GenerateSafePrime(ctx, rng, bits, workers)
+	if err != nil {
+		return nil, err
+	}
+	g, err := SelectGenerator(p, q, rng)
+	if err != nil {
+		return nil, err
+	}
+	return newGroup("", p, q, g), nil
+}
+
+// searchPrime runs the prime search on a pool of workers and returns the
+// first prime found. For safe primes it also returns q = (p-1)/2.
+func searchPrime(ctx context.Context, rng csprng.Source, bits, workers int,
+	safe bool) (*large.Int, *large.Int, error) {
+	if bits < MinPrimeBits {
+		return nil, nil, errors.Errorf("primes must have at least %d bits",
+			MinPrimeBits)
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		p, q *big.Int
+		err  error
+	}
+	results := make(chan result, workers)
+	// The source is shared between the workers and need not be safe for
+	// concurrent use
+	var rngMux sync.Mutex
+	readRandom := func(buf []byte) error {
+		rngMux.Lock()
+		defer rngMux.Unlock()
+		_, err := io.ReadFull(rng, buf)
+		return err
+	}
+
+	for w := 0; w < workers; w++ {
+		go func() {
+			p, q, err := primeWorker(ctx, readRandom, bits, safe)
+			results <- result{p, q, err}
+		}()
+	}
+
+	var firstErr error
+	for w := 0; w < workers; w++ {
+		res := <-results
+		if res.err == nil {
+			cancel()
+			var q *large.Int
+			if res.q != nil {
+				q = large.NewIntFromBigInt(res.q)
+			}
+			return large.NewIntFromBigInt(res.p), q, nil
+		}
+		// A failed source stops every worker, but report its error rather
+		// than the cancellation it causes in the others
+		cancel()
+		if firstErr == nil || errors.Cause(firstErr) == context.Canceled {
+			firstErr = res.err
+		}
+	}
+	return nil, nil, firstErr
+}
+
+// primeWorker draws random starting points and sieves the following odd
+// candidates until it finds a prime, or a safe prime, or ctx is done.
+func primeWorker(ctx context.Context, readRandom func([]byte) error,
+	bits int, safe bool) (*big.Int, *big.Int, error) {
+	// For safe primes the search runs over q, which is one bit shorter
+	qBits := bits
+	if safe {
+		qBits = bits - 1
+	}
+	buf := make([]byte, (qBits+7)/8)
+	composite := make([]bool, sieveSpan)
+	start := new(big.Int)
+	candidate := new(big.Int)
+	p := new(big.Int)
+	pMinus1 := new(big.Int)
+	mod := new(big.Int)
+	smallPrime := new(big.Int)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, errors.Wrap(err, "prime generation cancelled")
+		}
+		if err := readRandom(buf); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to read randomness")
+		}
+
+		// Trim to qBits bits, set the top two bits so the product of two
+		// such primes has exactly twice the bits, and make it odd
+		buf[0] &= byte(0xff >> uint(len(buf)*8-qBits))
+		start.SetBytes(buf)
+		start.SetBit(start, qBits-1, 1)
+		start.SetBit(start, qBits-2, 1)
+		start.SetBit(start, 0, 1)
+
+		// composite[j] marks start + 2j as divisible by a small prime, or
+		// 2(start + 2j) + 1 for safe primes
+		for j := range composite {
+			composite[j] = false
+		}
+		for _, s := range sievePrimes {
+			r := mod.Mod(start, smallPrime.SetUint64(s)).Uint64()
+			inv2 := (s + 1) / 2
+			// start + 2j ≡ 0 mod s when j ≡ -r/2 mod s
+			markSieve(composite, (s-r)*inv2%s, s)
+			if safe {
+				// 2(start + 2j) + 1 ≡ 0 mod s when
+				// start + 2j ≡ (s-1)/2 mod s
+				markSieve(composite, ((s-1)/2+s-r)*inv2%s, s)
+			}
+		}
+
+		for j := range composite {
+			if composite[j] {
+				continue
+			}
+			if j%64 == 0 && ctx.Err() != nil {
+				break
+			}
+			candidate.Add(start, big.NewInt(int64(2*j)))
+			if candidate.BitLen() != qBits {
+				break
+			}
+			if !safe {
+				if candidate.ProbablyPrime(40) {
+					return new(big.Int).Set(candidate), nil, nil
+				}
+				continue
+			}
+
+			// Most candidates fail a single Fermat test on p, which is
+			// much cheaper than testing q fully
+			pMinus1.Lsh(candidate, 1)
+			p.Add(pMinus1, big.NewInt(1))
+			if mod.Exp(big.NewInt(2), pMinus1, p).Cmp(big.NewInt(1)) != 0 {
+				continue
+			}
+			if candidate.ProbablyPrime(40) && p.ProbablyPrime(40) {
+				return new(big.Int).Set(p), new(big.Int).Set(candidate), nil
+			}
+		}
+	}
+}
+
+// markSieve marks every s-th entry of composite starting at first.
+func markSieve(composite []bool, first, s uint64) {
+	for j := first; j < uint64(len(composite)); j += s {
+		composite[j] = true
+	}
+}
+
+// SelectGenerator returns a random generator of the order q subgroup modulo
+// p, computed as h^((p-1)/q) for a random h.
+func SelectGenerator(p, q *large.Int, rng io.Reader) (*large.Int, error) {
+	if q.Cmp(large.NewInt(1)) <= 0 || p.Cmp(q) <= 0 {
+		return nil, errors.New("q must be greater than 1 and less than p")
+	}
+	pMinus1 := large.NewInt(0).Sub(p, large.NewInt(1))
+	if large.NewInt(0).Mod(pMinus1, q).Cmp(large.NewInt(0)) != 0 {
+		return nil, errors.New("q does not divide p-1")
+	}
+	cofactor := large.NewInt(0).Div(pMinus1, q)
+
+	buf := make([]byte, p.ByteLen())
+	one := large.NewInt(1)
+	for i := 0; i < maxGeneratorAttempts; i++ {
+		if _, err := io.ReadFull(rng, buf); err != nil {
+			return nil, errors.Wrap(err, "failed to read randomness")
+		}
+		h := large.NewIntFromBytes(buf)
+		h.Mod(h, p)
+		if h.Cmp(one) <= 0 {
+			continue
+		}
+		g := large.NewInt(0).Exp(h, cofactor, p)
+		if g.Cmp(one) != 0 && g.Cmp(pMinus1) != 0 {
+			return g, nil
+		}
+	}
+	return nil, errors.Errorf("failed to find a generator after %d "+
+		"attempts", maxGeneratorAttempts)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Validation                                                                 //
+////////////////////////////////////////////////////////////////////////////////
+
+// Names of the checks in a ParamReport.
+const (
+	CheckPPrime         = "p is prime"
+	CheckQPrime         = "q is prime"
+	CheckQDividesP      = "q divides p-1"
+	CheckSafePrime      = "p = 2q+1"
+	CheckGenerator      = "1 < g < p-1"
+	CheckGeneratorOrder = "g has order q"
+	CheckPBits          = "p bit length"
+	CheckQBits          = "q bit length"
+)
+
+// ParamCheck is the outcome of one check in a ParamReport.
+type ParamCheck struct {
+	Name   string
+	Passed bool
+	// Detail explains a failure, or is empty.
+	Detail string
+}
+
+// ParamReport is the result of validating group parameters.
+type ParamReport struct {
+	PBits, QBits int
+	Checks       []ParamCheck
+}
+
+// ParamRequirements are the requirements ValidateParams checks beyond the
+// structure of the group.
+type ParamRequirements struct {
+	// MinPBits and MinQBits are the smallest acceptable sizes of p and q.
+	MinPBits, MinQBits int
+	// SafePrime requires p = 2q + 1.
+	SafePrime bool
+}
+
+// ValidateParams checks that p and q are prime, q divides p-1, g generates
+// the order q subgroup and the sizes meet the requirements. Every check is
+// run and reported, even after a failure.
+func ValidateParams(p, q, g *large.Int, req ParamRequirements) *ParamReport {
+	r := &ParamReport{PBits: p.BitLen(), QBits: q.BitLen()}
+	add := func(name string, passed bool, detail string) {
+		if passed {
+			detail = ""
+		}
+		r.Checks = append(r.Checks, ParamCheck{name, passed, detail})
+	}
+
+	add(CheckPPrime, p.BigInt().Sign() > 0 && p.IsPrime(),
+		"p is composite")
+	add(CheckQPrime, q.BigInt().Sign() > 0 && q.IsPrime(),
+		"q is composite")
+
+	pMinus1 := large.NewInt(0).Sub(p, large.NewInt(1))
+	divides := q.BigInt().Sign() > 0 &&
+		large.NewInt(0).Mod(pMinus1, q).Cmp(large.NewInt(0)) == 0
+	add(CheckQDividesP, divides, "p-1 is not a multiple of q")
+	if req.SafePrime {
+		twoQ := large.NewInt(0).LeftShift(q, 1)
+		add(CheckSafePrime, twoQ.Cmp(pMinus1) == 0, "p is not 2q+1")
+	}
+
+	inRange := g.Cmp(large.NewInt(1)) > 0 && g.Cmp(pMinus1) < 0
+	add(CheckGenerator, inRange, "g is out of range")
+	order := inRange && p.BigInt().Sign() > 0 && q.BigInt().Sign() > 0 &&
+		large.NewInt(0).Exp(g, q, p).Cmp(large.NewInt(1)) == 0
+	add(CheckGeneratorOrder, order, "g^q is not 1 mod p")
+
+	add(CheckPBits, r.PBits >= req.MinPBits, fmt.Sprintf(
+		"p has %d bits, at least %d required", r.PBits, req.MinPBits))
+	add(CheckQBits, r.QBits >= req.MinQBits, fmt.Sprintf(
+		"q has %d bits, at least %d required", r.QBits, req.MinQBits))
+	return r
+}
+
+// Validate checks the parameters of the group with ValidateParams.
+func (g *Group) Validate(req ParamRequirements) *ParamReport {
+	return ValidateParams(g.p, g.q, g.g, req)
+}
+
+// Passed returns true if every check passed.
+func (r *ParamReport) Passed() bool {
+	return len(r.Failed()) == 0
+}
+
+// Failed returns the checks that failed.
+func (r *ParamReport) Failed() []ParamCheck {
+	var failed []ParamCheck
+	for _, c := range r.Checks {
+		if !c.Passed {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// String returns a table of the checks and their outcomes.
+func (r *ParamReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "group parameters: p %d bits, q %d bits\n", r.PBits,
+		r.QBits)
+	for _, c := range r.Checks {
+		status := "PASS"
+		if !c.Passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(&sb, "  %-16s %s", c.Name, status)
+		if c.Detail != "" {
+			fmt.Fprintf(&sb, " (%s)", c.Detail)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
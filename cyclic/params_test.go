@@ -0,0 +1,179 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package cyclic
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"gitlab.com/xx_network/crypto/csprng"
+	"gitlab.com/xx_network/crypto/large"
+)
+
+// Tests that GeneratePrime returns primes of exactly the requested size.
+func TestGeneratePrime(t *testing.T) {
+	for _, bits := range []int{MinPrimeBits, 64, 256, 512} {
+		for _, workers := range []int{1, 4} {
+			p, err := GeneratePrime(context.Background(),
+				csprng.NewSystemRNG(), bits, workers)
+			if err != nil {
+				t.Fatalf("GeneratePrime(%d) failed: %+v", bits, err)
+			}
+			if p.BitLen() != bits || !p.IsPrime() {
+				t.Errorf("GeneratePrime(%d) returned %s", bits, p.Text(16))
+			}
+		}
+	}
+}
+
+// Tests that GenerateSafePrime returns p = 2q + 1 with both prime.
+func TestGenerateSafePrime(t *testing.T) {
+	for _, bits := range []int{MinPrimeBits, 128, 512} {
+		p, q, err := GenerateSafePrime(context.Background(),
+			csprng.NewSystemRNG(), bits, 0)
+		if err != nil {
+			t.Fatalf("GenerateSafePrime(%d) failed: %+v", bits, err)
+		}
+		twoQ1 := large.NewInt(0).LeftShift(q, 1)
+		twoQ1.Add(twoQ1, large.NewInt(1))
+		if p.BitLen() != bits || !p.IsPrime() || !q.IsPrime() ||
+			twoQ1.Cmp(p) != 0 {
+			t.Errorf("GenerateSafePrime(%d) returned p=%s q=%s", bits,
+				p.Text(16), q.Text(16))
+		}
+	}
+}
+
+// Tests that a generated group passes validation and can be used.
+func TestGenerateGroup(t *testing.T) {
+	grp, err := GenerateGroup(context.Background(), csprng.NewSystemRNG(),
+		256, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := grp.Validate(ParamRequirements{MinPBits: 256,
+		MinQBits: 255, SafePrime: true})
+	if !report.Passed() {
+		t.Errorf("Generated group failed validation:\n%s", report)
+	}
+	if _, err = grp.GenerateKey(csprng.NewSystemRNG()); err != nil {
+		t.Errorf("Failed to generate a key in the group: %+v", err)
+	}
+}
+
+// Error path: generation stops on cancellation, failing sources and sizes
+// below the minimum.
+func TestGenerateSafePrime_Errors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := GenerateSafePrime(ctx, csprng.NewSystemRNG(), 1024, 2)
+	if errors.Cause(err) != context.Canceled {
+		t.Errorf("Expected a cancellation error, received %v", err)
+	}
+
+	rng := csprng.NewFailingSource(csprng.NewSystemRNG(), 0, nil)
+	_, _, err = GenerateSafePrime(context.Background(), rng, 1024, 3)
+	if errors.Cause(err) != csprng.ErrInjectedFault {
+		t.Errorf("Expected the source error, received %v", err)
+	}
+
+	if _, err = GeneratePrime(context.Background(), csprng.NewSystemRNG(),
+		MinPrimeBits-1, 1); err == nil {
+		t.Errorf("Prime below the minimum size generated")
+	}
+}
+
+// Tests that SelectGenerator returns an element of order q for both a safe
+// prime and a prime with a small subgroup, and rejects mismatched q.
+func TestSelectGenerator(t *testing.T) {
+	// 2039 = 2·1019 + 1 and 2311 = 2·3·5·7·11 + 1
+	tests := []struct{ p, q int64 }{{2039, 1019}, {2311, 7}, {2311, 11}}
+	for _, tt := range tests {
+		p, q := large.NewInt(tt.p), large.NewInt(tt.q)
+		for i := 0; i < 20; i++ {
+			g, err := SelectGenerator(p, q, csprng.NewSystemRNG())
+			if err != nil {
+				t.Fatal(err)
+			}
+			report := ValidateParams(p, q, g, ParamRequirements{})
+			if !report.Passed() {
+				t.Errorf("Generator %s failed validation:\n%s", g.Text(10),
+					report)
+			}
+		}
+	}
+
+	if _, err := SelectGenerator(large.NewInt(2039), large.NewInt(13),
+		csprng.NewSystemRNG()); err == nil {
+		t.Errorf("Generator selected for q not dividing p-1")
+	}
+	if _, err := SelectGenerator(large.NewInt(2039), large.NewInt(1019),
+		csprng.NewZeroSource()); err == nil {
+		t.Errorf("Generator selected from a zero source")
+	}
+}
+
+// Tests that ValidateParams reports each failure by name and runs every
+// check.
+func TestValidateParams(t *testing.T) {
+	tests := []struct {
+		p, q, g int64
+		req     ParamRequirements
+		failed  []string
+	}{
+		{2039, 1019, 4, ParamRequirements{SafePrime: true}, nil},
+		{2041, 1020, 4, ParamRequirements{SafePrime: true},
+			[]string{CheckPPrime, CheckQPrime, CheckGeneratorOrder}},
+		{2039, 1019, 7, ParamRequirements{},
+			[]string{CheckGeneratorOrder}},
+		{2039, 1019, 2038, ParamRequirements{},
+			[]string{CheckGenerator, CheckGeneratorOrder}},
+		{2311, 7, 1, ParamRequirements{SafePrime: true},
+			[]string{CheckSafePrime, CheckGenerator, CheckGeneratorOrder}},
+		{2311, 13, 2, ParamRequirements{},
+			[]string{CheckQDividesP, CheckGeneratorOrder}},
+		{2039, 1019, 4, ParamRequirements{MinPBits: 12, MinQBits: 11},
+			[]string{CheckPBits, CheckQBits}},
+		{2039, 0, 4, ParamRequirements{},
+			[]string{CheckQPrime, CheckQDividesP, CheckGeneratorOrder}},
+		{2039, -1019, 4, ParamRequirements{},
+			[]string{CheckQPrime, CheckQDividesP, CheckGeneratorOrder}},
+	}
+	for i, tt := range tests {
+		report := ValidateParams(large.NewInt(tt.p), large.NewInt(tt.q),
+			large.NewInt(tt.g), tt.req)
+		var failed []string
+		for _, c := range report.Failed() {
+			failed = append(failed, c.Name)
+			if c.Detail == "" {
+				t.Errorf("Test %d: failed check %q has no detail", i, c.Name)
+			}
+		}
+		if strings.Join(failed, ",") != strings.Join(tt.failed, ",") {
+			t.Errorf("Test %d: expected failures %v, received %v\n%s", i,
+				tt.failed, failed, report)
+		}
+		if report.Passed() != (len(tt.failed) == 0) {
+			t.Errorf("Test %d: Passed() is %t", i, report.Passed())
+		}
+	}
+}
+
+// Tests that the built-in groups pass validation as safe-prime groups.
+func TestGroup_Validate_Builtin(t *testing.T) {
+	report := MODP2048.Validate(ParamRequirements{MinPBits: 2048,
+		MinQBits: 2047, SafePrime: true})
+	if !report.Passed() {
+		t.Errorf("MODP2048 failed validation:\n%s", report)
+	}
+	if !strings.Contains(report.String(), "PASS") {
+		t.Errorf("Report does not list the checks:\n%s", report)
+	}
+}
@@ -5,62 +5,449 @@
 // LICENSE file.                                                              //
 ////////////////////////////////////////////////////////////////////////////////
 
+// Package hasher is a registry of hash functions identified by a one-byte
+// HashType, so that the choice of hash can be written into configuration
+// files and wire headers.
 package hasher
 
 import (
+	"crypto"
 	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/json"
+	"hash"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	jww "github.com/spf13/jwalterweatherman"
 	"github.com/zeebo/blake3"
 	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/blake2s"
 	"golang.org/x/crypto/sha3"
-	"hash"
 )
 
+// HashType identifies a hash function. The numeric values are written into
+// wire headers, so they never change; new built-in types are only appended.
 type HashType uint8
 
 const (
-	SHA2_224 HashType = iota
-	SHA2_256
-	SHA3_224
-	SHA3_256
-	BLAKE2
-	BLAKE3
+	SHA2_224    HashType = iota // SHA-224
+	SHA2_256                    // SHA-256
+	SHA3_224                    // SHA3-224
+	SHA3_256                    // SHA3-256
+	BLAKE2                      // BLAKE2b-256
+	BLAKE3                      // BLAKE3 with a 32-byte digest
+	SHA2_384                    // SHA-384
+	SHA2_512                    // SHA-512
+	SHA3_384                    // SHA3-384
+	SHA3_512                    // SHA3-512
+	BLAKE2B_512                 // BLAKE2b-512
+	BLAKE2S_256                 // BLAKE2s-256
+	SHAKE128                    // SHAKE128 with a 32-byte digest
+	SHAKE256                    // SHAKE256 with a 64-byte digest
 )
 
-func (h HashType) New() hash.Hash {
-	switch h {
-	case SHA2_224:
-		return sha256.New()
-	case SHA2_256:
-		return sha256.New()
-	case SHA3_224:
-		return sha3.New224()
-	case SHA3_256:
-		return sha3.New256()
-	case BLAKE2:
-		b, _ := blake2b.New256(nil)
-		return b
-	case BLAKE3:
-		return blake3.New()
-	default:
-		return nil
+// FirstCustom is the lowest HashType that applications may Register. Lower
+// values are reserved for built-in types.
+const FirstCustom HashType = 128
+
+// unknownHashString is the String of a HashType that is not registered.
+const unknownHashString = "UNKNOWN HASH FUNCTION"
+
+// Info describes a hash function for Register.
+type Info struct {
+	// Name is the String form of the type. It is matched ignoring case by
+	// Parse, so it must be unique ignoring case, and may only contain
+	// letters, digits, '_' and '-'.
+	Name string
+
+	// New returns a new instance of the hash function.
+	New func() hash.Hash
+
+	// NewKeyed returns a new instance keyed with key, or an error if the key
+	// is not valid. It is nil if the hash function has no keyed mode.
+	NewKeyed func(key []byte) (hash.Hash, error)
+
+	// Crypto is the matching crypto.Hash, or zero if there is none.
+	Crypto crypto.Hash
+
+	// NewXOF returns a new instance of the hash function as an
+	// extendable-output function. It is nil if the hash function has a
+	// fixed output size.
+	NewXOF func() XOF
+}
+
+// XOF is an extendable-output function: once the input is written, any
+// amount of output can be read. Writing after the first read is not allowed.
+type XOF interface {
+	io.Writer
+	io.Reader
+
+	// Reset discards the input and output, so the XOF can be reused.
+	Reset()
+}
+
+// entry is a registered hash function with its cached sizes.
+type entry struct {
+	Info
+	size, blockSize int
+}
+
+// registry holds every registered hash function.
+var registry = struct {
+	sync.RWMutex
+	types map[HashType]*entry
+}{types: make(map[HashType]*entry)}
+
+func init() {
+	builtins := map[HashType]Info{
+		SHA2_224: {Name: "SHA2_224", New: sha256.New224, Crypto: crypto.SHA224},
+		SHA2_256: {Name: "SHA2_256", New: sha256.New, Crypto: crypto.SHA256},
+		SHA2_384: {Name: "SHA2_384", New: sha512.New384, Crypto: crypto.SHA384},
+		SHA2_512: {Name: "SHA2_512", New: sha512.New, Crypto: crypto.SHA512},
+		SHA3_224: {Name: "SHA3_224", New: sha3.New224, Crypto: crypto.SHA3_224},
+		SHA3_256: {Name: "SHA3_256", New: sha3.New256, Crypto: crypto.SHA3_256},
+		SHA3_384: {Name: "SHA3_384", New: sha3.New384, Crypto: crypto.SHA3_384},
+		SHA3_512: {Name: "SHA3_512", New: sha3.New512, Crypto: crypto.SHA3_512},
+		BLAKE2: {
+			Name:     "BLAKE2",
+			New:      func() hash.Hash { h, _ := blake2b.New256(nil); return h },
+			NewKeyed: keyed(blake2b.New256),
+			Crypto:   crypto.BLAKE2b_256,
+		},
+		BLAKE2B_512: {
+			Name:     "BLAKE2B_512",
+			New:      func() hash.Hash { h, _ := blake2b.New512(nil); return h },
+			NewKeyed: keyed(blake2b.New512),
+			Crypto:   crypto.BLAKE2b_512,
+		},
+		BLAKE2S_256: {
+			Name:     "BLAKE2S_256",
+			New:      func() hash.Hash { h, _ := blake2s.New256(nil); return h },
+			NewKeyed: keyed(blake2s.New256),
+			Crypto:   crypto.BLAKE2s_256,
+		},
+		SHAKE128: {
+			Name:   "SHAKE128",
+			New:    func() hash.Hash { return sha3.NewShake128() },
+			NewXOF: func() XOF { return sha3.NewShake128() },
+		},
+		SHAKE256: {
+			Name:   "SHAKE256",
+			New:    func() hash.Hash { return sha3.NewShake256() },
+			NewXOF: func() XOF { return sha3.NewShake256() },
+		},
+		BLAKE3: {
+			Name:   "BLAKE3",
+			New:    func() hash.Hash { return blake3.New() },
+			NewXOF: func() XOF { return &blake3XOF{h: blake3.New()} },
+			NewKeyed: func(key []byte) (hash.Hash, error) {
+				h, err := blake3.NewKeyed(key)
+				if err != nil {
+					return nil, errors.Wrap(err, "invalid BLAKE3 key")
+				}
+				return h, nil
+			},
+		},
+	}
+	for h, info := range builtins {
+		if err := register(h, info); err != nil {
+			jww.FATAL.Panicf("Failed to register hash %s: %+v", info.Name,
+				err)
+		}
+	}
+}
+
+// keyed adapts a BLAKE2 constructor, which treats an empty key as no key, to
+// Info.NewKeyed.
+func keyed(newHash func(key []byte) (hash.Hash, error)) func(
+	key []byte) (hash.Hash, error) {
+	return func(key []byte) (hash.Hash, error) {
+		if len(key) == 0 {
+			return nil, errors.New("key is empty")
+		}
+		h, err := newHash(key)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid BLAKE2 key")
+		}
+		return h, nil
+	}
+}
+
+// blake3XOF adapts BLAKE3, whose output is read from a separate Digest, to
+// XOF.
+type blake3XOF struct {
+	h *blake3.Hasher
+	d *blake3.Digest
+}
+
+// Write adds more input. It panics after the first Read.
+func (x *blake3XOF) Write(p []byte) (int, error) {
+	if x.d != nil {
+		jww.FATAL.Panicf("BLAKE3 XOF written to after being read")
+	}
+	return x.h.Write(p)
+}
+
+// Read reads more output.
+func (x *blake3XOF) Read(p []byte) (int, error) {
+	if x.d == nil {
+		x.d = x.h.Digest()
+	}
+	return x.d.Read(p)
+}
+
+// Reset discards the input and output.
+func (x *blake3XOF) Reset() {
+	x.h.Reset()
+	x.d = nil
+}
+
+// Register adds a custom hash function under h, which must be at least
+// FirstCustom. It returns an error if h, the name or the crypto.Hash is
+// already registered, or if the Info is incomplete. Registration is meant to
+// happen during initialisation, before the type is used.
+func Register(h HashType, info Info) error {
+	if h < FirstCustom {
+		return errors.Errorf("hash type %d is reserved for built-in types; "+
+			"custom types start at %d", h, FirstCustom)
+	}
+	return register(h, info)
+}
+
+// register adds a hash function without checking the reserved range.
+func register(h HashType, info Info) error {
+	if info.Name == "" || strings.IndexFunc(info.Name, invalidNameRune) >= 0 {
+		return errors.Errorf("invalid hash name %q", info.Name)
+	}
+	if info.New == nil {
+		return errors.Errorf("hash %s has no constructor", info.Name)
+	}
+	sample := info.New()
+	if sample == nil {
+		return errors.Errorf("constructor of hash %s returned nil", info.Name)
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	if e, exists := registry.types[h]; exists {
+		return errors.Errorf("hash type %d is already registered as %s", h,
+			e.Name)
+	}
+	for other, e := range registry.types {
+		if strings.EqualFold(e.Name, info.Name) {
+			return errors.Errorf("hash name %s is already registered for "+
+				"type %d", info.Name, other)
+		}
+		if info.Crypto != 0 && e.Crypto == info.Crypto {
+			return errors.Errorf("%s is already registered as %s",
+				info.Crypto, e.Name)
+		}
+	}
+	registry.types[h] = &entry{Info: info, size: sample.Size(),
+		blockSize: sample.BlockSize()}
+	return nil
+}
+
+// invalidNameRune returns true for characters not allowed in a hash name.
+func invalidNameRune(r rune) bool {
+	return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' ||
+		r >= '0' && r <= '9' || r == '_' || r == '-')
+}
+
+// lookup returns the registry entry for h, or nil if it is not registered.
+func lookup(h HashType) *entry {
+	registry.RLock()
+	defer registry.RUnlock()
+	return registry.types[h]
+}
+
+// lookupErr returns the registry entry for h or an error if it is not
+// registered.
+func lookupErr(h HashType) (*entry, error) {
+	e := lookup(h)
+	if e == nil {
+		return nil, errors.Errorf("unknown hash type %d", uint8(h))
 	}
+	return e, nil
 }
 
+// Types returns every registered HashType in increasing order.
+func Types() []HashType {
+	registry.RLock()
+	types := make([]HashType, 0, len(registry.types))
+	for h := range registry.types {
+		types = append(types, h)
+	}
+	registry.RUnlock()
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// Available returns true if h is registered.
+func (h HashType) Available() bool {
+	return lookup(h) != nil
+}
+
+// New returns a new instance of the hash function, or an error if h is not
+// registered.
+func (h HashType) New() (hash.Hash, error) {
+	e, err := lookupErr(h)
+	if err != nil {
+		return nil, err
+	}
+	return e.New(), nil
+}
+
+// Func returns the constructor of the hash function, for APIs such as
+// hmac.New and hkdf.New that take a func() hash.Hash, or an error if h is not
+// registered.
+func (h HashType) Func() (func() hash.Hash, error) {
+	e, err := lookupErr(h)
+	if err != nil {
+		return nil, err
+	}
+	return e.New, nil
+}
+
+// NewKeyed returns a new instance of the hash function in its keyed mode. It
+// returns an error if h is not registered, has no keyed mode, as is the case
+// for SHA-2 and SHA-3, or rejects the key. BLAKE2b takes keys of 1 to 64
+// bytes, BLAKE2s of 1 to 32 bytes and BLAKE3 of exactly 32 bytes.
+func (h HashType) NewKeyed(key []byte) (hash.Hash, error) {
+	e, err := lookupErr(h)
+	if err != nil {
+		return nil, err
+	}
+	if e.NewKeyed == nil {
+		return nil, errors.Errorf("hash %s has no keyed mode", e.Name)
+	}
+	return e.NewKeyed(key)
+}
+
+// NewXOF returns a new instance of the hash function as an
+// extendable-output function. It returns an error if h is not registered or
+// has a fixed output size. SHAKE128, SHAKE256 and BLAKE3 are XOFs; their Size
+// is only the length of their digest as a hash.Hash.
+func (h HashType) NewXOF() (XOF, error) {
+	e, err := lookupErr(h)
+	if err != nil {
+		return nil, err
+	}
+	if e.NewXOF == nil {
+		return nil, errors.Errorf("hash %s is not an extendable-output "+
+			"function", e.Name)
+	}
+	return e.NewXOF(), nil
+}
+
+// Size returns the digest size in bytes, or zero if h is not registered.
+func (h HashType) Size() int {
+	if e := lookup(h); e != nil {
+		return e.size
+	}
+	return 0
+}
+
+// BlockSize returns the block size in bytes, or zero if h is not registered.
+func (h HashType) BlockSize() int {
+	if e := lookup(h); e != nil {
+		return e.blockSize
+	}
+	return 0
+}
+
+// CryptoHash returns the matching crypto.Hash, or zero if there is none or h
+// is not registered. BLAKE3 and SHAKE have none.
+func (h HashType) CryptoHash() crypto.Hash {
+	if e := lookup(h); e != nil {
+		return e.Crypto
+	}
+	return 0
+}
+
+// FromCryptoHash returns the HashType registered for c.
+func FromCryptoHash(c crypto.Hash) (HashType, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+	for h, e := range registry.types {
+		if c != 0 && e.Crypto == c {
+			return h, nil
+		}
+	}
+	return 0, errors.Errorf("no hash type is registered for %s", c)
+}
+
+// String returns the registered name of h, which Parse accepts.
 func (h HashType) String() string {
-	switch h {
-	case SHA2_224:
-		return "SHA2_224"
-	case SHA2_256:
-		return "SHA2_256"
-	case SHA3_224:
-		return "SHA3_224"
-	case SHA3_256:
-		return "SHA3_256"
-	case BLAKE2:
-		return "BLAKE2"
-	case BLAKE3:
-		return "BLAKE3"
-	default:
-		return "UNKNOWN HASH FUNCTION"
+	if e := lookup(h); e != nil {
+		return e.Name
+	}
+	return unknownHashString
+}
+
+// Parse returns the HashType registered under name, ignoring case.
+func Parse(name string) (HashType, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+	for h, e := range registry.types {
+		if strings.EqualFold(e.Name, name) {
+			return h, nil
+		}
+	}
+	return 0, errors.Errorf("unknown hash name %q", name)
+}
+
+// MarshalText encodes h as its name. It returns an error if h is not
+// registered. This function implements the encoding.TextMarshaler interface.
+func (h HashType) MarshalText() ([]byte, error) {
+	e, err := lookupErr(h)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(e.Name), nil
+}
+
+// UnmarshalText decodes a name made by MarshalText, ignoring case. This
+// function implements the encoding.TextUnmarshaler interface.
+func (h *HashType) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*h = parsed
+	return nil
+}
+
+// MarshalJSON encodes h as a JSON string of its name. This function
+// implements the json.Marshaler interface.
+func (h HashType) MarshalJSON() ([]byte, error) {
+	text, err := h.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+// UnmarshalJSON decodes a JSON string of a name or, for configuration written
+// before HashType had a text form, a JSON number. Either must be a registered
+// type. This function implements the json.Unmarshaler interface.
+func (h *HashType) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		return h.UnmarshalText([]byte(name))
+	}
+	var n uint8
+	if err := json.Unmarshal(data, &n); err != nil {
+		return errors.Errorf("hash type must be a name or a number from 0 "+
+			"to 255, received %s", data)
+	}
+	if !HashType(n).Available() {
+		return errors.Errorf("unknown hash type %d", n)
 	}
+	*h = HashType(n)
+	return nil
 }
@@ -7,30 +7,182 @@
 
 package hasher
 
-import "testing"
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
 
-func testNew(typ HashType, t *testing.T) {
-	h := typ.New()
+var (
+	_ encoding.TextMarshaler   = HashType(0)
+	_ encoding.TextUnmarshaler = (*HashType)(nil)
+	_ json.Marshaler           = HashType(0)
+	_ json.Unmarshaler         = (*HashType)(nil)
+)
 
-	if h == nil {
-		t.Errorf("HashType.New() returned nil hash function for a valid type!")
-	}
+// builtins lists every built-in type with its digest of "abc" and block size.
+var builtins = []struct {
+	typ       HashType
+	name      string
+	digest    string
+	blockSize int
+}{
+	{SHA2_224, "SHA2_224", "23097d223405d8228642a477bda255b32aadbce4bda0b3f7e36c9da7", 64},
+	{SHA2_256, "SHA2_256", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", 64},
+	{SHA3_224, "SHA3_224", "e642824c3f8cf24ad09234ee7d3c766fc9a3a5168d0c94ad73b46fdf", 144},
+	{SHA3_256, "SHA3_256", "3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532", 136},
+	{BLAKE2, "BLAKE2", "bddd813c634239723171ef3fee98579b94964e3bb1cb3e427262c8c068d52319", 128},
+	{BLAKE3, "BLAKE3", "6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85", 64},
+	{SHA2_384, "SHA2_384", "cb00753f45a35e8bb5a03d699ac65007272c32ab0eded1631a8b605a43ff5bed8086072ba1e7cc2358baeca134c825a7", 128},
+	{SHA2_512, "SHA2_512", "ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f", 128},
+	{SHA3_384, "SHA3_384", "ec01498288516fc926459f58e2c6ad8df9b473cb0fc08c2596da7cf0e49be4b298d88cea927ac7f539f1edf228376d25", 104},
+	{SHA3_512, "SHA3_512", "b751850b1a57168a5693cd924b6b096e08f621827444f70d884f5d0240d2712e10e116e9192af3c91a7ec57647e3934057340b4cf408d5a56592f8274eec53f0", 72},
+	{BLAKE2B_512, "BLAKE2B_512", "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923", 128},
+	{BLAKE2S_256, "BLAKE2S_256", "508c5e8c327c14e2e1a72ba34eeb452f37458b209ed63a294d999b4c86675982", 64},
+	{SHAKE128, "SHAKE128", "5881092dd818bf5cf8a3ddb793fbcba74097d5c526a6d35f97b83351940f2cc8", 168},
+	{SHAKE256, "SHAKE256", "483366601360a8771c6863080cc4114d8db44530f8f1e1ee4f94ea37e78b5739d5a15bef186a5386c75744c0527e1faa9f8726e462a12a4feb06bd8801e751e4", 136},
 }
 
+// Tests that every built-in type hashes "abc" to its published digest and
+// reports the right sizes. SHA2_224 used to return SHA-256.
 func TestHashType_New(t *testing.T) {
-	testNew(SHA2_224, t)
-	testNew(SHA2_256, t)
-	testNew(SHA3_224, t)
-	testNew(SHA3_256, t)
-	testNew(BLAKE2, t)
-	testNew(BLAKE3, t)
+	for _, tt := range builtins {
+		h, err := tt.typ.New()
+		if err != nil {
+			t.Fatalf("New() for %s returned an error: %+v", tt.name, err)
+		}
+		h.Write([]byte("abc"))
+		if got := hex.EncodeToString(h.Sum(nil)); got != tt.digest {
+			t.Errorf("%s(abc) = %s, expected %s", tt.name, got, tt.digest)
+		}
+		if tt.typ.Size() != len(tt.digest)/2 {
+			t.Errorf("%s.Size() = %d, expected %d", tt.name, tt.typ.Size(),
+				len(tt.digest)/2)
+		}
+		if tt.typ.BlockSize() != tt.blockSize {
+			t.Errorf("%s.BlockSize() = %d, expected %d", tt.name,
+				tt.typ.BlockSize(), tt.blockSize)
+		}
+
+		newHash, err := tt.typ.Func()
+		if err != nil || newHash().Size() != tt.typ.Size() {
+			t.Errorf("Func() for %s is wrong: %v", tt.name, err)
+		}
+	}
 
 	// Test non existing type
 	typ := HashType(20)
-	h := typ.New()
+	if h, err := typ.New(); err == nil || h != nil {
+		t.Errorf("HashType.New() should have returned an error for unknown type!")
+	}
+	if _, err := typ.Func(); err == nil {
+		t.Errorf("HashType.Func() should have returned an error for unknown type!")
+	}
+	if typ.Available() || typ.Size() != 0 || typ.BlockSize() != 0 {
+		t.Errorf("Unknown type should be unavailable with no sizes")
+	}
+}
+
+// Tests the keyed modes against the BLAKE2 and BLAKE3 reference vectors, and
+// that unkeyed types and bad keys are rejected.
+func TestHashType_NewKeyed(t *testing.T) {
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	tests := []struct {
+		typ    HashType
+		digest string
+	}{
+		// BLAKE3 reference test vector for an empty input
+		{BLAKE3, "92b2b75604ed3c761f9d6f62392c8a9227ad0ea3f09573e783f1498a4ed60d26"},
+		// BLAKE2s reference keyed vector for an empty input
+		{BLAKE2S_256, "48a8997da407876b3d79c0d92325ad3b89cbb754d86ab71aee047ad345fd2c49"},
+	}
+	for _, tt := range tests {
+		k := key
+		if tt.typ == BLAKE3 {
+			k = []byte("whats the Elvish word for friend")
+		}
+		h, err := tt.typ.NewKeyed(k)
+		if err != nil {
+			t.Fatalf("NewKeyed() for %s returned an error: %+v", tt.typ, err)
+		}
+		if got := hex.EncodeToString(h.Sum(nil)); got != tt.digest {
+			t.Errorf("keyed %s = %s, expected %s", tt.typ, got, tt.digest)
+		}
+	}
+
+	for _, typ := range []HashType{BLAKE2, BLAKE2B_512} {
+		keyed, err := typ.NewKeyed(key)
+		if err != nil {
+			t.Fatalf("NewKeyed() for %s returned an error: %+v", typ, err)
+		}
+		unkeyed, _ := typ.New()
+		if hex.EncodeToString(keyed.Sum(nil)) ==
+			hex.EncodeToString(unkeyed.Sum(nil)) {
+			t.Errorf("keyed %s ignored the key", typ)
+		}
+	}
+
+	bad := map[HashType][]byte{
+		SHA2_256:    key,
+		SHA3_256:    key,
+		BLAKE2:      nil,
+		BLAKE2S_256: make([]byte, 33),
+		BLAKE3:      make([]byte, 31),
+		200:         key,
+	}
+	for typ, k := range bad {
+		if _, err := typ.NewKeyed(k); err == nil {
+			t.Errorf("NewKeyed() for %s accepted a %d byte key", typ, len(k))
+		}
+	}
+}
+
+// Tests that the XOFs extend their digests, in any size of reads, and that
+// fixed-size types are not XOFs.
+func TestHashType_NewXOF(t *testing.T) {
+	for _, typ := range []HashType{SHAKE128, SHAKE256, BLAKE3} {
+		x, err := typ.NewXOF()
+		if err != nil {
+			t.Fatalf("NewXOF() for %s returned an error: %+v", typ, err)
+		}
+		x.Write([]byte("abc"))
+		out := make([]byte, 3*typ.Size())
+		for i := 0; i < len(out); i += 7 {
+			end := i + 7
+			if end > len(out) {
+				end = len(out)
+			}
+			x.Read(out[i:end])
+		}
+		h, _ := typ.New()
+		h.Write([]byte("abc"))
+		if !bytes.Equal(out[:typ.Size()], h.Sum(nil)) {
+			t.Errorf("%s XOF does not extend its digest", typ)
+		}
 
-	if h != nil {
-		t.Errorf("HashType.New() should have returned nil hash function for unknown type!")
+		x.Reset()
+		x.Write([]byte("abc"))
+		again := make([]byte, len(out))
+		x.Read(again)
+		if !bytes.Equal(out, again) {
+			t.Errorf("%s XOF output changed after Reset()", typ)
+		}
+	}
+
+	for _, typ := range []HashType{SHA2_256, SHA3_256, BLAKE2, 200} {
+		if _, err := typ.NewXOF(); err == nil {
+			t.Errorf("NewXOF() for %s did not return an error", typ)
+		}
 	}
 }
 
@@ -43,12 +195,12 @@ func testString(typ HashType, t *testing.T) {
 }
 
 func TestHashType_String(t *testing.T) {
-	testString(SHA2_224, t)
-	testString(SHA2_256, t)
-	testString(SHA3_224, t)
-	testString(SHA3_256, t)
-	testString(BLAKE2, t)
-	testString(BLAKE3, t)
+	for _, tt := range builtins {
+		testString(tt.typ, t)
+		if tt.typ.String() != tt.name {
+			t.Errorf("String() = %s, expected %s", tt.typ, tt.name)
+		}
+	}
 
 	// Test non existing type
 	typ := HashType(20)
@@ -58,3 +210,162 @@ func TestHashType_String(t *testing.T) {
 		t.Errorf("HashType.String() should have returned unknown hash function string for an unknown type!")
 	}
 }
+
+// Tests that Parse accepts every String form, ignoring case, and nothing else.
+func TestParse(t *testing.T) {
+	for _, tt := range builtins {
+		for _, s := range []string{tt.name, strings.ToLower(tt.name)} {
+			typ, err := Parse(s)
+			if err != nil || typ != tt.typ {
+				t.Errorf("Parse(%q) = %d, %v, expected %d", s, typ, err, tt.typ)
+			}
+		}
+	}
+	for _, s := range []string{"", "SHA2", "sha2-256", " BLAKE3",
+		"UNKNOWN HASH FUNCTION"} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) did not return an error", s)
+		}
+	}
+}
+
+// Tests the mapping to and from crypto.Hash.
+func TestHashType_CryptoHash(t *testing.T) {
+	expected := map[HashType]crypto.Hash{
+		SHA2_224:    crypto.SHA224,
+		SHA2_256:    crypto.SHA256,
+		SHA2_384:    crypto.SHA384,
+		SHA2_512:    crypto.SHA512,
+		SHA3_224:    crypto.SHA3_224,
+		SHA3_256:    crypto.SHA3_256,
+		SHA3_384:    crypto.SHA3_384,
+		SHA3_512:    crypto.SHA3_512,
+		BLAKE2:      crypto.BLAKE2b_256,
+		BLAKE2B_512: crypto.BLAKE2b_512,
+		BLAKE2S_256: crypto.BLAKE2s_256,
+		BLAKE3:      0,
+		SHAKE128:    0,
+		SHAKE256:    0,
+	}
+	for typ, c := range expected {
+		if typ.CryptoHash() != c {
+			t.Errorf("%s.CryptoHash() = %s, expected %s", typ,
+				typ.CryptoHash(), c)
+		}
+		if c == 0 {
+			continue
+		}
+		back, err := FromCryptoHash(c)
+		if err != nil || back != typ {
+			t.Errorf("FromCryptoHash(%s) = %s, %v, expected %s", c, back, err,
+				typ)
+		}
+	}
+	for _, c := range []crypto.Hash{0, crypto.MD5, crypto.SHA512_256} {
+		if _, err := FromCryptoHash(c); err == nil {
+			t.Errorf("FromCryptoHash(%s) did not return an error", c)
+		}
+	}
+}
+
+// Tests text and JSON round trips, and that JSON still accepts the numbers
+// written before HashType had a text form.
+func TestHashType_MarshalJSON(t *testing.T) {
+	type config struct {
+		Hash HashType
+	}
+	for _, tt := range builtins {
+		data, err := json.Marshal(config{tt.typ})
+		if err != nil {
+			t.Fatalf("json.Marshal() error: %+v", err)
+		}
+		if expected := `{"Hash":"` + tt.name + `"}`; string(data) != expected {
+			t.Errorf("json.Marshal() = %s, expected %s", data, expected)
+		}
+		var c config
+		if err = json.Unmarshal(data, &c); err != nil || c.Hash != tt.typ {
+			t.Errorf("json round trip of %s gave %s: %v", tt.name, c.Hash, err)
+		}
+	}
+
+	var c config
+	if err := json.Unmarshal([]byte(`{"Hash":3}`), &c); err != nil ||
+		c.Hash != SHA3_256 {
+		t.Errorf("json.Unmarshal() of a number gave %s: %v", c.Hash, err)
+	}
+	for _, data := range []string{`{"Hash":20}`, `{"Hash":256}`,
+		`{"Hash":-1}`, `{"Hash":"MD5"}`, `{"Hash":true}`} {
+		if err := json.Unmarshal([]byte(data), &c); err == nil {
+			t.Errorf("json.Unmarshal(%s) did not return an error", data)
+		}
+	}
+	if _, err := json.Marshal(config{20}); err == nil {
+		t.Errorf("json.Marshal() of an unknown type did not return an error")
+	}
+	if _, err := HashType(20).MarshalText(); err == nil {
+		t.Errorf("MarshalText() of an unknown type did not return an error")
+	}
+
+	var typ HashType
+	if err := typ.UnmarshalText([]byte("sha3_512")); err != nil ||
+		typ != SHA3_512 {
+		t.Errorf("UnmarshalText() gave %s: %v", typ, err)
+	}
+}
+
+// crc32Hash adapts CRC-32 to a custom hash type for the registration test.
+func crc32Hash() hash.Hash {
+	return crc32.NewIEEE()
+}
+
+// Tests registering a custom type and the errors Register returns.
+func TestRegister(t *testing.T) {
+	custom := FirstCustom + 7
+	if err := Register(custom, Info{Name: "CRC32", New: crc32Hash}); err != nil {
+		t.Fatalf("Register() returned an error: %+v", err)
+	}
+	defer func() {
+		registry.Lock()
+		delete(registry.types, custom)
+		registry.Unlock()
+	}()
+
+	if typ, err := Parse("crc32"); err != nil || typ != custom {
+		t.Errorf("Parse() of the custom type gave %d: %v", typ, err)
+	}
+	if custom.Size() != 4 || custom.String() != "CRC32" {
+		t.Errorf("custom type has size %d and name %s", custom.Size(), custom)
+	}
+	if _, err := custom.NewKeyed([]byte("key")); err == nil {
+		t.Errorf("NewKeyed() accepted a type with no keyed mode")
+	}
+	found := false
+	for _, typ := range Types() {
+		found = found || typ == custom
+	}
+	if !found || len(Types()) != len(builtins)+1 {
+		t.Errorf("Types() = %v", Types())
+	}
+
+	errs := map[string]struct {
+		typ  HashType
+		info Info
+	}{
+		"reserved":   {BLAKE3 + 50, Info{Name: "X", New: crc32Hash}},
+		"taken":      {custom, Info{Name: "X", New: crc32Hash}},
+		"same name":  {custom + 1, Info{Name: "crc32", New: crc32Hash}},
+		"empty name": {custom + 1, Info{New: crc32Hash}},
+		"bad name":   {custom + 1, Info{Name: "CRC 32", New: crc32Hash}},
+		"no new":     {custom + 1, Info{Name: "X"}},
+		"nil new":    {custom + 1, Info{Name: "X", New: func() hash.Hash { return nil }}},
+		"crypto":     {custom + 1, Info{Name: "X", New: sha256.New, Crypto: crypto.SHA256}},
+	}
+	for name, tt := range errs {
+		if err := Register(tt.typ, tt.info); err == nil {
+			t.Errorf("Register() accepted the %s case", name)
+		}
+	}
+	if HashType(custom + 1).Available() {
+		t.Errorf("a failed Register() left a type registered")
+	}
+}
@@ -0,0 +1,213 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package hashtocurve
+
+import (
+	"math/big"
+
+	"filippo.io/edwards25519"
+	"filippo.io/edwards25519/field"
+	jww "github.com/spf13/jwalterweatherman"
+	"gitlab.com/xx_network/crypto/hasher"
+	"gitlab.com/xx_network/crypto/large"
+)
+
+// Suite IDs of RFC 9380 section 8.5, for building DSTs.
+const (
+	// Edwards25519RO is the suite of HashToEdwards25519.
+	Edwards25519RO = "edwards25519_XMD:SHA-512_ELL2_RO_"
+
+	// Edwards25519NU is the suite of EncodeToEdwards25519.
+	Edwards25519NU = "edwards25519_XMD:SHA-512_ELL2_NU_"
+)
+
+// edwards25519Security is the security level k of both suites.
+const edwards25519Security = 128
+
+// Constants of the field of edwards25519 and of Elligator 2 on the birationally
+// equivalent curve25519, set by init.
+var (
+	// edwards25519P is the field prime 2^255 - 19
+	edwards25519P *large.Int
+
+	feZero, feOne *field.Element
+
+	// feJ is the curve25519 parameter A = 486662
+	feJ *field.Element
+
+	// feSqrtM1 is sqrt(-1), c3 of RFC 9380 appendix G.2.1
+	feSqrtM1 *field.Element
+
+	// feC2 is 2^((p+3)/8), c2 of RFC 9380 appendix G.2.1
+	feC2 *field.Element
+
+	// feEdwardsC1 is sqrt(-486664) with sgn0 of 0, c1 of RFC 9380 appendix
+	// G.2.2, which scales the rational map to edwards25519
+	feEdwardsC1 *field.Element
+)
+
+func init() {
+	p := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255),
+		big.NewInt(19))
+	edwards25519P = large.NewIntFromBigInt(p)
+
+	feZero, feOne = new(field.Element).Zero(), new(field.Element).One()
+	feJ = bigToElement(big.NewInt(486662))
+
+	// -1 and 2 are not squares modulo p, since p = 5 mod 8, so 2^((p-1)/4)
+	// is a square root of -1
+	e := new(big.Int).Rsh(new(big.Int).Sub(p, big.NewInt(1)), 2)
+	feSqrtM1 = bigToElement(new(big.Int).Exp(big.NewInt(2), e, p))
+	e.Rsh(new(big.Int).Add(p, big.NewInt(3)), 3)
+	feC2 = bigToElement(new(big.Int).Exp(big.NewInt(2), e, p))
+
+	c1 := new(big.Int).ModSqrt(new(big.Int).Sub(p, big.NewInt(486664)), p)
+	if c1 == nil {
+		jww.FATAL.Panicf("Failed to compute the edwards25519 map constant")
+	}
+	if c1.Bit(0) == 1 {
+		c1.Sub(p, c1)
+	}
+	feEdwardsC1 = bigToElement(c1)
+}
+
+// HashToEdwards25519 hashes msg to a point of the prime-order subgroup of
+// edwards25519 with the edwards25519_XMD:SHA-512_ELL2_RO_ suite of RFC 9380.
+// The point is indistinguishable from a random oracle's, which makes it
+// suitable wherever a protocol calls for one. It runs in constant time, so
+// msg may be secret, and only returns an error for an invalid DST.
+func HashToEdwards25519(msg, dst []byte) (*edwards25519.Point, error) {
+	u, err := hashToEdwards25519Field(msg, dst, 2)
+	if err != nil {
+		return nil, err
+	}
+	q := mapToEdwards25519(u[0])
+	q.Add(q, mapToEdwards25519(u[1]))
+	return q.MultByCofactor(q), nil
+}
+
+// EncodeToEdwards25519 hashes msg to a point of the prime-order subgroup of
+// edwards25519 with the edwards25519_XMD:SHA-512_ELL2_NU_ suite of RFC 9380.
+// It is faster than HashToEdwards25519 but its points are not uniform, as
+// they only cover about half of the subgroup, so it must only be used where
+// the protocol allows it.
+func EncodeToEdwards25519(msg, dst []byte) (*edwards25519.Point, error) {
+	u, err := hashToEdwards25519Field(msg, dst, 1)
+	if err != nil {
+		return nil, err
+	}
+	q := mapToEdwards25519(u[0])
+	return q.MultByCofactor(q), nil
+}
+
+// hashToEdwards25519Field hashes msg to count field elements with
+// expand_message_xmd and SHA-512.
+func hashToEdwards25519Field(msg, dst []byte,
+	count int) ([]*field.Element, error) {
+	u, err := hashToField(Expander{Hash: hasher.SHA2_512}, msg, dst,
+		edwards25519P, edwards25519Security, count)
+	if err != nil {
+		return nil, err
+	}
+	elements := make([]*field.Element, count)
+	for i, buf := range u {
+		elements[i] = bytesToElement(buf)
+	}
+	return elements, nil
+}
+
+// mapToEdwards25519 maps a field element to a point of edwards25519 with
+// Elligator 2 on curve25519 and the rational map to edwards25519, following
+// the straight-line, constant-time procedures of RFC 9380 appendices G.2.1
+// and G.2.2. The point is not yet in the prime-order subgroup.
+func mapToEdwards25519(u *field.Element) *edwards25519.Point {
+	// map_to_curve_elligator2_curve25519
+	tv1 := new(field.Element).Square(u)
+	tv1.Add(tv1, tv1)
+	xd := new(field.Element).Add(tv1, feOne)
+	x1n := new(field.Element).Negate(feJ)
+	tv2 := new(field.Element).Square(xd)
+	gxd := new(field.Element).Multiply(tv2, xd)
+	gx1 := new(field.Element).Multiply(feJ, tv1)
+	gx1.Multiply(gx1, x1n)
+	gx1.Add(gx1, tv2)
+	gx1.Multiply(gx1, x1n)
+	tv3 := new(field.Element).Square(gxd)
+	tv2.Square(tv3)
+	tv3.Multiply(tv3, gxd)
+	tv3.Multiply(tv3, gx1)
+	tv2.Multiply(tv2, tv3)
+	y11 := new(field.Element).Pow22523(tv2)
+	y11.Multiply(y11, tv3)
+	y12 := new(field.Element).Multiply(y11, feSqrtM1)
+	tv2.Square(y11)
+	tv2.Multiply(tv2, gxd)
+	e1 := tv2.Equal(gx1)
+	y1 := new(field.Element).Select(y11, y12, e1)
+	x2n := new(field.Element).Multiply(x1n, tv1)
+	y21 := new(field.Element).Multiply(y11, u)
+	y21.Multiply(y21, feC2)
+	y22 := new(field.Element).Multiply(y21, feSqrtM1)
+	gx2 := new(field.Element).Multiply(gx1, tv1)
+	tv2.Square(y21)
+	tv2.Multiply(tv2, gxd)
+	e2 := tv2.Equal(gx2)
+	y2 := new(field.Element).Select(y21, y22, e2)
+	tv2.Square(y1)
+	tv2.Multiply(tv2, gxd)
+	e3 := tv2.Equal(gx1)
+	xn := new(field.Element).Select(x1n, x2n, e3)
+	y := new(field.Element).Select(y1, y2, e3)
+	e4 := y.IsNegative()
+	y.Select(new(field.Element).Negate(y), y, e3^e4)
+
+	// map_to_curve_elligator2_edwards25519, with the curve25519 point
+	// (xn / xd, y / 1)
+	exn := new(field.Element).Multiply(xn, feEdwardsC1)
+	exd := new(field.Element).Multiply(xd, y)
+	eyn := new(field.Element).Subtract(xn, xd)
+	eyd := new(field.Element).Add(xn, xd)
+	tv1.Multiply(exd, eyd)
+	e := tv1.Equal(feZero)
+	exn.Select(feZero, exn, e)
+	exd.Select(feOne, exd, e)
+	eyn.Select(feOne, eyn, e)
+	eyd.Select(feOne, eyd, e)
+
+	// The extended coordinates of (exn / exd, eyn / eyd)
+	X := new(field.Element).Multiply(exn, eyd)
+	Y := new(field.Element).Multiply(eyn, exd)
+	Z := new(field.Element).Multiply(exd, eyd)
+	T := new(field.Element).Multiply(exn, eyn)
+	point, err := new(edwards25519.Point).SetExtendedCoordinates(X, Y, Z, T)
+	if err != nil {
+		jww.FATAL.Panicf("Elligator 2 mapped to a point off edwards25519: "+
+			"%+v", err)
+	}
+	return point
+}
+
+// bigToElement returns x, which must be less than p, as a field element.
+func bigToElement(x *big.Int) *field.Element {
+	return bytesToElement(x.FillBytes(make([]byte, 32)))
+}
+
+// bytesToElement returns the big-endian value in buf, which must be 32 bytes
+// and less than p, as a field element.
+func bytesToElement(buf []byte) *field.Element {
+	le := make([]byte, 32)
+	for i := range le {
+		le[i] = buf[31-i]
+	}
+	v, err := new(field.Element).SetBytes(le)
+	if err != nil {
+		jww.FATAL.Panicf("Failed to decode edwards25519 field element: %+v",
+			err)
+	}
+	return v
+}
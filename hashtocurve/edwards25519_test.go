@@ -0,0 +1,198 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package hashtocurve
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"filippo.io/edwards25519"
+	"filippo.io/edwards25519/field"
+	"gitlab.com/xx_network/crypto/hasher"
+)
+
+// edwards25519Vector is an RFC 9380 test vector of edwards25519, with the
+// affine coordinates of P and the field elements u, all big-endian.
+type edwards25519Vector struct {
+	x, y string
+	u    []string
+}
+
+// edwards25519ROVectors are the vectors of RFC 9380 appendix J.5.1 for each
+// of testMessages.
+var edwards25519ROVectors = []edwards25519Vector{
+	{
+		"3c3da6925a3c3c268448dcabb47ccde5439559d9599646a8260e47b1e4822fc6",
+		"09a6c8561a0b22bef63124c588ce4c62ea83a3c899763af26d795302e115dc21",
+		[]string{
+			"03fef4813c8cb5f98c6eef88fae174e6e7d5380de2b007799ac7ee712d203f3a",
+			"780bdddd137290c8f589dc687795aafae35f6b674668d92bf92ae793e6a60c75",
+		},
+	},
+	{
+		"608040b42285cc0d72cbb3985c6b04c935370c7361f4b7fbdb1ae7f8c1a8ecad",
+		"1a8395b88338f22e435bbd301183e7f20a5f9de643f11882fb237f88268a5531",
+		[]string{
+			"5081955c4141e4e7d02ec0e36becffaa1934df4d7a270f70679c78f9bd57c227",
+			"005bdc17a9b378b6272573a31b04361f21c371b256252ae5463119aa0b925b76",
+		},
+	},
+	{
+		"6d7fabf47a2dc03fe7d47f7dddd21082c5fb8f86743cd020f3fb147d57161472",
+		"53060a3d140e7fbcda641ed3cf42c88a75411e648a1add71217f70ea8ec561a6",
+		[]string{
+			"285ebaa3be701b79871bcb6e225ecc9b0b32dff2d60424b4c50642636a78d5b3",
+			"2e253e6a0ef658fedb8e4bd6a62d1544fd6547922acb3598ec6b369760b81b31",
+		},
+	},
+	{
+		"5fb0b92acedd16f3bcb0ef83f5c7b7a9466b5f1e0d8d217421878ea3686f8524",
+		"2eca15e355fcfa39d2982f67ddb0eea138e2994f5956ed37b7f72eea5e89d2f7",
+		[]string{
+			"4fedd25431c41f2a606952e2945ef5e3ac905a42cf64b8b4d4a83c533bf321af",
+			"02f20716a5801b843987097a8276b6d869295b2e11253751ca72c109d37485a9",
+		},
+	},
+	{
+		"0efcfde5898a839b00997fbe40d2ebe950bc81181afbd5cd6b9618aa336c1e8c",
+		"6dc2fc04f266c5c27f236a80b14f92ccd051ef1ff027f26a07f8c0f327d8f995",
+		[]string{
+			"6e34e04a5106e9bd59f64aba49601bf09d23b27f7b594e56d5de06df4a4ea33b",
+			"1c1c2cb59fc053f44b86c5d5eb8c1954b64976d0302d3729ff66e84068f5fd96",
+		},
+	},
+}
+
+// edwards25519NUVectors are the vectors of RFC 9380 appendix J.5.2 for each
+// of testMessages.
+var edwards25519NUVectors = []edwards25519Vector{
+	{
+		"1ff2b70ecf862799e11b7ae744e3489aa058ce805dd323a936375a84695e76da",
+		"222e314d04a4d5725e9f2aff9fb2a6b69ef375a1214eb19021ceab2d687f0f9b",
+		[]string{"7f3e7fb9428103ad7f52db32f9df32505d7b427d894c5093f7a0f0374a30641d"},
+	},
+	{
+		"5f13cc69c891d86927eb37bd4afc6672360007c63f68a33ab423a3aa040fd2a8",
+		"67732d50f9a26f73111dd1ed5dba225614e538599db58ba30aaea1f5c827fa42",
+		[]string{"09cfa30ad79bd59456594a0f5d3a76f6b71c6787b04de98be5cd201a556e253b"},
+	},
+	{
+		"1dd2fefce934ecfd7aae6ec998de088d7dd03316aa1847198aecf699ba6613f1",
+		"2f8a6c24dd1adde73909cada6a4a137577b0f179d336685c4a955a0a8e1a86fb",
+		[]string{"475ccff99225ef90d78cc9338e9f6a6bb7b17607c0c4428937de75d33edba941"},
+	},
+	{
+		"35fbdc5143e8a97afd3096f2b843e07df72e15bfca2eaf6879bf97c5d3362f73",
+		"2af6ff6ef5ebba128b0774f4296cb4c2279a074658b083b8dcca91f57a603450",
+		[]string{"049a1c8bd51bcb2aec339f387d1ff51428b88d0763a91bcdf6929814ac95d03d"},
+	},
+	{
+		"6e5e1f37e99345887fc12111575fc1c3e36df4b289b8759d23af14d774b66bff",
+		"2c90c3d39eb18ff291d33441b35f3262cdd307162cc97c31bfcc7a4245891a37",
+		[]string{"3cb0178a8137cefa5b79a3a57c858d7eeeaa787b2781be4a362a2f0750d24fa0"},
+	},
+}
+
+// Tests HashToEdwards25519 and EncodeToEdwards25519 against the RFC 9380
+// vectors, checking the field elements as well as the points.
+func TestHashToEdwards25519_Vectors(t *testing.T) {
+	suites := []struct {
+		suite   string
+		hash    func(msg, dst []byte) (*edwards25519.Point, error)
+		vectors []edwards25519Vector
+	}{
+		{Edwards25519RO, HashToEdwards25519, edwards25519ROVectors},
+		{Edwards25519NU, EncodeToEdwards25519, edwards25519NUVectors},
+	}
+	for _, s := range suites {
+		dst := []byte("QUUX-V01-CS02-with-" + s.suite)
+		for i, v := range s.vectors {
+			msg := []byte(testMessages[i])
+			u, err := HashToField(Expander{Hash: hasher.SHA2_512}, msg, dst,
+				edwards25519P, edwards25519Security, len(v.u))
+			if err != nil {
+				t.Fatalf("HashToField() error: %+v", err)
+			}
+			for j, expected := range v.u {
+				if got := hex.EncodeToString(u[j].LeftpadBytes(32)); got != expected {
+					t.Errorf("%s u%d of %.10q:\nexpected: %s\nreceived: %s",
+						s.suite, j, msg, expected, got)
+				}
+			}
+
+			p, err := s.hash(msg, dst)
+			if err != nil {
+				t.Fatalf("%s error: %+v", s.suite, err)
+			}
+			x, y := affine(p)
+			if x != v.x || y != v.y {
+				t.Errorf("%s of %.10q:\nexpected: (%s, %s)\nreceived: (%s, %s)",
+					s.suite, msg, v.x, v.y, x, y)
+			}
+			if !inPrimeOrderSubgroup(p) {
+				t.Errorf("%s of %.10q is not in the prime-order subgroup",
+					s.suite, msg)
+			}
+		}
+	}
+}
+
+// Tests that both suites reject an empty DST and separate DSTs.
+func TestHashToEdwards25519_DST(t *testing.T) {
+	for _, hash := range []func(msg, dst []byte) (*edwards25519.Point,
+		error){HashToEdwards25519, EncodeToEdwards25519} {
+		if _, err := hash([]byte("msg"), nil); err == nil {
+			t.Errorf("hashing accepted an empty DST")
+		}
+		p1, _ := hash([]byte("msg"), []byte("xx network test v1"))
+		p2, _ := hash([]byte("msg"), []byte("xx network test v2"))
+		if p1.Equal(p2) == 1 {
+			t.Errorf("different DSTs gave the same point")
+		}
+	}
+}
+
+// Tests that the map sends 0, where Elligator 2 returns the point of order
+// two (0, 0) of curve25519, to the identity as the exceptional case of the
+// rational map requires.
+func TestMapToEdwards25519_Zero(t *testing.T) {
+	p := mapToEdwards25519(feZero)
+	if p.Equal(edwards25519.NewIdentityPoint()) != 1 {
+		x, y := affine(p)
+		t.Errorf("0 mapped to (%s, %s) instead of the identity", x, y)
+	}
+}
+
+// affine returns the big-endian hex affine coordinates of p.
+func affine(p *edwards25519.Point) (x, y string) {
+	X, Y, Z, _ := p.ExtendedCoordinates()
+	zInv := new(field.Element).Invert(Z)
+	return beHex(new(field.Element).Multiply(X, zInv)),
+		beHex(new(field.Element).Multiply(Y, zInv))
+}
+
+// beHex returns the big-endian hex encoding of v.
+func beHex(v *field.Element) string {
+	b := v.Bytes()
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return hex.EncodeToString(b)
+}
+
+// inPrimeOrderSubgroup returns whether p has order dividing the group order
+// l, by checking that l * p is the identity.
+func inPrimeOrderSubgroup(p *edwards25519.Point) bool {
+	// l - 1 = 2^252 + 27742317777372353535851937790883648492, little-endian
+	lMinus1, _ := edwards25519.NewScalar().SetCanonicalBytes([]byte{
+		0xec, 0xd3, 0xf5, 0x5c, 0x1a, 0x63, 0x12, 0x58, 0xd6, 0x9c, 0xf7,
+		0xa2, 0xde, 0xf9, 0xde, 0x14, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0x10})
+	lp := new(edwards25519.Point).ScalarMult(lMinus1, p)
+	return lp.Add(lp, p).Equal(edwards25519.NewIdentityPoint()) == 1
+}
@@ -0,0 +1,160 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+// Package hashtocurve hashes arbitrary byte strings uniformly into prime
+// fields and prime-order groups as specified in RFC 9380, for protocols such
+// as OPRFs, VRFs and DLEQ proofs that need group elements whose discrete
+// logarithm nobody knows.
+//
+// It provides expand_message_xmd and expand_message_xof over hasher types,
+// hash_to_field for any prime, and the edwards25519_XMD:SHA-512_ELL2_RO_ and
+// _NU_ suites for edwards25519. Hashing into the prime-order subgroups of
+// cyclic groups is cyclic.Group.HashToElement, which builds on this package.
+//
+// Every function takes a domain separation tag (DST) that must be unique to
+// the protocol and its use of the hash; RFC 9380 section 3.1 recommends
+// tags of the form "APP-V01-CS02-with-" followed by the suite ID.
+package hashtocurve
+
+import (
+	"hash"
+
+	"github.com/pkg/errors"
+	"gitlab.com/xx_network/crypto/hasher"
+)
+
+const (
+	// maxDSTLen is the length above which a DST is hashed down first
+	maxDSTLen = 255
+
+	// maxExpandLen is the largest output of expand_message
+	maxExpandLen = 0xffff
+
+	// oversizeDSTPrefix prefixes a DST that is hashed down
+	oversizeDSTPrefix = "H2C-OVERSIZE-DST-"
+)
+
+// Expander selects the expand_message variant of RFC 9380 section 5.3 that
+// stretches a message into uniform bytes.
+type Expander struct {
+	// Hash is the hash function. For XMD it may be any registered type; for
+	// XOF it must be an extendable-output function such as SHAKE128,
+	// SHAKE256 or BLAKE3.
+	Hash hasher.HashType
+
+	// XOF selects expand_message_xof instead of expand_message_xmd.
+	XOF bool
+}
+
+// Expand returns length uniform bytes derived from msg and dst with the
+// selected variant.
+func (e Expander) Expand(msg, dst []byte, length int) ([]byte, error) {
+	if e.XOF {
+		return ExpandMessageXOF(e.Hash, msg, dst, length)
+	}
+	return ExpandMessageXMD(e.Hash, msg, dst, length)
+}
+
+// ExpandMessageXMD implements expand_message_xmd (RFC 9380 section 5.3.1).
+// It returns length bytes, which may be at most 255 times the digest size of
+// h and at most 65535. A DST longer than 255 bytes is first hashed down as in
+// section 5.3.3.
+func ExpandMessageXMD(h hasher.HashType, msg, dst []byte,
+	length int) ([]byte, error) {
+	newHash, err := h.Func()
+	if err != nil {
+		return nil, err
+	}
+	if err = checkExpand(dst, length); err != nil {
+		return nil, err
+	}
+	size, blockSize := h.Size(), h.BlockSize()
+	ell := (length + size - 1) / size
+	if ell > 255 {
+		return nil, errors.Errorf("%s cannot expand to %d bytes, more than "+
+			"255 digests", h, length)
+	}
+
+	if len(dst) > maxDSTLen {
+		dst = sum(newHash(), []byte(oversizeDSTPrefix), dst)
+	}
+	dstPrime := append(append([]byte{}, dst...), byte(len(dst)))
+
+	// b_0 = H(Z_pad || msg || l_i_b_str || 0 || DST_prime)
+	b0 := sum(newHash(), make([]byte, blockSize), msg,
+		[]byte{byte(length >> 8), byte(length), 0}, dstPrime)
+
+	// b_1 = H(b_0 || 1 || DST_prime) and
+	// b_i = H(strxor(b_0, b_(i-1)) || i || DST_prime)
+	out := make([]byte, 0, ell*size)
+	hash := newHash()
+	bi := sum(hash, b0, []byte{1}, dstPrime)
+	out = append(out, bi...)
+	for i := 2; i <= ell; i++ {
+		for j := range bi {
+			bi[j] ^= b0[j]
+		}
+		hash.Reset()
+		bi = sum(hash, bi, []byte{byte(i)}, dstPrime)
+		out = append(out, bi...)
+	}
+	return out[:length], nil
+}
+
+// ExpandMessageXOF implements expand_message_xof (RFC 9380 section 5.3.2)
+// with the extendable-output function h. It returns length bytes, at most
+// 65535. A DST longer than 255 bytes is first hashed down, as in section
+// 5.3.3, to h.Size() bytes, which is 2k/8 for the security level k of
+// SHAKE128, SHAKE256 and BLAKE3.
+func ExpandMessageXOF(h hasher.HashType, msg, dst []byte,
+	length int) ([]byte, error) {
+	xof, err := h.NewXOF()
+	if err != nil {
+		return nil, err
+	}
+	if err = checkExpand(dst, length); err != nil {
+		return nil, err
+	}
+
+	if len(dst) > maxDSTLen {
+		xof.Write([]byte(oversizeDSTPrefix))
+		xof.Write(dst)
+		dst = make([]byte, h.Size())
+		xof.Read(dst)
+		xof.Reset()
+	}
+
+	// H(msg || I2OSP(len_in_bytes, 2) || DST_prime, len_in_bytes)
+	xof.Write(msg)
+	xof.Write([]byte{byte(length >> 8), byte(length)})
+	xof.Write(dst)
+	xof.Write([]byte{byte(len(dst))})
+	out := make([]byte, length)
+	xof.Read(out)
+	return out, nil
+}
+
+// checkExpand returns an error for an empty DST, which RFC 9380 section 3.1
+// forbids, or an output length expand_message does not allow.
+func checkExpand(dst []byte, length int) error {
+	if len(dst) == 0 {
+		return errors.New("domain separation tag must not be empty")
+	}
+	if length <= 0 || length > maxExpandLen {
+		return errors.Errorf("cannot expand to %d bytes; the length must be "+
+			"from 1 to %d", length, maxExpandLen)
+	}
+	return nil
+}
+
+// sum writes each of data to h and returns the digest.
+func sum(h hash.Hash, data ...[]byte) []byte {
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
@@ -0,0 +1,75 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package hashtocurve
+
+import (
+	"github.com/pkg/errors"
+	"gitlab.com/xx_network/crypto/large"
+)
+
+// HashToField implements hash_to_field (RFC 9380 section 5.2) for the field
+// of integers modulo the odd prime p, returning count elements. The security
+// level k, in bits, sets the number of uniform bytes reduced to each element
+// to L = ceil((ceil(log2(p)) + k) / 8), which makes every element within
+// statistical distance 2^-k of uniform. RFC 9380 uses k = 128 for
+// edwards25519 and P-256.
+//
+// The reduction runs in time that depends only on the size of p, so msg may
+// be secret. Primality of p is not checked.
+func HashToField(e Expander, msg, dst []byte, p *large.Int, k,
+	count int) ([]*large.Int, error) {
+	encoded, err := hashToField(e, msg, dst, p, k, count)
+	if err != nil {
+		return nil, err
+	}
+	elements := make([]*large.Int, count)
+	for i, buf := range encoded {
+		elements[i] = large.NewIntFromBytes(buf)
+	}
+	return elements, nil
+}
+
+// hashToField is HashToField returning each element big-endian, the length
+// of p, so that callers can decode them in constant time.
+func hashToField(e Expander, msg, dst []byte, p *large.Int, k,
+	count int) ([][]byte, error) {
+	if count <= 0 {
+		return nil, errors.Errorf("cannot hash to %d field elements", count)
+	}
+	m, length, err := fieldParams(p, k)
+	if err != nil {
+		return nil, err
+	}
+	uniform, err := e.Expand(msg, dst, count*length)
+	if err != nil {
+		return nil, err
+	}
+
+	elements := make([][]byte, count)
+	for i := range elements {
+		tv := uniform[i*length : (i+1)*length]
+		elements[i] = m.NewMontIntFromBytes(tv).Bytes()
+	}
+	return elements, nil
+}
+
+// fieldParams returns the Montgomery context of p and the number of uniform
+// bytes L that hash_to_field reduces to each element at security level k.
+func fieldParams(p *large.Int, k int) (*large.Modulus, int, error) {
+	if k <= 0 {
+		return nil, 0, errors.Errorf("invalid security level of %d bits", k)
+	}
+	if p == nil || p.Cmp(large.NewInt(3)) < 0 {
+		return nil, 0, errors.New("field modulus must be an odd prime")
+	}
+	m, err := large.NewModulus(p)
+	if err != nil {
+		return nil, 0, errors.WithMessage(err, "invalid field modulus")
+	}
+	return m, (p.BitLen() + k + 7) / 8, nil
+}
@@ -0,0 +1,71 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package hashtocurve
+
+import (
+	"testing"
+
+	"gitlab.com/xx_network/crypto/hasher"
+	"gitlab.com/xx_network/crypto/large"
+)
+
+// Tests that HashToField returns distinct elements below p and that later
+// elements do not change with the count.
+func TestHashToField(t *testing.T) {
+	e := Expander{Hash: hasher.SHA2_256}
+	dst := []byte("xx network test v1")
+	p := large.NewInt(1000003)
+	u, err := HashToField(e, []byte("msg"), dst, p, 128, 50)
+	if err != nil {
+		t.Fatalf("HashToField() error: %+v", err)
+	}
+	seen := make(map[int64]bool)
+	for _, x := range u {
+		if x.Cmp(p) >= 0 {
+			t.Errorf("element %s is not reduced modulo %s", x.Text(10),
+				p.Text(10))
+		}
+		seen[x.Int64()] = true
+	}
+	if len(seen) < 49 {
+		t.Errorf("50 elements have only %d distinct values", len(seen))
+	}
+
+	// The count is part of the expanded length, so the elements differ
+	one, _ := HashToField(e, []byte("msg"), dst, p, 128, 1)
+	if one[0].Cmp(u[0]) == 0 {
+		t.Errorf("the count does not separate the outputs")
+	}
+}
+
+// Tests that HashToField rejects invalid parameters.
+func TestHashToField_Errors(t *testing.T) {
+	e := Expander{Hash: hasher.SHA2_256}
+	dst := []byte("DST")
+	p := large.NewInt(1000003)
+	invalid := []struct {
+		name     string
+		e        Expander
+		p        *large.Int
+		k, count int
+	}{
+		{"a count of 0", e, p, 128, 0},
+		{"a security level of 0", e, p, 0, 1},
+		{"a nil modulus", e, nil, 128, 1},
+		{"a modulus of 2", e, large.NewInt(2), 128, 1},
+		{"an even modulus", e, large.NewInt(1000004), 128, 1},
+		{"too long an output", e, p, 128, 1000},
+		{"an unknown hash", Expander{Hash: 200}, p, 128, 1},
+	}
+	for _, tt := range invalid {
+		if _, err := HashToField(tt.e, nil, dst, tt.p, tt.k,
+			tt.count); err == nil {
+			t.Errorf("HashToField() accepted %s", tt.name)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package kdf
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/zeebo/blake3"
+)
+
+// DeriveKey returns length bytes derived from the key material with BLAKE3 in
+// its derive_key mode. The context string separates unrelated uses of the same
+// material. It must be a hard-coded, globally unique constant, never derived
+// from input; the BLAKE3 authors recommend the form
+// "[application] [commit timestamp] [purpose]", for example
+// "xx network 2022-06-01 12:00:00 session keys v1".
+func DeriveKey(context string, material []byte, length int) ([]byte, error) {
+	if length <= 0 {
+		return nil, errors.Errorf("length must be positive, requested %d",
+			length)
+	}
+	r, err := NewDeriveKeyReader(context, material)
+	if err != nil {
+		return nil, err
+	}
+	return read(r, length)
+}
+
+// NewDeriveKeyReader returns a reader of the BLAKE3 derive_key output for the
+// context string and key material. Its first bytes are the output of
+// DeriveKey, and it can produce up to 2^64 bytes. See DeriveKey for how to
+// choose the context.
+func NewDeriveKeyReader(context string, material []byte) (io.Reader, error) {
+	if context == "" {
+		return nil, errors.New("context string must not be empty")
+	}
+	h := blake3.NewDeriveKey(context)
+	_, _ = h.Write(material)
+	return h.Digest(), nil
+}
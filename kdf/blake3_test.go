@@ -0,0 +1,92 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package kdf
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+)
+
+// blake3TestContext is the context of the official BLAKE3 test vectors.
+const blake3TestContext = "BLAKE3 2019-12-27 16:29:52 test vectors context"
+
+// blake3TestInput returns the input of the official BLAKE3 test vectors, the
+// bytes 0 to 250 repeated.
+func blake3TestInput(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i % 251)
+	}
+	return b
+}
+
+// Tests DeriveKey against the official BLAKE3 derive_key test vectors.
+func TestDeriveKey_Vectors(t *testing.T) {
+	tests := []struct {
+		inputLen int
+		expected string
+	}{
+		{0, "2cc39783c223154fea8dfb7c1b1660f2ac2dcbd1c1de8277b0b0dd39b7e50d7d"},
+		{1, "b3e2e340a117a499c6cf2398a19ee0d29cca2bb7404c73063382693bf66cb06c"},
+		{1024, "7356cd7720d5b66b6d0697eb3177d9f8d73a4a5c5e968896eb6a689684302706"},
+	}
+	for _, tt := range tests {
+		key, err := DeriveKey(blake3TestContext, blake3TestInput(tt.inputLen),
+			32)
+		if err != nil {
+			t.Fatalf("DeriveKey() error: %+v", err)
+		}
+		if got := hex.EncodeToString(key); got != tt.expected {
+			t.Errorf("DeriveKey() of %d bytes = %s, expected %s", tt.inputLen,
+				got, tt.expected)
+		}
+	}
+}
+
+// Tests that the reader extends DeriveKey and that the context separates
+// outputs.
+func TestNewDeriveKeyReader(t *testing.T) {
+	material := []byte("master secret")
+	key, err := DeriveKey("xx network test encryption", material, 100)
+	if err != nil {
+		t.Fatalf("DeriveKey() error: %+v", err)
+	}
+	r, err := NewDeriveKeyReader("xx network test encryption", material)
+	if err != nil {
+		t.Fatalf("NewDeriveKeyReader() error: %+v", err)
+	}
+	long := make([]byte, 10000)
+	if _, err = io.ReadFull(r, long); err != nil {
+		t.Fatalf("Read() error: %+v", err)
+	}
+	if !bytes.Equal(long[:100], key) {
+		t.Errorf("reader does not start with the DeriveKey output")
+	}
+	// Shorter outputs are prefixes, unlike the Schedule
+	short, _ := DeriveKey("xx network test encryption", material, 32)
+	if !bytes.Equal(short, key[:32]) {
+		t.Errorf("shorter DeriveKey output is not a prefix")
+	}
+
+	other, _ := DeriveKey("xx network test mac", material, 100)
+	if bytes.Equal(other, key) {
+		t.Errorf("different contexts derived the same key")
+	}
+
+	if _, err = DeriveKey("", material, 32); err == nil {
+		t.Errorf("DeriveKey() accepted an empty context")
+	}
+	if _, err = DeriveKey("context", material, 0); err == nil {
+		t.Errorf("DeriveKey() accepted a length of zero")
+	}
+	if _, err = NewDeriveKeyReader("", material); err == nil {
+		t.Errorf("NewDeriveKeyReader() accepted an empty context")
+	}
+}
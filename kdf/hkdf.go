@@ -0,0 +1,102 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+// Package kdf derives keys from secrets such as Diffie-Hellman outputs or
+// passwords that have already been stretched. It provides HKDF (RFC 5869)
+// over any hasher.HashType, the BLAKE3 derive_key mode and a Schedule that
+// derives labelled subkeys from one master secret.
+package kdf
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+	"gitlab.com/xx_network/crypto/hasher"
+	"golang.org/x/crypto/hkdf"
+)
+
+// MaxHKDFOutput returns the most bytes HKDF can expand to with the given
+// hash, 255 times its digest size, or zero if h is not registered.
+func MaxHKDFOutput(h hasher.HashType) int {
+	return 255 * h.Size()
+}
+
+// Extract returns the pseudorandom key of HKDF-Extract over the secret input
+// keying material and the salt. A nil or empty salt is treated as a string of
+// zeros of the digest size, as RFC 5869 specifies.
+func Extract(h hasher.HashType, secret, salt []byte) ([]byte, error) {
+	newHash, err := h.Func()
+	if err != nil {
+		return nil, err
+	}
+	return hkdf.Extract(newHash, secret, salt), nil
+}
+
+// Expand returns length bytes of HKDF-Expand over the pseudorandom key and
+// the context info. The key should come from Extract or otherwise be uniformly
+// random, and must be at least the digest size. The length must be positive
+// and at most MaxHKDFOutput.
+func Expand(h hasher.HashType, prk, info []byte, length int) ([]byte, error) {
+	newHash, err := h.Func()
+	if err != nil {
+		return nil, err
+	}
+	if len(prk) < h.Size() {
+		return nil, errors.Errorf("pseudorandom key of %d bytes is shorter "+
+			"than the %d byte digest of %s", len(prk), h.Size(), h)
+	}
+	if err = checkLength(h, length); err != nil {
+		return nil, err
+	}
+	return read(hkdf.Expand(newHash, prk, info), length)
+}
+
+// Key returns length bytes of HKDF output, extracting from the secret and
+// salt and then expanding with the context info. The length must be positive
+// and at most MaxHKDFOutput.
+func Key(h hasher.HashType, secret, salt, info []byte, length int) ([]byte,
+	error) {
+	newHash, err := h.Func()
+	if err != nil {
+		return nil, err
+	}
+	if err = checkLength(h, length); err != nil {
+		return nil, err
+	}
+	return read(hkdf.New(newHash, secret, salt, info), length)
+}
+
+// NewReader returns a reader of the HKDF output for the secret, salt and
+// context info, for callers that consume key material incrementally. The
+// reader returns an error once MaxHKDFOutput bytes have been read; use
+// NewDeriveKeyReader for unbounded output.
+func NewReader(h hasher.HashType, secret, salt, info []byte) (io.Reader,
+	error) {
+	newHash, err := h.Func()
+	if err != nil {
+		return nil, err
+	}
+	return hkdf.New(newHash, secret, salt, info), nil
+}
+
+// checkLength returns an error if HKDF cannot produce length bytes with h.
+func checkLength(h hasher.HashType, length int) error {
+	if length <= 0 || length > MaxHKDFOutput(h) {
+		return errors.Errorf("HKDF with %s can produce 1 to %d bytes, "+
+			"requested %d", h, MaxHKDFOutput(h), length)
+	}
+	return nil
+}
+
+// read returns the next length bytes of r.
+func read(r io.Reader, length int) ([]byte, error) {
+	key := make([]byte, length)
+	if _, err := io.ReadFull(r, key); err != nil {
+		return nil, errors.Wrap(err, "failed to derive key")
+	}
+	return key, nil
+}
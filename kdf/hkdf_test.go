@@ -0,0 +1,199 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package kdf
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"testing"
+
+	"gitlab.com/xx_network/crypto/hasher"
+)
+
+// sha1Type is SHA-1, which the registry does not include, registered for the
+// RFC 5869 test vectors that use it.
+const sha1Type = hasher.FirstCustom
+
+func init() {
+	err := hasher.Register(sha1Type, hasher.Info{
+		Name: "SHA1", New: sha1.New, Crypto: crypto.SHA1})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// span returns the bytes from through to inclusive.
+func span(from, to byte) []byte {
+	b := make([]byte, 0, int(to-from)+1)
+	for c := int(from); c <= int(to); c++ {
+		b = append(b, byte(c))
+	}
+	return b
+}
+
+// decodeHex decodes a hex test vector.
+func decodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("Invalid hex %q: %+v", s, err)
+	}
+	return b
+}
+
+// rfc5869Vectors are the test vectors of RFC 5869 appendix A.
+var rfc5869Vectors = []struct {
+	name            string
+	h               hasher.HashType
+	ikm, salt, info []byte
+	length          int
+	prk, okm        string
+}{
+	{"A.1", hasher.SHA2_256, bytes.Repeat([]byte{0x0b}, 22), span(0x00, 0x0c),
+		span(0xf0, 0xf9), 42,
+		"077709362c2e32df0ddc3f0dc47bba6390b6c73bb50f9c3122ec844ad7c2b3e5",
+		"3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf" +
+			"34007208d5b887185865"},
+	{"A.2", hasher.SHA2_256, span(0x00, 0x4f), span(0x60, 0xaf),
+		span(0xb0, 0xff), 82,
+		"06a6b88c5853361a06104c9ceb35b45cef760014904671014a193f40c15fc244",
+		"b11e398dc80327a1c8e7f78c596a49344f012eda2d4efad8a050cc4c19afa97c" +
+			"59045a99cac7827271cb41c65e590e09da3275600c2f09b8367793a9aca3db71" +
+			"cc30c58179ec3e87c14c01d5c1f3434f1d87"},
+	{"A.3", hasher.SHA2_256, bytes.Repeat([]byte{0x0b}, 22), nil, nil, 42,
+		"19ef24a32c717b167f33a91d6f648bdf96596776afdb6377ac434c1c293ccb04",
+		"8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d" +
+			"9d201395faa4b61a96c8"},
+	{"A.4", sha1Type, bytes.Repeat([]byte{0x0b}, 11), span(0x00, 0x0c),
+		span(0xf0, 0xf9), 42,
+		"9b6c18c432a7bf8f0e71c8eb88f4b30baa2ba243",
+		"085a01ea1b10f36933068b56efa5ad81a4f14b822f5b091568a9cdd4f155fda2" +
+			"c22e422478d305f3f896"},
+	{"A.5", sha1Type, span(0x00, 0x4f), span(0x60, 0xaf), span(0xb0, 0xff),
+		82,
+		"8adae09a2a307059478d309b26c4115a224cfaf6",
+		"0bd770a74d1160f7c9f12cd5912a06ebff6adcae899d92191fe4305673ba2ffe" +
+			"8fa3f1a4e5ad79f3f334b3b202b2173c486ea37ce3d397ed034c7f9dfeb15c5e" +
+			"927336d0441f4c4300e2cff0d0900b52d3b4"},
+	{"A.6", sha1Type, bytes.Repeat([]byte{0x0b}, 22), []byte{}, []byte{}, 42,
+		"da8c8a73c7fa77288ec6f5e7c297786aa0d32d01",
+		"0ac1af7002b3d761d1e55298da9d0506b9ae52057220a306e07b6b87e8df21d0" +
+			"ea00033de03984d34918"},
+	{"A.7", sha1Type, bytes.Repeat([]byte{0x0c}, 22), nil, []byte{}, 42,
+		"2adccada18779e7c2077ad2eb19d3f3e731385dd",
+		"2c91117204d745f3500d636a62f64f0ab3bae548aa53d423b0d1f27ebba6f5e5" +
+			"673a081d70cce7acfc48"},
+}
+
+// Tests Extract, Expand, Key and NewReader against RFC 5869.
+func TestHKDF_RFC5869(t *testing.T) {
+	for _, tt := range rfc5869Vectors {
+		prk, err := Extract(tt.h, tt.ikm, tt.salt)
+		if err != nil {
+			t.Fatalf("%s: Extract() error: %+v", tt.name, err)
+		}
+		if !bytes.Equal(prk, decodeHex(t, tt.prk)) {
+			t.Errorf("%s: PRK = %x, expected %s", tt.name, prk, tt.prk)
+		}
+
+		okm, err := Expand(tt.h, prk, tt.info, tt.length)
+		if err != nil {
+			t.Fatalf("%s: Expand() error: %+v", tt.name, err)
+		}
+		expected := decodeHex(t, tt.okm)
+		if !bytes.Equal(okm, expected) {
+			t.Errorf("%s: OKM = %x, expected %s", tt.name, okm, tt.okm)
+		}
+
+		key, err := Key(tt.h, tt.ikm, tt.salt, tt.info, tt.length)
+		if err != nil || !bytes.Equal(key, expected) {
+			t.Errorf("%s: Key() = %x, %v, expected %s", tt.name, key, err,
+				tt.okm)
+		}
+
+		r, err := NewReader(tt.h, tt.ikm, tt.salt, tt.info)
+		if err != nil {
+			t.Fatalf("%s: NewReader() error: %+v", tt.name, err)
+		}
+		got := make([]byte, tt.length)
+		for i := 0; i < tt.length; i += 5 {
+			end := i + 5
+			if end > tt.length {
+				end = tt.length
+			}
+			if _, err = io.ReadFull(r, got[i:end]); err != nil {
+				t.Fatalf("%s: Read() error: %+v", tt.name, err)
+			}
+		}
+		if !bytes.Equal(got, expected) {
+			t.Errorf("%s: reader gave %x, expected %s", tt.name, got, tt.okm)
+		}
+	}
+}
+
+// Tests the output limit and the errors for bad arguments.
+func TestHKDF_Errors(t *testing.T) {
+	h := hasher.SHA2_256
+	if MaxHKDFOutput(h) != 255*32 {
+		t.Errorf("MaxHKDFOutput() = %d, expected %d", MaxHKDFOutput(h), 255*32)
+	}
+	if _, err := Key(h, []byte("secret"), nil, nil, 255*32); err != nil {
+		t.Errorf("Key() rejected the maximum length: %+v", err)
+	}
+	for _, length := range []int{0, -1, 255*32 + 1} {
+		if _, err := Key(h, []byte("secret"), nil, nil, length); err == nil {
+			t.Errorf("Key() accepted a length of %d", length)
+		}
+		if _, err := Expand(h, make([]byte, 32), nil, length); err == nil {
+			t.Errorf("Expand() accepted a length of %d", length)
+		}
+	}
+	if _, err := Expand(h, make([]byte, 31), nil, 32); err == nil {
+		t.Errorf("Expand() accepted a short pseudorandom key")
+	}
+
+	r, _ := NewReader(h, []byte("secret"), nil, nil)
+	if _, err := io.ReadFull(r, make([]byte, 255*32)); err != nil {
+		t.Fatalf("Reading the maximum output failed: %+v", err)
+	}
+	if _, err := r.Read(make([]byte, 1)); err == nil {
+		t.Errorf("Reader returned more than the maximum output")
+	}
+
+	unknown := hasher.HashType(200)
+	if _, err := Extract(unknown, nil, nil); err == nil {
+		t.Errorf("Extract() accepted an unknown hash")
+	}
+	if _, err := Expand(unknown, nil, nil, 1); err == nil {
+		t.Errorf("Expand() accepted an unknown hash")
+	}
+	if _, err := Key(unknown, nil, nil, nil, 1); err == nil {
+		t.Errorf("Key() accepted an unknown hash")
+	}
+	if _, err := NewReader(unknown, nil, nil, nil); err == nil {
+		t.Errorf("NewReader() accepted an unknown hash")
+	}
+}
+
+// Tests that HKDF works over every registered hash type.
+func TestKey_AllHashes(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, h := range hasher.Types() {
+		key, err := Key(h, []byte("secret"), []byte("salt"), []byte("info"),
+			2*h.Size()+1)
+		if err != nil {
+			t.Fatalf("Key() with %s error: %+v", h, err)
+		}
+		if seen[string(key)] {
+			t.Errorf("Key() with %s repeats another hash's output", h)
+		}
+		seen[string(key)] = true
+	}
+}
@@ -0,0 +1,88 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package kdf
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+	"gitlab.com/xx_network/crypto/hasher"
+)
+
+// Labels for the subkeys most protocols need. Any other label may be used.
+const (
+	LabelEncryption = "encryption"
+	LabelMAC        = "mac"
+	LabelNonce      = "nonce"
+)
+
+// maxLabel is the longest context or label, in bytes, that fits the one-byte
+// length prefix of the HKDF info.
+const maxLabel = 255
+
+// maxSubkey is the longest subkey, in bytes, that fits the two-byte length
+// prefix of the HKDF info.
+const maxSubkey = 0xffff
+
+// Schedule derives named subkeys from one master secret. The secret is
+// extracted once with HKDF, and each subkey is expanded with an info string
+// binding the schedule's context, the label and the subkey length, so that
+// subkeys with different labels or lengths are independent. A Schedule is
+// read-only after creation and safe for concurrent use.
+type Schedule struct {
+	h       hasher.HashType
+	context string
+	prk     []byte
+}
+
+// NewSchedule extracts a schedule from the master secret and salt. The
+// context names the protocol, such as "xx network session v1", so that two
+// protocols sharing a secret derive unrelated keys. It must be non-empty and
+// at most 255 bytes.
+func NewSchedule(h hasher.HashType, context string, secret, salt []byte) (
+	*Schedule, error) {
+	if context == "" || len(context) > maxLabel {
+		return nil, errors.Errorf("context must be 1 to %d bytes, received "+
+			"%d", maxLabel, len(context))
+	}
+	prk, err := Extract(h, secret, salt)
+	if err != nil {
+		return nil, err
+	}
+	return &Schedule{h: h, context: context, prk: prk}, nil
+}
+
+// Derive returns the subkey of the given length for the label, such as
+// LabelEncryption. The same label and length always give the same subkey.
+// The label must be non-empty and at most 255 bytes, and the length at most
+// MaxHKDFOutput and 65535, the most the info can encode.
+func (s *Schedule) Derive(label string, length int) ([]byte, error) {
+	if label == "" || len(label) > maxLabel {
+		return nil, errors.Errorf("label must be 1 to %d bytes, received %d",
+			maxLabel, len(label))
+	}
+	if length > maxSubkey {
+		return nil, errors.Errorf("subkeys can be at most %d bytes, "+
+			"requested %d", maxSubkey, length)
+	}
+	return Expand(s.h, s.prk, s.info(label, length), length)
+}
+
+// info encodes the HKDF info for a subkey as
+//
+//	length (2 bytes) | len(context) (1 byte) | context | len(label) (1 byte) | label
+//
+// which, like the TLS 1.3 HkdfLabel, is unambiguous for every input.
+func (s *Schedule) info(label string, length int) []byte {
+	info := make([]byte, 2, 4+len(s.context)+len(label))
+	binary.BigEndian.PutUint16(info, uint16(length))
+	info = append(info, byte(len(s.context)))
+	info = append(info, s.context...)
+	info = append(info, byte(len(label)))
+	return append(info, label...)
+}
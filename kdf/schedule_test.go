@@ -0,0 +1,118 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package kdf
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"gitlab.com/xx_network/crypto/chacha"
+	"gitlab.com/xx_network/crypto/csprng"
+	"gitlab.com/xx_network/crypto/hasher"
+)
+
+// Tests that the schedule derives independent subkeys for each label, length
+// and context, and that the output is pinned.
+func TestSchedule_Derive(t *testing.T) {
+	s, err := NewSchedule(hasher.SHA2_256, "xx network test v1",
+		[]byte("master secret"), []byte("salt"))
+	if err != nil {
+		t.Fatalf("NewSchedule() error: %+v", err)
+	}
+
+	seen := make(map[string]string)
+	for _, label := range []string{LabelEncryption, LabelMAC, LabelNonce} {
+		for _, length := range []int{16, 32} {
+			key, err := s.Derive(label, length)
+			if err != nil {
+				t.Fatalf("Derive(%s, %d) error: %+v", label, length, err)
+			}
+			if len(key) != length {
+				t.Errorf("Derive(%s, %d) returned %d bytes", label, length,
+					len(key))
+			}
+			again, _ := s.Derive(label, length)
+			if !bytes.Equal(key, again) {
+				t.Errorf("Derive(%s, %d) is not deterministic", label, length)
+			}
+			// Keys of one length must not be prefixes of another
+			prefix := string(key[:16])
+			if other, exists := seen[prefix]; exists {
+				t.Errorf("Derive(%s, %d) shares a prefix with %s", label,
+					length, other)
+			}
+			seen[prefix] = label
+		}
+	}
+
+	key, _ := s.Derive(LabelEncryption, 32)
+	expected := "75a05adb9bdb7e06d3e86cec81f168785ab06de8390c7ee385e5294422fb35b7"
+	if got := hex.EncodeToString(key); got != expected {
+		t.Errorf("Derive() output changed.\nexpected: %s\nreceived: %s",
+			expected, got)
+	}
+
+	other, _ := NewSchedule(hasher.SHA2_256, "xx network test v2",
+		[]byte("master secret"), []byte("salt"))
+	otherKey, _ := other.Derive(LabelEncryption, 32)
+	if bytes.Equal(key, otherKey) {
+		t.Errorf("different contexts derived the same subkey")
+	}
+}
+
+// Tests that a derived encryption key works with the chacha package.
+func TestSchedule_Chacha(t *testing.T) {
+	s, err := NewSchedule(hasher.BLAKE2, "xx network test v1",
+		[]byte("shared secret"), nil)
+	if err != nil {
+		t.Fatalf("NewSchedule() error: %+v", err)
+	}
+	key, err := s.Derive(LabelEncryption, 32)
+	if err != nil {
+		t.Fatalf("Derive() error: %+v", err)
+	}
+	ciphertext, err := chacha.Encrypt(key, []byte("message"),
+		csprng.NewSystemRNG())
+	if err != nil {
+		t.Fatalf("Encrypt() error: %+v", err)
+	}
+	plaintext, err := chacha.Decrypt(key, ciphertext)
+	if err != nil || string(plaintext) != "message" {
+		t.Errorf("Decrypt() = %q, %v", plaintext, err)
+	}
+}
+
+// Tests the errors of NewSchedule and Derive.
+func TestSchedule_Errors(t *testing.T) {
+	long := strings.Repeat("x", 256)
+	for _, context := range []string{"", long} {
+		if _, err := NewSchedule(hasher.SHA2_256, context, nil, nil); err == nil {
+			t.Errorf("NewSchedule() accepted a %d byte context", len(context))
+		}
+	}
+	if _, err := NewSchedule(200, "context", nil, nil); err == nil {
+		t.Errorf("NewSchedule() accepted an unknown hash")
+	}
+
+	s, _ := NewSchedule(hasher.SHA2_256, strings.Repeat("x", 255), nil, nil)
+	if _, err := s.Derive(long[:255], 32); err != nil {
+		t.Errorf("Derive() rejected a 255 byte label: %+v", err)
+	}
+	for _, label := range []string{"", long} {
+		if _, err := s.Derive(label, 32); err == nil {
+			t.Errorf("Derive() accepted a %d byte label", len(label))
+		}
+	}
+	for _, length := range []int{0, -1, 255*32 + 1, 0x10000} {
+		if _, err := s.Derive(LabelMAC, length); err == nil {
+			t.Errorf("Derive() accepted a length of %d", length)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package large
+
+import (
+	"context"
+	"math/big"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// batchChunk is the number of jobs a worker claims at a time.
+const batchChunk = 16
+
+// ExpJob is a single modular exponentiation Base**Exp mod Modulus.
+type ExpJob struct {
+	Base, Exp, Modulus *Int
+}
+
+// MultiExpJob is a product of powers, the product of Bases[i]**Exps[i].
+type MultiExpJob struct {
+	Bases, Exps []*Int
+}
+
+// ExpEngine runs batches of independent modular exponentiations over a
+// bounded pool of workers. Every call starts its own workers, so an engine is
+// safe for concurrent use.
+type ExpEngine struct {
+	workers int
+}
+
+// NewExpEngine returns an engine that runs each batch on at most workers
+// goroutines. If workers is not positive, GOMAXPROCS workers are used.
+func NewExpEngine(workers int) *ExpEngine {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	return &ExpEngine{workers: workers}
+}
+
+// Workers returns the maximum number of workers per batch.
+func (e *ExpEngine) Workers() int {
+	return e.workers
+}
+
+// Exp computes every job and returns the results in order. Each worker reduces
+// the bases and computes the powers in its own reusable buffers. It stops
+// early and returns the context's error if ctx is cancelled. The exponents
+// must not be negative and the moduli must be positive.
+func (e *ExpEngine) Exp(ctx context.Context, jobs []ExpJob) ([]*Int, error) {
+	for i, job := range jobs {
+		if job.Exp.BigInt().Sign() < 0 {
+			return nil, errors.Errorf("job %d has a negative exponent", i)
+		}
+		if job.Modulus.BigInt().Sign() <= 0 {
+			return nil, errors.Errorf("job %d has a non-positive modulus", i)
+		}
+	}
+
+	results := make([]*Int, len(jobs))
+	err := e.run(ctx, len(jobs), func(i int, s *expScratch) {
+		job := jobs[i]
+		results[i] = NewInt(0)
+		s.exp(results[i].BigInt(), job.Base.BigInt(), job.Exp.BigInt(),
+			job.Modulus.BigInt())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ExpShared computes bases[i]**exps[i] mod m for every i and returns the
+// results in order. It stops early and returns the context's error if ctx is
+// cancelled.
+func (e *ExpEngine) ExpShared(ctx context.Context, bases, exps []*Int,
+	m *Int) ([]*Int, error) {
+	if len(bases) != len(exps) {
+		return nil, errors.Errorf("%d bases but %d exponents", len(bases),
+			len(exps))
+	}
+	jobs := make([]ExpJob, len(bases))
+	for i := range bases {
+		jobs[i] = ExpJob{Base: bases[i], Exp: exps[i], Modulus: m}
+	}
+	return e.Exp(ctx, jobs)
+}
+
+// MultiExp computes every product of powers modulo m with MultiExp and
+// returns the results in order. Each worker reuses its precomputation tables
+// between jobs. It stops early and returns the context's error if ctx is
+// cancelled.
+func (e *ExpEngine) MultiExp(ctx context.Context, jobs []MultiExpJob,
+	m *Int) ([]*Int, error) {
+	for i, job := range jobs {
+		if err := checkMultiExp(job.Bases, job.Exps, m); err != nil {
+			return nil, errors.WithMessagef(err, "job %d", i)
+		}
+	}
+
+	results := make([]*Int, len(jobs))
+	mb := m.BigInt()
+	err := e.run(ctx, len(jobs), func(i int, s *expScratch) {
+		results[i] = NewIntFromBigInt(
+			s.multiExp(jobs[i].Bases, jobs[i].Exps, mb))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// run calls f for every index in [0, n) on the worker pool. Each worker has
+// its own scratch space. It returns the context's error if ctx is cancelled
+// before every index is done.
+func (e *ExpEngine) run(ctx context.Context, n int,
+	f func(i int, s *expScratch)) error {
+	workers := e.workers
+	if chunks := (n + batchChunk - 1) / batchChunk; chunks < workers {
+		workers = chunks
+	}
+
+	var next int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			var s expScratch
+			for ctx.Err() == nil {
+				start := int(atomic.AddInt64(&next, batchChunk)) - batchChunk
+				if start >= n {
+					return
+				}
+				end := start + batchChunk
+				if end > n {
+					end = n
+				}
+				for i := start; i < end; i++ {
+					f(i, &s)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return errors.Wrap(err, "batch exponentiation cancelled")
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Flattened Buffers                                                          //
+////////////////////////////////////////////////////////////////////////////////
+
+// FlattenBits copies the words of every Int into one contiguous buffer with
+// wordsPerInt words per Int, least significant word first and zero padded,
+// for staging to accelerators. It returns an error if an Int is negative or
+// does not fit.
+func FlattenBits(ints []*Int, wordsPerInt int) (Bits, error) {
+	if wordsPerInt <= 0 {
+		return nil, errors.New("words per Int must be positive")
+	}
+	buf := make(Bits, len(ints)*wordsPerInt)
+	for i, x := range ints {
+		if x.BigInt().Sign() < 0 {
+			return nil, errors.Errorf("Int %d is negative", i)
+		}
+		words := x.Bits()
+		if len(words) > wordsPerInt {
+			return nil, errors.Errorf("Int %d has %d words, more than %d",
+				i, len(words), wordsPerInt)
+		}
+		copy(buf[i*wordsPerInt:], words)
+	}
+	return buf, nil
+}
+
+// UnflattenBits splits a buffer created by FlattenBits back into Ints.
+func UnflattenBits(buf Bits, wordsPerInt int) ([]*Int, error) {
+	if wordsPerInt <= 0 || len(buf)%wordsPerInt != 0 {
+		return nil, errors.Errorf("buffer of %d words is not a multiple "+
+			"of %d words", len(buf), wordsPerInt)
+	}
+	ints := make([]*Int, len(buf)/wordsPerInt)
+	for i := range ints {
+		words := make([]big.Word, wordsPerInt)
+		copy(words, buf[i*wordsPerInt:(i+1)*wordsPerInt])
+		ints[i] = NewIntFromBits(words)
+	}
+	return ints, nil
+}
+
+// WordsFor returns the number of words needed to hold an Int of the given
+// bit length.
+func WordsFor(bitLen int) int {
+	return (bitLen + _W - 1) / _W
+}
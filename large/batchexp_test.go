@@ -0,0 +1,251 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package large
+
+import (
+	"context"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// randomInts returns n random Ints below 2^bits.
+func randomInts(prng *rand.Rand, n, bits int) []*Int {
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	ints := make([]*Int, n)
+	for i := range ints {
+		ints[i] = NewIntFromBigInt(new(big.Int).Rand(prng, limit))
+	}
+	return ints
+}
+
+// Tests that ExpEngine.Exp and ExpShared match Int.Exp for batches that do
+// and do not divide evenly into chunks.
+func TestExpEngine_Exp(t *testing.T) {
+	prng := rand.New(rand.NewSource(3))
+	m := NewIntFromBigInt(new(big.Int).SetBit(big.NewInt(12345), 1023, 1))
+	for _, n := range []int{0, 1, 15, 100} {
+		bases := randomInts(prng, n, 1024)
+		exps := randomInts(prng, n, 256)
+		for _, workers := range []int{0, 1, 3} {
+			results, err := NewExpEngine(workers).ExpShared(
+				context.Background(), bases, exps, m)
+			if err != nil {
+				t.Fatalf("ExpShared failed: %+v", err)
+			}
+			if len(results) != n {
+				t.Fatalf("Received %d results, expected %d", len(results), n)
+			}
+			for i := range results {
+				expected := NewInt(0).Exp(bases[i], exps[i], m)
+				if results[i].Cmp(expected) != 0 {
+					t.Errorf("Result %d of %d with %d workers is wrong",
+						i, n, workers)
+				}
+			}
+		}
+	}
+}
+
+// Tests that ExpEngine.Exp matches Int.Exp when one worker reuses its buffers
+// across jobs with different moduli, bases larger than the modulus or
+// negative, zero exponents and a modulus of one.
+func TestExpEngine_Exp_Mixed(t *testing.T) {
+	prng := rand.New(rand.NewSource(9))
+	big1024 := randomInts(prng, 3, 1024)
+	jobs := []ExpJob{
+		{big1024[0], big1024[1], NewInt(1000003)},
+		{NewInt(-5), NewInt(3), NewInt(1000003)},
+		{NewInt(-5), NewInt(4), NewInt(96)},
+		{big1024[0], NewInt(0), big1024[2]},
+		{big1024[1], big1024[0], NewInt(1)},
+		{NewInt(0), NewInt(0), NewInt(7)},
+		{big1024[2], big1024[1], big1024[0]},
+	}
+	results, err := NewExpEngine(1).Exp(context.Background(), jobs)
+	if err != nil {
+		t.Fatalf("Exp failed: %+v", err)
+	}
+	for i, job := range jobs {
+		expected := NewInt(0).Exp(job.Base, job.Exp, job.Modulus)
+		if results[i].Cmp(expected) != 0 {
+			t.Errorf("Job %d: received %s, expected %s", i,
+				results[i].Text(10), expected.Text(10))
+		}
+	}
+}
+
+// Tests that a cancelled context stops the batch with its error.
+func TestExpEngine_Cancelled(t *testing.T) {
+	prng := rand.New(rand.NewSource(4))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	bases := randomInts(prng, 50, 512)
+	_, err := NewExpEngine(2).ExpShared(ctx, bases, bases, NewInt(1000003))
+	if err == nil {
+		t.Errorf("Cancelled batch did not return an error")
+	}
+}
+
+// Error path: invalid jobs are rejected before any work is done.
+func TestExpEngine_Invalid(t *testing.T) {
+	e := NewExpEngine(1)
+	ctx := context.Background()
+	if _, err := e.Exp(ctx, []ExpJob{{NewInt(2), NewInt(-1),
+		NewInt(7)}}); err == nil {
+		t.Errorf("Negative exponent accepted")
+	}
+	if _, err := e.Exp(ctx, []ExpJob{{NewInt(2), NewInt(1),
+		NewInt(0)}}); err == nil {
+		t.Errorf("Zero modulus accepted")
+	}
+	if _, err := e.ExpShared(ctx, []*Int{NewInt(2)}, nil,
+		NewInt(7)); err == nil {
+		t.Errorf("Mismatched lengths accepted")
+	}
+	if _, err := e.MultiExp(ctx, []MultiExpJob{{[]*Int{NewInt(2)},
+		[]*Int{NewInt(-3)}}}, NewInt(7)); err == nil {
+		t.Errorf("Negative multi-exponentiation exponent accepted")
+	}
+}
+
+// Tests that MultiExp matches the product of individual powers for both the
+// Straus and Pippenger paths, and that the engine runs it in batches.
+func TestMultiExp(t *testing.T) {
+	prng := rand.New(rand.NewSource(5))
+	m := NewIntFromBigInt(new(big.Int).SetBit(big.NewInt(977), 767, 1))
+	var jobs []MultiExpJob
+	var expected []*Int
+	for _, n := range []int{1, 2, 7, pippengerThreshold,
+		pippengerThreshold + 1, 200} {
+		bases := randomInts(prng, n, 800)
+		exps := randomInts(prng, n, 300)
+		// Exponents of different lengths, including zero
+		exps[0] = NewInt(int64(n - 1))
+
+		product := NewInt(1)
+		for i := range bases {
+			product.Mul(product, NewInt(0).Exp(bases[i], exps[i], m))
+			product.Mod(product, m)
+		}
+
+		result, err := MultiExp(bases, exps, m)
+		if err != nil {
+			t.Fatalf("MultiExp failed: %+v", err)
+		}
+		if result.Cmp(product) != 0 {
+			t.Errorf("MultiExp of %d powers is wrong", n)
+		}
+		jobs = append(jobs, MultiExpJob{Bases: bases, Exps: exps})
+		expected = append(expected, product)
+	}
+
+	results, err := NewExpEngine(2).MultiExp(context.Background(), jobs, m)
+	if err != nil {
+		t.Fatalf("ExpEngine.MultiExp failed: %+v", err)
+	}
+	for i := range results {
+		if results[i].Cmp(expected[i]) != 0 {
+			t.Errorf("ExpEngine.MultiExp result %d is wrong", i)
+		}
+	}
+}
+
+// Tests the edge cases of MultiExp: no powers, zero exponents and modulus 1.
+func TestMultiExp_Edges(t *testing.T) {
+	if r, err := MultiExp(nil, nil, NewInt(7)); err != nil ||
+		r.Int64() != 1 {
+		t.Errorf("Empty product is %v, %v", r, err)
+	}
+	if r, _ := MultiExp([]*Int{NewInt(3)}, []*Int{NewInt(0)},
+		NewInt(7)); r.Int64() != 1 {
+		t.Errorf("3^0 mod 7 is %v", r)
+	}
+	if r, _ := MultiExp([]*Int{NewInt(3)}, []*Int{NewInt(5)},
+		NewInt(1)); r.Int64() != 0 {
+		t.Errorf("3^5 mod 1 is %v", r)
+	}
+	if _, err := MultiExp([]*Int{NewInt(3)}, nil, NewInt(7)); err == nil {
+		t.Errorf("Mismatched lengths accepted")
+	}
+}
+
+// Tests that Ints survive flattening and that oversized and negative Ints
+// are rejected.
+func TestFlattenBits(t *testing.T) {
+	prng := rand.New(rand.NewSource(6))
+	ints := randomInts(prng, 10, 1000)
+	ints = append(ints, NewInt(0))
+	words := WordsFor(1000)
+
+	buf, err := FlattenBits(ints, words)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf) != len(ints)*words {
+		t.Errorf("Buffer has %d words, expected %d", len(buf),
+			len(ints)*words)
+	}
+	decoded, err := UnflattenBits(buf, words)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range ints {
+		if decoded[i].Cmp(ints[i]) != 0 {
+			t.Errorf("Int %d differs after flattening", i)
+		}
+	}
+
+	if _, err = FlattenBits(ints, 1); err == nil {
+		t.Errorf("Oversized Int accepted")
+	}
+	if _, err = FlattenBits([]*Int{NewInt(-1)}, 1); err == nil {
+		t.Errorf("Negative Int accepted")
+	}
+	if _, err = UnflattenBits(buf[1:], words); err == nil {
+		t.Errorf("Partial buffer accepted")
+	}
+}
+
+// Benchmarks a batch of 2048-bit exponentiations with a shared modulus.
+func BenchmarkExpEngine_ExpShared(b *testing.B) {
+	prng := rand.New(rand.NewSource(7))
+	m := NewIntFromBigInt(new(big.Int).SetBit(big.NewInt(1), 2047, 1))
+	bases := randomInts(prng, 256, 2048)
+	exps := randomInts(prng, 256, 256)
+	e := NewExpEngine(0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := e.ExpShared(context.Background(), bases, exps,
+			m); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Benchmarks a product of 64 powers against computing them separately.
+func BenchmarkMultiExp64(b *testing.B) {
+	prng := rand.New(rand.NewSource(8))
+	m := NewIntFromBigInt(new(big.Int).SetBit(big.NewInt(1), 2047, 1))
+	bases := randomInts(prng, 64, 2048)
+	exps := randomInts(prng, 64, 256)
+	b.Run("MultiExp", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = MultiExp(bases, exps, m)
+		}
+	})
+	b.Run("Separate", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			product := NewInt(1)
+			for j := range bases {
+				product.Mul(product, NewInt(0).Exp(bases[j], exps[j], m))
+				product.Mod(product, m)
+			}
+		}
+	})
+}
@@ -0,0 +1,221 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package large
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// Binary encoding layout:
+//
+//	version (1 byte) | flags (1 byte) | length (uvarint) | magnitude
+//
+// The magnitude is the big-endian absolute value. In the variable width form
+// it has no leading zero bytes, so zero has an empty magnitude. In the fixed
+// width form, flagged with encodingFixedWidth, it is left padded with zeros to
+// exactly length bytes. Negative values set encodingNegative; zero is never
+// negative. Every value has exactly one encoding in each form, and the
+// decoders reject anything else.
+const (
+	// EncodingVersion is the version of the binary encoding written by
+	// MarshalBinary and MarshalBinaryFixed.
+	EncodingVersion = 1
+
+	encodingNegative   = 1 << 0
+	encodingFixedWidth = 1 << 1
+	encodingFlags      = encodingNegative | encodingFixedWidth
+)
+
+// MarshalBinary encodes z, including its sign, in the canonical variable
+// width binary encoding. Unlike GobEncode, the encoding keeps the sign and is
+// framed with a version and a length. This function implements the
+// encoding.BinaryMarshaler interface.
+func (z *Int) MarshalBinary() ([]byte, error) {
+	return z.marshalBinary((*big.Int)(z).Bytes(), 0), nil
+}
+
+// MarshalBinaryFixed encodes z, including its sign, in the canonical binary
+// encoding with the magnitude left padded to exactly width bytes, such as the
+// byte length of a group's modulus. It returns an error if the magnitude does
+// not fit.
+func (z *Int) MarshalBinaryFixed(width int) ([]byte, error) {
+	if width <= 0 {
+		return nil, errors.New("width must be positive")
+	}
+	b := (*big.Int)(z)
+	if n := (b.BitLen() + 7) / 8; n > width {
+		return nil, errors.Errorf("value of %d bytes does not fit in %d "+
+			"bytes", n, width)
+	}
+	return z.marshalBinary(b.FillBytes(make([]byte, width)),
+		encodingFixedWidth), nil
+}
+
+// marshalBinary frames the magnitude with the header.
+func (z *Int) marshalBinary(magnitude []byte, flags byte) []byte {
+	if (*big.Int)(z).Sign() < 0 {
+		flags |= encodingNegative
+	}
+	buf := make([]byte, 2, 2+binary.MaxVarintLen64+len(magnitude))
+	buf[0], buf[1] = EncodingVersion, flags
+	buf = binary.AppendUvarint(buf, uint64(len(magnitude)))
+	return append(buf, magnitude...)
+}
+
+// UnmarshalBinary decodes an encoding made by MarshalBinary or
+// MarshalBinaryFixed into z. It returns an error, leaving z unchanged, for
+// any input that is not the canonical encoding of a value, including trailing
+// data. This function implements the encoding.BinaryUnmarshaler interface.
+func (z *Int) UnmarshalBinary(data []byte) error {
+	x, _, err := unmarshalBinary(data)
+	if err != nil {
+		return err
+	}
+	(*big.Int)(z).Set(x)
+	return nil
+}
+
+// UnmarshalBinaryFixed decodes an encoding made by MarshalBinaryFixed with
+// the given width into z. It returns an error, leaving z unchanged, for a
+// variable width encoding, a different width or any non-canonical input.
+func (z *Int) UnmarshalBinaryFixed(data []byte, width int) error {
+	x, w, err := unmarshalBinary(data)
+	if err != nil {
+		return err
+	}
+	if w != width {
+		if w < 0 {
+			return errors.Errorf("expected a fixed width encoding of %d "+
+				"bytes, found a variable width one", width)
+		}
+		return errors.Errorf("expected a fixed width encoding of %d bytes, "+
+			"found %d", width, w)
+	}
+	(*big.Int)(z).Set(x)
+	return nil
+}
+
+// unmarshalBinary strictly decodes either form of the binary encoding. It
+// returns the width of a fixed width encoding, or -1 for a variable width
+// one.
+func unmarshalBinary(data []byte) (*big.Int, int, error) {
+	if len(data) < 3 {
+		return nil, 0, errors.New("encoding too short")
+	}
+	if data[0] != EncodingVersion {
+		return nil, 0, errors.Errorf("unsupported encoding version %d",
+			data[0])
+	}
+	flags := data[1]
+	if flags&^encodingFlags != 0 {
+		return nil, 0, errors.Errorf("unknown flags %#02x", flags)
+	}
+
+	length, n := binary.Uvarint(data[2:])
+	if n <= 0 {
+		return nil, 0, errors.New("invalid length")
+	}
+	// A varint padded with continuation bytes is not canonical
+	if n != len(binary.AppendUvarint(nil, length)) {
+		return nil, 0, errors.New("length is not minimally encoded")
+	}
+	magnitude := data[2+n:]
+	if length != uint64(len(magnitude)) {
+		return nil, 0, errors.Errorf("length %d does not match the %d "+
+			"bytes of data", length, len(magnitude))
+	}
+
+	width := -1
+	if flags&encodingFixedWidth != 0 {
+		if len(magnitude) == 0 {
+			return nil, 0, errors.New("fixed width encoding has no width")
+		}
+		width = len(magnitude)
+	} else if len(magnitude) > 0 && magnitude[0] == 0 {
+		return nil, 0, errors.New("magnitude has leading zeros")
+	}
+
+	x := new(big.Int).SetBytes(magnitude)
+	if flags&encodingNegative != 0 {
+		if x.Sign() == 0 {
+			return nil, 0, errors.New("zero is encoded as negative")
+		}
+		x.Neg(x)
+	}
+	return x, width, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Text Encodings                                                             //
+////////////////////////////////////////////////////////////////////////////////
+
+// MarshalText encodes z as lower-case hexadecimal with no leading zeros and
+// no prefix, preceded by "-" if negative. Zero is "0". This function
+// implements the encoding.TextMarshaler interface.
+func (z *Int) MarshalText() ([]byte, error) {
+	return []byte((*big.Int)(z).Text(16)), nil
+}
+
+// UnmarshalText decodes hexadecimal made by MarshalText into z. It returns an
+// error, leaving z unchanged, for anything else, including upper-case digits,
+// leading zeros, a "+" sign, a "0x" prefix, whitespace and "-0". This function
+// implements the encoding.TextUnmarshaler interface.
+func (z *Int) UnmarshalText(text []byte) error {
+	digits := text
+	if len(digits) > 0 && digits[0] == '-' {
+		digits = digits[1:]
+	}
+	if len(digits) == 0 {
+		return errors.New("no hexadecimal digits")
+	}
+	for i, c := range digits {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return errors.Errorf("invalid hexadecimal digit %q at %d", c,
+				len(text)-len(digits)+i)
+		}
+	}
+	if digits[0] == '0' && (len(digits) > 1 || len(digits) != len(text)) {
+		return errors.New("hexadecimal has leading zeros or a " +
+			"negative zero")
+	}
+
+	x, _ := new(big.Int).SetString(string(text), 16)
+	(*big.Int)(z).Set(x)
+	return nil
+}
+
+// Base64 returns the canonical variable width binary encoding of z in
+// standard padded base64.
+func (z *Int) Base64() string {
+	b, _ := z.MarshalBinary()
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// SetBase64 sets z to the value of a base64 string made by Base64, or by
+// base64 encoding the output of MarshalBinaryFixed, and returns z. It returns
+// an error, leaving z unchanged, if the base64 or the binary encoding in it
+// is not canonical.
+func (z *Int) SetBase64(s string) (*Int, error) {
+	// Strict mode rejects non-zero padding bits, so every value has one
+	// base64 encoding
+	data, err := base64.StdEncoding.Strict().DecodeString(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid base64")
+	}
+	if base64.StdEncoding.EncodeToString(data) != s {
+		return nil, errors.New("base64 is not canonical")
+	}
+	if err = z.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return z, nil
+}
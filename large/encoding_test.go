@@ -0,0 +1,235 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package large
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*Int)(nil)
+	_ encoding.BinaryUnmarshaler = (*Int)(nil)
+	_ encoding.TextMarshaler     = (*Int)(nil)
+	_ encoding.TextUnmarshaler   = (*Int)(nil)
+)
+
+// encodingTestValues are the values round tripped through every encoding.
+var encodingTestValues = []*Int{
+	NewInt(0),
+	NewInt(1),
+	NewInt(-1),
+	NewInt(255),
+	NewInt(-256),
+	NewIntFromString("123456789abcdef0123456789abcdef0123456789abcdef", 16),
+	NewIntFromString("-fedcba9876543210fedcba9876543210", 16),
+	NewIntFromBytes(bytes.Repeat([]byte{0xff}, 300)),
+}
+
+// Tests the exact bytes of the binary encoding.
+func TestInt_MarshalBinary_Vectors(t *testing.T) {
+	tests := []struct {
+		x        *Int
+		expected []byte
+	}{
+		{NewInt(0), []byte{1, 0, 0}},
+		{NewInt(1), []byte{1, 0, 1, 1}},
+		{NewInt(-258), []byte{1, 1, 2, 1, 2}},
+		{NewIntFromBytes(bytes.Repeat([]byte{7}, 200)),
+			append([]byte{1, 0, 0xc8, 0x01}, bytes.Repeat([]byte{7}, 200)...)},
+	}
+	for _, tt := range tests {
+		got, err := tt.x.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary() error: %+v", err)
+		}
+		if !bytes.Equal(got, tt.expected) {
+			t.Errorf("MarshalBinary(%s) = %x, expected %x", tt.x.Text(10),
+				got, tt.expected)
+		}
+	}
+
+	got, err := NewInt(-258).MarshalBinaryFixed(4)
+	if err != nil {
+		t.Fatalf("MarshalBinaryFixed() error: %+v", err)
+	}
+	if expected := []byte{1, 3, 4, 0, 0, 1, 2}; !bytes.Equal(got, expected) {
+		t.Errorf("MarshalBinaryFixed() = %x, expected %x", got, expected)
+	}
+}
+
+// Tests that every value survives both binary forms with its sign, and that
+// the fixed width form has the requested width.
+func TestInt_MarshalBinary_RoundTrip(t *testing.T) {
+	for _, x := range encodingTestValues {
+		data, err := x.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary() error: %+v", err)
+		}
+		y := NewInt(99)
+		if err = y.UnmarshalBinary(data); err != nil {
+			t.Fatalf("UnmarshalBinary() error: %+v", err)
+		}
+		if x.Cmp(y) != 0 {
+			t.Errorf("binary round trip of %s gave %s", x.Text(16), y.Text(16))
+		}
+
+		width := len(x.Bytes()) + 3
+		data, err = x.MarshalBinaryFixed(width)
+		if err != nil {
+			t.Fatalf("MarshalBinaryFixed() error: %+v", err)
+		}
+		z := NewInt(99)
+		if err = z.UnmarshalBinaryFixed(data, width); err != nil {
+			t.Fatalf("UnmarshalBinaryFixed() error: %+v", err)
+		}
+		if x.Cmp(z) != 0 {
+			t.Errorf("fixed round trip of %s gave %s", x.Text(16), z.Text(16))
+		}
+		if err = z.UnmarshalBinaryFixed(data, width+1); err == nil {
+			t.Error("UnmarshalBinaryFixed() accepted the wrong width")
+		}
+	}
+}
+
+// Tests that MarshalBinaryFixed rejects values that do not fit and widths
+// that are not positive.
+func TestInt_MarshalBinaryFixed_Errors(t *testing.T) {
+	if _, err := NewInt(256).MarshalBinaryFixed(1); err == nil {
+		t.Error("MarshalBinaryFixed() accepted a value that does not fit")
+	}
+	if _, err := NewInt(-255).MarshalBinaryFixed(1); err != nil {
+		t.Errorf("MarshalBinaryFixed() rejected a value that fits: %+v", err)
+	}
+	if _, err := NewInt(0).MarshalBinaryFixed(0); err == nil {
+		t.Error("MarshalBinaryFixed() accepted a width of zero")
+	}
+}
+
+// Tests that the decoders reject every non-canonical or malformed encoding
+// and leave the Int unchanged.
+func TestInt_UnmarshalBinary_Strict(t *testing.T) {
+	tests := map[string][]byte{
+		"empty":             {},
+		"too short":         {1, 0},
+		"wrong version":     {2, 0, 1, 1},
+		"unknown flag":      {1, 4, 1, 1},
+		"leading zero":      {1, 0, 2, 0, 1},
+		"negative zero":     {1, 1, 0},
+		"negative zero pad": {1, 3, 1, 0},
+		"fixed no width":    {1, 2, 0},
+		"padded varint":     {1, 0, 0x81, 0x00, 1},
+		"truncated varint":  {1, 0, 0x80},
+		"short magnitude":   {1, 0, 2, 1},
+		"trailing data":     {1, 0, 1, 1, 0},
+	}
+	for name, data := range tests {
+		z := NewInt(42)
+		if err := z.UnmarshalBinary(data); err == nil {
+			t.Errorf("UnmarshalBinary() accepted %s encoding %x", name, data)
+		}
+		if z.Int64() != 42 {
+			t.Errorf("UnmarshalBinary() changed the Int for %s", name)
+		}
+	}
+
+	// A variable width encoding is not a fixed width one
+	data, _ := NewInt(5).MarshalBinary()
+	if err := NewInt(0).UnmarshalBinaryFixed(data, 1); err == nil {
+		t.Error("UnmarshalBinaryFixed() accepted a variable width encoding")
+	}
+}
+
+// Tests the hexadecimal text encoding and that xml uses it while JSON keeps
+// the decimal form.
+func TestInt_MarshalText(t *testing.T) {
+	tests := map[string]*Int{
+		"0":    NewInt(0),
+		"ff":   NewInt(255),
+		"-100": NewInt(-256),
+	}
+	for expected, x := range tests {
+		got, err := x.MarshalText()
+		if err != nil {
+			t.Fatalf("MarshalText() error: %+v", err)
+		}
+		if string(got) != expected {
+			t.Errorf("MarshalText(%s) = %q, expected %q", x.Text(10), got,
+				expected)
+		}
+	}
+
+	for _, x := range encodingTestValues {
+		text, _ := x.MarshalText()
+		y := NewInt(0)
+		if err := y.UnmarshalText(text); err != nil {
+			t.Fatalf("UnmarshalText(%q) error: %+v", text, err)
+		}
+		if x.Cmp(y) != 0 {
+			t.Errorf("text round trip of %s gave %s", text, y.Text(16))
+		}
+	}
+
+	type element struct{ X *Int }
+	out, err := xml.Marshal(element{NewInt(-255)})
+	if err != nil {
+		t.Fatalf("xml.Marshal() error: %+v", err)
+	}
+	if expected := "<element><X>-ff</X></element>"; string(out) != expected {
+		t.Errorf("xml.Marshal() = %s, expected %s", out, expected)
+	}
+	out, err = json.Marshal(NewInt(255))
+	if err != nil {
+		t.Fatalf("json.Marshal() error: %+v", err)
+	}
+	if string(out) != "255" {
+		t.Errorf("json.Marshal() = %s, expected the decimal form", out)
+	}
+}
+
+// Tests that UnmarshalText rejects every non-canonical form.
+func TestInt_UnmarshalText_Strict(t *testing.T) {
+	for _, text := range []string{"", "-", "00", "01", "-0", "-00", "+1",
+		"0x1", "FF", "fF", " 1", "1 ", "g", "1-"} {
+		z := NewInt(42)
+		if err := z.UnmarshalText([]byte(text)); err == nil {
+			t.Errorf("UnmarshalText() accepted %q", text)
+		}
+		if z.Int64() != 42 {
+			t.Errorf("UnmarshalText() changed the Int for %q", text)
+		}
+	}
+}
+
+// Tests the base64 encoding round trip and that non-canonical base64 is
+// rejected.
+func TestInt_Base64(t *testing.T) {
+	for _, x := range encodingTestValues {
+		s := x.Base64()
+		y, err := NewInt(0).SetBase64(s)
+		if err != nil {
+			t.Fatalf("SetBase64(%q) error: %+v", s, err)
+		}
+		if x.Cmp(y) != 0 {
+			t.Errorf("base64 round trip of %s gave %s", x.Text(16), y.Text(16))
+		}
+	}
+
+	if s := NewInt(1).Base64(); s != "AQABAQ==" {
+		t.Errorf("Base64() = %q, expected %q", s, "AQABAQ==")
+	}
+	for _, s := range []string{"AQABAQ", "AQABAR==", "AQAB\nAQ==", "AQAB",
+		"!!!!"} {
+		if _, err := NewInt(0).SetBase64(s); err == nil {
+			t.Errorf("SetBase64() accepted %q", s)
+		}
+	}
+}
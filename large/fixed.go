@@ -0,0 +1,165 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package large
+
+import (
+	"math/big"
+	"math/bits"
+
+	"github.com/pkg/errors"
+)
+
+// This file holds the limb arithmetic shared by the fixed-size integer types
+// Uint2048 and Uint4096. Every routine works on slices of 64-bit limbs, least
+// significant first. Their modular arithmetic is that of Modulus, run on words
+// and scratch space that the fixed-size types keep on the stack.
+
+// newFixedModulus returns the Montgomery context of the odd modulus m > 1
+// with R = 2^(64·len(m)), so that any value of the fixed-size type is below R
+// and can be passed to Modulus.mulUnreduced and Modulus.expUnreduced.
+func newFixedModulus(m []uint64) (*Modulus, error) {
+	if m[0]&1 == 0 || bitLenLimbs(m) <= 1 {
+		return nil, errors.New("modulus must be odd and greater than one")
+	}
+	words := make([]big.Word, len(m)*64/_W)
+	limbsToWords(words, m)
+	return newModulus(words), nil
+}
+
+// addLimbs sets z = x + y and returns the carry.
+func addLimbs(z, x, y []uint64) uint64 {
+	var c uint64
+	for i := range z {
+		z[i], c = bits.Add64(x[i], y[i], c)
+	}
+	return c
+}
+
+// subLimbs sets z = x - y and returns the borrow.
+func subLimbs(z, x, y []uint64) uint64 {
+	var b uint64
+	for i := range z {
+		z[i], b = bits.Sub64(x[i], y[i], b)
+	}
+	return b
+}
+
+// mulLimbs sets z to the low len(z) limbs of x·y. z must not alias x or y.
+func mulLimbs(z, x, y []uint64) {
+	for i := range z {
+		z[i] = 0
+	}
+	for i, xi := range x {
+		if i >= len(z) {
+			break
+		}
+		var c, cc uint64
+		for j := 0; j < len(y) && i+j < len(z); j++ {
+			hi, lo := bits.Mul64(xi, y[j])
+			lo, cc = bits.Add64(lo, z[i+j], 0)
+			hi += cc
+			lo, cc = bits.Add64(lo, c, 0)
+			hi += cc
+			z[i+j], c = lo, hi
+		}
+		if i+len(y) < len(z) {
+			z[i+len(y)] = c
+		}
+	}
+}
+
+// cmpLimbs compares x and y, returning -1, 0 or +1.
+func cmpLimbs(x, y []uint64) int {
+	for i := len(x) - 1; i >= 0; i-- {
+		switch {
+		case x[i] < y[i]:
+			return -1
+		case x[i] > y[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// bitLenLimbs returns the number of bits needed to represent x.
+func bitLenLimbs(x []uint64) int {
+	for i := len(x) - 1; i >= 0; i-- {
+		if x[i] != 0 {
+			return 64*i + bits.Len64(x[i])
+		}
+	}
+	return 0
+}
+
+// limbsToWords sets z, which must have 64·len(x)/_W words, to x.
+func limbsToWords(z []big.Word, x []uint64) {
+	for i := range z {
+		z[i] = big.Word(x[i*_W/64] >> (i * _W % 64))
+	}
+}
+
+// wordsToLimbs sets z to x, which must fit.
+func wordsToLimbs(z []uint64, x []big.Word) {
+	for i := range z {
+		z[i] = 0
+	}
+	for i, w := range x {
+		z[i*_W/64] |= uint64(w) << (i * _W % 64)
+	}
+}
+
+// limbsToBig returns x as a big.Int.
+func limbsToBig(x []uint64) *big.Int {
+	words := make([]big.Word, len(x)*64/_W)
+	limbsToWords(words, x)
+	return new(big.Int).SetBits(words)
+}
+
+// setIntLimbs sets z to x, returning an error if x is negative or does not
+// fit.
+func setIntLimbs(z []uint64, x *Int) error {
+	b := x.BigInt()
+	if b.Sign() < 0 {
+		return errors.New("value is negative")
+	}
+	if b.BitLen() > 64*len(z) {
+		return errors.Errorf("value of %d bits does not fit in %d bits",
+			b.BitLen(), 64*len(z))
+	}
+	wordsToLimbs(z, b.Bits())
+	return nil
+}
+
+// setBytesLimbs sets z to the big-endian buf, returning an error if it does
+// not fit.
+func setBytesLimbs(z []uint64, buf []byte) error {
+	for len(buf) > 0 && buf[0] == 0 {
+		buf = buf[1:]
+	}
+	if len(buf) > 8*len(z) {
+		return errors.Errorf("%d bytes do not fit in %d bytes", len(buf),
+			8*len(z))
+	}
+	for i := range z {
+		z[i] = 0
+	}
+	for i, b := range buf {
+		k := len(buf) - 1 - i
+		z[k/8] |= uint64(b) << (8 * (k % 8))
+	}
+	return nil
+}
+
+// fillBytesLimbs writes x big-endian into buf, which must have 8·len(x)
+// bytes.
+func fillBytesLimbs(buf []byte, x []uint64) {
+	for i := range buf {
+		k := len(buf) - 1 - i
+		buf[i] = byte(x[k/8] >> (8 * (k % 8)))
+	}
+}
@@ -0,0 +1,293 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package large
+
+import (
+	"bytes"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// randomBig returns a random non-negative big.Int of at most bits bits.
+func randomBig(prng *rand.Rand, bits int) *big.Int {
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	return new(big.Int).Rand(prng, limit)
+}
+
+// randomOddBig returns a random odd big.Int of exactly bits bits.
+func randomOddBig(prng *rand.Rand, bits int) *big.Int {
+	x := randomBig(prng, bits)
+	x.SetBit(x, bits-1, 1)
+	return x.SetBit(x, 0, 1)
+}
+
+// toUint2048 converts a big.Int that fits into a Uint2048.
+func toUint2048(t testing.TB, x *big.Int) *Uint2048 {
+	var z Uint2048
+	if err := z.SetInt(NewIntFromBigInt(x)); err != nil {
+		t.Fatalf("SetInt() error: %+v", err)
+	}
+	return &z
+}
+
+// toUint4096 converts a big.Int that fits into a Uint4096.
+func toUint4096(t testing.TB, x *big.Int) *Uint4096 {
+	var z Uint4096
+	if err := z.SetInt(NewIntFromBigInt(x)); err != nil {
+		t.Fatalf("SetInt() error: %+v", err)
+	}
+	return &z
+}
+
+// Tests that conversions to and from Int and bytes round trip and reject
+// values that do not fit.
+func TestUint2048_Conversions(t *testing.T) {
+	prng := rand.New(rand.NewSource(42))
+	for _, bits := range []int{0, 1, 64, 65, 2047, 2048} {
+		x := randomBig(prng, bits)
+		z := toUint2048(t, x)
+		if z.Int().BigInt().Cmp(x) != 0 {
+			t.Errorf("Int round trip of %d bits failed", bits)
+		}
+		if z.BitLen() != x.BitLen() || z.IsZero() != (x.Sign() == 0) {
+			t.Errorf("BitLen() = %d, expected %d", z.BitLen(), x.BitLen())
+		}
+
+		b := z.Bytes()
+		if !bytes.Equal(b, x.FillBytes(make([]byte, 256))) {
+			t.Errorf("Bytes() of %d bits is wrong", bits)
+		}
+		var y Uint2048
+		if err := y.SetBytes(x.Bytes()); err != nil || y != *z {
+			t.Errorf("SetBytes() of %d bits failed: %v", bits, err)
+		}
+		if err := y.SetBytes(b); err != nil || y != *z {
+			t.Errorf("SetBytes() of padded %d bits failed: %v", bits, err)
+		}
+	}
+
+	var z Uint2048
+	z.SetUint64(7)
+	if err := z.SetInt(NewInt(-1)); err == nil {
+		t.Error("SetInt() accepted a negative value")
+	}
+	if err := z.SetInt(NewIntFromBigInt(randomOddBig(prng, 2049))); err == nil {
+		t.Error("SetInt() accepted a 2049-bit value")
+	}
+	if err := z.SetBytes(make([]byte, 257)); err != nil {
+		t.Errorf("SetBytes() rejected zero padded to 257 bytes: %+v", err)
+	}
+	if err := z.SetBytes(append([]byte{1}, make([]byte, 256)...)); err == nil {
+		t.Error("SetBytes() accepted a 257-byte value")
+	}
+}
+
+// Tests wrapping addition, subtraction and multiplication against math/big.
+func TestUint2048_AddSubMul(t *testing.T) {
+	prng := rand.New(rand.NewSource(42))
+	mod := new(big.Int).Lsh(big.NewInt(1), 2048)
+	for i := 0; i < 20; i++ {
+		xb, yb := randomBig(prng, 2048), randomBig(prng, 2048)
+		x, y := toUint2048(t, xb), toUint2048(t, yb)
+
+		var z Uint2048
+		carry := z.Add(x, y)
+		sum := new(big.Int).Add(xb, yb)
+		if uint64(sum.Bit(2048)) != carry ||
+			z.Int().BigInt().Cmp(sum.Mod(sum, mod)) != 0 {
+			t.Errorf("Add() is wrong")
+		}
+
+		borrow := z.Sub(x, y)
+		diff := new(big.Int).Sub(xb, yb)
+		if (diff.Sign() < 0) != (borrow == 1) ||
+			z.Int().BigInt().Cmp(diff.Mod(diff, mod)) != 0 {
+			t.Errorf("Sub() is wrong")
+		}
+
+		prod := new(big.Int).Mul(xb, yb)
+		if z.Mul(x, y).Int().BigInt().Cmp(new(big.Int).Mod(prod, mod)) != 0 {
+			t.Errorf("Mul() is wrong")
+		}
+		var wide Uint4096
+		if wide.MulWide(x, y).Int().BigInt().Cmp(prod) != 0 {
+			t.Errorf("MulWide() is wrong")
+		}
+
+		// The receiver may be an operand
+		z = *x
+		z.Mul(&z, &z)
+		if z.Int().BigInt().Cmp(new(big.Int).Mod(prod.Mul(xb, xb), mod)) != 0 {
+			t.Errorf("Mul() with an aliased receiver is wrong")
+		}
+	}
+}
+
+// Tests modular multiplication and exponentiation against math/big for
+// moduli of several sizes and unreduced operands.
+func TestUint2048_ModMulModExp(t *testing.T) {
+	prng := rand.New(rand.NewSource(42))
+	for _, bits := range []int{3, 64, 1000, 2047, 2048} {
+		mb := randomOddBig(prng, bits)
+		m, err := NewModulus2048(toUint2048(t, mb))
+		if err != nil {
+			t.Fatalf("NewModulus2048() error: %+v", err)
+		}
+		if mm := m.Modulus(); mm.Int().BigInt().Cmp(mb) != 0 {
+			t.Errorf("Modulus() is wrong")
+		}
+
+		for i := 0; i < 5; i++ {
+			xb, yb := randomBig(prng, 2048), randomBig(prng, 2048)
+			eb := randomBig(prng, 1+prng.Intn(2048))
+			x, y, e := toUint2048(t, xb), toUint2048(t, yb), toUint2048(t, eb)
+
+			var z Uint2048
+			expected := new(big.Int).Mul(xb, yb)
+			expected.Mod(expected, mb)
+			if z.ModMul(x, y, m).Int().BigInt().Cmp(expected) != 0 {
+				t.Errorf("ModMul() mod %d bits is wrong", bits)
+			}
+
+			expected.Exp(xb, eb, mb)
+			if z.ModExp(x, e, m).Int().BigInt().Cmp(expected) != 0 {
+				t.Errorf("ModExp() mod %d bits is wrong", bits)
+			}
+		}
+
+		// x**0 = 1 and 0**e = 0
+		var z, zero, one Uint2048
+		one.SetUint64(1)
+		x := toUint2048(t, randomBig(prng, 2048))
+		if z.ModExp(x, &zero, m); z != one {
+			t.Errorf("x**0 mod %d bits is %s", bits, z.Int().Text(10))
+		}
+		if z.ModExp(&zero, x, m); !z.IsZero() && !x.IsZero() {
+			t.Errorf("0**e mod %d bits is %s", bits, z.Int().Text(10))
+		}
+	}
+}
+
+// Tests that NewModulus2048 rejects even moduli and moduli below two.
+func TestNewModulus2048_Errors(t *testing.T) {
+	for _, v := range []uint64{0, 1, 2, 1 << 40} {
+		var m Uint2048
+		if _, err := NewModulus2048(m.SetUint64(v)); err == nil {
+			t.Errorf("NewModulus2048() accepted %d", v)
+		}
+	}
+}
+
+// Tests Uint4096 arithmetic against math/big.
+func TestUint4096_ModMulModExp(t *testing.T) {
+	prng := rand.New(rand.NewSource(42))
+	mb := randomOddBig(prng, 4096)
+	m, err := NewModulus4096(toUint4096(t, mb))
+	if err != nil {
+		t.Fatalf("NewModulus4096() error: %+v", err)
+	}
+	for i := 0; i < 3; i++ {
+		xb, yb := randomBig(prng, 4096), randomBig(prng, 4096)
+		eb := randomBig(prng, 256)
+		x, y, e := toUint4096(t, xb), toUint4096(t, yb), toUint4096(t, eb)
+
+		var z Uint4096
+		expected := new(big.Int).Mul(xb, yb)
+		if z.Mul(x, y).Int().BigInt().Cmp(
+			new(big.Int).Mod(expected, MaxUint4096.Int().BigInt().Add(
+				MaxUint4096.Int().BigInt(), big.NewInt(1)))) != 0 {
+			t.Errorf("Mul() is wrong")
+		}
+		if z.ModMul(x, y, m).Int().BigInt().Cmp(expected.Mod(expected,
+			mb)) != 0 {
+			t.Errorf("ModMul() is wrong")
+		}
+		if z.ModExp(x, e, m).Int().BigInt().Cmp(expected.Exp(xb, eb,
+			mb)) != 0 {
+			t.Errorf("ModExp() is wrong")
+		}
+	}
+}
+
+// Tests that MaxUint4096 is 2^4096 - 1.
+func TestMaxUint4096(t *testing.T) {
+	expected := new(big.Int).Lsh(big.NewInt(1), 4096)
+	expected.Sub(expected, big.NewInt(1))
+	if MaxUint4096.Int().BigInt().Cmp(expected) != 0 {
+		t.Errorf("MaxUint4096 is not 2^4096 - 1")
+	}
+}
+
+// Tests that the fixed-size operations do not allocate.
+func TestUint4096_NoAllocations(t *testing.T) {
+	prng := rand.New(rand.NewSource(42))
+	m, err := NewModulus4096(toUint4096(t, randomOddBig(prng, 4096)))
+	if err != nil {
+		t.Fatalf("NewModulus4096() error: %+v", err)
+	}
+	x := toUint4096(t, randomBig(prng, 4096))
+	e := toUint4096(t, randomBig(prng, 64))
+	var z Uint4096
+	allocs := testing.AllocsPerRun(10, func() {
+		z.Add(x, x)
+		z.Mul(x, x)
+		z.ModMul(x, x, m)
+		z.ModExp(x, e, m)
+	})
+	if allocs != 0 {
+		t.Errorf("fixed-size arithmetic made %.0f allocations", allocs)
+	}
+}
+
+func BenchmarkUint4096_ModExp256(b *testing.B) {
+	prng := rand.New(rand.NewSource(42))
+	m, _ := NewModulus4096(toUint4096(b, randomOddBig(prng, 4096)))
+	x := toUint4096(b, randomBig(prng, 4096))
+	e := toUint4096(b, randomBig(prng, 256))
+	var z Uint4096
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		z.ModExp(x, e, m)
+	}
+}
+
+func BenchmarkInt_Exp4096_256(b *testing.B) {
+	prng := rand.New(rand.NewSource(42))
+	m := NewIntFromBigInt(randomOddBig(prng, 4096))
+	x := NewIntFromBigInt(randomBig(prng, 4095))
+	e := NewIntFromBigInt(randomBig(prng, 256))
+	z := NewInt(0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		z.Exp(x, e, m)
+	}
+}
+
+func BenchmarkUint4096_ModMul(b *testing.B) {
+	prng := rand.New(rand.NewSource(42))
+	m, _ := NewModulus4096(toUint4096(b, randomOddBig(prng, 4096)))
+	x := toUint4096(b, randomBig(prng, 4096))
+	var z Uint4096
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		z.ModMul(x, x, m)
+	}
+}
+
+func BenchmarkInt_ModMul4096(b *testing.B) {
+	prng := rand.New(rand.NewSource(42))
+	m := NewIntFromBigInt(randomOddBig(prng, 4096))
+	x := NewIntFromBigInt(randomBig(prng, 4095))
+	z := NewInt(0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		z.Mul(x, x)
+		z.Mod(z, m)
+	}
+}
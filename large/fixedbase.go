@@ -0,0 +1,285 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package large
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// Fixed-base table serialization layout:
+//
+//	"XXFB" | version (1 byte) | window (1 byte) | maxBits (uvarint) |
+//	modulus | base | entries | SHA-256 of everything before it
+//
+// The modulus and base are prefixed with their length as an unsigned varint.
+// The entries are stored row by row, each left padded to the byte length of
+// the modulus.
+const (
+	fixedBaseMagic   = "XXFB"
+	fixedBaseVersion = 1
+
+	// MaxFixedBaseWindow is the largest window size a FixedBaseTable
+	// accepts; the table holds 2^window - 1 entries per window.
+	MaxFixedBaseWindow = 8
+
+	// fixedBaseSpotChecks is the number of random entries UnmarshalBinary
+	// checks beyond the first column
+	fixedBaseSpotChecks = 64
+)
+
+// FixedBaseTable holds precomputed powers of a fixed base modulo a fixed
+// modulus so that exponentiations of that base need no squarings.
+//
+// For a window size w the exponent is split into w-bit digits e_i and the
+// table holds base**(d·2^(w·i)) for every digit position i and non-zero digit
+// d, so base**e is the product of one table entry per non-zero digit. A
+// table for maxBits bit exponents holds ceil(maxBits/w)·(2^w - 1) entries.
+//
+// A FixedBaseTable is read-only after creation and safe for concurrent use.
+// Its exponentiations are not constant time.
+type FixedBaseTable struct {
+	base, modulus *big.Int
+	window        int
+	maxBits       int
+	// rows[i][d-1] = base**(d·2^(window·i)) mod modulus
+	rows [][]*big.Int
+}
+
+// NewFixedBaseTable precomputes the powers of base modulo m needed to
+// exponentiate by exponents of up to maxBits bits with the given window size.
+func NewFixedBaseTable(base, m *Int, maxBits, window int) (*FixedBaseTable,
+	error) {
+	if m == nil || m.Cmp(NewInt(1)) <= 0 {
+		return nil, errors.New("modulus must be greater than one")
+	}
+	if maxBits <= 0 {
+		return nil, errors.New("maximum exponent size must be positive")
+	}
+	if window < 1 || window > MaxFixedBaseWindow {
+		return nil, errors.Errorf("window must be between 1 and %d",
+			MaxFixedBaseWindow)
+	}
+
+	t := &FixedBaseTable{
+		base:    new(big.Int).Mod(base.BigInt(), m.BigInt()),
+		modulus: new(big.Int).Set(m.BigInt()),
+		window:  window,
+		maxBits: maxBits,
+		rows:    make([][]*big.Int, (maxBits+window-1)/window),
+	}
+
+	first := new(big.Int).Set(t.base)
+	for i := range t.rows {
+		row := make([]*big.Int, 1<<window-1)
+		row[0] = first
+		for d := 1; d < len(row); d++ {
+			row[d] = new(big.Int).Mul(row[d-1], first)
+			row[d].Mod(row[d], t.modulus)
+		}
+		t.rows[i] = row
+
+		// The next row starts at base**(2^(window·(i+1))), which is the
+		// last entry of this row times one more step
+		first = new(big.Int).Mul(row[len(row)-1], row[0])
+		first.Mod(first, t.modulus)
+	}
+	return t, nil
+}
+
+// Base returns the base of the table reduced modulo the modulus.
+func (t *FixedBaseTable) Base() *Int {
+	return NewIntFromBigInt(t.base)
+}
+
+// Modulus returns the modulus of the table.
+func (t *FixedBaseTable) Modulus() *Int {
+	return NewIntFromBigInt(t.modulus)
+}
+
+// MaxBits returns the longest exponent, in bits, the table covers.
+func (t *FixedBaseTable) MaxBits() int {
+	return t.maxBits
+}
+
+// Window returns the window size of the table.
+func (t *FixedBaseTable) Window() int {
+	return t.window
+}
+
+// Exp returns base**e mod m. Exponents that are negative or longer than
+// MaxBits fall back to Int.Exp.
+func (t *FixedBaseTable) Exp(e *Int) *Int {
+	eb := e.BigInt()
+	if eb.Sign() < 0 || eb.BitLen() > t.maxBits {
+		return NewIntFromBigInt(new(big.Int).Exp(t.base, eb, t.modulus))
+	}
+
+	acc := new(big.Int).Mod(big.NewInt(1), t.modulus)
+	var tmp big.Int
+	for i := 0; i*t.window < eb.BitLen(); i++ {
+		if d := window(eb, i*t.window, t.window); d != 0 {
+			tmp.Mul(acc, t.rows[i][d-1])
+			acc.Mod(&tmp, t.modulus)
+		}
+	}
+	return NewIntFromBigInt(acc)
+}
+
+// MarshalBinary encodes the table so it can be cached and loaded with
+// UnmarshalBinary instead of being recomputed.
+func (t *FixedBaseTable) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	var tmp [binary.MaxVarintLen64]byte
+	writeBytes := func(b []byte) {
+		buf.Write(tmp[:binary.PutUvarint(tmp[:], uint64(len(b)))])
+		buf.Write(b)
+	}
+
+	buf.WriteString(fixedBaseMagic)
+	buf.WriteByte(fixedBaseVersion)
+	buf.WriteByte(byte(t.window))
+	buf.Write(tmp[:binary.PutUvarint(tmp[:], uint64(t.maxBits))])
+	writeBytes(t.modulus.Bytes())
+	writeBytes(t.base.Bytes())
+
+	entry := make([]byte, (t.modulus.BitLen()+7)/8)
+	for _, row := range t.rows {
+		for _, x := range row {
+			buf.Write(x.FillBytes(entry))
+		}
+	}
+
+	digest := sha256.Sum256(buf.Bytes())
+	buf.Write(digest[:])
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a table encoded with MarshalBinary. It checks the
+// checksum, that every entry is reduced, that the first column of the table
+// is the expected chain of powers of the base and, for a few random entries,
+// that they are the expected power of the base.
+func (t *FixedBaseTable) UnmarshalBinary(data []byte) error {
+	if len(data) < len(fixedBaseMagic)+2+sha256.Size {
+		return errors.New("fixed-base table data too short")
+	}
+	body, digest := data[:len(data)-sha256.Size], data[len(data)-sha256.Size:]
+	if sum := sha256.Sum256(body); !bytes.Equal(sum[:], digest) {
+		return errors.New("fixed-base table checksum mismatch")
+	}
+
+	r := bytes.NewReader(body)
+	header := make([]byte, len(fixedBaseMagic)+2)
+	_, _ = r.Read(header)
+	if string(header[:len(fixedBaseMagic)]) != fixedBaseMagic {
+		return errors.New("not a fixed-base table: bad magic")
+	}
+	if header[len(fixedBaseMagic)] != fixedBaseVersion {
+		return errors.Errorf("unsupported fixed-base table version %d",
+			header[len(fixedBaseMagic)])
+	}
+	window := int(header[len(fixedBaseMagic)+1])
+	if window < 1 || window > MaxFixedBaseWindow {
+		return errors.Errorf("invalid window %d", window)
+	}
+
+	maxBits, err := binary.ReadUvarint(r)
+	if err != nil || maxBits == 0 || maxBits > 1<<20 {
+		return errors.New("invalid maximum exponent size")
+	}
+	readBytes := func() ([]byte, error) {
+		n, err := binary.ReadUvarint(r)
+		if err != nil || n > uint64(r.Len()) {
+			return nil, errors.New("truncated fixed-base table")
+		}
+		b := make([]byte, n)
+		_, _ = r.Read(b)
+		return b, nil
+	}
+	modBytes, err := readBytes()
+	if err != nil {
+		return err
+	}
+	baseBytes, err := readBytes()
+	if err != nil {
+		return err
+	}
+
+	modulus := new(big.Int).SetBytes(modBytes)
+	base := new(big.Int).SetBytes(baseBytes)
+	if modulus.Cmp(big.NewInt(1)) <= 0 || base.Cmp(modulus) >= 0 {
+		return errors.New("invalid modulus or base")
+	}
+
+	entrySize := (modulus.BitLen() + 7) / 8
+	numRows := (int(maxBits) + window - 1) / window
+	perRow := 1<<window - 1
+	if r.Len() != numRows*perRow*entrySize {
+		return errors.Errorf("expected %d bytes of entries, found %d",
+			numRows*perRow*entrySize, r.Len())
+	}
+
+	rows := make([][]*big.Int, numRows)
+	entry := make([]byte, entrySize)
+	for i := range rows {
+		rows[i] = make([]*big.Int, perRow)
+		for d := range rows[i] {
+			_, _ = r.Read(entry)
+			x := new(big.Int).SetBytes(entry)
+			if x.Cmp(modulus) >= 0 {
+				return errors.Errorf("entry %d of row %d is not reduced", d,
+					i)
+			}
+			rows[i][d] = x
+		}
+	}
+
+	// Each row must start with the previous row's start raised to 2^window
+	expected := new(big.Int).Set(base)
+	shift := new(big.Int).Lsh(big.NewInt(1), uint(window))
+	for i, row := range rows {
+		if row[0].Cmp(expected) != 0 {
+			return errors.Errorf("row %d does not match the base", i)
+		}
+		expected.Exp(expected, shift, modulus)
+	}
+
+	// Spot check random entries of the other columns, each of which is the
+	// one before it times the first entry of its row
+	if perRow > 1 {
+		var idx [8]byte
+		var tmp big.Int
+		for n := 0; n < fixedBaseSpotChecks; n++ {
+			if _, err = rand.Read(idx[:]); err != nil {
+				return errors.Wrap(err, "failed to pick entries to check")
+			}
+			v := binary.BigEndian.Uint64(idx[:])
+			i := int(v % uint64(numRows))
+			d := 1 + int(v/uint64(numRows)%uint64(perRow-1))
+			tmp.Mul(rows[i][d-1], rows[i][0])
+			if tmp.Mod(&tmp, modulus).Cmp(rows[i][d]) != 0 {
+				return errors.Errorf("entry %d of row %d does not match "+
+					"the base", d, i)
+			}
+		}
+	}
+
+	*t = FixedBaseTable{
+		base:    base,
+		modulus: modulus,
+		window:  window,
+		maxBits: int(maxBits),
+		rows:    rows,
+	}
+	return nil
+}
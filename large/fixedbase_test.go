@@ -0,0 +1,151 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package large
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// Tests that FixedBaseTable.Exp matches Int.Exp for every window size,
+// including exponents that need the fallback.
+func TestFixedBaseTable_Exp(t *testing.T) {
+	prng := rand.New(rand.NewSource(21))
+	m := randomInts(prng, 1, 1024)[0]
+	m.Or(m, NewInt(1))
+	base := randomInts(prng, 1, 1100)[0]
+
+	for window := 1; window <= MaxFixedBaseWindow; window++ {
+		table, err := NewFixedBaseTable(base, m, 300, window)
+		if err != nil {
+			t.Fatalf("NewFixedBaseTable failed: %+v", err)
+		}
+		exps := append(randomInts(prng, 10, 300), NewInt(0), NewInt(1),
+			NewIntFromBigInt(new(big.Int).Lsh(big.NewInt(1), 299)),
+			randomInts(prng, 1, 600)[0], NewInt(-5))
+		for _, e := range exps {
+			expected := NewInt(0).Exp(base, e, m)
+			if got := table.Exp(e); got.Cmp(expected) != 0 {
+				t.Errorf("Window %d: %s**%s is wrong", window,
+					base.Text(16), e.Text(16))
+			}
+		}
+	}
+}
+
+// Error path: invalid parameters are rejected.
+func TestNewFixedBaseTable_Invalid(t *testing.T) {
+	tests := []struct {
+		m             *Int
+		maxBits, wind int
+	}{
+		{NewInt(1), 64, 4},
+		{nil, 64, 4},
+		{NewInt(101), 0, 4},
+		{NewInt(101), 64, 0},
+		{NewInt(101), 64, MaxFixedBaseWindow + 1},
+	}
+	for i, tt := range tests {
+		if _, err := NewFixedBaseTable(NewInt(3), tt.m, tt.maxBits,
+			tt.wind); err == nil {
+			t.Errorf("Invalid parameters %d accepted", i)
+		}
+	}
+}
+
+// Tests that a table survives serialization and that corrupted data is
+// rejected.
+func TestFixedBaseTable_Marshal(t *testing.T) {
+	prng := rand.New(rand.NewSource(22))
+	m := randomInts(prng, 1, 512)[0]
+	base := randomInts(prng, 1, 512)[0]
+	table, err := NewFixedBaseTable(base, m, 256, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := table.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := &FixedBaseTable{}
+	if err = decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %+v", err)
+	}
+	if decoded.Window() != 3 || decoded.MaxBits() != 256 ||
+		decoded.Modulus().Cmp(m) != 0 {
+		t.Errorf("Decoded table has different parameters")
+	}
+	e := randomInts(prng, 1, 256)[0]
+	if decoded.Exp(e).Cmp(table.Exp(e)) != 0 {
+		t.Errorf("Decoded table computes a different power")
+	}
+
+	corrupt := append([]byte{}, data...)
+	corrupt[len(corrupt)/2] ^= 1
+	if err = decoded.UnmarshalBinary(corrupt); err == nil {
+		t.Errorf("Corrupted table accepted")
+	}
+	if err = decoded.UnmarshalBinary(data[:20]); err == nil {
+		t.Errorf("Truncated table accepted")
+	}
+
+	// Every entry outside the first column changed with the checksum
+	// recomputed, which only the spot checks catch
+	entrySize := (m.BitLen() + 7) / 8
+	body := append([]byte{}, data[:len(data)-sha256.Size]...)
+	entries := len(body) - len(table.rows)*len(table.rows[0])*entrySize
+	for i := range table.rows {
+		for d := 1; d < len(table.rows[i]); d++ {
+			body[entries+(i*len(table.rows[i])+d+1)*entrySize-1] ^= 1
+		}
+	}
+	digest := sha256.Sum256(body)
+	decoded = &FixedBaseTable{}
+	if err = decoded.UnmarshalBinary(append(body, digest[:]...)); err == nil {
+		t.Errorf("Table with a changed entry and valid checksum accepted")
+	}
+	if decoded.rows != nil {
+		t.Errorf("Failed UnmarshalBinary changed the table")
+	}
+}
+
+// benchmarkFixedBase compares a fixed-base table to Int.Exp for a modulus and
+// exponent of the given size.
+func benchmarkFixedBase(b *testing.B, bits int) {
+	prng := rand.New(rand.NewSource(int64(bits)))
+	m := randomInts(prng, 1, bits)[0]
+	m.Or(m, NewInt(1))
+	base := NewInt(2)
+	exps := randomInts(prng, 64, bits)
+
+	for _, window := range []int{4, 6} {
+		table, err := NewFixedBaseTable(base, m, bits, window)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(fmt.Sprintf("Table/w%d", window), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				table.Exp(exps[i%len(exps)])
+			}
+		})
+	}
+	b.Run("IntExp", func(b *testing.B) {
+		z := NewInt(0)
+		for i := 0; i < b.N; i++ {
+			z.Exp(base, exps[i%len(exps)], m)
+		}
+	})
+}
+
+func BenchmarkFixedBaseTable_2048(b *testing.B) { benchmarkFixedBase(b, 2048) }
+func BenchmarkFixedBaseTable_3072(b *testing.B) { benchmarkFixedBase(b, 3072) }
+func BenchmarkFixedBaseTable_4096(b *testing.B) { benchmarkFixedBase(b, 4096) }
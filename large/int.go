@@ -58,6 +58,8 @@ func NewIntFromBigInt(x *big.Int) *Int {
 }
 
 // NewMaxInt creates a new Int with the value Max4kBitInt.
+//
+// Deprecated: Use MaxUint4096.Int, the largest 4096-bit value.
 func NewMaxInt() *Int {
 	return NewIntFromBytes(Max4kBitInt)
 }
@@ -411,8 +413,9 @@ func (z *Int) GobDecode(in []byte) error {
 	return nil
 }
 
-// GobEncode encodes the Int into a byte slice. Error is always nil.
-// This function implements the gob.GobEncoder interface.
+// GobEncode encodes the absolute value of the Int into a byte slice; the
+// sign is lost, so use MarshalBinary for values that may be negative. Error
+// is always nil. This function implements the gob.GobEncoder interface.
 func (z *Int) GobEncode() ([]byte, error) {
 	return z.Bytes(), nil
 }
@@ -447,6 +450,8 @@ func (z *Int) UnmarshalJSON(b []byte) error {
 //
 // It will probably be made to hold this 4096 bit prime:
 // https://tools.ietf.org/html/rfc3526#page-5
+//
+// Deprecated: Use MaxUint4096, which is typed and exactly 4096 bits.
 var Max4kBitInt = []byte{
 	0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
 	0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
@@ -0,0 +1,598 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package large
+
+import (
+	"math/big"
+	"math/bits"
+
+	"github.com/pkg/errors"
+	jww "github.com/spf13/jwalterweatherman"
+)
+
+// Constant-time modular arithmetic.
+//
+// Int wraps math/big, whose operations run in time that depends on the values
+// of their operands. That is fine for public values but leaks secrets such as
+// private exponents through timing. Modulus and MontInt instead store values
+// in Montgomery form as fixed-width little-endian word slices, one word slice
+// the width of the modulus per value, and every operation on them runs in time
+// that depends only on the size of the modulus, never on the values.
+//
+// Only the modulus is treated as public. Conversions from Int take time
+// proportional to the word length of the Int, so callers holding a secret Int
+// whose length could be shorter than the modulus should convert it with
+// NewMontIntFromBytes and a fixed-length buffer instead.
+
+// expWindow is the number of exponent bits processed per multiplication in
+// MontInt.Exp.
+const expWindow = 4
+
+// Modulus is an odd modulus together with the precomputed values needed for
+// Montgomery multiplication. It is safe for concurrent use.
+type Modulus struct {
+	n      []big.Word // the modulus, little-endian
+	nInv   big.Word   // -n⁻¹ mod 2^_W
+	rr     []big.Word // R² mod n where R = 2^(_W*len(n))
+	rrr    []big.Word // R³ mod n
+	one    []big.Word // R mod n, i.e. 1 in Montgomery form
+	bitLen int
+}
+
+// MontInt is a value modulo a Modulus stored in Montgomery form. The zero
+// value is not usable; MontInts are created from a Modulus and may only be
+// combined with MontInts from the same Modulus.
+type MontInt struct {
+	m     *Modulus
+	limbs []big.Word
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Modulus                                                                    //
+////////////////////////////////////////////////////////////////////////////////
+
+// NewModulus creates a Montgomery context for the odd modulus n > 1.
+func NewModulus(n *Int) (*Modulus, error) {
+	if n == nil || n.Cmp(NewInt(1)) <= 0 {
+		return nil, errors.New("modulus must be greater than one")
+	}
+	if n.BigInt().Bit(0) != 1 {
+		return nil, errors.New("modulus must be odd for Montgomery " +
+			"arithmetic")
+	}
+	return newModulus(append([]big.Word(nil), n.Bits()...)), nil
+}
+
+// newModulus creates a Montgomery context for the odd modulus n > 1, given as
+// little-endian words that the Modulus takes ownership of. The number of words
+// sets R, so n may have leading zero words to widen it.
+func newModulus(n []big.Word) *Modulus {
+	m := &Modulus{n: n, nInv: negInverseWord(n[0])}
+	for i := len(n) - 1; i >= 0; i-- {
+		if n[i] != 0 {
+			m.bitLen = i*_W + bits.Len(uint(n[i]))
+			break
+		}
+	}
+
+	// R mod n is computed by shifting 1 in one bit at a time, and the higher
+	// powers of R from it by Montgomery multiplication
+	m.one = make([]big.Word, len(m.n))
+	m.one[0] = 1
+	for i := 0; i < _W*len(m.n); i++ {
+		m.shiftIn(m.one, 0)
+	}
+	m.rr = make([]big.Word, len(m.n))
+	copy(m.rr, m.one)
+	for i := 0; i < _W*len(m.n); i++ {
+		m.shiftIn(m.rr, 0)
+	}
+	m.rrr = make([]big.Word, len(m.n))
+	m.montMul(m.rrr, m.rr, m.rr)
+
+	return m
+}
+
+// Int returns the modulus as an Int.
+func (m *Modulus) Int() *Int {
+	return NewIntFromBits(append(Bits(nil), m.n...))
+}
+
+// BitLen returns the length of the modulus in bits.
+func (m *Modulus) BitLen() int {
+	return m.bitLen
+}
+
+// ByteLen returns the length of the modulus in bytes.
+func (m *Modulus) ByteLen() int {
+	return (m.bitLen + 7) / 8
+}
+
+// NewMontInt returns x mod n in Montgomery form. x must not be negative. The
+// time taken depends on the word length of x but not on its value.
+func (m *Modulus) NewMontInt(x *Int) *MontInt {
+	if x.BigInt().Sign() < 0 {
+		jww.FATAL.Panicf("large.Modulus.NewMontInt(): negative value")
+	}
+	z := m.Zero()
+	words := x.Bits()
+	for i := len(words) - 1; i >= 0; i-- {
+		for j := _W - 1; j >= 0; j-- {
+			m.shiftIn(z.limbs, (words[i]>>uint(j))&1)
+		}
+	}
+	m.montMul(z.limbs, z.limbs, m.rr)
+	return z
+}
+
+// NewMontIntFromBytes returns the big-endian unsigned integer in buf mod n in
+// Montgomery form. The time taken depends on len(buf) but not on its contents.
+func (m *Modulus) NewMontIntFromBytes(buf []byte) *MontInt {
+	z := m.Zero()
+	for _, b := range buf {
+		for j := 7; j >= 0; j-- {
+			m.shiftIn(z.limbs, big.Word(b>>uint(j))&1)
+		}
+	}
+	m.montMul(z.limbs, z.limbs, m.rr)
+	return z
+}
+
+// Zero returns a new MontInt set to 0.
+func (m *Modulus) Zero() *MontInt {
+	return &MontInt{m: m, limbs: make([]big.Word, len(m.n))}
+}
+
+// One returns a new MontInt set to 1.
+func (m *Modulus) One() *MontInt {
+	z := m.Zero()
+	copy(z.limbs, m.one)
+	return z
+}
+
+// shiftIn sets x to 2x + bit mod n for x < n and bit in {0, 1}.
+func (m *Modulus) shiftIn(x []big.Word, bit big.Word) {
+	carry := bit
+	for i := range x {
+		x[i], carry = x[i]<<1|carry, x[i]>>(_W-1)
+	}
+	// 2x + bit < 2n, so at most one subtraction is needed
+	m.reduceOnce(x, carry)
+}
+
+// reduceOnce subtracts n from the value hi·R + x if it is at least n. The value
+// must be less than 2n. The comparison is a separate pass so that no
+// temporary is needed for the difference.
+func (m *Modulus) reduceOnce(x []big.Word, hi big.Word) {
+	var borrow uint
+	for i := range x {
+		_, borrow = bits.Sub(uint(x[i]), uint(m.n[i]), borrow)
+	}
+	// x ≥ n unless the subtraction borrowed and there is no high word
+	mask := uint(-(hi | big.Word(borrow^1)))
+	borrow = 0
+	for i := range x {
+		var d uint
+		d, borrow = bits.Sub(uint(x[i]), uint(m.n[i])&mask, borrow)
+		x[i] = big.Word(d)
+	}
+}
+
+// montMul sets z to x·y·R⁻¹ mod n using coarsely integrated operand
+// scanning. It requires x·y < n·R, which holds when one operand is below n and
+// the other below R. z may alias x or y.
+func (m *Modulus) montMul(z, x, y []big.Word) {
+	m.montMulScratch(z, x, y, make([]big.Word, len(m.n)+1))
+}
+
+// montMulScratch is montMul with its temporary in t, which must have len(n)+1
+// words, so that callers can avoid allocating. The multiplication and
+// reduction of each row are fused into one pass.
+func (m *Modulus) montMulScratch(z, x, y, t []big.Word) {
+	size := len(m.n)
+	n, x, y, t := m.n[:size], x[:size], y[:size], t[:size+1]
+	for i := range t {
+		t[i] = 0
+	}
+	for i := 0; i < size; i++ {
+		// t = (t + x[i]·y + q·n) / 2^_W where q makes the low word zero
+		xi := uint(x[i])
+		hi, lo := bits.Mul(xi, uint(y[0]))
+		lo, c := bits.Add(lo, uint(t[0]), 0)
+		c1 := hi + c
+		q := lo * uint(m.nInv)
+		hi, lo2 := bits.Mul(q, uint(n[0]))
+		_, c = bits.Add(lo2, lo, 0)
+		c2 := hi + c
+		for j := 1; j < size; j++ {
+			hi, lo = bits.Mul(xi, uint(y[j]))
+			lo, c = bits.Add(lo, uint(t[j]), 0)
+			hi += c
+			lo, c = bits.Add(lo, c1, 0)
+			c1 = hi + c
+
+			hi, lo2 = bits.Mul(q, uint(n[j]))
+			lo2, c = bits.Add(lo2, lo, 0)
+			hi += c
+			lo2, c = bits.Add(lo2, c2, 0)
+			c2 = hi + c
+			t[j-1] = big.Word(lo2)
+		}
+		lo, c = bits.Add(uint(t[size]), c1, 0)
+		hi = c
+		lo, c = bits.Add(lo, c2, 0)
+		t[size-1], t[size] = big.Word(lo), big.Word(hi+c)
+	}
+
+	// t < 2n, so z is t - n unless the subtraction borrowed and t has no
+	// high word
+	var borrow uint
+	for i := range z[:size] {
+		var d uint
+		d, borrow = bits.Sub(uint(t[i]), uint(n[i]), borrow)
+		z[i] = big.Word(d)
+	}
+	ctSelect(z, z, t[:size], t[size]|big.Word(borrow^1))
+}
+
+// mulUnreduced sets z to x·y mod n, out of Montgomery form, for any x and y
+// below R. t must have len(n)+1 words.
+func (m *Modulus) mulUnreduced(z, x, y, t []big.Word) {
+	// x·R² < R·n, so the first product is x·R mod n, reduced, and the second
+	// is then x·y mod n
+	m.montMulScratch(z, x, m.rr, t)
+	m.montMulScratch(z, z, y, t)
+}
+
+// expUnreduced sets z to x**y mod n, out of Montgomery form, for any x below
+// R and the big-endian exponent y. scratch must have expScratchLen words.
+func (m *Modulus) expUnreduced(z, x []big.Word, y []byte, scratch []big.Word) {
+	size := len(m.n)
+	t := scratch[len(scratch)-size-2:]
+	m.montMulScratch(z, x, m.rr, t)
+	m.exp(z, z, y, scratch)
+
+	// Leave Montgomery form by multiplying by one
+	one := scratch[:size]
+	for i := range one {
+		one[i] = 0
+	}
+	one[0] = 1
+	m.montMulScratch(z, z, one, t)
+}
+
+// expScratchLen returns the number of scratch words exp needs for a modulus
+// of size words: the table, the selected entry and the product.
+func expScratchLen(size int) int {
+	return (1<<expWindow+2)*size + 2
+}
+
+// exp sets z to x**y mod n for x in Montgomery form and the big-endian
+// exponent y, leaving z in Montgomery form. scratch must have expScratchLen
+// words. z may alias x. The time taken depends on len(y) but not on its
+// contents.
+func (m *Modulus) exp(z, x []big.Word, y []byte, scratch []big.Word) {
+	size := len(m.n)
+	var table [1 << expWindow][]big.Word
+	for i := range table {
+		table[i] = scratch[i*size : (i+1)*size]
+	}
+	entry := scratch[len(table)*size : (len(table)+1)*size]
+	t := scratch[(len(table)+1)*size:]
+
+	// table[i] = x**i
+	copy(table[0], m.one)
+	copy(table[1], x)
+	for i := 2; i < len(table); i++ {
+		m.montMulScratch(table[i], table[i-1], x, t)
+	}
+
+	copy(z, m.one)
+	for _, b := range y {
+		for _, window := range [2]byte{b >> 4, b & 0x0f} {
+			for i := 0; i < expWindow; i++ {
+				m.montMulScratch(z, z, z, t)
+			}
+			// Read every table entry so the access pattern does not
+			// depend on the exponent
+			for i := range table {
+				ctSelect(entry, table[i], entry,
+					ctEqWord(big.Word(i), big.Word(window)))
+			}
+			m.montMulScratch(z, z, entry, t)
+		}
+	}
+}
+
+// fromMont returns the value of x out of Montgomery form.
+func (m *Modulus) fromMont(x []big.Word) []big.Word {
+	one := make([]big.Word, len(m.n))
+	one[0] = 1
+	z := make([]big.Word, len(m.n))
+	m.montMul(z, x, one)
+	return z
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// MontInt                                                                    //
+////////////////////////////////////////////////////////////////////////////////
+
+// Modulus returns the modulus of x.
+func (x *MontInt) Modulus() *Modulus {
+	return x.m
+}
+
+// Int returns the value of x as an Int.
+func (x *MontInt) Int() *Int {
+	return NewIntFromBits(Bits(x.m.fromMont(x.limbs)))
+}
+
+// Bytes returns the value of x as a big-endian byte slice the length of the
+// modulus.
+func (x *MontInt) Bytes() []byte {
+	limbs := x.m.fromMont(x.limbs)
+	buf := make([]byte, len(limbs)*_S)
+	for i, w := range limbs {
+		for j := 0; j < _S; j++ {
+			buf[len(buf)-1-i*_S-j] = byte(w >> uint(8*j))
+		}
+	}
+	return buf[len(buf)-x.m.ByteLen():]
+}
+
+// DeepCopy returns a copy of x.
+func (x *MontInt) DeepCopy() *MontInt {
+	return &MontInt{m: x.m, limbs: append([]big.Word(nil), x.limbs...)}
+}
+
+// Set sets z to x and returns z.
+func (z *MontInt) Set(x *MontInt) *MontInt {
+	z.checkModulus(x)
+	copy(z.limbs, x.limbs)
+	return z
+}
+
+// Add sets z to x + y mod n and returns z.
+func (z *MontInt) Add(x, y *MontInt) *MontInt {
+	z.checkModulus(x, y)
+	var carry uint
+	for i := range z.limbs {
+		var s uint
+		s, carry = bits.Add(uint(x.limbs[i]), uint(y.limbs[i]), carry)
+		z.limbs[i] = big.Word(s)
+	}
+	z.m.reduceOnce(z.limbs, big.Word(carry))
+	return z
+}
+
+// Sub sets z to x - y mod n and returns z.
+func (z *MontInt) Sub(x, y *MontInt) *MontInt {
+	z.checkModulus(x, y)
+	ctSubMod(z.limbs, x.limbs, y.limbs, z.m.n)
+	return z
+}
+
+// Mul sets z to x·y mod n and returns z.
+func (z *MontInt) Mul(x, y *MontInt) *MontInt {
+	z.checkModulus(x, y)
+	z.m.montMul(z.limbs, x.limbs, y.limbs)
+	return z
+}
+
+// Exp sets z to x**y mod n and returns z. y must not be negative. The time
+// taken depends on the length of y only if it is longer than the modulus.
+func (z *MontInt) Exp(x *MontInt, y *Int) *MontInt {
+	if y.BigInt().Sign() < 0 {
+		jww.FATAL.Panicf("large.MontInt.Exp(): negative exponent")
+	}
+	size := z.m.ByteLen()
+	if y.ByteLen() > size {
+		size = y.ByteLen()
+	}
+	return z.ExpBytes(x, y.FillBytes(make([]byte, size)))
+}
+
+// ExpBytes sets z to x**y mod n, where y is a big-endian unsigned integer, and
+// returns z. The time taken depends on len(y) but not on its contents.
+func (z *MontInt) ExpBytes(x *MontInt, y []byte) *MontInt {
+	z.checkModulus(x)
+	z.m.exp(z.limbs, x.limbs, y, make([]big.Word, expScratchLen(len(z.m.n))))
+	return z
+}
+
+// Inverse sets z to x⁻¹ mod n and returns z and true. If x has no inverse,
+// z is set to 0 and false is returned. The time taken does not depend on
+// whether the inverse exists.
+func (z *MontInt) Inverse(x *MontInt) (*MontInt, bool) {
+	z.checkModulus(x)
+	m := z.m
+	size := len(m.n)
+
+	// Binary extended Euclid on a = x·R mod n and b = n keeping
+	// a ≡ u·(xR) and b ≡ v·(xR) mod n. Every iteration halves a and so
+	// removes at least one bit from a and b, so 2·bitLen iterations always
+	// reach a = 0 with b = gcd(x, n).
+	a := append([]big.Word(nil), x.limbs...)
+	b := append([]big.Word(nil), m.n...)
+	u := make([]big.Word, size)
+	u[0] = 1
+	v := make([]big.Word, size)
+	diff := make([]big.Word, size)
+	for i := 0; i < 2*m.bitLen; i++ {
+		odd := a[0] & 1
+
+		// if a is odd and a < b, swap a and b and u and v
+		var borrow uint
+		for j := range a {
+			var d uint
+			d, borrow = bits.Sub(uint(a[j]), uint(b[j]), borrow)
+			diff[j] = big.Word(d)
+		}
+		swap := odd & big.Word(borrow)
+		ctSwap(a, b, swap)
+		ctSwap(u, v, swap)
+
+		// if a is odd, a = a - b and u = u - v
+		borrow = 0
+		for j := range a {
+			var d uint
+			d, borrow = bits.Sub(uint(a[j]), uint(b[j]), borrow)
+			diff[j] = big.Word(d)
+		}
+		ctSelect(a, diff, a, odd)
+		ctSubMod(diff, u, v, m.n)
+		ctSelect(u, diff, u, odd)
+
+		// a is now even, so halve a and u
+		ctShiftRight(a, 0)
+		ctHalveMod(u, m.n)
+	}
+
+	// v·(xR) ≡ b = gcd(x, n), so if it is 1 then v = x⁻¹R⁻¹ and
+	// v·R³·R⁻¹ = x⁻¹R
+	b[0] ^= 1
+	var acc big.Word
+	for _, w := range b {
+		acc |= w
+	}
+	ok := ctEqWord(acc, 0)
+	m.montMul(z.limbs, v, m.rrr)
+	ctSelect(z.limbs, z.limbs, make([]big.Word, size), ok)
+	return z, ok == 1
+}
+
+// Select sets z to x if cond is 1 and to y if cond is 0 and returns z. cond
+// must be 0 or 1.
+func (z *MontInt) Select(x, y *MontInt, cond int) *MontInt {
+	z.checkModulus(x, y)
+	ctSelect(z.limbs, x.limbs, y.limbs, big.Word(cond))
+	return z
+}
+
+// Equal returns 1 if x and y are equal and 0 otherwise.
+func (x *MontInt) Equal(y *MontInt) int {
+	x.checkModulus(y)
+	var acc big.Word
+	for i := range x.limbs {
+		acc |= x.limbs[i] ^ y.limbs[i]
+	}
+	return int(ctEqWord(acc, 0))
+}
+
+// IsZero returns 1 if x is 0 and 0 otherwise.
+func (x *MontInt) IsZero() int {
+	var acc big.Word
+	for _, w := range x.limbs {
+		acc |= w
+	}
+	return int(ctEqWord(acc, 0))
+}
+
+// checkModulus panics if any of the MontInts use a different modulus than z.
+func (z *MontInt) checkModulus(xs ...*MontInt) {
+	for _, x := range xs {
+		if x.m != z.m {
+			jww.FATAL.Panicf("large.MontInt: operands have different " +
+				"moduli")
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Word Helpers                                                               //
+////////////////////////////////////////////////////////////////////////////////
+
+const (
+	_W = bits.UintSize // word size in bits
+	_S = _W / 8        // word size in bytes
+)
+
+// mulAddWWW returns x·y + a + c as a double word.
+func mulAddWWW(x, y, a, c big.Word) (hi, lo big.Word) {
+	h, l := bits.Mul(uint(x), uint(y))
+	var cc uint
+	l, cc = bits.Add(l, uint(a), 0)
+	h += cc
+	l, cc = bits.Add(l, uint(c), 0)
+	h += cc
+	return big.Word(h), big.Word(l)
+}
+
+// negInverseWord returns -x⁻¹ mod 2^_W for odd x using Newton's iteration,
+// which doubles the number of correct low bits every step.
+func negInverseWord(x big.Word) big.Word {
+	inv := x // correct to 3 bits for odd x
+	for i := 0; i < 6; i++ {
+		inv *= 2 - x*inv
+	}
+	return -inv
+}
+
+// ctEqWord returns 1 if x == y and 0 otherwise.
+func ctEqWord(x, y big.Word) big.Word {
+	d := uint(x ^ y)
+	// d | -d has its top bit set unless d is zero
+	return big.Word(((d | -d) >> (_W - 1)) ^ 1)
+}
+
+// ctSelect sets z to x if cond is 1 and to y if cond is 0.
+func ctSelect(z, x, y []big.Word, cond big.Word) {
+	mask := -cond
+	for i := range z {
+		z[i] = y[i] ^ (mask & (x[i] ^ y[i]))
+	}
+}
+
+// ctSwap swaps x and y if cond is 1.
+func ctSwap(x, y []big.Word, cond big.Word) {
+	mask := -cond
+	for i := range x {
+		t := mask & (x[i] ^ y[i])
+		x[i] ^= t
+		y[i] ^= t
+	}
+}
+
+// ctSubMod sets z to x - y mod n for x, y < n.
+func ctSubMod(z, x, y, n []big.Word) {
+	var borrow uint
+	for i := range z {
+		var d uint
+		d, borrow = bits.Sub(uint(x[i]), uint(y[i]), borrow)
+		z[i] = big.Word(d)
+	}
+	// add n back if the subtraction wrapped
+	mask := -big.Word(borrow)
+	var carry uint
+	for i := range z {
+		var s uint
+		s, carry = bits.Add(uint(z[i]), uint(n[i]&mask), carry)
+		z[i] = big.Word(s)
+	}
+}
+
+// ctShiftRight sets x to (hi·R + x) / 2 for hi in {0, 1}.
+func ctShiftRight(x []big.Word, hi big.Word) {
+	for i := 0; i < len(x)-1; i++ {
+		x[i] = x[i]>>1 | x[i+1]<<(_W-1)
+	}
+	x[len(x)-1] = x[len(x)-1]>>1 | hi<<(_W-1)
+}
+
+// ctHalveMod sets x to x/2 mod n for odd n and x < n.
+func ctHalveMod(x, n []big.Word) {
+	// if x is odd, x + n is even and (x + n) / 2 < n
+	mask := -(x[0] & 1)
+	var carry uint
+	for i := range x {
+		var s uint
+		s, carry = bits.Add(uint(x[i]), uint(n[i]&mask), carry)
+		x[i] = big.Word(s)
+	}
+	ctShiftRight(x, big.Word(carry))
+}
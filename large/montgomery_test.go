@@ -0,0 +1,251 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package large
+
+import (
+	"bytes"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// testModuli returns odd moduli of various sizes, including ones with a
+// single word, a full top word and a prime.
+func testModuli(prng *rand.Rand) []*Int {
+	moduli := []*Int{
+		NewInt(3),
+		NewInt(0x7fffffff),
+		NewIntFromString("ffffffffffffffffffffffffffffffff", 16),
+		// 2^255 - 19
+		NewIntFromString("7fffffffffffffffffffffffffffffffffffffffffffffff"+
+			"ffffffffffffffed", 16),
+	}
+	for _, size := range []int{64, 520, 1024, 2048} {
+		n := new(big.Int).Rand(prng, new(big.Int).Lsh(big.NewInt(1),
+			uint(size)))
+		n.SetBit(n, 0, 1)
+		n.SetBit(n, size-1, 1)
+		moduli = append(moduli, NewIntFromBigInt(n))
+	}
+	return moduli
+}
+
+// Tests that NewModulus rejects even and too small moduli.
+func TestNewModulus_Invalid(t *testing.T) {
+	for _, n := range []*Int{nil, NewInt(0), NewInt(1), NewInt(-7),
+		NewInt(10)} {
+		if _, err := NewModulus(n); err == nil {
+			t.Errorf("NewModulus(%v) did not return an error", n)
+		}
+	}
+}
+
+// Tests that values survive the round trip into and out of Montgomery form
+// and are reduced on the way in.
+func TestMontInt_RoundTrip(t *testing.T) {
+	prng := rand.New(rand.NewSource(42))
+	for _, n := range testModuli(prng) {
+		m, err := NewModulus(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Int().Cmp(n) != 0 || m.BitLen() != n.BitLen() {
+			t.Errorf("Modulus does not match %s", n.TextVerbose(16, 0))
+		}
+
+		for i := 0; i < 10; i++ {
+			x := new(big.Int).Rand(prng,
+				new(big.Int).Lsh(n.BigInt(), 70))
+			expected := new(big.Int).Mod(x, n.BigInt())
+
+			z := m.NewMontInt(NewIntFromBigInt(x))
+			if z.Int().BigInt().Cmp(expected) != 0 {
+				t.Errorf("NewMontInt(%s) mod %s = %s", x, n.BigInt(),
+					z.Int().BigInt())
+			}
+			fromBytes := m.NewMontIntFromBytes(x.Bytes())
+			if fromBytes.Equal(z) != 1 {
+				t.Errorf("NewMontIntFromBytes differs from NewMontInt")
+			}
+			buf := expected.FillBytes(make([]byte, m.ByteLen()))
+			if !bytes.Equal(z.Bytes(), buf) {
+				t.Errorf("Bytes() = %x, expected %x", z.Bytes(), buf)
+			}
+		}
+	}
+}
+
+// Tests that converting into Montgomery form makes a fixed number of
+// allocations rather than one per input bit.
+func TestMontInt_RoundTrip_Allocations(t *testing.T) {
+	prng := rand.New(rand.NewSource(42))
+	moduli := testModuli(prng)
+	m, err := NewModulus(moduli[len(moduli)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := NewIntFromBigInt(new(big.Int).Rand(prng, m.Int().BigInt()))
+	buf := x.Bytes()
+	allocs := testing.AllocsPerRun(10, func() {
+		m.NewMontInt(x)
+		m.NewMontIntFromBytes(buf)
+	})
+	if allocs > 6 {
+		t.Errorf("Converting a %d bit value made %.0f allocations",
+			x.BitLen(), allocs)
+	}
+}
+
+// Tests Add, Sub, Mul and Exp against math/big.
+func TestMontInt_Arithmetic(t *testing.T) {
+	prng := rand.New(rand.NewSource(7))
+	for _, n := range testModuli(prng) {
+		m, err := NewModulus(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nb := n.BigInt()
+		for i := 0; i < 10; i++ {
+			x := new(big.Int).Rand(prng, nb)
+			y := new(big.Int).Rand(prng, nb)
+			e := new(big.Int).Rand(prng, new(big.Int).Lsh(nb, 8))
+			mx := m.NewMontInt(NewIntFromBigInt(x))
+			my := m.NewMontInt(NewIntFromBigInt(y))
+
+			check := func(name string, got *MontInt, expected *big.Int) {
+				expected.Mod(expected, nb)
+				if got.Int().BigInt().Cmp(expected) != 0 {
+					t.Errorf("%s mod %s: expected %s, received %s", name,
+						nb, expected, got.Int().BigInt())
+				}
+			}
+			check("Add", m.Zero().Add(mx, my), new(big.Int).Add(x, y))
+			check("Sub", m.Zero().Sub(mx, my), new(big.Int).Sub(x, y))
+			check("Mul", m.Zero().Mul(mx, my), new(big.Int).Mul(x, y))
+			check("Exp", m.Zero().Exp(mx, NewIntFromBigInt(e)),
+				new(big.Int).Exp(x, e, nb))
+
+			// Aliased receiver
+			check("Mul aliased", mx.DeepCopy().Mul(mx, mx),
+				new(big.Int).Mul(x, x))
+			check("Exp aliased", mx.DeepCopy().Exp(mx,
+				NewIntFromBigInt(e)), new(big.Int).Exp(x, e, nb))
+		}
+	}
+}
+
+// Tests that Exp handles a zero exponent and exponents with leading zero
+// bytes.
+func TestMontInt_Exp_Edges(t *testing.T) {
+	m, err := NewModulus(NewInt(1000003))
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := m.NewMontInt(NewInt(12345))
+	if m.Zero().Exp(x, NewInt(0)).Equal(m.One()) != 1 {
+		t.Errorf("x**0 is not 1")
+	}
+	a := m.Zero().ExpBytes(x, []byte{0, 0, 0, 5})
+	b := m.Zero().ExpBytes(x, []byte{5})
+	if a.Equal(b) != 1 || a.Int().Int64() != new(big.Int).Exp(
+		big.NewInt(12345), big.NewInt(5), big.NewInt(1000003)).Int64() {
+		t.Errorf("Leading zero bytes change the result of ExpBytes")
+	}
+}
+
+// Tests Inverse against math/big for invertible and non-invertible values.
+func TestMontInt_Inverse(t *testing.T) {
+	prng := rand.New(rand.NewSource(11))
+	for _, n := range testModuli(prng) {
+		m, err := NewModulus(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nb := n.BigInt()
+		for i := 0; i < 10; i++ {
+			x := new(big.Int).Rand(prng, nb)
+			expected := new(big.Int).ModInverse(x, nb)
+			inv, ok := m.Zero().Inverse(m.NewMontInt(NewIntFromBigInt(x)))
+			if ok != (expected != nil) {
+				t.Errorf("Inverse of %s mod %s: ok = %t", x, nb, ok)
+			} else if ok && inv.Int().BigInt().Cmp(expected) != 0 {
+				t.Errorf("Inverse of %s mod %s: expected %s, received %s",
+					x, nb, expected, inv.Int().BigInt())
+			}
+		}
+
+		if inv, ok := m.Zero().Inverse(m.Zero()); ok || inv.IsZero() != 1 {
+			t.Errorf("Zero has an inverse mod %s", nb)
+		}
+	}
+
+	// 15 = 3·5 so multiples of 3 or 5 have no inverse
+	m, _ := NewModulus(NewInt(15))
+	for x := int64(0); x < 15; x++ {
+		_, ok := m.Zero().Inverse(m.NewMontInt(NewInt(x)))
+		expected := new(big.Int).ModInverse(big.NewInt(x),
+			big.NewInt(15)) != nil
+		if ok != expected {
+			t.Errorf("Inverse of %d mod 15: ok = %t", x, ok)
+		}
+	}
+}
+
+// Tests Select, Equal and IsZero.
+func TestMontInt_SelectEqual(t *testing.T) {
+	m, err := NewModulus(NewInt(101))
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := m.NewMontInt(NewInt(5))
+	y := m.NewMontInt(NewInt(106))
+
+	if x.Equal(y) != 1 {
+		t.Errorf("5 and 106 are not equal mod 101")
+	}
+	y = m.NewMontInt(NewInt(6))
+	if x.Equal(y) != 0 {
+		t.Errorf("5 and 6 are equal mod 101")
+	}
+	if m.Zero().Select(x, y, 1).Equal(x) != 1 ||
+		m.Zero().Select(x, y, 0).Equal(y) != 1 {
+		t.Errorf("Select returned the wrong value")
+	}
+	if m.Zero().IsZero() != 1 || m.NewMontInt(NewInt(101)).IsZero() != 1 ||
+		x.IsZero() != 0 {
+		t.Errorf("IsZero returned the wrong value")
+	}
+}
+
+// Tests that combining MontInts from different moduli panics.
+func TestMontInt_DifferentModuli(t *testing.T) {
+	m1, _ := NewModulus(NewInt(101))
+	m2, _ := NewModulus(NewInt(101))
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Combining different moduli did not panic")
+		}
+	}()
+	m1.Zero().Add(m1.One(), m2.One())
+}
+
+// Benchmarks constant-time 2048-bit exponentiation.
+func BenchmarkMontInt_Exp2048(b *testing.B) {
+	prng := rand.New(rand.NewSource(1))
+	n := new(big.Int).Rand(prng, new(big.Int).Lsh(big.NewInt(1), 2048))
+	n.SetBit(n, 0, 1)
+	n.SetBit(n, 2047, 1)
+	m, _ := NewModulus(NewIntFromBigInt(n))
+	x := m.NewMontInt(NewIntFromBigInt(new(big.Int).Rand(prng, n)))
+	e := NewIntFromBigInt(new(big.Int).Rand(prng, n))
+	z := m.Zero()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		z.Exp(x, e)
+	}
+}
@@ -0,0 +1,195 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package large
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// pippengerThreshold is the number of bases above which MultiExp switches
+// from Straus's interleaved windows to Pippenger's bucket method.
+const pippengerThreshold = 32
+
+// MultiExp returns the product of bases[i]**exps[i] mod m. The exponents must
+// not be negative. The computation shares the squarings between all the
+// powers, using Straus's method for a few bases and Pippenger's bucket
+// method for many. It is not constant time.
+func MultiExp(bases, exps []*Int, m *Int) (*Int, error) {
+	if err := checkMultiExp(bases, exps, m); err != nil {
+		return nil, err
+	}
+	var s expScratch
+	return NewIntFromBigInt(s.multiExp(bases, exps, m.BigInt())), nil
+}
+
+// checkMultiExp validates the inputs to a multi-exponentiation.
+func checkMultiExp(bases, exps []*Int, m *Int) error {
+	if len(bases) != len(exps) {
+		return errors.Errorf("%d bases but %d exponents", len(bases),
+			len(exps))
+	}
+	if m == nil || m.BigInt().Sign() <= 0 {
+		return errors.New("modulus must be positive")
+	}
+	for i, e := range exps {
+		if e.BigInt().Sign() < 0 {
+			return errors.Errorf("exponent %d is negative", i)
+		}
+	}
+	return nil
+}
+
+// expScratch holds buffers reused between the exponentiations and
+// multi-exponentiations run by the same worker.
+type expScratch struct {
+	table   []*big.Int
+	buckets []*big.Int
+	tmp     big.Int
+
+	// base and power hold the reduced base and the result of exp
+	base, power big.Int
+}
+
+// ints returns a slice of at least n allocated big.Ints, reusing buf.
+func (s *expScratch) ints(buf []*big.Int, n int) []*big.Int {
+	for len(buf) < n {
+		buf = append(buf, new(big.Int))
+	}
+	return buf[:n]
+}
+
+// exp sets z to x**y mod m for y ≥ 0 and m > 0 and returns z. The base is
+// reduced and the power computed in the scratch buffers, so apart from the
+// temporaries of big.Int.Exp only z is allocated.
+func (s *expScratch) exp(z, x, y, m *big.Int) *big.Int {
+	s.base.Mod(x, m)
+	s.power.Exp(&s.base, y, m)
+	return z.Set(&s.power)
+}
+
+// mulMod sets z to x·y mod m.
+func (s *expScratch) mulMod(z, x, y, m *big.Int) {
+	s.tmp.Mul(x, y)
+	z.Mod(&s.tmp, m)
+}
+
+// multiExp computes the product of powers for validated inputs.
+func (s *expScratch) multiExp(bases, exps []*Int, m *big.Int) *big.Int {
+	maxBits := 0
+	for _, e := range exps {
+		if bl := e.BitLen(); bl > maxBits {
+			maxBits = bl
+		}
+	}
+	if maxBits == 0 || m.Cmp(big.NewInt(1)) == 0 {
+		return new(big.Int).Mod(big.NewInt(1), m)
+	}
+	if len(bases) > pippengerThreshold {
+		return s.pippenger(bases, exps, m, maxBits)
+	}
+	return s.straus(bases, exps, m, maxBits)
+}
+
+// straus computes the product with a table of the first 2^w powers of every
+// base and one pass over the exponent windows.
+func (s *expScratch) straus(bases, exps []*Int, m *big.Int,
+	maxBits int) *big.Int {
+	const w = 4
+	const size = 1 << w
+
+	s.table = s.ints(s.table, len(bases)*size)
+	for i, b := range bases {
+		row := s.table[i*size : (i+1)*size]
+		row[0].SetInt64(1)
+		row[1].Mod(b.BigInt(), m)
+		for j := 2; j < size; j++ {
+			s.mulMod(row[j], row[j-1], row[1], m)
+		}
+	}
+
+	acc := big.NewInt(1)
+	for pos := (maxBits + w - 1) / w * w; pos > 0; pos -= w {
+		for k := 0; k < w; k++ {
+			s.mulMod(acc, acc, acc, m)
+		}
+		for i, e := range exps {
+			if d := window(e.BigInt(), pos-w, w); d != 0 {
+				s.mulMod(acc, acc, s.table[i*size+int(d)], m)
+			}
+		}
+	}
+	return acc
+}
+
+// pippenger computes the product by sorting the bases into buckets by their
+// exponent window and combining the buckets with a running product.
+func (s *expScratch) pippenger(bases, exps []*Int, m *big.Int,
+	maxBits int) *big.Int {
+	// A window of about log2(n) - 2 bits balances the n bucket insertions
+	// against the 2·2^c bucket combinations per window
+	c := 2
+	for n := len(bases); n > 16; n >>= 1 {
+		c++
+	}
+	size := 1 << c
+
+	s.table = s.ints(s.table, len(bases))
+	for i, b := range bases {
+		s.table[i].Mod(b.BigInt(), m)
+	}
+	s.buckets = s.ints(s.buckets, size)
+
+	acc := big.NewInt(1)
+	running, total := new(big.Int), new(big.Int)
+	used := make([]bool, size)
+	for pos := (maxBits + c - 1) / c * c; pos > 0; pos -= c {
+		for k := 0; k < c; k++ {
+			s.mulMod(acc, acc, acc, m)
+		}
+
+		for d := range used {
+			used[d] = false
+		}
+		for i, e := range exps {
+			d := window(e.BigInt(), pos-c, c)
+			if d == 0 {
+				continue
+			}
+			if used[d] {
+				s.mulMod(s.buckets[d], s.buckets[d], s.table[i], m)
+			} else {
+				s.buckets[d].Set(s.table[i])
+				used[d] = true
+			}
+		}
+
+		// total = prod bucket[d]**d, computed as the product of the
+		// running products from the highest bucket down
+		running.SetInt64(1)
+		total.SetInt64(1)
+		for d := size - 1; d > 0; d-- {
+			if used[d] {
+				s.mulMod(running, running, s.buckets[d], m)
+			}
+			s.mulMod(total, total, running, m)
+		}
+		s.mulMod(acc, acc, total, m)
+	}
+	return acc
+}
+
+// window returns the w bits of e starting at bit pos.
+func window(e *big.Int, pos, w int) uint {
+	var d uint
+	for k := w - 1; k >= 0; k-- {
+		d = d<<1 | e.Bit(pos+k)
+	}
+	return d
+}
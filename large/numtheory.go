@@ -0,0 +1,256 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package large
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+	jww "github.com/spf13/jwalterweatherman"
+)
+
+// maxNonResidueSearch bounds the search for a quadratic non-residue in
+// ModSqrt. For a prime p the smallest non-residue is far below it.
+const maxNonResidueSearch = 1 << 16
+
+////////////////////////////////////////////////////////////////////////////////
+// Jacobi and Legendre Symbols                                                //
+////////////////////////////////////////////////////////////////////////////////
+
+// Jacobi returns the Jacobi symbol (x/y), either +1, -1 or 0. y must be odd
+// and positive; Jacobi panics otherwise.
+func Jacobi(x, y *Int) int {
+	if y.BigInt().Sign() <= 0 || y.BigInt().Bit(0) == 0 {
+		jww.FATAL.Panicf("large.Jacobi(): y must be odd and positive, "+
+			"received %s", y.Text(10))
+	}
+
+	a := new(big.Int).Mod(x.BigInt(), y.BigInt())
+	n := new(big.Int).Set(y.BigInt())
+	t := 1
+	for a.Sign() != 0 {
+		// Remove factors of two: (2/n) = -1 when n ≡ 3, 5 mod 8
+		twos := a.TrailingZeroBits()
+		a.Rsh(a, twos)
+		if r := n.Bits()[0] & 7; twos&1 == 1 && (r == 3 || r == 5) {
+			t = -t
+		}
+
+		// Quadratic reciprocity: flip the sign when both are 3 mod 4
+		a, n = n, a
+		if a.Bits()[0]&3 == 3 && n.Bits()[0]&3 == 3 {
+			t = -t
+		}
+		a.Mod(a, n)
+	}
+	if n.Cmp(big.NewInt(1)) == 0 {
+		return t
+	}
+	return 0
+}
+
+// Legendre returns the Legendre symbol (a/p) for an odd prime p: 1 if a is a
+// non-zero square mod p, -1 if it is not a square and 0 if p divides a.
+func Legendre(a, p *Int) int {
+	return Jacobi(a, p)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Modular Square Roots                                                       //
+////////////////////////////////////////////////////////////////////////////////
+
+// ModSqrt sets z to a square root of x mod p for a prime p and returns z. If x
+// is not a square mod p, z is unchanged and nil is returned. Primes
+// p ≡ 3 mod 4 use a single exponentiation; others use Tonelli-Shanks.
+func (z *Int) ModSqrt(x, p *Int) *Int {
+	pb := p.BigInt()
+	if pb.Cmp(big.NewInt(2)) < 0 {
+		jww.FATAL.Panicf("large.Int.ModSqrt(): modulus must be prime")
+	}
+	a := new(big.Int).Mod(x.BigInt(), pb)
+
+	var root *big.Int
+	switch {
+	case a.Sign() == 0 || pb.Cmp(big.NewInt(2)) == 0:
+		root = a
+	case pb.Bit(0) == 0:
+		return nil
+	case Jacobi(NewIntFromBigInt(a), p) != 1:
+		return nil
+	case pb.Bits()[0]&3 == 3:
+		root = sqrt3Mod4(a, pb)
+	default:
+		root = tonelliShanks(a, pb)
+	}
+
+	// Composite moduli can produce a wrong answer or none, so check it
+	if root == nil || new(big.Int).Exp(root, big.NewInt(2), pb).Cmp(a) != 0 {
+		return nil
+	}
+	return z.SetBigInt(root)
+}
+
+// sqrt3Mod4 returns a^((p+1)/4) mod p, a square root of the residue a for a
+// prime p ≡ 3 mod 4.
+func sqrt3Mod4(a, p *big.Int) *big.Int {
+	e := new(big.Int).Add(p, big.NewInt(1))
+	e.Rsh(e, 2)
+	return new(big.Int).Exp(a, e, p)
+}
+
+// tonelliShanks returns a square root of the residue a mod the odd prime p,
+// or nil if the search fails because p is not prime.
+func tonelliShanks(a, p *big.Int) *big.Int {
+	// p - 1 = q·2^s with q odd
+	pMinus1 := new(big.Int).Sub(p, big.NewInt(1))
+	s := pMinus1.TrailingZeroBits()
+	q := new(big.Int).Rsh(pMinus1, s)
+
+	// Find a quadratic non-residue
+	nonResidue := big.NewInt(2)
+	for Jacobi(NewIntFromBigInt(nonResidue), NewIntFromBigInt(p)) != -1 {
+		nonResidue.Add(nonResidue, big.NewInt(1))
+		if nonResidue.Cmp(big.NewInt(maxNonResidueSearch)) > 0 ||
+			nonResidue.Cmp(p) >= 0 {
+			return nil
+		}
+	}
+
+	m := s
+	c := new(big.Int).Exp(nonResidue, q, p)
+	t := new(big.Int).Exp(a, q, p)
+	e := new(big.Int).Add(q, big.NewInt(1))
+	r := new(big.Int).Exp(a, e.Rsh(e, 1), p)
+	one := big.NewInt(1)
+	tmp := new(big.Int)
+	for t.Cmp(one) != 0 {
+		// Find the least i with t^(2^i) = 1
+		i := uint(0)
+		tmp.Set(t)
+		for tmp.Cmp(one) != 0 {
+			tmp.Mul(tmp, tmp).Mod(tmp, p)
+			i++
+			if i >= m {
+				return nil
+			}
+		}
+
+		// b = c^(2^(m-i-1))
+		b := new(big.Int).Set(c)
+		for k := uint(0); k < m-i-1; k++ {
+			b.Mul(b, b).Mod(b, p)
+		}
+		m = i
+		c.Mul(b, b).Mod(c, p)
+		t.Mul(t, c).Mod(t, p)
+		r.Mul(r, b).Mod(r, p)
+	}
+	return r
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Chinese Remainder Theorem                                                  //
+////////////////////////////////////////////////////////////////////////////////
+
+// CRT returns the smallest non-negative x with x ≡ residues[i] mod moduli[i]
+// for every i, and the least common multiple of the moduli, so that every
+// solution is x plus a multiple of it. The moduli must be positive but need
+// not be coprime; an error is returned if the congruences are inconsistent.
+func CRT(residues, moduli []*Int) (x, lcm *Int, err error) {
+	if len(residues) != len(moduli) {
+		return nil, nil, errors.Errorf("%d residues but %d moduli",
+			len(residues), len(moduli))
+	}
+	if len(moduli) == 0 {
+		return nil, nil, errors.New("no congruences to combine")
+	}
+
+	// Combine the congruences one at a time: for x ≡ a mod m and
+	// x ≡ b mod n with g = gcd(m, n), x = a + m·k where
+	// k ≡ (b-a)/g · (m/g)⁻¹ mod n/g
+	a := new(big.Int)
+	m := big.NewInt(1)
+	g, mg, ng, k := new(big.Int), new(big.Int), new(big.Int), new(big.Int)
+	for i := range moduli {
+		n := moduli[i].BigInt()
+		if n.Sign() <= 0 {
+			return nil, nil, errors.Errorf("modulus %d is not positive", i)
+		}
+		b := new(big.Int).Mod(residues[i].BigInt(), n)
+
+		g.GCD(nil, nil, m, n)
+		diff := new(big.Int).Sub(b, a)
+		if new(big.Int).Mod(diff, g).Sign() != 0 {
+			return nil, nil, errors.Errorf("congruence %d is inconsistent "+
+				"with the previous ones", i)
+		}
+		mg.Quo(m, g)
+		ng.Quo(n, g)
+		diff.Quo(diff, g)
+		if ng.Cmp(big.NewInt(1)) == 0 {
+			k.SetInt64(0)
+		} else {
+			k.ModInverse(mg, ng)
+			k.Mul(k, diff).Mod(k, ng)
+		}
+
+		a.Add(a, k.Mul(k, m))
+		m.Mul(m, ng)
+		a.Mod(a, m)
+	}
+	return NewIntFromBigInt(a), NewIntFromBigInt(m), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Batch Inversion                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+// BatchModInverse returns the inverse mod m of every value using Montgomery's
+// trick, which replaces n inversions with one inversion and 3(n-1)
+// multiplications. It returns an error naming the first value with no
+// inverse.
+func BatchModInverse(xs []*Int, m *Int) ([]*Int, error) {
+	mb := m.BigInt()
+	if mb.Sign() <= 0 {
+		return nil, errors.New("modulus must be positive")
+	}
+	if len(xs) == 0 {
+		return []*Int{}, nil
+	}
+
+	// prefix[i] = x_0·…·x_i mod m
+	prefix := make([]*big.Int, len(xs))
+	acc := big.NewInt(1)
+	for i, x := range xs {
+		acc = new(big.Int).Mul(acc, x.BigInt())
+		acc.Mod(acc, mb)
+		prefix[i] = acc
+	}
+
+	inv := new(big.Int).ModInverse(prefix[len(xs)-1], mb)
+	if inv == nil {
+		// Find the culprit to report it
+		for i, x := range xs {
+			if new(big.Int).ModInverse(x.BigInt(), mb) == nil {
+				return nil, errors.Errorf("value %d has no inverse mod m", i)
+			}
+		}
+		return nil, errors.New("product has no inverse mod m")
+	}
+
+	// Walk back: inv holds (x_0·…·x_i)⁻¹, so x_i⁻¹ = inv·prefix[i-1] and
+	// multiplying by x_i leaves (x_0·…·x_{i-1})⁻¹
+	results := make([]*Int, len(xs))
+	for i := len(xs) - 1; i > 0; i-- {
+		r := new(big.Int).Mul(inv, prefix[i-1])
+		results[i] = NewIntFromBigInt(r.Mod(r, mb))
+		inv.Mul(inv, xs[i].BigInt()).Mod(inv, mb)
+	}
+	results[0] = NewIntFromBigInt(inv)
+	return results, nil
+}
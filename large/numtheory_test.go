@@ -0,0 +1,255 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package large
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// testPrimes returns primes covering p ≡ 3 mod 4, p ≡ 1 mod 4 with small and
+// large powers of two dividing p-1, and large random primes.
+func testPrimes(prng *rand.Rand) []*big.Int {
+	primes := []*big.Int{
+		big.NewInt(3), big.NewInt(5), big.NewInt(7), big.NewInt(13),
+		big.NewInt(17), big.NewInt(97), big.NewInt(257), big.NewInt(65537),
+		big.NewInt(1000003), big.NewInt(998244353),
+		// 2^255 - 19 ≡ 5 mod 8
+		new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255),
+			big.NewInt(19)),
+	}
+	for _, bits := range []int{128, 512} {
+		limit := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+		for {
+			p := new(big.Int).Rand(prng, limit)
+			if p.ProbablyPrime(20) {
+				primes = append(primes, p)
+				break
+			}
+		}
+	}
+	return primes
+}
+
+// Tests Jacobi against math/big for random values and odd moduli, including
+// negative and composite values.
+func TestJacobi(t *testing.T) {
+	prng := rand.New(rand.NewSource(31))
+	for i := 0; i < 2000; i++ {
+		y := big.NewInt(prng.Int63n(1<<20)*2 + 1)
+		x := big.NewInt(prng.Int63n(1<<22) - 1<<21)
+		if i%4 == 0 {
+			y = new(big.Int).Rand(prng, new(big.Int).Lsh(big.NewInt(1), 600))
+			y.SetBit(y, 0, 1)
+			x = new(big.Int).Rand(prng, new(big.Int).Lsh(big.NewInt(1), 700))
+		}
+		expected := big.Jacobi(x, y)
+		if got := Jacobi(NewIntFromBigInt(x), NewIntFromBigInt(y)); got !=
+			expected {
+			t.Fatalf("Jacobi(%s, %s) = %d, expected %d", x, y, got, expected)
+		}
+	}
+}
+
+// Tests that Jacobi panics on an even modulus.
+func TestJacobi_EvenModulus(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Jacobi with an even modulus did not panic")
+		}
+	}()
+	Jacobi(NewInt(3), NewInt(10))
+}
+
+// Tests that Legendre identifies the squares modulo a small prime.
+func TestLegendre(t *testing.T) {
+	p := int64(23)
+	squares := map[int64]bool{}
+	for x := int64(1); x < p; x++ {
+		squares[x*x%p] = true
+	}
+	for a := int64(0); a < 2*p; a++ {
+		expected := -1
+		if a%p == 0 {
+			expected = 0
+		} else if squares[a%p] {
+			expected = 1
+		}
+		if got := Legendre(NewInt(a), NewInt(p)); got != expected {
+			t.Errorf("Legendre(%d, %d) = %d, expected %d", a, p, got,
+				expected)
+		}
+	}
+}
+
+// Tests ModSqrt against math/big for squares and non-squares modulo primes
+// of every residue class.
+func TestInt_ModSqrt(t *testing.T) {
+	prng := rand.New(rand.NewSource(32))
+	for _, p := range testPrimes(prng) {
+		for i := 0; i < 30; i++ {
+			x := new(big.Int).Rand(prng, p)
+			if i%2 == 0 {
+				x.Mul(x, x).Mod(x, p)
+			}
+			expected := new(big.Int).ModSqrt(x, p)
+
+			z := NewInt(-1)
+			got := z.ModSqrt(NewIntFromBigInt(x), NewIntFromBigInt(p))
+			if (got == nil) != (expected == nil) {
+				t.Fatalf("ModSqrt(%s, %s): square root exists is %t, "+
+					"expected %t", x, p, got != nil, expected != nil)
+			}
+			if got == nil {
+				if z.Int64() != -1 {
+					t.Errorf("ModSqrt changed z without a root")
+				}
+				continue
+			}
+			sq := new(big.Int).Mul(got.BigInt(), got.BigInt())
+			if sq.Mod(sq, p).Cmp(x) != 0 {
+				t.Errorf("ModSqrt(%s, %s) = %s is not a root", x, p,
+					got.BigInt())
+			}
+		}
+	}
+
+	if NewInt(0).ModSqrt(NewInt(0), NewInt(13)).Int64() != 0 ||
+		NewInt(0).ModSqrt(NewInt(3), NewInt(2)).Int64() != 1 {
+		t.Errorf("ModSqrt of the edge cases is wrong")
+	}
+	// Any root returned for a composite modulus must still be correct
+	if r := NewInt(0).ModSqrt(NewInt(4), NewInt(15)); r != nil &&
+		new(big.Int).Exp(r.BigInt(), big.NewInt(2),
+			big.NewInt(15)).Int64() != 4 {
+		t.Errorf("ModSqrt returned a wrong root for a composite modulus")
+	}
+}
+
+// Tests CRT with coprime moduli against direct checks of every congruence.
+func TestCRT(t *testing.T) {
+	prng := rand.New(rand.NewSource(33))
+	primes := testPrimes(prng)
+	for trial := 0; trial < 20; trial++ {
+		n := 1 + prng.Intn(5)
+		residues := make([]*Int, n)
+		moduli := make([]*Int, n)
+		product := big.NewInt(1)
+		for i := range moduli {
+			// Distinct primes, some squared, are pairwise coprime
+			m := new(big.Int).Set(primes[(trial+i)%len(primes)])
+			if (trial+i)%3 == 0 {
+				m.Mul(m, m)
+			}
+			moduli[i] = NewIntFromBigInt(m)
+			residues[i] = NewIntFromBigInt(new(big.Int).Rand(prng,
+				new(big.Int).Lsh(m, 2)))
+			product.Mul(product, m)
+		}
+
+		x, lcm, err := CRT(residues, moduli)
+		if err != nil {
+			t.Fatalf("CRT failed: %+v", err)
+		}
+		if lcm.BigInt().Cmp(product) != 0 {
+			t.Errorf("Combined modulus is %s, expected %s", lcm.BigInt(),
+				product)
+		}
+		if x.BigInt().Sign() < 0 || x.BigInt().Cmp(product) >= 0 {
+			t.Errorf("CRT result is not reduced")
+		}
+		for i := range moduli {
+			r1 := new(big.Int).Mod(x.BigInt(), moduli[i].BigInt())
+			r2 := new(big.Int).Mod(residues[i].BigInt(), moduli[i].BigInt())
+			if r1.Cmp(r2) != 0 {
+				t.Errorf("CRT result does not satisfy congruence %d", i)
+			}
+		}
+	}
+}
+
+// Tests CRT with moduli that share factors, both consistent and not.
+func TestCRT_NonCoprime(t *testing.T) {
+	// x ≡ 3 mod 4, x ≡ 1 mod 6 (both say x odd) → x ≡ 7 mod 12
+	x, lcm, err := CRT([]*Int{NewInt(3), NewInt(1)},
+		[]*Int{NewInt(4), NewInt(6)})
+	if err != nil || x.Int64() != 7 || lcm.Int64() != 12 {
+		t.Errorf("CRT = %v mod %v, %v; expected 7 mod 12", x, lcm, err)
+	}
+	// x ≡ 2 mod 4 and x ≡ 1 mod 6 disagree on parity
+	if _, _, err = CRT([]*Int{NewInt(2), NewInt(1)},
+		[]*Int{NewInt(4), NewInt(6)}); err == nil {
+		t.Errorf("Inconsistent congruences combined")
+	}
+	// Repeated and unit moduli
+	x, lcm, err = CRT([]*Int{NewInt(5), NewInt(12), NewInt(0)},
+		[]*Int{NewInt(7), NewInt(7), NewInt(1)})
+	if err != nil || x.Int64() != 5 || lcm.Int64() != 7 {
+		t.Errorf("CRT = %v mod %v, %v; expected 5 mod 7", x, lcm, err)
+	}
+	// Negative residues
+	x, _, err = CRT([]*Int{NewInt(-1), NewInt(-1)},
+		[]*Int{NewInt(5), NewInt(7)})
+	if err != nil || x.Int64() != 34 {
+		t.Errorf("CRT = %v, %v; expected 34", x, err)
+	}
+}
+
+// Error path: CRT rejects mismatched, empty and non-positive inputs.
+func TestCRT_Invalid(t *testing.T) {
+	if _, _, err := CRT([]*Int{NewInt(1)}, nil); err == nil {
+		t.Errorf("Mismatched lengths accepted")
+	}
+	if _, _, err := CRT(nil, nil); err == nil {
+		t.Errorf("Empty input accepted")
+	}
+	if _, _, err := CRT([]*Int{NewInt(1)}, []*Int{NewInt(0)}); err == nil {
+		t.Errorf("Zero modulus accepted")
+	}
+}
+
+// Tests BatchModInverse against math/big.
+func TestBatchModInverse(t *testing.T) {
+	prng := rand.New(rand.NewSource(34))
+	primes := testPrimes(prng)
+	p := primes[len(primes)-1]
+	for _, n := range []int{0, 1, 2, 100} {
+		xs := make([]*Int, n)
+		for i := range xs {
+			x := new(big.Int).Rand(prng, p)
+			x.Add(x, big.NewInt(1))
+			xs[i] = NewIntFromBigInt(x)
+		}
+		invs, err := BatchModInverse(xs, NewIntFromBigInt(p))
+		if err != nil {
+			t.Fatalf("BatchModInverse failed: %+v", err)
+		}
+		if len(invs) != n {
+			t.Fatalf("Received %d inverses, expected %d", len(invs), n)
+		}
+		for i := range xs {
+			expected := new(big.Int).ModInverse(xs[i].BigInt(), p)
+			if invs[i].BigInt().Cmp(expected) != 0 {
+				t.Errorf("Inverse %d of %d is wrong", i, n)
+			}
+		}
+	}
+}
+
+// Error path: BatchModInverse names the first value without an inverse.
+func TestBatchModInverse_NotInvertible(t *testing.T) {
+	xs := []*Int{NewInt(2), NewInt(7), NewInt(5), NewInt(10)}
+	_, err := BatchModInverse(xs, NewInt(15))
+	if err == nil || err.Error() != "value 2 has no inverse mod m" {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if _, err = BatchModInverse(xs, NewInt(0)); err == nil {
+		t.Errorf("Zero modulus accepted")
+	}
+}
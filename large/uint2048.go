@@ -0,0 +1,144 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package large
+
+import "math/big"
+
+// Uint2048Limbs is the number of 64-bit limbs in a Uint2048.
+const Uint2048Limbs = 32
+
+// uint2048Words is the number of big.Words in a Uint2048.
+const uint2048Words = Uint2048Limbs * 64 / _W
+
+// Uint2048 is an unsigned 2048-bit integer held in a fixed array of 64-bit
+// limbs, least significant first. Unlike Int it never allocates, so it can
+// live on the stack in hot loops. The zero value is zero, and Add, Sub and
+// Mul wrap around modulo 2^2048 like the built-in unsigned types.
+type Uint2048 [Uint2048Limbs]uint64
+
+// SetUint64 sets z to v and returns z.
+func (z *Uint2048) SetUint64(v uint64) *Uint2048 {
+	*z = Uint2048{v}
+	return z
+}
+
+// SetInt sets z to x. It returns an error, leaving z unchanged, if x is
+// negative or longer than 2048 bits.
+func (z *Uint2048) SetInt(x *Int) error {
+	var tmp Uint2048
+	if err := setIntLimbs(tmp[:], x); err != nil {
+		return err
+	}
+	*z = tmp
+	return nil
+}
+
+// Int returns x as a new Int.
+func (x *Uint2048) Int() *Int {
+	return NewIntFromBigInt(limbsToBig(x[:]))
+}
+
+// SetBytes sets z to the big-endian unsigned integer in buf. It returns an
+// error, leaving z unchanged, if the value is longer than 2048 bits.
+func (z *Uint2048) SetBytes(buf []byte) error {
+	var tmp Uint2048
+	if err := setBytesLimbs(tmp[:], buf); err != nil {
+		return err
+	}
+	*z = tmp
+	return nil
+}
+
+// Bytes returns x as 256 big-endian bytes.
+func (x *Uint2048) Bytes() []byte {
+	buf := make([]byte, 8*Uint2048Limbs)
+	fillBytesLimbs(buf, x[:])
+	return buf
+}
+
+// Cmp compares x and y and returns -1, 0 or +1.
+func (x *Uint2048) Cmp(y *Uint2048) int {
+	return cmpLimbs(x[:], y[:])
+}
+
+// IsZero returns true if x is zero.
+func (x *Uint2048) IsZero() bool {
+	return *x == Uint2048{}
+}
+
+// BitLen returns the number of bits needed to represent x.
+func (x *Uint2048) BitLen() int {
+	return bitLenLimbs(x[:])
+}
+
+// Add sets z = x + y mod 2^2048 and returns the carry out of the top bit.
+func (z *Uint2048) Add(x, y *Uint2048) uint64 {
+	return addLimbs(z[:], x[:], y[:])
+}
+
+// Sub sets z = x - y mod 2^2048 and returns the borrow out of the top bit.
+func (z *Uint2048) Sub(x, y *Uint2048) uint64 {
+	return subLimbs(z[:], x[:], y[:])
+}
+
+// Mul sets z = x·y mod 2^2048 and returns z. Uint4096.MulWide returns the
+// full product.
+func (z *Uint2048) Mul(x, y *Uint2048) *Uint2048 {
+	var tmp Uint2048
+	mulLimbs(tmp[:], x[:], y[:])
+	*z = tmp
+	return z
+}
+
+// ModMul sets z = x·y mod m and returns z. x and y need not be reduced.
+func (z *Uint2048) ModMul(x, y *Uint2048, m *Modulus2048) *Uint2048 {
+	var xw, yw, zw [uint2048Words]big.Word
+	var t [uint2048Words + 1]big.Word
+	limbsToWords(xw[:], x[:])
+	limbsToWords(yw[:], y[:])
+	m.mod.mulUnreduced(zw[:], xw[:], yw[:], t[:])
+	wordsToLimbs(z[:], zw[:])
+	return z
+}
+
+// ModExp sets z = x**e mod m and returns z. x need not be reduced. The
+// running time does not depend on e or x; every exponent is processed at the
+// full 2048 bit width.
+func (z *Uint2048) ModExp(x, e *Uint2048, m *Modulus2048) *Uint2048 {
+	var xw, zw [uint2048Words]big.Word
+	var scratch [(1<<expWindow+2)*uint2048Words + 2]big.Word
+	var eb [8 * Uint2048Limbs]byte
+	limbsToWords(xw[:], x[:])
+	fillBytesLimbs(eb[:], e[:])
+	m.mod.expUnreduced(zw[:], xw[:], eb[:], scratch[:])
+	wordsToLimbs(z[:], zw[:])
+	return z
+}
+
+// Modulus2048 is an odd modulus of up to 2048 bits with its precomputed
+// Montgomery constants, for use with ModMul and ModExp. It is read-only after
+// creation and safe for concurrent use.
+type Modulus2048 struct {
+	m   Uint2048
+	mod *Modulus
+}
+
+// NewModulus2048 precomputes the Montgomery constants for m, which must be
+// odd and greater than one.
+func NewModulus2048(m *Uint2048) (*Modulus2048, error) {
+	mod, err := newFixedModulus(m[:])
+	if err != nil {
+		return nil, err
+	}
+	return &Modulus2048{m: *m, mod: mod}, nil
+}
+
+// Modulus returns the modulus.
+func (m *Modulus2048) Modulus() Uint2048 {
+	return m.m
+}
@@ -0,0 +1,159 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package large
+
+import "math/big"
+
+// Uint4096Limbs is the number of 64-bit limbs in a Uint4096.
+const Uint4096Limbs = 64
+
+// uint4096Words is the number of big.Words in a Uint4096.
+const uint4096Words = Uint4096Limbs * 64 / _W
+
+// Uint4096 is an unsigned 4096-bit integer held in a fixed array of 64-bit
+// limbs, least significant first. Unlike Int it never allocates, so it can
+// live on the stack in hot loops. The zero value is zero, and Add, Sub and
+// Mul wrap around modulo 2^4096 like the built-in unsigned types.
+type Uint4096 [Uint4096Limbs]uint64
+
+// SetUint64 sets z to v and returns z.
+func (z *Uint4096) SetUint64(v uint64) *Uint4096 {
+	*z = Uint4096{v}
+	return z
+}
+
+// SetInt sets z to x. It returns an error, leaving z unchanged, if x is
+// negative or longer than 4096 bits.
+func (z *Uint4096) SetInt(x *Int) error {
+	var tmp Uint4096
+	if err := setIntLimbs(tmp[:], x); err != nil {
+		return err
+	}
+	*z = tmp
+	return nil
+}
+
+// Int returns x as a new Int.
+func (x *Uint4096) Int() *Int {
+	return NewIntFromBigInt(limbsToBig(x[:]))
+}
+
+// SetBytes sets z to the big-endian unsigned integer in buf. It returns an
+// error, leaving z unchanged, if the value is longer than 4096 bits.
+func (z *Uint4096) SetBytes(buf []byte) error {
+	var tmp Uint4096
+	if err := setBytesLimbs(tmp[:], buf); err != nil {
+		return err
+	}
+	*z = tmp
+	return nil
+}
+
+// Bytes returns x as 512 big-endian bytes.
+func (x *Uint4096) Bytes() []byte {
+	buf := make([]byte, 8*Uint4096Limbs)
+	fillBytesLimbs(buf, x[:])
+	return buf
+}
+
+// Cmp compares x and y and returns -1, 0 or +1.
+func (x *Uint4096) Cmp(y *Uint4096) int {
+	return cmpLimbs(x[:], y[:])
+}
+
+// IsZero returns true if x is zero.
+func (x *Uint4096) IsZero() bool {
+	return *x == Uint4096{}
+}
+
+// BitLen returns the number of bits needed to represent x.
+func (x *Uint4096) BitLen() int {
+	return bitLenLimbs(x[:])
+}
+
+// Add sets z = x + y mod 2^4096 and returns the carry out of the top bit.
+func (z *Uint4096) Add(x, y *Uint4096) uint64 {
+	return addLimbs(z[:], x[:], y[:])
+}
+
+// Sub sets z = x - y mod 2^4096 and returns the borrow out of the top bit.
+func (z *Uint4096) Sub(x, y *Uint4096) uint64 {
+	return subLimbs(z[:], x[:], y[:])
+}
+
+// Mul sets z = x·y mod 2^4096 and returns z.
+func (z *Uint4096) Mul(x, y *Uint4096) *Uint4096 {
+	var tmp Uint4096
+	mulLimbs(tmp[:], x[:], y[:])
+	*z = tmp
+	return z
+}
+
+// MulWide sets z to the full 4096-bit product x·y of two Uint2048 and returns
+// z.
+func (z *Uint4096) MulWide(x, y *Uint2048) *Uint4096 {
+	mulLimbs(z[:], x[:], y[:])
+	return z
+}
+
+// ModMul sets z = x·y mod m and returns z. x and y need not be reduced.
+func (z *Uint4096) ModMul(x, y *Uint4096, m *Modulus4096) *Uint4096 {
+	var xw, yw, zw [uint4096Words]big.Word
+	var t [uint4096Words + 1]big.Word
+	limbsToWords(xw[:], x[:])
+	limbsToWords(yw[:], y[:])
+	m.mod.mulUnreduced(zw[:], xw[:], yw[:], t[:])
+	wordsToLimbs(z[:], zw[:])
+	return z
+}
+
+// ModExp sets z = x**e mod m and returns z. x need not be reduced. The
+// running time does not depend on e or x; every exponent is processed at the
+// full 4096 bit width.
+func (z *Uint4096) ModExp(x, e *Uint4096, m *Modulus4096) *Uint4096 {
+	var xw, zw [uint4096Words]big.Word
+	var scratch [(1<<expWindow+2)*uint4096Words + 2]big.Word
+	var eb [8 * Uint4096Limbs]byte
+	limbsToWords(xw[:], x[:])
+	fillBytesLimbs(eb[:], e[:])
+	m.mod.expUnreduced(zw[:], xw[:], eb[:], scratch[:])
+	wordsToLimbs(z[:], zw[:])
+	return z
+}
+
+// MaxUint4096 is the largest Uint4096, 2^4096 - 1.
+var MaxUint4096 = func() Uint4096 {
+	var max Uint4096
+	for i := range max {
+		max[i] = ^uint64(0)
+	}
+	return max
+}()
+
+// Modulus4096 is an odd modulus of up to 4096 bits with its precomputed
+// Montgomery constants, for use with ModMul and ModExp. It is read-only after
+// creation and safe for concurrent use.
+type Modulus4096 struct {
+	m   Uint4096
+	mod *Modulus
+}
+
+// NewModulus4096 precomputes the Montgomery constants for m, which must be
+// odd and greater than one.
+func NewModulus4096(m *Uint4096) (*Modulus4096, error) {
+	mod, err := newFixedModulus(m[:])
+	if err != nil {
+		return nil, err
+	}
+	return &Modulus4096{m: *m, mod: mod}, nil
+}
+
+// Modulus returns the modulus.
+func (m *Modulus4096) Modulus() Uint4096 {
+	return m.m
+}
@@ -0,0 +1,299 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package merkle
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+	"gitlab.com/xx_network/crypto/hasher"
+)
+
+// ErrInvalidProof is returned, wrapped with the reason, when a proof does not
+// verify against the given roots.
+var ErrInvalidProof = errors.New("invalid Merkle proof")
+
+// InclusionProof proves that the leaf at Index is in the tree of Size leaves.
+// Path holds the sibling subtree hashes from the leaf up to the root. A root
+// does not commit to its size, so Size must come from the same trusted
+// source as the root, such as a signed tree head.
+type InclusionProof struct {
+	Hash  hasher.HashType
+	Index uint64
+	Size  uint64
+	Path  [][]byte
+}
+
+// ConsistencyProof proves that the tree of NewSize leaves extends the tree of
+// OldSize leaves, which is to say that its first OldSize leaves are the
+// leaves of the older tree. As with InclusionProof, the sizes must come from
+// the same trusted source as the roots.
+type ConsistencyProof struct {
+	Hash    hasher.HashType
+	OldSize uint64
+	NewSize uint64
+	Path    [][]byte
+}
+
+// Verify checks that the leaf with the given data is at the proof's index in
+// the tree with the given root.
+func (p *InclusionProof) Verify(data, root []byte) error {
+	if !p.Hash.Available() {
+		return errors.Errorf("unknown hash type %d", uint8(p.Hash))
+	}
+	return p.VerifyLeafHash(HashLeaf(p.Hash, data), root)
+}
+
+// VerifyLeafHash checks that the leaf with the given hash is at the proof's
+// index in the tree with the given root. It follows RFC 9162 section
+// 2.1.3.2.
+func (p *InclusionProof) VerifyLeafHash(leafHash, root []byte) error {
+	if err := checkHashes(p.Hash, p.Path, leafHash, root); err != nil {
+		return err
+	}
+	if p.Index >= p.Size {
+		return errors.Wrapf(ErrInvalidProof, "leaf %d is not in a tree of %d "+
+			"leaves", p.Index, p.Size)
+	}
+
+	fn, sn := p.Index, p.Size-1
+	r := leafHash
+	for _, node := range p.Path {
+		if sn == 0 {
+			return errors.Wrap(ErrInvalidProof, "path is too long")
+		}
+		if fn&1 == 1 || fn == sn {
+			r = HashNode(p.Hash, node, r)
+			for fn&1 == 0 && fn != 0 {
+				fn, sn = fn>>1, sn>>1
+			}
+		} else {
+			r = HashNode(p.Hash, r, node)
+		}
+		fn, sn = fn>>1, sn>>1
+	}
+	if sn != 0 {
+		return errors.Wrap(ErrInvalidProof, "path is too short")
+	}
+	if !bytes.Equal(r, root) {
+		return errors.Wrap(ErrInvalidProof, "root does not match")
+	}
+	return nil
+}
+
+// Verify checks that the tree with newRoot extends the tree with oldRoot. It
+// follows RFC 9162 section 2.1.4.2. Every tree extends the empty tree, and a
+// tree extends itself, with an empty path.
+func (p *ConsistencyProof) Verify(oldRoot, newRoot []byte) error {
+	if err := checkHashes(p.Hash, p.Path, oldRoot, newRoot); err != nil {
+		return err
+	}
+	switch {
+	case p.OldSize > p.NewSize:
+		return errors.Wrapf(ErrInvalidProof, "old size %d is larger than "+
+			"new size %d", p.OldSize, p.NewSize)
+	case p.OldSize == 0 || p.OldSize == p.NewSize:
+		if len(p.Path) != 0 {
+			return errors.Wrap(ErrInvalidProof, "path is too long")
+		}
+		expected := newRoot
+		if p.OldSize == 0 {
+			expected = emptyRoot(p.Hash)
+		}
+		if !bytes.Equal(oldRoot, expected) {
+			return errors.Wrap(ErrInvalidProof, "old root does not match")
+		}
+		return nil
+	case len(p.Path) == 0:
+		return errors.Wrap(ErrInvalidProof, "path is empty")
+	}
+
+	// A complete old tree is a node of the new one and is not in the path
+	path := p.Path
+	if p.OldSize&(p.OldSize-1) == 0 {
+		path = append([][]byte{oldRoot}, path...)
+	}
+
+	fn, sn := p.OldSize-1, p.NewSize-1
+	for fn&1 == 1 {
+		fn, sn = fn>>1, sn>>1
+	}
+	fr, sr := path[0], path[0]
+	for _, c := range path[1:] {
+		if sn == 0 {
+			return errors.Wrap(ErrInvalidProof, "path is too long")
+		}
+		if fn&1 == 1 || fn == sn {
+			fr = HashNode(p.Hash, c, fr)
+			sr = HashNode(p.Hash, c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn, sn = fn>>1, sn>>1
+			}
+		} else {
+			sr = HashNode(p.Hash, sr, c)
+		}
+		fn, sn = fn>>1, sn>>1
+	}
+	if sn != 0 {
+		return errors.Wrap(ErrInvalidProof, "path is too short")
+	}
+	if !bytes.Equal(fr, oldRoot) {
+		return errors.Wrap(ErrInvalidProof, "old root does not match")
+	}
+	if !bytes.Equal(sr, newRoot) {
+		return errors.Wrap(ErrInvalidProof, "new root does not match")
+	}
+	return nil
+}
+
+// checkHashes returns an error if h is not registered or any hash does not
+// have its digest size.
+func checkHashes(h hasher.HashType, path [][]byte, hashes ...[]byte) error {
+	if !h.Available() {
+		return errors.Errorf("unknown hash type %d", uint8(h))
+	}
+	size := h.Size()
+	for _, hash := range append(hashes, path...) {
+		if len(hash) != size {
+			return errors.Wrapf(ErrInvalidProof, "hash has %d bytes, "+
+				"expected %d", len(hash), size)
+		}
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Serialization                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+// Proof encoding layout:
+//
+//	version (1 byte) | kind (1 byte) | hash type (1 byte) |
+//	first (uvarint) | second (uvarint) | count (1 byte) | count hashes
+//
+// First and second are the index and size of an inclusion proof, or the old
+// and new sizes of a consistency proof. The hashes are concatenated, each of
+// the hash type's digest size. The decoders reject any other encoding.
+const (
+	// ProofEncodingVersion is the version of the proof encoding.
+	ProofEncodingVersion = 1
+
+	inclusionKind   = 1
+	consistencyKind = 2
+)
+
+// MarshalBinary encodes the proof compactly. This function implements the
+// encoding.BinaryMarshaler interface.
+func (p *InclusionProof) MarshalBinary() ([]byte, error) {
+	return marshalProof(inclusionKind, p.Hash, p.Index, p.Size, p.Path)
+}
+
+// UnmarshalBinary decodes a proof made by MarshalBinary. It returns an error,
+// leaving p unchanged, for anything that is not the canonical encoding of an
+// inclusion proof. This function implements the encoding.BinaryUnmarshaler
+// interface.
+func (p *InclusionProof) UnmarshalBinary(data []byte) error {
+	h, index, size, path, err := unmarshalProof(inclusionKind, data)
+	if err != nil {
+		return err
+	}
+	*p = InclusionProof{Hash: h, Index: index, Size: size, Path: path}
+	return nil
+}
+
+// MarshalBinary encodes the proof compactly. This function implements the
+// encoding.BinaryMarshaler interface.
+func (p *ConsistencyProof) MarshalBinary() ([]byte, error) {
+	return marshalProof(consistencyKind, p.Hash, p.OldSize, p.NewSize, p.Path)
+}
+
+// UnmarshalBinary decodes a proof made by MarshalBinary. It returns an error,
+// leaving p unchanged, for anything that is not the canonical encoding of a
+// consistency proof. This function implements the encoding.BinaryUnmarshaler
+// interface.
+func (p *ConsistencyProof) UnmarshalBinary(data []byte) error {
+	h, oldSize, newSize, path, err := unmarshalProof(consistencyKind, data)
+	if err != nil {
+		return err
+	}
+	*p = ConsistencyProof{Hash: h, OldSize: oldSize, NewSize: newSize,
+		Path: path}
+	return nil
+}
+
+// marshalProof encodes either kind of proof.
+func marshalProof(kind byte, h hasher.HashType, first, second uint64,
+	path [][]byte) ([]byte, error) {
+	if !h.Available() {
+		return nil, errors.Errorf("unknown hash type %d", uint8(h))
+	}
+	if len(path) > 0xff {
+		return nil, errors.Errorf("path of %d hashes is too long", len(path))
+	}
+	size := h.Size()
+	buf := make([]byte, 3, 4+2*binary.MaxVarintLen64+len(path)*size)
+	buf[0], buf[1], buf[2] = ProofEncodingVersion, kind, byte(h)
+	buf = binary.AppendUvarint(buf, first)
+	buf = binary.AppendUvarint(buf, second)
+	buf = append(buf, byte(len(path)))
+	for _, node := range path {
+		if len(node) != size {
+			return nil, errors.Errorf("hash has %d bytes, expected %d",
+				len(node), size)
+		}
+		buf = append(buf, node...)
+	}
+	return buf, nil
+}
+
+// unmarshalProof strictly decodes either kind of proof.
+func unmarshalProof(kind byte, data []byte) (hasher.HashType, uint64, uint64,
+	[][]byte, error) {
+	if len(data) < 6 {
+		return 0, 0, 0, nil, errors.New("encoding too short")
+	}
+	if data[0] != ProofEncodingVersion {
+		return 0, 0, 0, nil, errors.Errorf("unsupported encoding version %d",
+			data[0])
+	}
+	if data[1] != kind {
+		return 0, 0, 0, nil, errors.Errorf("encoding is of proof kind %d, "+
+			"expected %d", data[1], kind)
+	}
+	h := hasher.HashType(data[2])
+	if !h.Available() {
+		return 0, 0, 0, nil, errors.Errorf("unknown hash type %d", data[2])
+	}
+
+	data = data[3:]
+	var fields [2]uint64
+	for i := range fields {
+		v, n := binary.Uvarint(data)
+		// A varint padded with continuation bytes is not canonical
+		if n <= 0 || n != len(binary.AppendUvarint(nil, v)) {
+			return 0, 0, 0, nil, errors.New("invalid varint")
+		}
+		fields[i], data = v, data[n:]
+	}
+
+	if len(data) == 0 {
+		return 0, 0, 0, nil, errors.New("missing path length")
+	}
+	count, size := int(data[0]), h.Size()
+	data = data[1:]
+	if len(data) != count*size {
+		return 0, 0, 0, nil, errors.Errorf("%d bytes do not hold %d hashes "+
+			"of %d bytes", len(data), count, size)
+	}
+	path := make([][]byte, count)
+	for i := range path {
+		path[i] = append([]byte{}, data[i*size:(i+1)*size]...)
+	}
+	return h, fields[0], fields[1], path, nil
+}
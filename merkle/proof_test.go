@@ -0,0 +1,346 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package merkle
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/hex"
+	"strconv"
+	"testing"
+
+	"github.com/pkg/errors"
+	"gitlab.com/xx_network/crypto/hasher"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*InclusionProof)(nil)
+	_ encoding.BinaryUnmarshaler = (*InclusionProof)(nil)
+	_ encoding.BinaryMarshaler   = (*ConsistencyProof)(nil)
+	_ encoding.BinaryUnmarshaler = (*ConsistencyProof)(nil)
+)
+
+// checkPath compares a proof path to hex test vectors.
+func checkPath(t *testing.T, name string, path [][]byte, expected []string) {
+	if len(path) != len(expected) {
+		t.Errorf("%s has %d hashes, expected %d", name, len(path),
+			len(expected))
+		return
+	}
+	for i := range path {
+		if got := hex.EncodeToString(path[i]); got != expected[i] {
+			t.Errorf("%s hash %d = %s, expected %s", name, i, got, expected[i])
+		}
+	}
+}
+
+// Tests inclusion and consistency proofs against the Certificate Transparency
+// reference vectors.
+func TestProofs_Vectors(t *testing.T) {
+	tree := newTestTree(t)
+	inclusion := []struct {
+		index, size uint64
+		path        []string
+	}{
+		{0, 1, nil},
+		{0, 8, []string{
+			"96a296d224f285c67bee93c30f8a309157f0daa35dc5b87e410b78630a09cfc7",
+			"5f083f0a1a33ca076a95279832580db3e0ef4584bdff1f54c8a360f50de3031e",
+			"6b47aaf29ee3c2af9af889bc1fb9254dabd31177f16232dd6aab035ca39bf6e4"}},
+		{5, 8, []string{
+			"bc1a0643b12e4d2d7c77918f44e0f4f79a838b6cf9ec5b5c283e1f4d88599e6b",
+			"ca854ea128ed050b41b35ffc1b87b8eb2bde461e9e3b5596ece6b9d5975a0ae0",
+			"d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7"}},
+		{2, 3, []string{
+			"fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125"}},
+	}
+	for _, tt := range inclusion {
+		p, err := tree.InclusionProof(tt.index, tt.size)
+		if err != nil {
+			t.Fatalf("InclusionProof(%d, %d) error: %+v", tt.index, tt.size,
+				err)
+		}
+		checkPath(t, "inclusion proof", p.Path, tt.path)
+		root := decodeHex(t, testRoots[tt.size-1])
+		if err = p.Verify(decodeHex(t, testLeaves[tt.index]), root); err != nil {
+			t.Errorf("inclusion proof of %d in %d did not verify: %+v",
+				tt.index, tt.size, err)
+		}
+	}
+
+	consistency := []struct {
+		oldSize, newSize uint64
+		path             []string
+	}{
+		{1, 1, nil},
+		{1, 8, []string{
+			"96a296d224f285c67bee93c30f8a309157f0daa35dc5b87e410b78630a09cfc7",
+			"5f083f0a1a33ca076a95279832580db3e0ef4584bdff1f54c8a360f50de3031e",
+			"6b47aaf29ee3c2af9af889bc1fb9254dabd31177f16232dd6aab035ca39bf6e4"}},
+		{6, 8, []string{
+			"0ebc5d3437fbe2db158b9f126a1d118e308181031d0a949f8dededebc558ef6a",
+			"ca854ea128ed050b41b35ffc1b87b8eb2bde461e9e3b5596ece6b9d5975a0ae0",
+			"d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7"}},
+		{2, 5, []string{
+			"5f083f0a1a33ca076a95279832580db3e0ef4584bdff1f54c8a360f50de3031e",
+			"bc1a0643b12e4d2d7c77918f44e0f4f79a838b6cf9ec5b5c283e1f4d88599e6b"}},
+	}
+	for _, tt := range consistency {
+		p, err := tree.ConsistencyProof(tt.oldSize, tt.newSize)
+		if err != nil {
+			t.Fatalf("ConsistencyProof(%d, %d) error: %+v", tt.oldSize,
+				tt.newSize, err)
+		}
+		checkPath(t, "consistency proof", p.Path, tt.path)
+		err = p.Verify(decodeHex(t, testRoots[tt.oldSize-1]),
+			decodeHex(t, testRoots[tt.newSize-1]))
+		if err != nil {
+			t.Errorf("consistency proof from %d to %d did not verify: %+v",
+				tt.oldSize, tt.newSize, err)
+		}
+	}
+}
+
+// Tests that every inclusion proof in trees of up to 33 leaves verifies and
+// that a proof for one leaf or index does not verify another, nor a
+// truncated or extended proof.
+func TestInclusionProof_All(t *testing.T) {
+	for _, h := range []hasher.HashType{hasher.SHA2_256, hasher.BLAKE3} {
+		tree := newNumberedTree(t, h, 33)
+		for size := uint64(1); size <= 33; size++ {
+			root, _ := tree.RootAt(size)
+			for index := uint64(0); index < size; index++ {
+				p, err := tree.InclusionProof(index, size)
+				if err != nil {
+					t.Fatalf("InclusionProof(%d, %d) error: %+v", index,
+						size, err)
+				}
+				leaf := []byte(strconv.FormatUint(index, 10))
+				if err = p.Verify(leaf, root); err != nil {
+					t.Errorf("%s proof of %d in %d did not verify: %+v", h,
+						index, size, err)
+				}
+
+				if p.Verify([]byte("x"), root) == nil {
+					t.Errorf("proof verified the wrong leaf")
+				}
+				wrong := *p
+				wrong.Index = (index + 1) % (size + 1)
+				if wrong.Verify(leaf, root) == nil {
+					t.Errorf("proof of %d in %d verified at index %d", index,
+						size, wrong.Index)
+				}
+				if len(p.Path) > 0 {
+					wrong = *p
+					wrong.Path = p.Path[:len(p.Path)-1]
+					if wrong.Verify(leaf, root) == nil {
+						t.Errorf("truncated proof verified")
+					}
+				}
+				wrong = *p
+				wrong.Path = append(append([][]byte{}, p.Path...), root)
+				if wrong.Verify(leaf, root) == nil {
+					t.Errorf("extended proof verified")
+				}
+			}
+		}
+	}
+}
+
+// Tests that every consistency proof in trees of up to 33 leaves verifies and
+// that it does not verify other roots.
+func TestConsistencyProof_All(t *testing.T) {
+	for _, h := range []hasher.HashType{hasher.SHA2_256, hasher.BLAKE3} {
+		tree := newNumberedTree(t, h, 33)
+		for newSize := uint64(0); newSize <= 33; newSize++ {
+			newRoot, _ := tree.RootAt(newSize)
+			for oldSize := uint64(0); oldSize <= newSize; oldSize++ {
+				oldRoot, _ := tree.RootAt(oldSize)
+				p, err := tree.ConsistencyProof(oldSize, newSize)
+				if err != nil {
+					t.Fatalf("ConsistencyProof(%d, %d) error: %+v", oldSize,
+						newSize, err)
+				}
+				if err = p.Verify(oldRoot, newRoot); err != nil {
+					t.Errorf("%s proof from %d to %d did not verify: %+v", h,
+						oldSize, newSize, err)
+				}
+
+				if oldSize > 0 && oldSize < newSize {
+					wrongOld, _ := tree.RootAt(oldSize - 1)
+					if p.Verify(wrongOld, newRoot) == nil {
+						t.Errorf("proof from %d to %d verified the wrong "+
+							"old root", oldSize, newSize)
+					}
+					if p.Verify(oldRoot, oldRoot) == nil {
+						t.Errorf("proof from %d to %d verified the wrong "+
+							"new root", oldSize, newSize)
+					}
+					for i := range p.Path {
+						wrong := *p
+						wrong.Path = append([][]byte{}, p.Path...)
+						wrong.Path[i] = HashLeaf(h, p.Path[i])
+						if wrong.Verify(oldRoot, newRoot) == nil {
+							t.Errorf("proof from %d to %d verified with "+
+								"hash %d changed", oldSize, newSize, i)
+						}
+					}
+				}
+			}
+		}
+
+		// A tree that rewrote history is not consistent
+		forked, _ := New(h)
+		forked.Append([]byte("rewritten"))
+		for i := 1; i < 10; i++ {
+			forked.Append([]byte(strconv.Itoa(i)))
+		}
+		oldRoot, _ := tree.RootAt(5)
+		p, _ := forked.ConsistencyProof(5, 10)
+		if err := p.Verify(oldRoot, forked.Root()); !errors.Is(err,
+			ErrInvalidProof) {
+			t.Errorf("forked tree verified as consistent: %v", err)
+		}
+	}
+}
+
+// Tests the proof generation and verification errors.
+func TestProofs_Errors(t *testing.T) {
+	tree := newNumberedTree(t, hasher.SHA2_256, 5)
+	if _, err := tree.InclusionProof(5, 5); err == nil {
+		t.Errorf("InclusionProof() accepted an index past the size")
+	}
+	if _, err := tree.InclusionProof(0, 6); err == nil {
+		t.Errorf("InclusionProof() accepted a size past the tree")
+	}
+	if _, err := tree.ConsistencyProof(4, 3); err == nil {
+		t.Errorf("ConsistencyProof() accepted a decreasing size")
+	}
+	if _, err := tree.ConsistencyProof(0, 6); err == nil {
+		t.Errorf("ConsistencyProof() accepted a size past the tree")
+	}
+
+	root := tree.Root()
+	p, _ := tree.InclusionProof(1, 5)
+	if err := p.VerifyLeafHash(make([]byte, 31), root); err == nil {
+		t.Errorf("VerifyLeafHash() accepted a short leaf hash")
+	}
+	if err := p.Verify([]byte("1"), root[:31]); err == nil {
+		t.Errorf("Verify() accepted a short root")
+	}
+	unknown := *p
+	unknown.Hash = 200
+	if err := unknown.Verify([]byte("1"), root); err == nil {
+		t.Errorf("Verify() accepted an unknown hash")
+	}
+
+	c, _ := tree.ConsistencyProof(0, 5)
+	if err := c.Verify(root, root); err == nil {
+		t.Errorf("proof from the empty tree accepted a non-empty old root")
+	}
+	c, _ = tree.ConsistencyProof(5, 5)
+	old, _ := tree.RootAt(4)
+	if err := c.Verify(old, root); err == nil {
+		t.Errorf("proof between equal sizes accepted different roots")
+	}
+	c = &ConsistencyProof{Hash: hasher.SHA2_256, OldSize: 3, NewSize: 5}
+	if err := c.Verify(old, root); err == nil {
+		t.Errorf("empty consistency proof verified")
+	}
+}
+
+// Tests that proofs survive serialization and still verify.
+func TestProofs_MarshalBinary(t *testing.T) {
+	tree := newNumberedTree(t, hasher.BLAKE3, 21)
+	p, _ := tree.InclusionProof(13, 21)
+	data, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error: %+v", err)
+	}
+	if expected := 3 + 1 + 1 + 1 + len(p.Path)*32; len(data) != expected {
+		t.Errorf("encoding has %d bytes, expected %d", len(data), expected)
+	}
+	var decoded InclusionProof
+	if err = decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() error: %+v", err)
+	}
+	if err = decoded.Verify([]byte("13"), tree.Root()); err != nil {
+		t.Errorf("decoded inclusion proof did not verify: %+v", err)
+	}
+
+	c, _ := tree.ConsistencyProof(7, 21)
+	data, err = c.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error: %+v", err)
+	}
+	var decodedC ConsistencyProof
+	if err = decodedC.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() error: %+v", err)
+	}
+	oldRoot, _ := tree.RootAt(7)
+	if err = decodedC.Verify(oldRoot, tree.Root()); err != nil {
+		t.Errorf("decoded consistency proof did not verify: %+v", err)
+	}
+
+	// An inclusion proof is not a consistency proof
+	if err = decoded.UnmarshalBinary(data); err == nil {
+		t.Errorf("UnmarshalBinary() accepted the wrong kind of proof")
+	}
+
+	// Large indices use multi-byte varints
+	big := &InclusionProof{Hash: hasher.SHA2_256, Index: 1 << 40,
+		Size: 1<<40 + 1, Path: [][]byte{make([]byte, 32)}}
+	data, _ = big.MarshalBinary()
+	var decodedBig InclusionProof
+	if err = decodedBig.UnmarshalBinary(data); err != nil ||
+		decodedBig.Index != big.Index || decodedBig.Size != big.Size {
+		t.Errorf("large index round trip gave %+v: %v", decodedBig, err)
+	}
+}
+
+// Tests that the decoders reject every malformed encoding and leave the
+// proof unchanged.
+func TestProofs_UnmarshalBinary_Strict(t *testing.T) {
+	valid := []byte{1, 1, byte(hasher.SHA2_256), 1, 2, 1}
+	valid = append(valid, bytes.Repeat([]byte{7}, 32)...)
+	var p InclusionProof
+	if err := p.UnmarshalBinary(valid); err != nil {
+		t.Fatalf("UnmarshalBinary() rejected a valid encoding: %+v", err)
+	}
+
+	tests := map[string][]byte{
+		"empty":          {},
+		"too short":      valid[:5],
+		"wrong version":  append([]byte{2}, valid[1:]...),
+		"wrong kind":     append([]byte{1, 2}, valid[2:]...),
+		"unknown hash":   append([]byte{1, 1, 200}, valid[3:]...),
+		"padded varint":  append([]byte{1, 1, byte(hasher.SHA2_256), 0x81, 0x00, 2, 1}, valid[6:]...),
+		"no path length": {1, 1, byte(hasher.SHA2_256), 1, 2},
+		"short path":     valid[:len(valid)-1],
+		"trailing data":  append(append([]byte{}, valid...), 0),
+		"wrong count":    append([]byte{1, 1, byte(hasher.SHA2_256), 1, 2, 2}, valid[6:]...),
+	}
+	for name, data := range tests {
+		before := p
+		if err := p.UnmarshalBinary(data); err == nil {
+			t.Errorf("UnmarshalBinary() accepted the %s encoding", name)
+		}
+		if p.Index != before.Index || len(p.Path) != len(before.Path) {
+			t.Errorf("UnmarshalBinary() changed the proof for %s", name)
+		}
+	}
+
+	bad := &InclusionProof{Hash: hasher.SHA2_256, Path: [][]byte{{1}}}
+	if _, err := bad.MarshalBinary(); err == nil {
+		t.Errorf("MarshalBinary() accepted a short hash")
+	}
+	bad = &InclusionProof{Hash: 200}
+	if _, err := bad.MarshalBinary(); err == nil {
+		t.Errorf("MarshalBinary() accepted an unknown hash")
+	}
+}
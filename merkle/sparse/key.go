@@ -0,0 +1,92 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+// Package sparse implements a sparse Merkle tree over 256-bit keys for
+// authenticated key/value state, such as the public key registered for each
+// ID. A proof for a key shows either that it holds a value or that it is
+// absent, and is verified against a root alone, without the tree.
+//
+// The tree is the full binary tree of depth 256 with a leaf at the position
+// of every key, in which each subtree holding no key is replaced by a
+// placeholder of zero bytes and each subtree holding a single key is replaced
+// by that key's leaf. This makes the shape, and so the root, depend only on
+// the keys and values, while keeping paths about log₂(n) long. Leaves are
+// hashed as H(0x00 || key || H(value)) and interior nodes as
+// H(0x01 || left || right), as in package merkle. Proofs omit the
+// placeholder siblings, marking where they go in a bitmap.
+//
+// Nodes are kept in a Store, keyed by their hash, so the state may be kept
+// in memory with MemoryStore or in a database.
+package sparse
+
+import (
+	"hash"
+
+	jww "github.com/spf13/jwalterweatherman"
+	"gitlab.com/xx_network/crypto/hasher"
+	"gitlab.com/xx_network/crypto/merkle"
+	"gitlab.com/xx_network/primitives/id"
+)
+
+// KeySize is the size of a key in bytes.
+const KeySize = 32
+
+// Depth is the number of levels of the full tree, one for each key bit.
+const Depth = KeySize * 8
+
+// Key is the position of a value in the tree. Keys should be the output of a
+// hash, such as NewKey, so that they are evenly spread and paths stay short.
+type Key [KeySize]byte
+
+// NewKey returns the key for arbitrary data, its BLAKE2b-256 hash.
+func NewKey(data []byte) Key {
+	h := newHash(hasher.BLAKE2)
+	h.Write(data)
+	var key Key
+	copy(key[:], h.Sum(nil))
+	return key
+}
+
+// IDKey returns the key of an ID, the BLAKE2b-256 hash of its marshalled
+// form.
+func IDKey(id *id.ID) Key {
+	return NewKey(id.Marshal())
+}
+
+// bit returns bit i of the key, counting from the most significant bit of
+// the first byte. It is the direction taken at depth i, 0 for left.
+func (k *Key) bit(i int) byte {
+	return k[i/8] >> (7 - uint(i%8)) & 1
+}
+
+// EmptyRoot returns the root of a tree holding no keys, which is the
+// placeholder of h.Size() zero bytes.
+func EmptyRoot(h hasher.HashType) []byte {
+	return make([]byte, h.Size())
+}
+
+// HashValue returns H(value), the value hash that a leaf commits to. It
+// panics if h is not registered.
+func HashValue(h hasher.HashType, value []byte) []byte {
+	hash := newHash(h)
+	hash.Write(value)
+	return hash.Sum(nil)
+}
+
+// hashLeaf returns the hash of the leaf for key with the given value hash.
+func hashLeaf(h hasher.HashType, key Key, valueHash []byte) []byte {
+	return merkle.HashLeaf(h, append(key[:], valueHash...))
+}
+
+// newHash returns a new instance of h, panicking if it is not registered.
+func newHash(h hasher.HashType) hash.Hash {
+	hash, err := h.New()
+	if err != nil {
+		jww.FATAL.Panicf("Failed to hash sparse Merkle tree node: %+v", err)
+	}
+	return hash
+}
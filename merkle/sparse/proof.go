@@ -0,0 +1,260 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package sparse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/bits"
+
+	"github.com/pkg/errors"
+	"gitlab.com/xx_network/crypto/hasher"
+	"gitlab.com/xx_network/crypto/merkle"
+)
+
+// ErrInvalidProof is returned, wrapped with the reason, when a proof does not
+// verify against the given root.
+var ErrInvalidProof = errors.New("invalid sparse Merkle proof")
+
+// Proof is the path from the root towards a key. It ends at the key's leaf,
+// proving the key's value; at the placeholder of an empty subtree, proving
+// the key is absent; or at the leaf of another key sharing the path, also
+// proving the key is absent.
+type Proof struct {
+	Hash hasher.HashType
+
+	// Depth is the length of the path
+	Depth int
+
+	// Bitmap has bit i, counting from the most significant bit of the first
+	// byte, set when the sibling at depth i is not a placeholder. It holds
+	// (Depth+7)/8 bytes.
+	Bitmap []byte
+
+	// Siblings holds the siblings that are not placeholders, from the root
+	// down
+	Siblings [][]byte
+
+	// Leaf is the leaf the path ends at, or nil if it ends at a placeholder
+	Leaf *Leaf
+}
+
+// Leaf is a leaf in a proof, which commits to the hash of its value.
+type Leaf struct {
+	Key       Key
+	ValueHash []byte
+}
+
+// VerifyMembership checks that key holds value in the tree with the given
+// root.
+func (p *Proof) VerifyMembership(root []byte, key Key, value []byte) error {
+	if err := p.check(root); err != nil {
+		return err
+	}
+	if p.Leaf == nil || p.Leaf.Key != key {
+		return errors.Wrap(ErrInvalidProof, "proof is of absence")
+	}
+	if !bytes.Equal(p.Leaf.ValueHash, HashValue(p.Hash, value)) {
+		return errors.Wrap(ErrInvalidProof, "value does not match")
+	}
+	return p.verify(root, key)
+}
+
+// VerifyNonMembership checks that key holds no value in the tree with the
+// given root.
+func (p *Proof) VerifyNonMembership(root []byte, key Key) error {
+	if err := p.check(root); err != nil {
+		return err
+	}
+	if p.Leaf != nil {
+		if p.Leaf.Key == key {
+			return errors.Wrap(ErrInvalidProof, "proof is of membership")
+		}
+		for i := 0; i < p.Depth; i++ {
+			if p.Leaf.Key.bit(i) != key.bit(i) {
+				return errors.Wrap(ErrInvalidProof, "leaf is not on the "+
+					"path of the key")
+			}
+		}
+	}
+	return p.verify(root, key)
+}
+
+// Exists reports whether the proof claims that key holds a value. The claim
+// still has to be verified.
+func (p *Proof) Exists(key Key) bool {
+	return p.Leaf != nil && p.Leaf.Key == key
+}
+
+// check returns an error if the proof is malformed.
+func (p *Proof) check(root []byte) error {
+	if !p.Hash.Available() {
+		return errors.Errorf("unknown hash type %d", uint8(p.Hash))
+	}
+	size := p.Hash.Size()
+	if len(root) != size {
+		return errors.Wrapf(ErrInvalidProof, "root has %d bytes, expected %d",
+			len(root), size)
+	}
+	if p.Depth < 0 || p.Depth > Depth {
+		return errors.Wrapf(ErrInvalidProof, "depth %d is out of range",
+			p.Depth)
+	}
+	if len(p.Bitmap) != (p.Depth+7)/8 {
+		return errors.Wrapf(ErrInvalidProof, "bitmap has %d bytes for a "+
+			"depth of %d", len(p.Bitmap), p.Depth)
+	}
+	if p.Depth%8 != 0 && p.Bitmap[len(p.Bitmap)-1]<<uint(p.Depth%8) != 0 {
+		return errors.Wrap(ErrInvalidProof, "bitmap has bits past the depth")
+	}
+	count := 0
+	for _, b := range p.Bitmap {
+		count += bits.OnesCount8(b)
+	}
+	if count != len(p.Siblings) {
+		return errors.Wrapf(ErrInvalidProof, "bitmap marks %d siblings, "+
+			"proof has %d", count, len(p.Siblings))
+	}
+	for _, sibling := range p.Siblings {
+		if len(sibling) != size {
+			return errors.Wrapf(ErrInvalidProof, "sibling has %d bytes, "+
+				"expected %d", len(sibling), size)
+		}
+	}
+	if p.Leaf != nil && len(p.Leaf.ValueHash) != size {
+		return errors.Wrapf(ErrInvalidProof, "value hash has %d bytes, "+
+			"expected %d", len(p.Leaf.ValueHash), size)
+	}
+	return nil
+}
+
+// verify hashes from the end of the path up along key and compares the
+// result to root.
+func (p *Proof) verify(root []byte, key Key) error {
+	r := EmptyRoot(p.Hash)
+	if p.Leaf != nil {
+		r = hashLeaf(p.Hash, p.Leaf.Key, p.Leaf.ValueHash)
+	}
+	next := len(p.Siblings)
+	for depth := p.Depth - 1; depth >= 0; depth-- {
+		sibling := EmptyRoot(p.Hash)
+		if p.Bitmap[depth/8]&(0x80>>uint(depth%8)) != 0 {
+			next--
+			sibling = p.Siblings[next]
+		}
+		if key.bit(depth) == 0 {
+			r = merkle.HashNode(p.Hash, r, sibling)
+		} else {
+			r = merkle.HashNode(p.Hash, sibling, r)
+		}
+	}
+	if !bytes.Equal(r, root) {
+		return errors.Wrap(ErrInvalidProof, "root does not match")
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Serialization                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+// Proof encoding layout:
+//
+//	version (1 byte) | hash type (1 byte) | depth (2 bytes) | bitmap |
+//	siblings | leaf flag (1 byte) | [key (32 bytes) | value hash]
+//
+// The depth is big endian, the bitmap is (depth+7)/8 bytes, and the number
+// of siblings, each of the hash type's digest size, is the number of bits
+// set in the bitmap. The key and value hash follow a leaf flag of 1 and are
+// absent for a flag of 0. The decoder rejects any other encoding.
+const (
+	// ProofEncodingVersion is the version of the proof encoding.
+	ProofEncodingVersion = 1
+
+	proofHeaderLen = 4
+)
+
+// MarshalBinary encodes the proof compactly. This function implements the
+// encoding.BinaryMarshaler interface.
+func (p *Proof) MarshalBinary() ([]byte, error) {
+	if !p.Hash.Available() {
+		return nil, errors.Errorf("unknown hash type %d", uint8(p.Hash))
+	}
+	// The placeholder root only stands in for one of the right size
+	if err := p.check(EmptyRoot(p.Hash)); err != nil {
+		return nil, err
+	}
+	size := p.Hash.Size()
+	buf := make([]byte, proofHeaderLen, proofHeaderLen+len(p.Bitmap)+
+		len(p.Siblings)*size+1+KeySize+size)
+	buf[0], buf[1] = ProofEncodingVersion, byte(p.Hash)
+	binary.BigEndian.PutUint16(buf[2:], uint16(p.Depth))
+	buf = append(buf, p.Bitmap...)
+	for _, sibling := range p.Siblings {
+		buf = append(buf, sibling...)
+	}
+	if p.Leaf == nil {
+		return append(buf, 0), nil
+	}
+	buf = append(append(buf, 1), p.Leaf.Key[:]...)
+	return append(buf, p.Leaf.ValueHash...), nil
+}
+
+// UnmarshalBinary decodes a proof made by MarshalBinary. It returns an error,
+// leaving p unchanged, for anything that is not the canonical encoding of a
+// proof. This function implements the encoding.BinaryUnmarshaler interface.
+func (p *Proof) UnmarshalBinary(data []byte) error {
+	if len(data) < proofHeaderLen+1 {
+		return errors.New("encoding too short")
+	}
+	if data[0] != ProofEncodingVersion {
+		return errors.Errorf("unsupported encoding version %d", data[0])
+	}
+	h := hasher.HashType(data[1])
+	if !h.Available() {
+		return errors.Errorf("unknown hash type %d", data[1])
+	}
+	size := h.Size()
+	decoded := Proof{Hash: h, Depth: int(binary.BigEndian.Uint16(data[2:]))}
+	if decoded.Depth > Depth {
+		return errors.Errorf("depth %d is out of range", decoded.Depth)
+	}
+	data = data[proofHeaderLen:]
+
+	n := (decoded.Depth + 7) / 8
+	if len(data) < n {
+		return errors.New("encoding too short for bitmap")
+	}
+	decoded.Bitmap, data = append([]byte{}, data[:n]...), data[n:]
+	count := 0
+	for _, b := range decoded.Bitmap {
+		count += bits.OnesCount8(b)
+	}
+	if len(data) < count*size+1 {
+		return errors.New("encoding too short for siblings")
+	}
+	decoded.Siblings = make([][]byte, count)
+	for i := range decoded.Siblings {
+		decoded.Siblings[i] = append([]byte{}, data[i*size:(i+1)*size]...)
+	}
+	data = data[count*size:]
+
+	switch {
+	case data[0] == 0 && len(data) == 1:
+	case data[0] == 1 && len(data) == 1+KeySize+size:
+		decoded.Leaf = &Leaf{ValueHash: append([]byte{}, data[1+KeySize:]...)}
+		copy(decoded.Leaf.Key[:], data[1:])
+	default:
+		return errors.New("invalid leaf encoding")
+	}
+	if err := decoded.check(EmptyRoot(h)); err != nil {
+		return err
+	}
+	*p = decoded
+	return nil
+}
@@ -0,0 +1,228 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package sparse
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"gitlab.com/xx_network/crypto/hasher"
+)
+
+// newNumberedTree returns a tree holding the numbers below n under their
+// keys.
+func newNumberedTree(t *testing.T, h hasher.HashType, n int) *Tree {
+	tree, _ := newTestTree(t, h)
+	b := NewBatch()
+	for i := 0; i < n; i++ {
+		b.Set(numberedKey(i), numberedValue(i))
+	}
+	if err := tree.Apply(b); err != nil {
+		t.Fatalf("Apply() error: %+v", err)
+	}
+	return tree
+}
+
+// numberedValue returns the value of the number i.
+func numberedValue(i int) []byte {
+	return []byte{byte(i), byte(i >> 8)}
+}
+
+// Tests membership proofs of every key and non-membership proofs of absent
+// keys ending at both placeholders and other leaves.
+func TestProof_Verify(t *testing.T) {
+	for _, h := range []hasher.HashType{hasher.BLAKE2, hasher.BLAKE3} {
+		for _, n := range []int{0, 1, 2, 3, 200} {
+			tree := newNumberedTree(t, h, n)
+			root := tree.Root()
+			for i := 0; i < n; i++ {
+				p, err := tree.Prove(numberedKey(i))
+				if err != nil {
+					t.Fatalf("Prove() error: %+v", err)
+				}
+				if !p.Exists(numberedKey(i)) {
+					t.Errorf("proof of a present key claims absence")
+				}
+				err = p.VerifyMembership(root, numberedKey(i), numberedValue(i))
+				if err != nil {
+					t.Errorf("%s VerifyMembership(%d) of %d keys error: %+v",
+						h, i, n, err)
+				}
+				if p.VerifyNonMembership(root, numberedKey(i)) == nil {
+					t.Errorf("membership proof verified absence")
+				}
+			}
+
+			var empty, leaf int
+			for i := n; i < n+200; i++ {
+				p, err := tree.Prove(numberedKey(i))
+				if err != nil {
+					t.Fatalf("Prove() error: %+v", err)
+				}
+				if p.Leaf == nil {
+					empty++
+				} else {
+					leaf++
+				}
+				if err = p.VerifyNonMembership(root, numberedKey(i)); err != nil {
+					t.Errorf("%s VerifyNonMembership(%d) of %d keys error: %+v",
+						h, i, n, err)
+				}
+				if p.VerifyMembership(root, numberedKey(i), nil) == nil {
+					t.Errorf("non-membership proof verified a value")
+				}
+			}
+			if n == 200 && (empty == 0 || leaf == 0) {
+				t.Errorf("%d proofs ended at placeholders and %d at leaves",
+					empty, leaf)
+			}
+		}
+	}
+}
+
+// Tests that proofs fail against wrong values, keys, roots and tampering.
+func TestProof_Invalid(t *testing.T) {
+	tree := newNumberedTree(t, hasher.BLAKE2, 100)
+	root := tree.Root()
+	key, value := numberedKey(7), numberedValue(7)
+	p, _ := tree.Prove(key)
+	if len(p.Siblings) == 0 {
+		t.Fatalf("proof has no siblings")
+	}
+
+	expectInvalid := func(name string, err error) {
+		t.Helper()
+		if err == nil {
+			t.Errorf("%s verified", name)
+		} else if errors.Cause(err) != ErrInvalidProof {
+			t.Errorf("%s returned %v, expected ErrInvalidProof", name, err)
+		}
+	}
+	expectInvalid("a wrong value",
+		p.VerifyMembership(root, key, numberedValue(8)))
+	expectInvalid("another key",
+		p.VerifyMembership(root, numberedKey(8), numberedValue(8)))
+	otherRoot := append([]byte{}, root...)
+	otherRoot[0] ^= 1
+	expectInvalid("a wrong root", p.VerifyMembership(otherRoot, key, value))
+	expectInvalid("a short root", p.VerifyMembership(root[1:], key, value))
+
+	tamper := func(name string, modify func(p *Proof)) {
+		t.Helper()
+		q, _ := tree.Prove(key)
+		modify(q)
+		expectInvalid(name, q.VerifyMembership(root, key, value))
+	}
+	tamper("a changed sibling", func(q *Proof) { q.Siblings[0][0] ^= 1 })
+	tamper("a short sibling", func(q *Proof) { q.Siblings[0] = q.Siblings[0][1:] })
+	tamper("a missing sibling", func(q *Proof) { q.Siblings = q.Siblings[1:] })
+	tamper("an extra sibling", func(q *Proof) {
+		q.Siblings = append(q.Siblings, root)
+	})
+	tamper("a changed bitmap", func(q *Proof) { q.Bitmap[0] ^= 0x80 })
+	tamper("a longer path", func(q *Proof) {
+		q.Depth++
+		q.Bitmap = append(q.Bitmap, 0)
+	})
+	tamper("a bitmap past the depth", func(q *Proof) {
+		q.Depth = 9
+		q.Bitmap = []byte{0, 0x7f}
+	})
+	tamper("a depth out of range", func(q *Proof) { q.Depth = Depth + 1 })
+	tamper("a changed value hash", func(q *Proof) { q.Leaf.ValueHash[0] ^= 1 })
+
+	// A leaf off the key's path cannot prove absence
+	absent := numberedKey(1000)
+	q, _ := tree.Prove(absent)
+	for q.Leaf == nil {
+		absent[0]++
+		q, _ = tree.Prove(absent)
+	}
+	if err := q.VerifyNonMembership(root, absent); err != nil {
+		t.Fatalf("VerifyNonMembership() error: %+v", err)
+	}
+	if q.Depth == 0 {
+		t.Fatalf("proof of absence has no path")
+	}
+	absent[0] ^= 0x80
+	expectInvalid("a leaf off the path", q.VerifyNonMembership(root, absent))
+
+	p.Hash = 200
+	if err := p.VerifyMembership(root, key, value); err == nil {
+		t.Errorf("VerifyMembership() accepted an unknown hash")
+	}
+}
+
+// Tests that proofs survive encoding and that the decoder is strict.
+func TestProof_MarshalBinary(t *testing.T) {
+	tree := newNumberedTree(t, hasher.BLAKE2, 50)
+	root := tree.Root()
+	for i := 45; i < 55; i++ {
+		p, _ := tree.Prove(numberedKey(i))
+		data, err := p.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary() error: %+v", err)
+		}
+		expected := 5 + len(p.Bitmap) + 32*len(p.Siblings)
+		if p.Leaf != nil {
+			expected += KeySize + 32
+		}
+		if len(data) != expected {
+			t.Errorf("encoding has %d bytes, expected %d", len(data), expected)
+		}
+		var decoded Proof
+		if err = decoded.UnmarshalBinary(data); err != nil {
+			t.Fatalf("UnmarshalBinary() error: %+v", err)
+		}
+		if i < 50 {
+			err = decoded.VerifyMembership(root, numberedKey(i),
+				numberedValue(i))
+		} else {
+			err = decoded.VerifyNonMembership(root, numberedKey(i))
+		}
+		if err != nil {
+			t.Errorf("decoded proof %d does not verify: %+v", i, err)
+		}
+
+		// Every truncation and extension is rejected
+		for n := 0; n < len(data); n++ {
+			if decoded.UnmarshalBinary(data[:n]) == nil {
+				t.Errorf("UnmarshalBinary() accepted %d of %d bytes", n,
+					len(data))
+			}
+		}
+		if decoded.UnmarshalBinary(append(data, 0)) == nil {
+			t.Errorf("UnmarshalBinary() accepted a trailing byte")
+		}
+	}
+
+	p, _ := tree.Prove(numberedKey(0))
+	data, _ := p.MarshalBinary()
+	invalid := map[string]func(d []byte){
+		"version":   func(d []byte) { d[0] = 2 },
+		"hash type": func(d []byte) { d[1] = 200 },
+		"depth":     func(d []byte) { d[2], d[3] = 1, 1 },
+		"leaf flag": func(d []byte) { d[len(d)-65] = 2 },
+	}
+	for name, modify := range invalid {
+		d := append([]byte{}, data...)
+		modify(d)
+		decoded := Proof{Depth: 7}
+		if decoded.UnmarshalBinary(d) == nil {
+			t.Errorf("UnmarshalBinary() accepted an invalid %s", name)
+		}
+		if decoded.Depth != 7 {
+			t.Errorf("failed UnmarshalBinary() changed the proof")
+		}
+	}
+
+	p.Siblings = p.Siblings[1:]
+	if _, err := p.MarshalBinary(); err == nil {
+		t.Errorf("MarshalBinary() accepted a malformed proof")
+	}
+}
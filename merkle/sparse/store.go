@@ -0,0 +1,77 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package sparse
+
+import (
+	"encoding/hex"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Store holds the nodes of a tree, keyed by their hash. Nodes are opaque to
+// the store. Implementations must be safe for concurrent use and must not
+// keep or modify the slices passed to them, nor expect the caller not to
+// modify the slices they return.
+type Store interface {
+	// Get returns the node with the given hash or an error if there is none.
+	Get(hash []byte) ([]byte, error)
+
+	// Put saves the node with the given hash.
+	Put(hash, node []byte) error
+
+	// Delete removes the node with the given hash. Deleting a node that is
+	// not present is not an error.
+	Delete(hash []byte) error
+}
+
+// MemoryStore is a Store that keeps nodes in memory.
+type MemoryStore struct {
+	nodes map[string][]byte
+	mux   sync.RWMutex
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{nodes: make(map[string][]byte)}
+}
+
+// Get returns a copy of the node with the given hash.
+func (s *MemoryStore) Get(hash []byte) ([]byte, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	node, exists := s.nodes[string(hash)]
+	if !exists {
+		return nil, errors.Errorf("no node with hash %s",
+			hex.EncodeToString(hash))
+	}
+	return append([]byte{}, node...), nil
+}
+
+// Put saves a copy of the node with the given hash.
+func (s *MemoryStore) Put(hash, node []byte) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.nodes[string(hash)] = append([]byte{}, node...)
+	return nil
+}
+
+// Delete removes the node with the given hash.
+func (s *MemoryStore) Delete(hash []byte) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.nodes, string(hash))
+	return nil
+}
+
+// Len returns the number of nodes held.
+func (s *MemoryStore) Len() int {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	return len(s.nodes)
+}
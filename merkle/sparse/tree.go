@@ -0,0 +1,392 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package sparse
+
+import (
+	"bytes"
+	"encoding/hex"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	jww "github.com/spf13/jwalterweatherman"
+	"gitlab.com/xx_network/crypto/hasher"
+	"gitlab.com/xx_network/crypto/merkle"
+)
+
+// ErrKeyNotFound is returned by Tree.Get for a key that holds no value.
+var ErrKeyNotFound = errors.New("key not found")
+
+// Stored node layout:
+//
+//	leaf:     0x00 | key (32 bytes) | value
+//	interior: 0x01 | left hash | right hash
+const (
+	leafRecord     = 0x00
+	interiorRecord = 0x01
+)
+
+// Tree is a sparse Merkle tree whose nodes are kept in a Store. It only
+// keeps the nodes of its current root, deleting those an update replaces, so
+// the store must not be shared with another tree. It is safe for concurrent
+// use.
+type Tree struct {
+	h     hasher.HashType
+	store Store
+	root  []byte
+	mux   sync.RWMutex
+}
+
+// New returns an empty tree over the given hash, keeping its nodes in store.
+func New(h hasher.HashType, store Store) (*Tree, error) {
+	if !h.Available() {
+		return nil, errors.Errorf("unknown hash type %d", uint8(h))
+	}
+	return &Tree{h: h, store: store, root: EmptyRoot(h)}, nil
+}
+
+// Load returns the tree with the given root, whose nodes are already in
+// store, such as a tree saved by an earlier process.
+func Load(h hasher.HashType, store Store, root []byte) (*Tree, error) {
+	t, err := New(h, store)
+	if err != nil {
+		return nil, err
+	}
+	if len(root) != h.Size() {
+		return nil, errors.Errorf("root has %d bytes, expected %d", len(root),
+			h.Size())
+	}
+	if !t.isEmpty(root) {
+		if _, err = t.load(root); err != nil {
+			return nil, errors.WithMessage(err, "failed to load root")
+		}
+	}
+	t.root = append([]byte{}, root...)
+	return t, nil
+}
+
+// Hash returns the hash type of the tree.
+func (t *Tree) Hash() hasher.HashType {
+	return t.h
+}
+
+// Root returns the root of the tree.
+func (t *Tree) Root() []byte {
+	t.mux.RLock()
+	defer t.mux.RUnlock()
+	return append([]byte{}, t.root...)
+}
+
+// Get returns the value of key, or ErrKeyNotFound if it has none.
+func (t *Tree) Get(key Key) ([]byte, error) {
+	t.mux.RLock()
+	defer t.mux.RUnlock()
+	hash := t.root
+	for depth := 0; !t.isEmpty(hash); depth++ {
+		n, err := t.load(hash)
+		if err != nil {
+			return nil, err
+		}
+		if n.leaf {
+			if n.key != key {
+				break
+			}
+			return n.value, nil
+		}
+		hash = n.child(key.bit(depth))
+	}
+	return nil, ErrKeyNotFound
+}
+
+// Set sets the value of key.
+func (t *Tree) Set(key Key, value []byte) error {
+	b := NewBatch()
+	b.Set(key, value)
+	return t.Apply(b)
+}
+
+// Delete removes key and its value. Deleting an absent key does nothing.
+func (t *Tree) Delete(key Key) error {
+	b := NewBatch()
+	b.Delete(key)
+	return t.Apply(b)
+}
+
+// Apply makes all the changes in the batch at once, which takes fewer hashes
+// and store writes than making them one at a time. The new nodes are all
+// saved before the root changes, so if the store fails the tree keeps its
+// old root.
+func (t *Tree) Apply(b *Batch) error {
+	changes := b.sorted()
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	w := &writes{puts: make(map[string][]byte),
+		deletes: make(map[string]struct{})}
+	root, _, err := t.update(t.root, 0, changes, w)
+	if err != nil {
+		return err
+	}
+	for hash, node := range w.puts {
+		if err = t.store.Put([]byte(hash), node); err != nil {
+			return errors.Wrap(err, "failed to save node")
+		}
+	}
+	t.root = root
+
+	// Stale nodes that are left behind only waste space
+	for hash := range w.deletes {
+		if _, exists := w.puts[hash]; exists {
+			continue
+		}
+		if err = t.store.Delete([]byte(hash)); err != nil {
+			jww.WARN.Printf("Failed to delete stale sparse Merkle tree node "+
+				"%s: %+v", hex.EncodeToString([]byte(hash)), err)
+		}
+	}
+	return nil
+}
+
+// Prove returns the proof that key holds its value or, if it has none, that
+// it is absent.
+func (t *Tree) Prove(key Key) (*Proof, error) {
+	t.mux.RLock()
+	defer t.mux.RUnlock()
+
+	p := &Proof{Hash: t.h}
+	var bitmap [Depth / 8]byte
+	hash := t.root
+	for depth := 0; !t.isEmpty(hash); depth++ {
+		n, err := t.load(hash)
+		if err != nil {
+			return nil, err
+		}
+		if n.leaf {
+			p.Leaf = &Leaf{Key: n.key, ValueHash: HashValue(t.h, n.value)}
+			break
+		}
+		bit := key.bit(depth)
+		if sibling := n.child(bit ^ 1); !t.isEmpty(sibling) {
+			bitmap[depth/8] |= 0x80 >> uint(depth%8)
+			p.Siblings = append(p.Siblings, sibling)
+		}
+		hash = n.child(bit)
+		p.Depth++
+	}
+	p.Bitmap = append([]byte{}, bitmap[:(p.Depth+7)/8]...)
+	return p, nil
+}
+
+// change is a new value for a key, or its deletion if the value is nil.
+type change struct {
+	key   Key
+	value []byte
+}
+
+// writes collects the nodes an update adds and removes, so the store is only
+// written once the whole update has been computed.
+type writes struct {
+	puts    map[string][]byte
+	deletes map[string]struct{}
+}
+
+// update applies the changes, sorted by key, to the subtree with the given
+// hash at depth, whose keys all share the first depth bits of the changes.
+// It returns the new subtree's hash and whether it is a leaf.
+func (t *Tree) update(hash []byte, depth int, changes []change,
+	w *writes) ([]byte, bool, error) {
+	if t.isEmpty(hash) {
+		hash, leaf := t.build(depth, changes, w)
+		return hash, leaf, nil
+	}
+	n, err := t.load(hash)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(changes) == 0 {
+		return hash, n.leaf, nil
+	}
+	w.deletes[string(hash)] = struct{}{}
+
+	if n.leaf {
+		// Rebuild the subtree from the old leaf and the changes, unless one
+		// of them replaces it
+		i := sort.Search(len(changes), func(i int) bool {
+			return bytes.Compare(changes[i].key[:], n.key[:]) >= 0
+		})
+		if i == len(changes) || changes[i].key != n.key {
+			changes = append(changes[:i:i],
+				append([]change{{n.key, n.value}}, changes[i:]...)...)
+		}
+		hash, leaf := t.build(depth, changes, w)
+		return hash, leaf, nil
+	}
+
+	i := splitChanges(depth, changes)
+	left, leftLeaf, err := t.update(n.left, depth+1, changes[:i], w)
+	if err != nil {
+		return nil, false, err
+	}
+	right, rightLeaf, err := t.update(n.right, depth+1, changes[i:], w)
+	if err != nil {
+		return nil, false, err
+	}
+	hash, leaf := t.join(left, leftLeaf, right, rightLeaf, w)
+	return hash, leaf, nil
+}
+
+// build adds the subtree at depth holding the values of the changes, sorted
+// by key, skipping deletions, and returns its hash and whether it is a leaf.
+func (t *Tree) build(depth int, changes []change, w *writes) ([]byte, bool) {
+	var leaves []change
+	for _, c := range changes {
+		if c.value != nil {
+			leaves = append(leaves, c)
+		}
+	}
+	return t.buildLeaves(depth, leaves, w)
+}
+
+// buildLeaves adds the subtree at depth holding the given leaves, sorted by
+// key, and returns its hash and whether it is a leaf.
+func (t *Tree) buildLeaves(depth int, leaves []change,
+	w *writes) ([]byte, bool) {
+	switch len(leaves) {
+	case 0:
+		return EmptyRoot(t.h), false
+	case 1:
+		l := leaves[0]
+		hash := hashLeaf(t.h, l.key, HashValue(t.h, l.value))
+		node := make([]byte, 0, 1+KeySize+len(l.value))
+		node = append(append(append(node, leafRecord), l.key[:]...), l.value...)
+		w.puts[string(hash)] = node
+		return hash, true
+	}
+	i := splitChanges(depth, leaves)
+	left, leftLeaf := t.buildLeaves(depth+1, leaves[:i], w)
+	right, rightLeaf := t.buildLeaves(depth+1, leaves[i:], w)
+	return t.join(left, leftLeaf, right, rightLeaf, w)
+}
+
+// join returns the parent of the given subtrees and whether it is a leaf. A
+// subtree holding one leaf is replaced by the leaf, and one holding none by
+// the placeholder.
+func (t *Tree) join(left []byte, leftLeaf bool, right []byte,
+	rightLeaf bool, w *writes) ([]byte, bool) {
+	leftEmpty, rightEmpty := t.isEmpty(left), t.isEmpty(right)
+	switch {
+	case leftEmpty && rightEmpty:
+		return left, false
+	case leftEmpty && rightLeaf:
+		return right, true
+	case rightEmpty && leftLeaf:
+		return left, true
+	}
+	hash := merkle.HashNode(t.h, left, right)
+	node := make([]byte, 0, 1+len(left)+len(right))
+	w.puts[string(hash)] = append(append(append(node, interiorRecord),
+		left...), right...)
+	return hash, false
+}
+
+// splitChanges returns the index of the first change, sorted by key, whose
+// key goes right at depth.
+func splitChanges(depth int, changes []change) int {
+	return sort.Search(len(changes), func(i int) bool {
+		return changes[i].key.bit(depth) == 1
+	})
+}
+
+// isEmpty reports whether hash is the placeholder of an empty subtree.
+func (t *Tree) isEmpty(hash []byte) bool {
+	for _, b := range hash {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// node is a decoded stored node.
+type node struct {
+	leaf        bool
+	key         Key
+	value       []byte
+	left, right []byte
+}
+
+// child returns the hash of the left child for bit 0 or the right for 1.
+func (n *node) child(bit byte) []byte {
+	if bit == 0 {
+		return n.left
+	}
+	return n.right
+}
+
+// load gets and decodes the node with the given hash.
+func (t *Tree) load(hash []byte) (*node, error) {
+	data, err := t.store.Get(hash)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get node")
+	}
+	size := t.h.Size()
+	switch {
+	case len(data) > KeySize && data[0] == leafRecord:
+		n := &node{leaf: true, value: data[1+KeySize:]}
+		copy(n.key[:], data[1:])
+		return n, nil
+	case len(data) == 1+2*size && data[0] == interiorRecord:
+		return &node{left: data[1 : 1+size], right: data[1+size:]}, nil
+	}
+	return nil, errors.Errorf("node %s is corrupt", hex.EncodeToString(hash))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Batch                                                                      //
+////////////////////////////////////////////////////////////////////////////////
+
+// Batch is a set of changes to apply to a tree at once with Tree.Apply. A
+// later change to a key replaces an earlier one. It is not safe for
+// concurrent use.
+type Batch struct {
+	// values holds the new value of each key, or nil for a deletion
+	values map[Key][]byte
+}
+
+// NewBatch returns an empty batch.
+func NewBatch() *Batch {
+	return &Batch{values: make(map[Key][]byte)}
+}
+
+// Set sets the value of key.
+func (b *Batch) Set(key Key, value []byte) {
+	// Copying makes an empty value non-nil
+	b.values[key] = append([]byte{}, value...)
+}
+
+// Delete removes key and its value.
+func (b *Batch) Delete(key Key) {
+	b.values[key] = nil
+}
+
+// Len returns the number of keys changed.
+func (b *Batch) Len() int {
+	return len(b.values)
+}
+
+// sorted returns the changes sorted by key.
+func (b *Batch) sorted() []change {
+	changes := make([]change, 0, len(b.values))
+	for key, value := range b.values {
+		changes = append(changes, change{key, value})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return bytes.Compare(changes[i].key[:], changes[j].key[:]) < 0
+	})
+	return changes
+}
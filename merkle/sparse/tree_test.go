@@ -0,0 +1,379 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package sparse
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	"gitlab.com/xx_network/crypto/hasher"
+	"gitlab.com/xx_network/crypto/merkle"
+	"gitlab.com/xx_network/primitives/id"
+	"golang.org/x/crypto/blake2b"
+)
+
+// newTestTree returns an empty tree in a new MemoryStore.
+func newTestTree(t testing.TB, h hasher.HashType) (*Tree, *MemoryStore) {
+	store := NewMemoryStore()
+	tree, err := New(h, store)
+	if err != nil {
+		t.Fatalf("New() error: %+v", err)
+	}
+	return tree, store
+}
+
+// numberedKey returns the key of the number i.
+func numberedKey(i int) Key {
+	return NewKey([]byte(strconv.Itoa(i)))
+}
+
+// referenceRoot computes the root of the given key/value state from its
+// definition, a subtree holding no keys being a placeholder and one holding
+// a single key being its leaf.
+func referenceRoot(h hasher.HashType, state map[Key][]byte) []byte {
+	keys := make([]Key, 0, len(state))
+	for key := range state {
+		keys = append(keys, key)
+	}
+	var root func(depth int, keys []Key) []byte
+	root = func(depth int, keys []Key) []byte {
+		switch len(keys) {
+		case 0:
+			return EmptyRoot(h)
+		case 1:
+			return hashLeaf(h, keys[0], HashValue(h, state[keys[0]]))
+		}
+		var left, right []Key
+		for _, key := range keys {
+			if key.bit(depth) == 0 {
+				left = append(left, key)
+			} else {
+				right = append(right, key)
+			}
+		}
+		return merkle.HashNode(h, root(depth+1, left), root(depth+1, right))
+	}
+	return root(0, keys)
+}
+
+// Tests that a root, encoding the format of the tree, is unchanged.
+func TestTree_Root_Vector(t *testing.T) {
+	tree, _ := newTestTree(t, hasher.BLAKE2)
+	b := NewBatch()
+	for i := 0; i < 5; i++ {
+		b.Set(numberedKey(i), []byte("value "+strconv.Itoa(i)))
+	}
+	if err := tree.Apply(b); err != nil {
+		t.Fatalf("Apply() error: %+v", err)
+	}
+	expected := "05a4f5f58e90940b1dec01bb909b5948b80788ed519e4cf65107e7e7908d3fb0"
+	if got := hex.EncodeToString(tree.Root()); got != expected {
+		t.Errorf("Root() changed.\nexpected: %s\nreceived: %s", expected, got)
+	}
+}
+
+// Tests random sets, updates and deletions, one at a time and in batches,
+// against a map and the definition of the root.
+func TestTree_Apply_Reference(t *testing.T) {
+	for _, h := range []hasher.HashType{hasher.BLAKE2, hasher.SHA3_256} {
+		tree, store := newTestTree(t, h)
+		prng := rand.New(rand.NewSource(42))
+		state := make(map[Key][]byte)
+		for round := 0; round < 40; round++ {
+			b := NewBatch()
+			for i := 0; i < 1+prng.Intn(20); i++ {
+				key := numberedKey(prng.Intn(100))
+				if prng.Intn(3) == 0 {
+					b.Delete(key)
+					delete(state, key)
+				} else {
+					value := []byte(strconv.Itoa(prng.Int()))
+					b.Set(key, value)
+					state[key] = value
+				}
+			}
+			if round%2 == 0 {
+				if err := tree.Apply(b); err != nil {
+					t.Fatalf("Apply() error: %+v", err)
+				}
+			} else {
+				for key, value := range b.values {
+					var err error
+					if value == nil {
+						err = tree.Delete(key)
+					} else {
+						err = tree.Set(key, value)
+					}
+					if err != nil {
+						t.Fatalf("Set() or Delete() error: %+v", err)
+					}
+				}
+			}
+			if !bytes.Equal(tree.Root(), referenceRoot(h, state)) {
+				t.Fatalf("%s root after round %d is wrong", h, round)
+			}
+		}
+
+		for i := 0; i < 100; i++ {
+			key := numberedKey(i)
+			value, err := tree.Get(key)
+			if expected, exists := state[key]; exists {
+				if err != nil || !bytes.Equal(value, expected) {
+					t.Errorf("Get(%d) = %q, %v, expected %q", i, value, err,
+						expected)
+				}
+			} else if err != ErrKeyNotFound {
+				t.Errorf("Get(%d) of an absent key returned %v", i, err)
+			}
+		}
+
+		// Removing every key must leave no stale nodes behind
+		b := NewBatch()
+		for key := range state {
+			b.Delete(key)
+		}
+		if err := tree.Apply(b); err != nil {
+			t.Fatalf("Apply() error: %+v", err)
+		}
+		if !bytes.Equal(tree.Root(), EmptyRoot(h)) {
+			t.Errorf("%s root of the emptied tree is not empty", h)
+		}
+		if store.Len() != 0 {
+			t.Errorf("%s store holds %d nodes after emptying", h, store.Len())
+		}
+	}
+}
+
+// Tests that the root does not depend on the order or batching of changes
+// and that the store only holds the current nodes.
+func TestTree_Apply_Order(t *testing.T) {
+	one, oneStore := newTestTree(t, hasher.BLAKE2)
+	all, allStore := newTestTree(t, hasher.BLAKE2)
+	b := NewBatch()
+	for i := 0; i < 50; i++ {
+		if err := one.Set(numberedKey(49-i), []byte{byte(i)}); err != nil {
+			t.Fatalf("Set() error: %+v", err)
+		}
+		b.Set(numberedKey(i), []byte{byte(49 - i)})
+	}
+	if err := all.Apply(b); err != nil {
+		t.Fatalf("Apply() error: %+v", err)
+	}
+	if !bytes.Equal(one.Root(), all.Root()) {
+		t.Errorf("the same state has different roots")
+	}
+	if oneStore.Len() != allStore.Len() {
+		t.Errorf("stores hold %d and %d nodes", oneStore.Len(), allStore.Len())
+	}
+
+	// Setting values they already hold changes nothing
+	root, n := all.Root(), allStore.Len()
+	if err := all.Set(numberedKey(3), []byte{46}); err != nil {
+		t.Fatalf("Set() error: %+v", err)
+	}
+	if err := all.Delete(numberedKey(50)); err != nil {
+		t.Fatalf("Delete() error: %+v", err)
+	}
+	if !bytes.Equal(all.Root(), root) || allStore.Len() != n {
+		t.Errorf("setting an unchanged value changed the tree")
+	}
+}
+
+// Tests empty values and keys that share all but their last bit, which sit
+// at the deepest level.
+func TestTree_Edges(t *testing.T) {
+	tree, _ := newTestTree(t, hasher.BLAKE2)
+	var a, b Key
+	b[KeySize-1] = 1
+	if err := tree.Set(a, nil); err != nil {
+		t.Fatalf("Set() error: %+v", err)
+	}
+	if value, err := tree.Get(a); err != nil || value == nil ||
+		len(value) != 0 {
+		t.Errorf("Get() of an empty value = %v, %v", value, err)
+	}
+	if bytes.Equal(tree.Root(), EmptyRoot(hasher.BLAKE2)) {
+		t.Errorf("an empty value left the tree empty")
+	}
+
+	if err := tree.Set(b, []byte("b")); err != nil {
+		t.Fatalf("Set() error: %+v", err)
+	}
+	state := map[Key][]byte{a: {}, b: []byte("b")}
+	if !bytes.Equal(tree.Root(), referenceRoot(hasher.BLAKE2, state)) {
+		t.Errorf("root of the deepest keys is wrong")
+	}
+	p, err := tree.Prove(b)
+	if err != nil {
+		t.Fatalf("Prove() error: %+v", err)
+	}
+	if p.Depth != Depth || len(p.Siblings) != 1 {
+		t.Errorf("proof has depth %d and %d siblings", p.Depth,
+			len(p.Siblings))
+	}
+	if err = p.VerifyMembership(tree.Root(), b, []byte("b")); err != nil {
+		t.Errorf("VerifyMembership() error: %+v", err)
+	}
+
+	// Removing one collapses the other back to the root
+	if err = tree.Delete(a); err != nil {
+		t.Fatalf("Delete() error: %+v", err)
+	}
+	if !bytes.Equal(tree.Root(), hashLeaf(hasher.BLAKE2, b,
+		HashValue(hasher.BLAKE2, []byte("b")))) {
+		t.Errorf("a single key is not the root")
+	}
+}
+
+// Tests loading a tree from the store of another.
+func TestLoad(t *testing.T) {
+	tree, store := newTestTree(t, hasher.BLAKE2)
+	for i := 0; i < 10; i++ {
+		_ = tree.Set(numberedKey(i), []byte{byte(i)})
+	}
+	loaded, err := Load(hasher.BLAKE2, store, tree.Root())
+	if err != nil {
+		t.Fatalf("Load() error: %+v", err)
+	}
+	if value, err := loaded.Get(numberedKey(7)); err != nil ||
+		!bytes.Equal(value, []byte{7}) {
+		t.Errorf("Get() on a loaded tree = %v, %v", value, err)
+	}
+	if _, err = Load(hasher.BLAKE2, store, EmptyRoot(hasher.BLAKE2)); err != nil {
+		t.Errorf("Load() of the empty root error: %+v", err)
+	}
+
+	unknown := tree.Root()
+	unknown[0] ^= 1
+	if _, err = Load(hasher.BLAKE2, store, unknown); err == nil {
+		t.Errorf("Load() accepted a root not in the store")
+	}
+	if _, err = Load(hasher.BLAKE2, store, unknown[1:]); err == nil {
+		t.Errorf("Load() accepted a short root")
+	}
+	if _, err = Load(200, store, unknown); err == nil {
+		t.Errorf("Load() accepted an unknown hash")
+	}
+}
+
+// Tests that IDKey is the BLAKE2b-256 hash of the ID.
+func TestIDKey(t *testing.T) {
+	userID := id.NewIdFromString("zezima", id.User, t)
+	expected := blake2b.Sum256(userID.Marshal())
+	if key := IDKey(userID); key != expected {
+		t.Errorf("IDKey() = %x, expected %x", key, expected)
+	}
+	if NewKey([]byte("a")) == NewKey([]byte("b")) {
+		t.Errorf("different data have the same key")
+	}
+}
+
+// Tests that the MemoryStore copies nodes in and out.
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	node := []byte("node")
+	_ = store.Put([]byte("hash"), node)
+	node[0] = 'x'
+	got, err := store.Get([]byte("hash"))
+	if err != nil || string(got) != "node" {
+		t.Errorf("Get() = %q, %v", got, err)
+	}
+	got[0] = 'x'
+	if got, _ = store.Get([]byte("hash")); string(got) != "node" {
+		t.Errorf("modifying a returned node changed the store")
+	}
+	_ = store.Delete([]byte("hash"))
+	if _, err = store.Get([]byte("hash")); err == nil {
+		t.Errorf("Get() returned a deleted node")
+	}
+	if err = store.Delete([]byte("hash")); err != nil {
+		t.Errorf("Delete() of an absent node error: %+v", err)
+	}
+}
+
+// Tests concurrent updates, reads and proofs.
+func TestTree_Concurrent(t *testing.T) {
+	tree, _ := newTestTree(t, hasher.BLAKE3)
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				key := numberedKey(g*25 + i)
+				if err := tree.Set(key, []byte{byte(i)}); err != nil {
+					t.Errorf("Set() error: %+v", err)
+				}
+				if _, err := tree.Get(key); err != nil {
+					t.Errorf("Get() error: %+v", err)
+				}
+				if _, err := tree.Prove(key); err != nil {
+					t.Errorf("Prove() error: %+v", err)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	state := make(map[Key][]byte)
+	for i := 0; i < 100; i++ {
+		state[numberedKey(i)] = []byte{byte(i % 25)}
+	}
+	if !bytes.Equal(tree.Root(), referenceRoot(hasher.BLAKE3, state)) {
+		t.Errorf("root after concurrent updates is wrong")
+	}
+}
+
+// Tests that Batch keeps the last change to a key and copies values.
+func TestBatch(t *testing.T) {
+	b := NewBatch()
+	value := []byte("first")
+	b.Set(numberedKey(1), value)
+	value[0] = 'x'
+	b.Delete(numberedKey(2))
+	b.Set(numberedKey(2), []byte("second"))
+	b.Delete(numberedKey(3))
+	if b.Len() != 3 {
+		t.Errorf("Len() = %d, expected 3", b.Len())
+	}
+	changes := b.sorted()
+	if !sort.SliceIsSorted(changes, func(i, j int) bool {
+		return bytes.Compare(changes[i].key[:], changes[j].key[:]) < 0
+	}) {
+		t.Errorf("changes are not sorted")
+	}
+	if string(b.values[numberedKey(1)]) != "first" ||
+		string(b.values[numberedKey(2)]) != "second" ||
+		b.values[numberedKey(3)] != nil {
+		t.Errorf("batch holds the wrong changes")
+	}
+}
+
+func BenchmarkTree_Apply(b *testing.B) {
+	tree, _ := newTestTree(b, hasher.BLAKE2)
+	batch := NewBatch()
+	for i := 0; i < 10000; i++ {
+		batch.Set(numberedKey(i), []byte("value"))
+	}
+	_ = tree.Apply(batch)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		batch = NewBatch()
+		for j := 0; j < 100; j++ {
+			batch.Set(numberedKey((i*100+j)%20000), []byte(strconv.Itoa(i)))
+		}
+		if err := tree.Apply(batch); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
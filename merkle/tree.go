@@ -0,0 +1,269 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+// Package merkle implements append-only Merkle trees in the style of RFC 6962
+// (Certificate Transparency), with inclusion proofs that a leaf is in a tree
+// and consistency proofs that a larger tree extends a smaller one. Proofs are
+// verified against a root alone, without the tree.
+//
+// Leaves are hashed as H(0x00 || data) and interior nodes as
+// H(0x01 || left || right), so a leaf can never be mistaken for a node. The
+// root of the empty tree is H(""). A tree of n leaves splits into a left
+// subtree of the largest power of two smaller than n leaves and a right
+// subtree of the rest. With SHA2_256 the roots and proofs are exactly those
+// of RFC 6962; any other hasher.HashType, such as BLAKE3, may be used.
+package merkle
+
+import (
+	"hash"
+	"math/bits"
+	"sync"
+
+	"github.com/pkg/errors"
+	jww "github.com/spf13/jwalterweatherman"
+	"gitlab.com/xx_network/crypto/hasher"
+)
+
+// Domain separation prefixes for leaf and node hashes.
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+// Tree is an append-only Merkle tree. It keeps the hash of every complete
+// subtree, about twice the leaf hashes in all, so the root and proofs for
+// the current or any earlier size take O(log n) hashes. It is safe for
+// concurrent use.
+type Tree struct {
+	h hasher.HashType
+
+	// levels[k][i] is the hash of the complete subtree of the 2^k leaves
+	// starting at leaf i·2^k
+	levels [][][]byte
+	mux    sync.RWMutex
+}
+
+// New returns an empty tree over the given hash.
+func New(h hasher.HashType) (*Tree, error) {
+	if !h.Available() {
+		return nil, errors.Errorf("unknown hash type %d", uint8(h))
+	}
+	return &Tree{h: h, levels: [][][]byte{nil}}, nil
+}
+
+// Hash returns the hash type of the tree.
+func (t *Tree) Hash() hasher.HashType {
+	return t.h
+}
+
+// Size returns the number of leaves.
+func (t *Tree) Size() uint64 {
+	t.mux.RLock()
+	defer t.mux.RUnlock()
+	return uint64(len(t.levels[0]))
+}
+
+// Append adds a leaf with the given data and returns its index.
+func (t *Tree) Append(data []byte) uint64 {
+	return t.appendLeafHash(HashLeaf(t.h, data))
+}
+
+// AppendLeafHash adds a leaf whose hash, from HashLeaf, is already known and
+// returns its index. It returns an error if the hash has the wrong size.
+func (t *Tree) AppendLeafHash(leafHash []byte) (uint64, error) {
+	if len(leafHash) != t.h.Size() {
+		return 0, errors.Errorf("leaf hash has %d bytes, expected %d",
+			len(leafHash), t.h.Size())
+	}
+	return t.appendLeafHash(leafHash), nil
+}
+
+// appendLeafHash adds a leaf hash of the right size and returns its index.
+func (t *Tree) appendLeafHash(leafHash []byte) uint64 {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	index := uint64(len(t.levels[0]))
+	node := append([]byte{}, leafHash...)
+	for k := 0; ; k++ {
+		t.levels[k] = append(t.levels[k], node)
+		n := len(t.levels[k])
+		if n%2 == 1 {
+			return index
+		}
+		// A pair has just been completed, so its parent is complete too
+		if k+1 == len(t.levels) {
+			t.levels = append(t.levels, nil)
+		}
+		node = HashNode(t.h, t.levels[k][n-2], node)
+	}
+}
+
+// LeafHash returns the hash of the leaf at index.
+func (t *Tree) LeafHash(index uint64) ([]byte, error) {
+	t.mux.RLock()
+	defer t.mux.RUnlock()
+	if index >= uint64(len(t.levels[0])) {
+		return nil, errors.Errorf("leaf %d is not in a tree of %d leaves",
+			index, len(t.levels[0]))
+	}
+	return append([]byte{}, t.levels[0][index]...), nil
+}
+
+// Root returns the root of the tree.
+func (t *Tree) Root() []byte {
+	t.mux.RLock()
+	defer t.mux.RUnlock()
+	return t.subtreeHash(0, uint64(len(t.levels[0])))
+}
+
+// RootAt returns the root the tree had when it had size leaves.
+func (t *Tree) RootAt(size uint64) ([]byte, error) {
+	t.mux.RLock()
+	defer t.mux.RUnlock()
+	if err := t.checkSize(size); err != nil {
+		return nil, err
+	}
+	return t.subtreeHash(0, size), nil
+}
+
+// InclusionProof returns the proof that the leaf at index is in the tree of
+// the first size leaves.
+func (t *Tree) InclusionProof(index, size uint64) (*InclusionProof, error) {
+	t.mux.RLock()
+	defer t.mux.RUnlock()
+	if err := t.checkSize(size); err != nil {
+		return nil, err
+	}
+	if index >= size {
+		return nil, errors.Errorf("leaf %d is not in a tree of %d leaves",
+			index, size)
+	}
+
+	// PATH(m, D[n]) of RFC 6962 section 2.1.1, collected from the leaf up
+	var path [][]byte
+	var build func(m, start, n uint64)
+	build = func(m, start, n uint64) {
+		if n <= 1 {
+			return
+		}
+		k := splitPoint(n)
+		if m < k {
+			build(m, start, k)
+			path = append(path, t.subtreeHash(start+k, n-k))
+		} else {
+			build(m-k, start+k, n-k)
+			path = append(path, t.subtreeHash(start, k))
+		}
+	}
+	build(index, 0, size)
+	return &InclusionProof{Hash: t.h, Index: index, Size: size, Path: path},
+		nil
+}
+
+// ConsistencyProof returns the proof that the tree of the first newSize
+// leaves extends the tree of the first oldSize leaves.
+func (t *Tree) ConsistencyProof(oldSize, newSize uint64) (*ConsistencyProof,
+	error) {
+	t.mux.RLock()
+	defer t.mux.RUnlock()
+	if err := t.checkSize(newSize); err != nil {
+		return nil, err
+	}
+	if oldSize > newSize {
+		return nil, errors.Errorf("old size %d is larger than new size %d",
+			oldSize, newSize)
+	}
+
+	// SUBPROOF(m, D[n], b) of RFC 6962 section 2.1.2, collected from the
+	// bottom up. The proof between equal sizes, or from the empty tree, is
+	// empty.
+	var path [][]byte
+	var build func(m, start, n uint64, complete bool)
+	build = func(m, start, n uint64, complete bool) {
+		if m == n {
+			if !complete {
+				path = append(path, t.subtreeHash(start, n))
+			}
+			return
+		}
+		k := splitPoint(n)
+		if m <= k {
+			build(m, start, k, complete)
+			path = append(path, t.subtreeHash(start+k, n-k))
+		} else {
+			build(m-k, start+k, n-k, false)
+			path = append(path, t.subtreeHash(start, k))
+		}
+	}
+	if oldSize > 0 {
+		build(oldSize, 0, newSize, true)
+	}
+	return &ConsistencyProof{Hash: t.h, OldSize: oldSize, NewSize: newSize,
+		Path: path}, nil
+}
+
+// checkSize returns an error if the tree has never had size leaves.
+func (t *Tree) checkSize(size uint64) error {
+	if size > uint64(len(t.levels[0])) {
+		return errors.Errorf("tree has %d leaves, fewer than %d",
+			len(t.levels[0]), size)
+	}
+	return nil
+}
+
+// subtreeHash returns a copy of MTH(D[start:start+n]). Every subtree the
+// RFC 6962 recursion visits starts at a multiple of its largest complete
+// part, so the complete parts are always stored.
+func (t *Tree) subtreeHash(start, n uint64) []byte {
+	if n == 0 {
+		return emptyRoot(t.h)
+	}
+	if n&(n-1) == 0 && start%n == 0 {
+		return append([]byte{}, t.levels[bits.TrailingZeros64(n)][start/n]...)
+	}
+	k := splitPoint(n)
+	return HashNode(t.h, t.subtreeHash(start, k), t.subtreeHash(start+k, n-k))
+}
+
+// splitPoint returns the largest power of two smaller than n, for n > 1.
+func splitPoint(n uint64) uint64 {
+	return 1 << (bits.Len64(n-1) - 1)
+}
+
+// HashLeaf returns the leaf hash H(0x00 || data). It panics if h is not
+// registered.
+func HashLeaf(h hasher.HashType, data []byte) []byte {
+	hash := newHash(h)
+	hash.Write([]byte{leafPrefix})
+	hash.Write(data)
+	return hash.Sum(nil)
+}
+
+// HashNode returns the interior node hash H(0x01 || left || right). It
+// panics if h is not registered.
+func HashNode(h hasher.HashType, left, right []byte) []byte {
+	hash := newHash(h)
+	hash.Write([]byte{nodePrefix})
+	hash.Write(left)
+	hash.Write(right)
+	return hash.Sum(nil)
+}
+
+// emptyRoot returns the root of the empty tree, H("").
+func emptyRoot(h hasher.HashType) []byte {
+	return newHash(h).Sum(nil)
+}
+
+// newHash returns a new instance of h, panicking if it is not registered.
+func newHash(h hasher.HashType) hash.Hash {
+	hash, err := h.New()
+	if err != nil {
+		jww.FATAL.Panicf("Failed to hash Merkle tree node: %+v", err)
+	}
+	return hash
+}
@@ -0,0 +1,221 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package merkle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"testing"
+
+	"gitlab.com/xx_network/crypto/hasher"
+)
+
+// testLeaves are the leaves of the Certificate Transparency reference tree.
+var testLeaves = []string{"", "00", "10", "2021", "3031", "40414243",
+	"5051525354555657", "606162636465666768696a6b6c6d6e6f"}
+
+// testRoots are the SHA-256 roots of the first 1 to 8 testLeaves.
+var testRoots = []string{
+	"6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d",
+	"fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125",
+	"aeb6bcfe274b70a14fb067a5e5578264db0fa9b51af5e0ba159158f329e06e77",
+	"d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+	"4e3bbb1f7b478dcfe71fb631631519a3bca12c9aefca1612bfce4c13a86264d4",
+	"76e67dadbcdf1e10e1b74ddc608abd2f98dfb16fbce75277b5232a127f2087ef",
+	"ddb89be403809e325750d3d263cd78929c2942b7942a34b77e122c9594a74c8c",
+	"5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328",
+}
+
+// decodeHex decodes a hex test vector.
+func decodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("Invalid hex %q: %+v", s, err)
+	}
+	return b
+}
+
+// newTestTree returns a tree of the testLeaves.
+func newTestTree(t *testing.T) *Tree {
+	tree, err := New(hasher.SHA2_256)
+	if err != nil {
+		t.Fatalf("New() error: %+v", err)
+	}
+	for i, leaf := range testLeaves {
+		if index := tree.Append(decodeHex(t, leaf)); index != uint64(i) {
+			t.Fatalf("Append() returned index %d, expected %d", index, i)
+		}
+	}
+	return tree
+}
+
+// newNumberedTree returns a tree of n leaves holding their indices.
+func newNumberedTree(t *testing.T, h hasher.HashType, n int) *Tree {
+	tree, err := New(h)
+	if err != nil {
+		t.Fatalf("New() error: %+v", err)
+	}
+	for i := 0; i < n; i++ {
+		tree.Append([]byte(strconv.Itoa(i)))
+	}
+	return tree
+}
+
+// referenceRoot is the recursive MTH of RFC 6962 section 2.1.
+func referenceRoot(h hasher.HashType, leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		return emptyRoot(h)
+	case 1:
+		return HashLeaf(h, leaves[0])
+	}
+	k := splitPoint(uint64(len(leaves)))
+	return HashNode(h, referenceRoot(h, leaves[:k]),
+		referenceRoot(h, leaves[k:]))
+}
+
+// Tests the roots of the Certificate Transparency reference tree at every
+// size, including the empty tree.
+func TestTree_Root_Vectors(t *testing.T) {
+	tree := newTestTree(t)
+	empty, err := tree.RootAt(0)
+	if err != nil {
+		t.Fatalf("RootAt(0) error: %+v", err)
+	}
+	if got := hex.EncodeToString(empty); got !=
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("empty root = %s", got)
+	}
+	for i, expected := range testRoots {
+		root, err := tree.RootAt(uint64(i + 1))
+		if err != nil {
+			t.Fatalf("RootAt(%d) error: %+v", i+1, err)
+		}
+		if got := hex.EncodeToString(root); got != expected {
+			t.Errorf("RootAt(%d) = %s, expected %s", i+1, got, expected)
+		}
+	}
+	if got := hex.EncodeToString(tree.Root()); got != testRoots[7] {
+		t.Errorf("Root() = %s, expected %s", got, testRoots[7])
+	}
+	if _, err = tree.RootAt(9); err == nil {
+		t.Errorf("RootAt() accepted a size larger than the tree")
+	}
+}
+
+// Tests that incremental roots match the recursive definition for every size
+// with both SHA-256 and BLAKE3.
+func TestTree_Root_Reference(t *testing.T) {
+	for _, h := range []hasher.HashType{hasher.SHA2_256, hasher.BLAKE3} {
+		tree, _ := New(h)
+		var leaves [][]byte
+		for n := 0; n <= 70; n++ {
+			if got := tree.Root(); !bytes.Equal(got,
+				referenceRoot(h, leaves)) {
+				t.Errorf("%s root of %d leaves is wrong", h, n)
+			}
+			leaf := []byte(strconv.Itoa(n))
+			tree.Append(leaf)
+			leaves = append(leaves, leaf)
+		}
+		for n := 0; n <= 70; n++ {
+			root, _ := tree.RootAt(uint64(n))
+			if !bytes.Equal(root, referenceRoot(h, leaves[:n])) {
+				t.Errorf("%s RootAt(%d) is wrong", h, n)
+			}
+		}
+	}
+
+	sha, blake := newNumberedTree(t, hasher.SHA2_256, 5),
+		newNumberedTree(t, hasher.BLAKE3, 5)
+	if bytes.Equal(sha.Root(), blake.Root()) {
+		t.Errorf("SHA-256 and BLAKE3 trees have the same root")
+	}
+	if blake.Hash() != hasher.BLAKE3 {
+		t.Errorf("Hash() = %s, expected BLAKE3", blake.Hash())
+	}
+}
+
+// Tests that the tree returns copies its caller cannot use to corrupt it.
+func TestTree_Copies(t *testing.T) {
+	tree := newNumberedTree(t, hasher.SHA2_256, 4)
+	root := tree.Root()
+	expected := append([]byte{}, root...)
+	root[0] ^= 0xff
+	leaf, _ := tree.LeafHash(0)
+	leaf[0] ^= 0xff
+	if !bytes.Equal(tree.Root(), expected) {
+		t.Errorf("modifying a returned root changed the tree")
+	}
+	leaf, _ = tree.LeafHash(0)
+	if !bytes.Equal(leaf, HashLeaf(hasher.SHA2_256, []byte("0"))) {
+		t.Errorf("modifying a returned leaf hash changed the tree")
+	}
+}
+
+// Tests appending leaf hashes and LeafHash.
+func TestTree_AppendLeafHash(t *testing.T) {
+	tree, _ := New(hasher.SHA2_256)
+	for i := 0; i < 3; i++ {
+		index, err := tree.AppendLeafHash(HashLeaf(hasher.SHA2_256,
+			[]byte(strconv.Itoa(i))))
+		if err != nil || index != uint64(i) {
+			t.Fatalf("AppendLeafHash() = %d, %v", index, err)
+		}
+	}
+	if !bytes.Equal(tree.Root(),
+		newNumberedTree(t, hasher.SHA2_256, 3).Root()) {
+		t.Errorf("appending leaf hashes gave a different root")
+	}
+	if _, err := tree.AppendLeafHash(make([]byte, 31)); err == nil {
+		t.Errorf("AppendLeafHash() accepted a short hash")
+	}
+	if tree.Size() != 3 {
+		t.Errorf("Size() = %d, expected 3", tree.Size())
+	}
+	if _, err := tree.LeafHash(3); err == nil {
+		t.Errorf("LeafHash() accepted an index past the end")
+	}
+	if _, err := New(200); err == nil {
+		t.Errorf("New() accepted an unknown hash")
+	}
+}
+
+// Tests concurrent appends and reads.
+func TestTree_Concurrent(t *testing.T) {
+	tree, _ := New(hasher.BLAKE3)
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				tree.Append([]byte("leaf"))
+				size := tree.Size()
+				if _, err := tree.InclusionProof(0, size); err != nil {
+					t.Errorf("InclusionProof() error: %+v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if tree.Size() != 200 {
+		t.Errorf("Size() = %d, expected 200", tree.Size())
+	}
+}
+
+func BenchmarkTree_Append(b *testing.B) {
+	tree, _ := New(hasher.BLAKE3)
+	data := make([]byte, 64)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree.Append(data)
+	}
+}
@@ -10,6 +10,8 @@ package mnemonic
 import (
 	"bytes"
 	"testing"
+
+	"gitlab.com/xx_network/crypto/csprng"
 )
 
 type TestByteBuf struct {
@@ -44,3 +46,19 @@ func TestMnemonic(t *testing.T) {
 		t.Errorf("Unable to read entropy that was put in")
 	}
 }
+
+// Error path: GenerateMnemonic should fail when the source errors or
+// returns a short read
+func TestGenerateMnemonic_SourceFaults(t *testing.T) {
+	sources := []csprng.Source{
+		csprng.NewFailingSource(csprng.NewSystemRNG(), 16, nil),
+		csprng.NewShortReadSource(csprng.NewSystemRNG(), 31),
+	}
+	for i, rng := range sources {
+		mnemonic, err := GenerateMnemonic(rng, 32)
+		if err == nil || mnemonic != "" {
+			t.Errorf("Source %d: should not have been able to create "+
+				"mnemonic!", i)
+		}
+	}
+}
@@ -86,8 +86,27 @@ type PrivateKey interface {
 //
 // The message must be no longer than the length of the public modulus minus
 // twice the hash length, minus a further 2.
+//
+// The private exponent is applied with large.Int.Exp, which is not constant
+// time. Use EncryptOAEPConstantTime when timing side channels are a concern.
 func EncryptOAEP(hash hash.Hash, random io.Reader, priv PrivateKey,
 	msg []byte, label []byte) ([]byte, error) {
+	return encryptOAEP(hash, random, priv, msg, label, false)
+}
+
+// EncryptOAEPConstantTime is EncryptOAEP with the private exponent applied
+// using constant-time Montgomery arithmetic, so the time taken does not depend
+// on the private exponent or the message. The ciphertext is identical to the
+// one EncryptOAEP produces for the same inputs and is decrypted with
+// DecryptOAEP.
+func EncryptOAEPConstantTime(hash hash.Hash, random io.Reader,
+	priv PrivateKey, msg []byte, label []byte) ([]byte, error) {
+	return encryptOAEP(hash, random, priv, msg, label, true)
+}
+
+// encryptOAEP implements EncryptOAEP and EncryptOAEPConstantTime.
+func encryptOAEP(hash hash.Hash, random io.Reader, priv PrivateKey,
+	msg []byte, label []byte, constantTime bool) ([]byte, error) {
 	if err := checkPub(priv); err != nil {
 		return nil, err
 	}
@@ -117,6 +136,10 @@ func EncryptOAEP(hash hash.Hash, random io.Reader, priv PrivateKey,
 	mgf1XOR(db, hash, seed)
 	mgf1XOR(seed, hash, db)
 
+	if constantTime {
+		return encryptConstantTime(priv, em)
+	}
+
 	m := new(large.Int)
 	m.SetBytes(em)
 	c := encrypt(new(large.Int), priv, m)
@@ -208,6 +231,20 @@ func encrypt(c *large.Int, priv PrivateKey, m *large.Int) *large.Int {
 	return c
 }
 
+// encryptConstantTime returns em^d mod n as a big-endian byte slice the size
+// of the key, computed in constant time.
+func encryptConstantTime(priv PrivateKey, em []byte) ([]byte, error) {
+	n, err := large.NewModulus(priv.GetN())
+	if err != nil {
+		return nil, err
+	}
+	m := n.NewMontIntFromBytes(em)
+	c := n.Zero().Exp(m, priv.GetD())
+	out := make([]byte, priv.Size())
+	copy(out[len(out)-n.ByteLen():], c.Bytes())
+	return out, nil
+}
+
 func decrypt(m *large.Int, pub PublicKey, c *large.Int) *large.Int {
 	// Again, c^e instead of c^d, so c^e -> (m^d)^e = m
 	e := large.NewIntFromUInt(uint64(pub.GetE()))
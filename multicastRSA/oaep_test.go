@@ -86,3 +86,45 @@ func TestEncryptRSATooLong(t *testing.T) {
 		t.Fatalf("Message should have been too long to encrypt!")
 	}
 }
+
+// Tests that the constant-time encryption produces the same ciphertext as
+// EncryptOAEP for the same randomness and that it decrypts.
+func TestEncryptOAEPConstantTime(t *testing.T) {
+	priv, err := xxrsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Failed to generate private key: %+v", err)
+	}
+	pub := priv.GetPublic()
+
+	h := sha256.New()
+	label := []byte("testing123")
+	seed := make([]byte, h.Size())
+	if _, err = rand.Read(seed); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, inM := range [][]byte{[]byte("Hello"), []byte(""),
+		[]byte("This is a short little message to test it.")} {
+		expected, err := EncryptOAEP(h, bytes.NewReader(seed), priv, inM,
+			label)
+		if err != nil {
+			t.Fatalf("'%s': %+v", inM, err)
+		}
+		c, err := EncryptOAEPConstantTime(h, bytes.NewReader(seed), priv,
+			inM, label)
+		if err != nil {
+			t.Fatalf("'%s': %+v", inM, err)
+		}
+		if !bytes.Equal(c, expected) {
+			t.Errorf("Constant-time ciphertext differs for '%s'", inM)
+		}
+
+		m, err := DecryptOAEP(h, pub, c, label)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if !bytes.Equal(inM, m) {
+			t.Errorf("Encrypt/Decrypt Mismatch, in: %v, out: %v", inM, m)
+		}
+	}
+}
@@ -10,7 +10,7 @@
 package nonce
 
 import (
-	jww "github.com/spf13/jwalterweatherman"
+	"github.com/pkg/errors"
 	"gitlab.com/xx_network/crypto/csprng"
 	"time"
 )
@@ -35,20 +35,25 @@ type Nonce struct {
 
 // NewNonce generate a fresh nonce with the given TTL in seconds
 func NewNonce(ttl uint) (Nonce, error) {
+	return NewNonceFromSource(csprng.NewSystemRNG(), ttl)
+}
+
+// NewNonceFromSource generates a fresh nonce with the given TTL in seconds
+// using the given source of randomness. It returns an error if the TTL is 0
+// or the source fails.
+func NewNonceFromSource(rng csprng.Source, ttl uint) (Nonce, error) {
 	if ttl == 0 {
-		jww.FATAL.Panicf("TTL cannot be 0")
+		return Nonce{}, errors.New("TTL cannot be 0")
 	}
-	newValue := make([]byte, NonceLen)
-	randGen := csprng.SystemRNG{}
-	size, err := randGen.Read(newValue)
-	if err != nil || size != len(newValue) {
-		jww.FATAL.Panicf("Could not generate nonce: %v", err.Error())
+	newValue, err := csprng.Generate(NonceLen, rng)
+	if err != nil {
+		return Nonce{}, errors.WithMessage(err, "could not generate nonce")
 	}
 	newNonce := Nonce{GenTime: time.Now(),
 		TTL: time.Duration(ttl) * time.Second}
 	copy(newNonce.Value[:], newValue)
 	newNonce.ExpiryTime = newNonce.GenTime.Add(newNonce.TTL)
-	return newNonce, err
+	return newNonce, nil
 }
 
 // Bytes returns the nonce's value in a byte slice
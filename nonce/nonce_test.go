@@ -9,6 +9,7 @@ package nonce
 
 import (
 	"encoding/hex"
+	"gitlab.com/xx_network/crypto/csprng"
 	"testing"
 	"time"
 )
@@ -95,14 +96,41 @@ func TestNewNonceVarious(t *testing.T) {
 	}
 }
 
-// Test panic if TTL is 0
-func TestNewNoncePanic(t *testing.T) {
-	defer func() {
-		if r := recover(); r == nil {
-			t.Errorf("Nonce should panic on 0 TTL")
+// Test error if TTL is 0
+func TestNewNonceZeroTTL(t *testing.T) {
+	if _, err := NewNonce(0); err == nil {
+		t.Errorf("Nonce should return an error on 0 TTL")
+	}
+}
+
+// Test error if the source fails or returns a short read
+func TestNewNonceFromSourceError(t *testing.T) {
+	sources := []csprng.Source{
+		csprng.NewFailingSource(csprng.NewSystemRNG(), NonceLen/2, nil),
+		csprng.NewShortReadSource(csprng.NewSystemRNG(), NonceLen-1),
+	}
+	for i, rng := range sources {
+		n, err := NewNonceFromSource(rng, NormalTTL)
+		if err == nil {
+			t.Errorf("Source %d: nonce should fail when the source fails", i)
+		}
+		if n.Value != (Value{}) {
+			t.Errorf("Source %d: failed nonce should be empty: %x", i, n.Value)
+		}
+	}
+}
+
+// Test that the nonce value comes from the given source
+func TestNewNonceFromSource(t *testing.T) {
+	n, err := NewNonceFromSource(csprng.NewConstantSource(0x42), NormalTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, b := range n.Bytes() {
+		if b != 0x42 {
+			t.Fatalf("Byte %d of the nonce did not come from the source", i)
 		}
-	}()
-	NewNonce(0)
+	}
 }
 
 // Test TTL correctly set
@@ -0,0 +1,193 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package poly
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+	"gitlab.com/xx_network/crypto/large"
+)
+
+// Interpolate returns the unique polynomial mod p of degree less than
+// len(xs) with f(xs[i]) = ys[i] for every i. The xs must be distinct mod p.
+//
+// It uses the Lagrange form f = Σ ys[i]/m'(xs[i]) · m(x)/(x - xs[i]) with
+// m = Π (x - xs[i]), evaluating m' at every point and summing the terms up a
+// subproduct tree, which takes O(n log² n) field operations.
+func Interpolate(p *large.Int, xs, ys []*large.Int) (*Polynomial, error) {
+	if err := checkPrime(p); err != nil {
+		return nil, err
+	}
+	pb := new(big.Int).Set(p.BigInt())
+	points, values, err := checkPoints(pb, xs, ys)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := newSubproductTree(points, pb)
+	d := evaluateMany(derivative(tree.root(), pb), points, pb)
+	dInv, err := large.BatchModInverse(toLarge(d), p)
+	if err != nil {
+		// m'(x_i) is only zero when x_i is a repeated root, which
+		// checkPoints rules out
+		return nil, errors.WithMessage(err, "points are not distinct")
+	}
+
+	weights := make([]*big.Int, len(values))
+	for i, y := range values {
+		weights[i] = new(big.Int).Mul(y, dInv[i].BigInt())
+		weights[i].Mod(weights[i], pb)
+	}
+	return newPoly(pb, tree.combine(weights)), nil
+}
+
+// LagrangeCoefficients returns the Lagrange basis polynomials for the points
+// xs evaluated at x, the values l_i = Π_{j≠i} (x - xs[j])/(xs[i] - xs[j]). The
+// interpolating polynomial through the points (xs[i], y_i) takes the value
+// Σ l_i·y_i at x. With x = 0 they are the coefficients that combine shares
+// into the secret in threshold schemes. The xs must be distinct mod p.
+func LagrangeCoefficients(p *large.Int, xs []*large.Int, x *large.Int) (
+	[]*large.Int, error) {
+	if err := checkPrime(p); err != nil {
+		return nil, err
+	}
+	pb := new(big.Int).Set(p.BigInt())
+	points, _, err := checkPoints(pb, xs, nil)
+	if err != nil {
+		return nil, err
+	}
+	return lagrangeCoefficients(pb, points, new(big.Int).Mod(x.BigInt(), pb))
+}
+
+// lagrangeCoefficients computes the basis values for distinct reduced points
+// at a reduced x.
+func lagrangeCoefficients(p *big.Int, points []*big.Int, x *big.Int) (
+	[]*large.Int, error) {
+	n := len(points)
+	var tmp big.Int
+
+	// The numerator of l_i is the product of x - x_j over j ≠ i, the product
+	// of a prefix and a suffix of the factors
+	diffs := make([]*big.Int, n)
+	for i, xi := range points {
+		diffs[i] = new(big.Int).Sub(x, xi)
+		diffs[i].Mod(diffs[i], p)
+	}
+	suffix := make([]*big.Int, n+1)
+	suffix[n] = big.NewInt(1)
+	for i := n - 1; i >= 0; i-- {
+		suffix[i] = new(big.Int).Mul(suffix[i+1], diffs[i])
+		suffix[i].Mod(suffix[i], p)
+	}
+
+	// The denominator of l_i is the product of x_i - x_j over j ≠ i
+	dens := make([]*large.Int, n)
+	for i, xi := range points {
+		den := big.NewInt(1)
+		for j, xj := range points {
+			if j != i {
+				den.Mul(den, tmp.Sub(xi, xj))
+				den.Mod(den, p)
+			}
+		}
+		dens[i] = large.NewIntFromBigInt(den)
+	}
+	inv, err := large.BatchModInverse(dens, large.NewIntFromBigInt(p))
+	if err != nil {
+		return nil, errors.WithMessage(err, "points are not distinct")
+	}
+
+	coeffs := make([]*large.Int, n)
+	prefix := big.NewInt(1)
+	for i := range points {
+		l := new(big.Int).Mul(prefix, suffix[i+1])
+		l.Mod(l, p)
+		l.Mul(l, inv[i].BigInt())
+		coeffs[i] = large.NewIntFromBigInt(l.Mod(l, p))
+		prefix.Mul(prefix, diffs[i])
+		prefix.Mod(prefix, p)
+	}
+	return coeffs, nil
+}
+
+// InterpolateAt returns f(x) for the polynomial f mod p of degree less than
+// len(xs) with f(xs[i]) = ys[i], without computing f itself. It takes O(n²)
+// field operations. The xs must be distinct mod p.
+func InterpolateAt(p *large.Int, xs, ys []*large.Int, x *large.Int) (
+	*large.Int, error) {
+	if err := checkPrime(p); err != nil {
+		return nil, err
+	}
+	pb := new(big.Int).Set(p.BigInt())
+	points, values, err := checkPoints(pb, xs, ys)
+	if err != nil {
+		return nil, err
+	}
+	coeffs, err := lagrangeCoefficients(pb, points,
+		new(big.Int).Mod(x.BigInt(), pb))
+	if err != nil {
+		return nil, err
+	}
+
+	sum := new(big.Int)
+	var tmp big.Int
+	for i, l := range coeffs {
+		sum.Add(sum, tmp.Mul(l.BigInt(), values[i]))
+	}
+	return large.NewIntFromBigInt(sum.Mod(sum, pb)), nil
+}
+
+// InterpolateAtZero returns f(0) for the polynomial f mod p of degree less
+// than len(xs) with f(xs[i]) = ys[i], such as the secret shared by Shamir
+// shares (xs[i], ys[i]). The xs must be distinct mod p.
+func InterpolateAtZero(p *large.Int, xs, ys []*large.Int) (*large.Int,
+	error) {
+	return InterpolateAt(p, xs, ys, large.NewInt(0))
+}
+
+// checkPoints reduces the xs and ys mod p, checking that there is one y per x,
+// that there is at least one point and that the xs are distinct. ys may be
+// nil when only the xs are needed.
+func checkPoints(p *big.Int, xs, ys []*large.Int) (points, values []*big.Int,
+	err error) {
+	if ys != nil && len(xs) != len(ys) {
+		return nil, nil, errors.Errorf("%d x values but %d y values",
+			len(xs), len(ys))
+	}
+	if len(xs) == 0 {
+		return nil, nil, errors.New("no points given")
+	}
+
+	seen := make(map[string]int, len(xs))
+	points = make([]*big.Int, len(xs))
+	for i, x := range xs {
+		points[i] = new(big.Int).Mod(x.BigInt(), p)
+		key := points[i].Text(16)
+		if j, ok := seen[key]; ok {
+			return nil, nil, errors.Errorf("x values %d and %d are equal "+
+				"mod p", j, i)
+		}
+		seen[key] = i
+	}
+
+	values = make([]*big.Int, len(ys))
+	for i, y := range ys {
+		values[i] = new(big.Int).Mod(y.BigInt(), p)
+	}
+	return points, values, nil
+}
+
+// toLarge converts a slice of big.Int to large.Int.
+func toLarge(c []*big.Int) []*large.Int {
+	res := make([]*large.Int, len(c))
+	for i, x := range c {
+		res[i] = large.NewIntFromBigInt(x)
+	}
+	return res
+}
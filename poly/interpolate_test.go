@@ -0,0 +1,133 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package poly
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"gitlab.com/xx_network/crypto/csprng"
+	"gitlab.com/xx_network/crypto/large"
+)
+
+// randomPoints returns n distinct random points mod p.
+func randomPoints(prng *rand.Rand, p *large.Int, n int) []*large.Int {
+	seen := make(map[string]bool, n)
+	xs := make([]*large.Int, 0, n)
+	for len(xs) < n {
+		x := new(big.Int).Rand(prng, p.BigInt())
+		if !seen[x.Text(16)] {
+			seen[x.Text(16)] = true
+			xs = append(xs, large.NewIntFromBigInt(x))
+		}
+	}
+	return xs
+}
+
+// Tests that interpolating the values of a polynomial recovers it, for small
+// and large point sets over both kinds of prime.
+func TestInterpolate(t *testing.T) {
+	prng := rand.New(rand.NewSource(42))
+	for _, p := range []*large.Int{p25519, p998} {
+		for _, n := range []int{1, 2, 7, 100} {
+			f := randomPoly(t, prng, p, n)
+			xs := randomPoints(prng, p, n)
+			g, err := Interpolate(p, xs, f.EvaluateMany(xs))
+			if err != nil {
+				t.Fatalf("Interpolate() error: %+v", err)
+			}
+			if !g.Equal(f) {
+				t.Errorf("interpolation of %d points mod %s did not recover "+
+					"the polynomial", n, p.Text(10))
+			}
+		}
+	}
+}
+
+// Tests that InterpolateAtZero recovers the constant term from enough
+// points and InterpolateAt agrees with the interpolated polynomial.
+func TestInterpolateAt(t *testing.T) {
+	rng := csprng.NewSystemRNG()
+	prng := rand.New(rand.NewSource(42))
+	secret := large.NewInt(1234567)
+	f, err := RandomWithConstant(p25519, 4, secret, rng)
+	if err != nil {
+		t.Fatalf("RandomWithConstant() error: %+v", err)
+	}
+
+	xs := ints(1, 2, 3, 4, 5)
+	ys := f.EvaluateMany(xs)
+	got, err := InterpolateAtZero(p25519, xs, ys)
+	if err != nil {
+		t.Fatalf("InterpolateAtZero() error: %+v", err)
+	}
+	if got.Cmp(secret) != 0 {
+		t.Errorf("InterpolateAtZero() = %s, expected %s", got.Text(10),
+			secret.Text(10))
+	}
+
+	for _, x := range append(randomPoints(prng, p25519, 3), xs[2]) {
+		got, err := InterpolateAt(p25519, xs, ys, x)
+		if err != nil {
+			t.Fatalf("InterpolateAt() error: %+v", err)
+		}
+		if expected := f.Evaluate(x); got.Cmp(expected) != 0 {
+			t.Errorf("InterpolateAt(%s) = %s, expected %s", x.Text(10),
+				got.Text(10), expected.Text(10))
+		}
+	}
+
+	// Too few points do not determine the secret
+	got, err = InterpolateAtZero(p25519, xs[:4], ys[:4])
+	if err != nil {
+		t.Fatalf("InterpolateAtZero() error: %+v", err)
+	}
+	if got.Cmp(secret) == 0 {
+		t.Error("four points recovered the constant of a degree 4 polynomial")
+	}
+}
+
+// Tests that the Lagrange coefficients at any point sum to one and match
+// the ones used by InterpolateAt.
+func TestLagrangeCoefficients(t *testing.T) {
+	p := large.NewInt(101)
+	xs := ints(1, 3, 4, 10)
+	for _, x := range ints(0, 2, 3, 50) {
+		coeffs, err := LagrangeCoefficients(p, xs, x)
+		if err != nil {
+			t.Fatalf("LagrangeCoefficients() error: %+v", err)
+		}
+		sum := large.NewInt(0)
+		for _, l := range coeffs {
+			sum.Add(sum, l)
+		}
+		if sum.Mod(sum, p).Int64() != 1 {
+			t.Errorf("coefficients at %s sum to %s", x.Text(10),
+				sum.Text(10))
+		}
+	}
+}
+
+// Tests that repeated x values, mismatched lengths and empty inputs are
+// rejected.
+func TestInterpolate_Errors(t *testing.T) {
+	p := large.NewInt(101)
+	if _, err := Interpolate(p, ints(1, 102), ints(5, 6)); err == nil {
+		t.Error("Interpolate() accepted x values equal mod p")
+	}
+	if _, err := InterpolateAt(p, ints(1, 2), ints(5), large.NewInt(0)); err == nil {
+		t.Error("InterpolateAt() accepted mismatched lengths")
+	}
+	if _, err := LagrangeCoefficients(p, nil, large.NewInt(0)); err == nil {
+		t.Error("LagrangeCoefficients() accepted no points")
+	}
+	if _, err := InterpolateAtZero(large.NewInt(100), ints(1), ints(1)); err == nil {
+		t.Error("InterpolateAtZero() accepted a composite modulus")
+	}
+}
@@ -0,0 +1,214 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package poly
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+const (
+	// schoolbookThreshold is the length of the shorter factor below which
+	// products are computed term by term.
+	schoolbookThreshold = 16
+
+	// nttThreshold is the length of the shorter factor from which products
+	// use the number-theoretic transform when the modulus supports it.
+	nttThreshold = 64
+
+	// newtonThreshold is the quotient and divisor length from which
+	// division uses a Newton iteration for the inverse of the divisor
+	// instead of long division.
+	newtonThreshold = 32
+)
+
+// mulSlices returns a·b mod p. The inputs need not be trimmed.
+func mulSlices(a, b []*big.Int, p *big.Int) []*big.Int {
+	a, b = trim(a), trim(b)
+	if len(a) == 0 || len(b) == 0 {
+		return nil
+	}
+	shorter := len(a)
+	if len(b) < shorter {
+		shorter = len(b)
+	}
+	switch {
+	case shorter < schoolbookThreshold:
+		return mulSchoolbook(a, b, p)
+	case shorter >= nttThreshold:
+		if t := nttFor(p, len(a)+len(b)-1); t != nil {
+			return t.mul(a, b)
+		}
+	}
+	return mulKronecker(a, b, p)
+}
+
+// mulSchoolbook returns a·b mod p, summing the products for each coefficient
+// before reducing it.
+func mulSchoolbook(a, b []*big.Int, p *big.Int) []*big.Int {
+	res := make([]*big.Int, len(a)+len(b)-1)
+	for i := range res {
+		res[i] = new(big.Int)
+	}
+	var tmp big.Int
+	for i, x := range a {
+		if x.Sign() == 0 {
+			continue
+		}
+		for j, y := range b {
+			res[i+j].Add(res[i+j], tmp.Mul(x, y))
+		}
+	}
+	for _, c := range res {
+		c.Mod(c, p)
+	}
+	return trim(res)
+}
+
+// mulKronecker returns a·b mod p by packing each polynomial into one integer
+// with a slot of whole words per coefficient, multiplying the integers and
+// unpacking the slots. The slots are wide enough that no coefficient of the
+// integer product carries into the next.
+func mulKronecker(a, b []*big.Int, p *big.Int) []*big.Int {
+	shorter := len(a)
+	if len(b) < shorter {
+		shorter = len(b)
+	}
+	// Each slot of the product is a sum of at most shorter products of two
+	// values below p
+	slotBits := 2*p.BitLen() + bits.Len(uint(shorter))
+	slot := (slotBits + bits.UintSize - 1) / bits.UintSize
+
+	pack := func(c []*big.Int) *big.Int {
+		words := make([]big.Word, len(c)*slot)
+		for i, x := range c {
+			copy(words[i*slot:], x.Bits())
+		}
+		return new(big.Int).SetBits(words)
+	}
+	prod := new(big.Int).Mul(pack(a), pack(b)).Bits()
+
+	res := make([]*big.Int, len(a)+len(b)-1)
+	for i := range res {
+		res[i] = new(big.Int)
+		start := i * slot
+		if start >= len(prod) {
+			continue
+		}
+		end := start + slot
+		if end > len(prod) {
+			end = len(prod)
+		}
+		// Cap the slice so that nothing can write past the slot
+		x := new(big.Int).SetBits(prod[start:end:end])
+		res[i].Mod(x, p)
+	}
+	return trim(res)
+}
+
+// divModSlices returns the quotient and remainder of a divided by b mod p.
+// The leading coefficient of b must be non-zero.
+func divModSlices(a, b []*big.Int, p *big.Int) (q, r []*big.Int) {
+	a, b = trim(a), trim(b)
+	if len(a) < len(b) {
+		return nil, copySlice(a)
+	}
+	m := len(a) - len(b) + 1
+	if m < newtonThreshold || len(b) < newtonThreshold {
+		return divSchoolbook(a, b, p)
+	}
+
+	// With rev(f) = x^deg(f)·f(1/x), rev(q) = rev(a)·rev(b)⁻¹ mod x^m
+	inv := invertSeries(reverse(b), m, p)
+	revA := reverse(a)[:m]
+	revQ := truncate(mulSlices(revA, inv, p), m)
+	q = make([]*big.Int, m)
+	for i := range q {
+		if j := m - 1 - i; j < len(revQ) {
+			q[i] = revQ[j]
+		} else {
+			q[i] = new(big.Int)
+		}
+	}
+	q = trim(q)
+
+	// Only the coefficients below deg(b) of a - q·b are non-zero
+	r = subSlices(truncate(a, len(b)-1), truncate(mulSlices(q, b, p),
+		len(b)-1), p)
+	return q, r
+}
+
+// divSchoolbook returns the quotient and remainder of a divided by b mod p by
+// long division.
+func divSchoolbook(a, b []*big.Int, p *big.Int) (q, r []*big.Int) {
+	rem := copySlice(a)
+	lcInv := new(big.Int).ModInverse(b[len(b)-1], p)
+	q = make([]*big.Int, len(a)-len(b)+1)
+	var tmp big.Int
+	for i := len(q) - 1; i >= 0; i-- {
+		c := new(big.Int).Mul(rem[i+len(b)-1], lcInv)
+		c.Mod(c, p)
+		q[i] = c
+		if c.Sign() == 0 {
+			continue
+		}
+		for j := 0; j < len(b)-1; j++ {
+			rem[i+j].Sub(rem[i+j], tmp.Mul(c, b[j]))
+			rem[i+j].Mod(rem[i+j], p)
+		}
+	}
+	return trim(q), trim(rem[:len(b)-1])
+}
+
+// invertSeries returns g with f·g = 1 mod x^n, using the Newton iteration
+// g ← g·(2 - f·g), which doubles the number of correct coefficients each
+// step. The constant term of f must be non-zero.
+func invertSeries(f []*big.Int, n int, p *big.Int) []*big.Int {
+	g := []*big.Int{new(big.Int).ModInverse(f[0], p)}
+	for k := 1; k < n; {
+		k *= 2
+		if k > n {
+			k = n
+		}
+		// e = 2 - f·g mod x^k
+		e := subSlices(nil, truncate(mulSlices(truncate(f, k), g, p), k), p)
+		if len(e) == 0 {
+			e = []*big.Int{new(big.Int)}
+		}
+		e[0].Add(e[0], big.NewInt(2))
+		e[0].Mod(e[0], p)
+		g = truncate(mulSlices(g, e, p), k)
+	}
+	return g
+}
+
+// reverse returns the coefficients of c in the opposite order.
+func reverse(c []*big.Int) []*big.Int {
+	res := make([]*big.Int, len(c))
+	for i, x := range c {
+		res[len(c)-1-i] = x
+	}
+	return res
+}
+
+// truncate returns c mod x^n without copying it.
+func truncate(c []*big.Int, n int) []*big.Int {
+	if len(c) > n {
+		c = c[:n]
+	}
+	return trim(c)
+}
+
+// copySlice returns a deep copy of c.
+func copySlice(c []*big.Int) []*big.Int {
+	res := make([]*big.Int, len(c))
+	for i, x := range c {
+		res[i] = new(big.Int).Set(x)
+	}
+	return res
+}
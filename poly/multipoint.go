@@ -0,0 +1,136 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package poly
+
+import (
+	"math/big"
+
+	"gitlab.com/xx_network/crypto/large"
+)
+
+const (
+	// multipointThreshold is the number of points from which EvaluateMany
+	// uses a subproduct tree instead of evaluating at each point separately.
+	multipointThreshold = 64
+
+	// hornerLevel is the level of the subproduct tree at which evaluation
+	// stops reducing and evaluates the remainders at each of the up to
+	// 2^hornerLevel points of their node instead.
+	hornerLevel = 3
+)
+
+// EvaluateMany returns f(x) mod p for every x, in order. For many points it
+// reduces f down a subproduct tree of the points, which takes
+// O(n log² n) field operations instead of the O(n²) of evaluating at each
+// point.
+func (f *Polynomial) EvaluateMany(xs []*large.Int) []*large.Int {
+	points := make([]*big.Int, len(xs))
+	for i, x := range xs {
+		points[i] = new(big.Int).Mod(x.BigInt(), f.p)
+	}
+	return toLarge(evaluateMany(f.coeffs, points, f.p))
+}
+
+// evaluateMany evaluates c at every reduced point.
+func evaluateMany(c, points []*big.Int, p *big.Int) []*big.Int {
+	if len(points) < multipointThreshold || len(c) < multipointThreshold {
+		values := make([]*big.Int, len(points))
+		for i, x := range points {
+			values[i] = horner(c, x, p)
+		}
+		return values
+	}
+	return newSubproductTree(points, p).evaluate(c)
+}
+
+// subproductTree holds the products of the linear factors x - x_i over
+// ranges of points. Level 0 holds the factors themselves and every node is
+// the product of its two children; an unpaired last node is carried up as is.
+// The top level holds the product of all the factors.
+type subproductTree struct {
+	p      *big.Int
+	points []*big.Int
+	levels [][][]*big.Int
+}
+
+// newSubproductTree builds the tree over reduced points.
+func newSubproductTree(points []*big.Int, p *big.Int) *subproductTree {
+	level := make([][]*big.Int, len(points))
+	for i, x := range points {
+		neg := new(big.Int).Sub(p, x)
+		level[i] = []*big.Int{neg.Mod(neg, p), big.NewInt(1)}
+	}
+
+	t := &subproductTree{p: p, points: points, levels: [][][]*big.Int{level}}
+	for len(level) > 1 {
+		next := make([][]*big.Int, (len(level)+1)/2)
+		for i := range next {
+			if 2*i+1 < len(level) {
+				next[i] = mulSlices(level[2*i], level[2*i+1], p)
+			} else {
+				next[i] = level[2*i]
+			}
+		}
+		t.levels = append(t.levels, next)
+		level = next
+	}
+	return t
+}
+
+// root returns the product of all the linear factors.
+func (t *subproductTree) root() []*big.Int {
+	return t.levels[len(t.levels)-1][0]
+}
+
+// evaluate returns c at every point by reducing it modulo each node on the
+// way down. The remainder modulo a node agrees with c on the node's points,
+// which are evaluated directly once there are only a few of them.
+func (t *subproductTree) evaluate(c []*big.Int) []*big.Int {
+	top := len(t.levels) - 1
+	_, r := divModSlices(c, t.root(), t.p)
+	rems := [][]*big.Int{r}
+	level := top
+	for ; level > hornerLevel; level-- {
+		next := make([][]*big.Int, len(t.levels[level-1]))
+		for i := range next {
+			_, next[i] = divModSlices(rems[i/2], t.levels[level-1][i], t.p)
+		}
+		rems = next
+	}
+
+	// Node i of a level covers the points from i·2^level
+	values := make([]*big.Int, len(t.points))
+	for i, x := range t.points {
+		values[i] = horner(rems[i>>level], x, t.p)
+	}
+	return values
+}
+
+// combine returns the sum of weights[i]·m(x)/(x - x_i), where m is the root,
+// by combining the weights up the tree: a node with children L and R holds
+// L's sum times R's product plus R's sum times L's product.
+func (t *subproductTree) combine(weights []*big.Int) []*big.Int {
+	sums := make([][]*big.Int, len(weights))
+	for i, w := range weights {
+		sums[i] = trim([]*big.Int{w})
+	}
+	for l := 0; l < len(t.levels)-1; l++ {
+		level := t.levels[l]
+		next := make([][]*big.Int, len(t.levels[l+1]))
+		for i := range next {
+			if 2*i+1 < len(sums) {
+				next[i] = addSlices(mulSlices(sums[2*i], level[2*i+1], t.p),
+					mulSlices(sums[2*i+1], level[2*i], t.p), t.p)
+			} else {
+				next[i] = sums[2*i]
+			}
+		}
+		sums = next
+	}
+	return sums[0]
+}
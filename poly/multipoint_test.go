@@ -0,0 +1,70 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package poly
+
+import (
+	"math/rand"
+	"testing"
+
+	"gitlab.com/xx_network/crypto/large"
+)
+
+// Tests that multi-point evaluation matches evaluating at each point, both
+// below and above the subproduct tree threshold, including points that are
+// not reduced and repeated points.
+func TestPolynomial_EvaluateMany(t *testing.T) {
+	prng := rand.New(rand.NewSource(42))
+	for _, p := range []*large.Int{p25519, pBLS} {
+		for _, size := range [][2]int{{10, 5}, {100, 150}, {300, 129}} {
+			f := randomPoly(t, prng, p, size[0])
+			xs := randomPoints(prng, p, size[1])
+			xs[0] = large.NewInt(0).Add(xs[1], p)
+			xs[2] = large.NewInt(0)
+
+			values := f.EvaluateMany(xs)
+			if len(values) != len(xs) {
+				t.Fatalf("got %d values for %d points", len(values), len(xs))
+			}
+			for i, x := range xs {
+				if expected := f.Evaluate(x); values[i].Cmp(expected) != 0 {
+					t.Errorf("value %d of %v is %s, expected %s", i, size,
+						values[i].Text(10), expected.Text(10))
+				}
+			}
+		}
+	}
+}
+
+// Tests that the zero polynomial evaluates to zero everywhere.
+func TestPolynomial_EvaluateMany_Zero(t *testing.T) {
+	zero, _ := Zero(p25519)
+	for _, v := range zero.EvaluateMany(ints(1, 2, 3)) {
+		if v.BigInt().Sign() != 0 {
+			t.Errorf("zero polynomial evaluated to %s", v.Text(10))
+		}
+	}
+}
+
+func benchmarkEvaluateMany(b *testing.B, tree bool) {
+	prng := rand.New(rand.NewSource(42))
+	f := randomPoly(b, prng, pBLS, 1024)
+	xs := randomPoints(prng, pBLS, 1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if tree {
+			f.EvaluateMany(xs)
+		} else {
+			for _, x := range xs {
+				f.Evaluate(x)
+			}
+		}
+	}
+}
+
+func BenchmarkEvaluateMany_Tree1024(b *testing.B)   { benchmarkEvaluateMany(b, true) }
+func BenchmarkEvaluateMany_Horner1024(b *testing.B) { benchmarkEvaluateMany(b, false) }
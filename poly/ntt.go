@@ -0,0 +1,364 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package poly
+
+import (
+	"math/big"
+	"math/bits"
+
+	"github.com/pkg/errors"
+	"gitlab.com/xx_network/crypto/large"
+)
+
+const (
+	// maxNTTLog bounds the transform sizes, so that sizes fit in an int.
+	maxNTTLog = 30
+
+	// maxWordBits is the size of the largest primes transformed with
+	// machine words instead of big.Int, small enough that the sum of two
+	// reduced values does not overflow.
+	maxWordBits = 63
+)
+
+// NTT is the number-theoretic transform of a fixed power-of-two size n over
+// Z_p: the discrete Fourier transform with a primitive n-th root of unity mod
+// p. It exists when n divides p-1, so NTT-friendly primes are of the form
+// c·2^k + 1 for a large k, such as 998244353 = 119·2^23 + 1.
+//
+// Primes of up to 63 bits are transformed with machine words. An NTT is
+// read-only after creation and safe for concurrent use.
+type NTT struct {
+	p *big.Int
+	n int
+	// roots[i] = w^i and invRoots[i] = w^-i for i < n/2, where w is the
+	// primitive n-th root of unity. They are nil when word is set.
+	roots, invRoots []*big.Int
+	// nInv is n⁻¹ mod p, the scale of the inverse transform
+	nInv *big.Int
+
+	// word holds the tables as machine words instead when p fits in one,
+	// and is nil otherwise
+	word *wordNTT
+}
+
+// wordNTT holds the tables of a transform mod a prime of at most 63 bits.
+// The roots and scales are kept in Montgomery form, multiplied by R = 2^64,
+// so that multiplying a plain value by one with a Montgomery reduction gives
+// a plain result without any division.
+type wordNTT struct {
+	p uint64
+	// pInv is -p⁻¹ mod 2^64
+	pInv            uint64
+	roots, invRoots []uint64
+	// nInv is n⁻¹·R mod p, the scale of the inverse transform, and nInvR
+	// is n⁻¹·R² mod p, which also cancels the R⁻¹ left by a pointwise
+	// product of two plain values
+	nInv, nInvR uint64
+}
+
+// MaxNTTSize returns the largest transform size supported by p, the largest
+// power of two dividing p-1, capped at 2^30. It returns 1 for even p.
+func MaxNTTSize(p *large.Int) int {
+	return 1 << maxNTTLogFor(p.BigInt())
+}
+
+// maxNTTLogFor returns the base two logarithm of MaxNTTSize.
+func maxNTTLogFor(p *big.Int) uint {
+	if p.Sign() <= 0 || p.Bit(0) == 0 {
+		return 0
+	}
+	k := new(big.Int).Sub(p, big.NewInt(1)).TrailingZeroBits()
+	if k > maxNTTLog {
+		k = maxNTTLog
+	}
+	return k
+}
+
+// NewNTT returns the transform of size n mod the prime p. n must be a power
+// of two of at least 2 that divides p-1.
+func NewNTT(p *large.Int, n int) (*NTT, error) {
+	if err := checkPrime(p); err != nil {
+		return nil, err
+	}
+	if n < 2 || n&(n-1) != 0 {
+		return nil, errors.Errorf("transform size %d is not a power of two "+
+			"of at least 2", n)
+	}
+	if max := MaxNTTSize(p); n > max {
+		return nil, errors.Errorf("transform size %d is larger than %d, "+
+			"the largest the modulus supports", n, max)
+	}
+	return newNTT(new(big.Int).Set(p.BigInt()), n), nil
+}
+
+// nttFor returns a transform mod p large enough for a product with size
+// coefficients, or nil if p does not support one. Only primes that fit in a
+// machine word get one, as for larger primes a single large.Int product by
+// Kronecker substitution is faster than a transform on big.Int values.
+func nttFor(p *big.Int, size int) *wordNTT {
+	if p.BitLen() > maxWordBits {
+		return nil
+	}
+	n, log := 2, uint(1)
+	for n < size {
+		n <<= 1
+		log++
+	}
+	if log > maxNTTLogFor(p) {
+		return nil
+	}
+	return newNTT(p, n).word
+}
+
+// newNTT builds the tables for a transform of size n, which must divide p-1.
+func newNTT(p *big.Int, n int) *NTT {
+	// A quadratic non-residue g has an order divisible by the whole power of
+	// two in p-1, so g^((p-1)/n) has order exactly n
+	g := large.NewInt(2)
+	lp := large.NewIntFromBigInt(p)
+	for large.Jacobi(g, lp) != -1 {
+		g.Add(g, large.NewInt(1))
+	}
+	e := new(big.Int).Sub(p, big.NewInt(1))
+	e.Quo(e, big.NewInt(int64(n)))
+	w := new(big.Int).Exp(g.BigInt(), e, p)
+	wInv := new(big.Int).ModInverse(w, p)
+
+	t := &NTT{
+		p:    p,
+		n:    n,
+		nInv: new(big.Int).ModInverse(big.NewInt(int64(n)), p),
+	}
+	if p.BitLen() <= maxWordBits {
+		t.word = newWordNTT(p, n, w, wInv, t.nInv)
+		return t
+	}
+
+	t.roots, t.invRoots = make([]*big.Int, n/2), make([]*big.Int, n/2)
+	t.roots[0], t.invRoots[0] = big.NewInt(1), big.NewInt(1)
+	for i := 1; i < n/2; i++ {
+		t.roots[i] = new(big.Int).Mul(t.roots[i-1], w)
+		t.roots[i].Mod(t.roots[i], p)
+		t.invRoots[i] = new(big.Int).Mul(t.invRoots[i-1], wInv)
+		t.invRoots[i].Mod(t.invRoots[i], p)
+	}
+	return t
+}
+
+// newWordNTT builds the Montgomery form tables for a transform of size n mod
+// a prime of at most 63 bits, from the root w, its inverse and n⁻¹.
+func newWordNTT(p *big.Int, n int, w, wInv, nInv *big.Int) *wordNTT {
+	r := new(big.Int).Lsh(big.NewInt(1), 64)
+	r.Mod(r, p)
+	var tmp big.Int
+	toMont := func(x *big.Int) uint64 {
+		return tmp.Mod(tmp.Mul(x, r), p).Uint64()
+	}
+
+	// Newton's iteration doubles the correct low bits of p⁻¹ each step,
+	// and p is its own inverse mod 8
+	p64 := p.Uint64()
+	inv := p64
+	for i := 0; i < 5; i++ {
+		inv *= 2 - p64*inv
+	}
+
+	t := &wordNTT{
+		p:        p64,
+		pInv:     -inv,
+		roots:    make([]uint64, n/2),
+		invRoots: make([]uint64, n/2),
+		nInv:     toMont(nInv),
+	}
+	t.nInvR = toMont(new(big.Int).SetUint64(t.nInv))
+
+	// The Montgomery product of two values in Montgomery form stays in it
+	t.roots[0], t.invRoots[0] = toMont(big.NewInt(1)), toMont(big.NewInt(1))
+	wMont, wInvMont := toMont(w), toMont(wInv)
+	for i := 1; i < n/2; i++ {
+		t.roots[i] = t.mulMod(t.roots[i-1], wMont)
+		t.invRoots[i] = t.mulMod(t.invRoots[i-1], wInvMont)
+	}
+	return t
+}
+
+// Size returns the size of the transform.
+func (t *NTT) Size() int {
+	return t.n
+}
+
+// Modulus returns the prime of the field.
+func (t *NTT) Modulus() *large.Int {
+	return large.NewIntFromBigInt(t.p)
+}
+
+// Forward returns the transform of the n values a, that is f(w^i) for
+// i < n where f is the polynomial with coefficients a.
+func (t *NTT) Forward(a []*large.Int) ([]*large.Int, error) {
+	return t.apply(a, false)
+}
+
+// Inverse returns the inverse transform of the n values a, recovering the
+// coefficients from Forward's output.
+func (t *NTT) Inverse(a []*large.Int) ([]*large.Int, error) {
+	return t.apply(a, true)
+}
+
+// apply copies a, transforms it and converts the result back.
+func (t *NTT) apply(a []*large.Int, inverse bool) ([]*large.Int, error) {
+	if len(a) != t.n {
+		return nil, errors.Errorf("transform of size %d cannot take %d "+
+			"values", t.n, len(a))
+	}
+	res := make([]*large.Int, t.n)
+	if t.word != nil {
+		buf := make([]uint64, t.n)
+		var tmp big.Int
+		for i, x := range a {
+			buf[i] = tmp.Mod(x.BigInt(), t.p).Uint64()
+		}
+		t.word.transform(buf, inverse, t.word.nInv)
+		for i, x := range buf {
+			res[i] = large.NewIntFromUInt(x)
+		}
+		return res, nil
+	}
+
+	buf := make([]*big.Int, t.n)
+	for i, x := range a {
+		buf[i] = new(big.Int).Mod(x.BigInt(), t.p)
+	}
+	t.transform(buf, inverse)
+	for i, x := range buf {
+		res[i] = large.NewIntFromBigInt(x)
+	}
+	return res, nil
+}
+
+// transform runs the iterative radix-2 Cooley-Tukey transform in place on n
+// reduced values. The inverse uses the inverse roots and scales by n⁻¹.
+func (t *NTT) transform(a []*big.Int, inverse bool) {
+	n := t.n
+	roots := t.roots
+	if inverse {
+		roots = t.invRoots
+	}
+
+	bitReverse(n, func(i, j int) { a[i], a[j] = a[j], a[i] })
+
+	var tmp, v big.Int
+	for size := 2; size <= n; size <<= 1 {
+		half, step := size/2, n/size
+		for start := 0; start < n; start += size {
+			for j := 0; j < half; j++ {
+				u, w := a[start+j], a[start+j+half]
+				v.Mod(tmp.Mul(w, roots[j*step]), t.p)
+				w.Sub(u, &v)
+				if w.Sign() < 0 {
+					w.Add(w, t.p)
+				}
+				u.Add(u, &v)
+				if u.Cmp(t.p) >= 0 {
+					u.Sub(u, t.p)
+				}
+			}
+		}
+	}
+
+	if inverse {
+		for _, x := range a {
+			x.Mod(tmp.Mul(x, t.nInv), t.p)
+		}
+	}
+}
+
+// mul returns a·b mod p for reduced coefficients whose product has at most
+// as many coefficients as the transform size.
+func (t *wordNTT) mul(a, b []*big.Int) []*big.Int {
+	n := 2 * len(t.roots)
+	fa, fb := make([]uint64, n), make([]uint64, n)
+	for i, x := range a {
+		fa[i] = x.Uint64()
+	}
+	for i, x := range b {
+		fb[i] = x.Uint64()
+	}
+	t.transform(fa, false, 0)
+	t.transform(fb, false, 0)
+	for i := range fa {
+		fa[i] = t.mulMod(fa[i], fb[i])
+	}
+	t.transform(fa, true, t.nInvR)
+
+	res := make([]*big.Int, len(a)+len(b)-1)
+	for i := range res {
+		res[i] = new(big.Int).SetUint64(fa[i])
+	}
+	return trim(res)
+}
+
+// transform is NTT.transform on machine words. The inverse transform
+// multiplies the result by scale with a Montgomery reduction.
+func (t *wordNTT) transform(a []uint64, inverse bool, scale uint64) {
+	n := len(a)
+	roots := t.roots
+	if inverse {
+		roots = t.invRoots
+	}
+
+	bitReverse(n, func(i, j int) { a[i], a[j] = a[j], a[i] })
+
+	for size := 2; size <= n; size <<= 1 {
+		half, step := size/2, n/size
+		for start := 0; start < n; start += size {
+			for j := 0; j < half; j++ {
+				u := a[start+j]
+				v := t.mulMod(a[start+j+half], roots[j*step])
+				a[start+j] = t.reduce(u + v)
+				a[start+j+half] = t.reduce(u + t.p - v)
+			}
+		}
+	}
+
+	if inverse {
+		for i := range a {
+			a[i] = t.mulMod(a[i], scale)
+		}
+	}
+}
+
+// mulMod returns the Montgomery product x·y·R⁻¹ mod p for reduced x and y.
+// As p < 2^63, x·y + m·p does not overflow 128 bits.
+func (t *wordNTT) mulMod(x, y uint64) uint64 {
+	hi, lo := bits.Mul64(x, y)
+	mHi, mLo := bits.Mul64(lo*t.pInv, t.p)
+	_, carry := bits.Add64(lo, mLo, 0)
+	return t.reduce(hi + mHi + carry)
+}
+
+// reduce returns x mod p for x < 2p, without branching on x so that random
+// data does not defeat the branch predictor.
+func (t *wordNTT) reduce(x uint64) uint64 {
+	d, borrow := bits.Sub64(x, t.p, 0)
+	return d + t.p&-borrow
+}
+
+// bitReverse permutes n values, n a power of two, into bit-reversed index
+// order by calling swap on the pairs of indices to exchange.
+func bitReverse(n int, swap func(i, j int)) {
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j |= bit
+		if i < j {
+			swap(i, j)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package poly
+
+import (
+	"math/rand"
+	"testing"
+
+	"gitlab.com/xx_network/crypto/large"
+)
+
+// Tests the largest transform sizes of known primes.
+func TestMaxNTTSize(t *testing.T) {
+	tests := []struct {
+		p        *large.Int
+		expected int
+	}{
+		{p998, 1 << 23},
+		{pBLS, 1 << 30},
+		{p25519, 4},
+		{p63, 1 << 30},
+		{large.NewInt(65537), 1 << 16},
+		{large.NewInt(2), 1},
+	}
+	for _, tt := range tests {
+		if got := MaxNTTSize(tt.p); got != tt.expected {
+			t.Errorf("MaxNTTSize(%s) = %d, expected %d", tt.p.Text(10), got,
+				tt.expected)
+		}
+	}
+}
+
+// Tests that the forward transform evaluates the polynomial at the powers of
+// a primitive root of unity and that the inverse undoes it.
+func TestNTT_ForwardInverse(t *testing.T) {
+	prng := rand.New(rand.NewSource(42))
+	for _, test := range []struct {
+		p *large.Int
+		n int
+	}{{p998, 2}, {p998, 8}, {p998, 64}, {p63, 16}, {pBLS, 4}, {pBLS, 32}} {
+		p, n := test.p, test.n
+		ntt, err := NewNTT(p, n)
+		if err != nil {
+			t.Fatalf("NewNTT() error: %+v", err)
+		}
+		if ntt.Size() != n || ntt.Modulus().Cmp(p) != 0 {
+			t.Errorf("unexpected size %d or modulus %s", ntt.Size(),
+				ntt.Modulus().Text(10))
+		}
+
+		// The transform of x is the list of powers of the root
+		x := ints(make([]int64, n)...)
+		x[1] = large.NewInt(1)
+		powers, err := ntt.Forward(x)
+		if err != nil {
+			t.Fatalf("Forward() error: %+v", err)
+		}
+		w := powers[1]
+		pMinus1 := large.NewInt(0).Sub(p, large.NewInt(1))
+		half := large.NewInt(0).Exp(w, large.NewInt(int64(n/2)), p)
+		if half.Cmp(pMinus1) != 0 {
+			t.Errorf("root for size %d is not primitive", n)
+		}
+
+		f := randomPoly(t, prng, p, n)
+		coeffs := make([]*large.Int, n)
+		for i := range coeffs {
+			coeffs[i] = f.Coefficient(i)
+		}
+		values, err := ntt.Forward(coeffs)
+		if err != nil {
+			t.Fatalf("Forward() error: %+v", err)
+		}
+		for i, v := range values {
+			if expected := f.Evaluate(powers[i]); v.Cmp(expected) != 0 {
+				t.Errorf("value %d of size %d is %s, expected %s", i, n,
+					v.Text(10), expected.Text(10))
+			}
+		}
+
+		back, err := ntt.Inverse(values)
+		if err != nil {
+			t.Fatalf("Inverse() error: %+v", err)
+		}
+		for i := range back {
+			if back[i].Cmp(coeffs[i]) != 0 {
+				t.Errorf("coefficient %d of size %d did not round trip", i,
+					n)
+			}
+		}
+	}
+}
+
+// Tests that NewNTT rejects sizes that are not powers of two or that the
+// modulus does not support, and that transforms reject inputs of the wrong
+// length.
+func TestNewNTT_Errors(t *testing.T) {
+	for _, n := range []int{0, 1, 6, 1 << 24} {
+		if _, err := NewNTT(p998, n); err == nil {
+			t.Errorf("NewNTT() accepted size %d", n)
+		}
+	}
+	if _, err := NewNTT(p25519, 8); err == nil {
+		t.Error("NewNTT() accepted a size the modulus does not support")
+	}
+	if _, err := NewNTT(large.NewInt(998244351), 2); err == nil {
+		t.Error("NewNTT() accepted a composite modulus")
+	}
+
+	ntt, _ := NewNTT(p998, 8)
+	if _, err := ntt.Forward(ints(1, 2, 3)); err == nil {
+		t.Error("Forward() accepted the wrong number of values")
+	}
+}
@@ -0,0 +1,347 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+// Package poly implements polynomials over a prime field Z_p, as needed by
+// secret sharing, threshold schemes and commitment proofs.
+//
+// A Polynomial is immutable; every operation returns a new one. Large
+// products use a number-theoretic transform on machine words when p fits in
+// one and p-1 is divisible by a large enough power of two, and Kronecker
+// substitution into a single large.Int product otherwise. Division,
+// multi-point evaluation and interpolation build on the fast product, so they
+// run in quasi-linear time for large degrees.
+package poly
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	jww "github.com/spf13/jwalterweatherman"
+	"gitlab.com/xx_network/crypto/csprng"
+	"gitlab.com/xx_network/crypto/large"
+	"gitlab.com/xx_network/crypto/randomness"
+)
+
+// Polynomial is a polynomial with coefficients in Z_p for a prime p.
+type Polynomial struct {
+	p *big.Int
+	// coeffs[i] is the coefficient of x^i, reduced mod p. The last
+	// coefficient is never zero, so the zero polynomial has no coefficients.
+	coeffs []*big.Int
+}
+
+// New returns the polynomial with the given coefficients mod p, lowest degree
+// first, so coeffs[i] is the coefficient of x^i. Coefficients are reduced
+// mod p. It returns an error if p is not prime.
+func New(p *large.Int, coeffs []*large.Int) (*Polynomial, error) {
+	if err := checkPrime(p); err != nil {
+		return nil, err
+	}
+	pb := new(big.Int).Set(p.BigInt())
+	c := make([]*big.Int, len(coeffs))
+	for i, x := range coeffs {
+		c[i] = new(big.Int).Mod(x.BigInt(), pb)
+	}
+	return newPoly(pb, c), nil
+}
+
+// Zero returns the zero polynomial mod p. It returns an error if p is not
+// prime.
+func Zero(p *large.Int) (*Polynomial, error) {
+	return New(p, nil)
+}
+
+// Random returns a uniformly random polynomial of exactly the given degree
+// mod p, drawing its coefficients from rng.
+func Random(p *large.Int, degree int, rng csprng.Source) (*Polynomial, error) {
+	if degree < 0 {
+		return nil, errors.New("degree must not be negative")
+	}
+	if err := checkPrime(p); err != nil {
+		return nil, err
+	}
+	c, err := randomCoefficients(p, degree+1, rng)
+	if err != nil {
+		return nil, err
+	}
+	return newPoly(new(big.Int).Set(p.BigInt()), c), nil
+}
+
+// RandomWithConstant returns a uniformly random polynomial of exactly the
+// given degree mod p whose constant term is constant, such as the dealer's
+// polynomial in Shamir secret sharing. The other coefficients are drawn from
+// rng.
+func RandomWithConstant(p *large.Int, degree int, constant *large.Int,
+	rng csprng.Source) (*Polynomial, error) {
+	if degree < 0 {
+		return nil, errors.New("degree must not be negative")
+	}
+	if err := checkPrime(p); err != nil {
+		return nil, err
+	}
+	pb := new(big.Int).Set(p.BigInt())
+	c := []*big.Int{new(big.Int).Mod(constant.BigInt(), pb)}
+	if degree > 0 {
+		rest, err := randomCoefficients(p, degree, rng)
+		if err != nil {
+			return nil, err
+		}
+		c = append(c, rest...)
+	}
+	return newPoly(pb, c), nil
+}
+
+// randomCoefficients draws n coefficients mod p, the last of them non-zero so
+// that the degree is exact.
+func randomCoefficients(p *large.Int, n int, rng csprng.Source) ([]*big.Int,
+	error) {
+	c := make([]*big.Int, n)
+	for i := range c {
+		min := large.NewInt(0)
+		if i == n-1 {
+			min = large.NewInt(1)
+		}
+		x, err := randomness.RandomInt(min, p, rng)
+		if err != nil {
+			return nil, errors.WithMessagef(err,
+				"failed to draw coefficient %d", i)
+		}
+		c[i] = x.BigInt()
+	}
+	return c, nil
+}
+
+// checkPrime returns an error if p is not a prime.
+func checkPrime(p *large.Int) error {
+	if p == nil || p.Cmp(large.NewInt(2)) < 0 || !p.IsPrime() {
+		return errors.New("modulus is not prime")
+	}
+	return nil
+}
+
+// newPoly wraps reduced coefficients, trimming leading zeros.
+func newPoly(p *big.Int, coeffs []*big.Int) *Polynomial {
+	return &Polynomial{p: p, coeffs: trim(coeffs)}
+}
+
+// trim drops the zero coefficients from the top of c.
+func trim(c []*big.Int) []*big.Int {
+	n := len(c)
+	for n > 0 && c[n-1].Sign() == 0 {
+		n--
+	}
+	return c[:n]
+}
+
+// Modulus returns the prime p of the field.
+func (f *Polynomial) Modulus() *large.Int {
+	return large.NewIntFromBigInt(f.p)
+}
+
+// Degree returns the degree of the polynomial, or -1 for the zero polynomial.
+func (f *Polynomial) Degree() int {
+	return len(f.coeffs) - 1
+}
+
+// IsZero returns true for the zero polynomial.
+func (f *Polynomial) IsZero() bool {
+	return len(f.coeffs) == 0
+}
+
+// Coefficient returns the coefficient of x^i, which is zero above the degree.
+func (f *Polynomial) Coefficient(i int) *large.Int {
+	if i < 0 || i >= len(f.coeffs) {
+		return large.NewInt(0)
+	}
+	return large.NewIntFromBigInt(f.coeffs[i])
+}
+
+// Coefficients returns a copy of the coefficients, lowest degree first.
+func (f *Polynomial) Coefficients() []*large.Int {
+	return toLarge(f.coeffs)
+}
+
+// Equal returns true if both polynomials have the same modulus and
+// coefficients.
+func (f *Polynomial) Equal(g *Polynomial) bool {
+	if f.p.Cmp(g.p) != 0 || len(f.coeffs) != len(g.coeffs) {
+		return false
+	}
+	for i := range f.coeffs {
+		if f.coeffs[i].Cmp(g.coeffs[i]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the polynomial in base 10, highest degree first, such as
+// "3x^2 + x + 5".
+func (f *Polynomial) String() string {
+	if f.IsZero() {
+		return "0"
+	}
+	var terms []string
+	for i := len(f.coeffs) - 1; i >= 0; i-- {
+		c := f.coeffs[i]
+		if c.Sign() == 0 {
+			continue
+		}
+		coeff := c.Text(10)
+		if i > 0 && c.Cmp(big.NewInt(1)) == 0 {
+			coeff = ""
+		}
+		switch i {
+		case 0:
+			terms = append(terms, coeff)
+		case 1:
+			terms = append(terms, coeff+"x")
+		default:
+			terms = append(terms, coeff+"x^"+strconv.Itoa(i))
+		}
+	}
+	return strings.Join(terms, " + ")
+}
+
+// checkField panics if g is over a different field than f.
+func (f *Polynomial) checkField(g *Polynomial) {
+	if f.p.Cmp(g.p) != 0 {
+		jww.FATAL.Panicf("poly: polynomials mod %s and mod %s cannot be "+
+			"combined", f.p.Text(10), g.p.Text(10))
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Evaluation                                                                 //
+////////////////////////////////////////////////////////////////////////////////
+
+// Evaluate returns f(x) mod p using Horner's rule.
+func (f *Polynomial) Evaluate(x *large.Int) *large.Int {
+	return large.NewIntFromBigInt(horner(f.coeffs,
+		new(big.Int).Mod(x.BigInt(), f.p), f.p))
+}
+
+// horner evaluates the coefficients at a reduced x.
+func horner(c []*big.Int, x, p *big.Int) *big.Int {
+	acc := new(big.Int)
+	for i := len(c) - 1; i >= 0; i-- {
+		acc.Mul(acc, x)
+		acc.Add(acc, c[i])
+		acc.Mod(acc, p)
+	}
+	return acc
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Arithmetic                                                                 //
+////////////////////////////////////////////////////////////////////////////////
+
+// Add returns f + g. It panics if the polynomials are over different fields.
+func (f *Polynomial) Add(g *Polynomial) *Polynomial {
+	f.checkField(g)
+	return newPoly(f.p, addSlices(f.coeffs, g.coeffs, f.p))
+}
+
+// Sub returns f - g. It panics if the polynomials are over different fields.
+func (f *Polynomial) Sub(g *Polynomial) *Polynomial {
+	f.checkField(g)
+	return newPoly(f.p, subSlices(f.coeffs, g.coeffs, f.p))
+}
+
+// Neg returns -f.
+func (f *Polynomial) Neg() *Polynomial {
+	return newPoly(f.p, subSlices(nil, f.coeffs, f.p))
+}
+
+// Scale returns c·f.
+func (f *Polynomial) Scale(c *large.Int) *Polynomial {
+	cb := new(big.Int).Mod(c.BigInt(), f.p)
+	res := make([]*big.Int, len(f.coeffs))
+	for i, x := range f.coeffs {
+		res[i] = new(big.Int).Mul(x, cb)
+		res[i].Mod(res[i], f.p)
+	}
+	return newPoly(f.p, res)
+}
+
+// Mul returns f·g. It panics if the polynomials are over different fields.
+func (f *Polynomial) Mul(g *Polynomial) *Polynomial {
+	f.checkField(g)
+	return newPoly(f.p, mulSlices(f.coeffs, g.coeffs, f.p))
+}
+
+// DivMod returns the quotient and remainder of f divided by g, so that
+// f = q·g + r with r of lower degree than g. It returns an error if g is
+// zero, and panics if the polynomials are over different fields.
+func (f *Polynomial) DivMod(g *Polynomial) (q, r *Polynomial, err error) {
+	f.checkField(g)
+	if g.IsZero() {
+		return nil, nil, errors.New("division by the zero polynomial")
+	}
+	quo, rem := divModSlices(f.coeffs, g.coeffs, f.p)
+	return newPoly(f.p, quo), newPoly(f.p, rem), nil
+}
+
+// Derivative returns the formal derivative of f.
+func (f *Polynomial) Derivative() *Polynomial {
+	return newPoly(f.p, derivative(f.coeffs, f.p))
+}
+
+// addSlices returns a + b mod p.
+func addSlices(a, b []*big.Int, p *big.Int) []*big.Int {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	res := make([]*big.Int, len(a))
+	for i := range a {
+		res[i] = new(big.Int).Set(a[i])
+		if i < len(b) {
+			res[i].Add(res[i], b[i])
+			if res[i].Cmp(p) >= 0 {
+				res[i].Sub(res[i], p)
+			}
+		}
+	}
+	return trim(res)
+}
+
+// subSlices returns a - b mod p.
+func subSlices(a, b []*big.Int, p *big.Int) []*big.Int {
+	n := len(a)
+	if len(b) > n {
+		n = len(b)
+	}
+	res := make([]*big.Int, n)
+	for i := range res {
+		res[i] = new(big.Int)
+		if i < len(a) {
+			res[i].Set(a[i])
+		}
+		if i < len(b) {
+			res[i].Sub(res[i], b[i])
+			if res[i].Sign() < 0 {
+				res[i].Add(res[i], p)
+			}
+		}
+	}
+	return trim(res)
+}
+
+// derivative returns the formal derivative of c mod p.
+func derivative(c []*big.Int, p *big.Int) []*big.Int {
+	if len(c) <= 1 {
+		return nil
+	}
+	res := make([]*big.Int, len(c)-1)
+	for i := range res {
+		res[i] = new(big.Int).Mul(c[i+1], big.NewInt(int64(i+1)))
+		res[i].Mod(res[i], p)
+	}
+	return trim(res)
+}
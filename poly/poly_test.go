@@ -0,0 +1,263 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package poly
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/pkg/errors"
+	"gitlab.com/xx_network/crypto/csprng"
+	"gitlab.com/xx_network/crypto/large"
+)
+
+var (
+	// p25519 is 2^255 - 19, which only supports transforms of size 4
+	p25519 = large.NewIntFromString(
+		"7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffed", 16)
+	// pBLS is the scalar field order of BLS12-381, which supports
+	// transforms of size up to 2^32
+	pBLS = large.NewIntFromString(
+		"73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001", 16)
+	// p998 is 119·2^23 + 1
+	p998 = large.NewInt(998244353)
+	// p63 is 2147483641·2^32 + 1, the largest prime transformed with
+	// machine words
+	p63 = large.NewIntFromString("7ffffff900000001", 16)
+)
+
+// randomPoly returns a polynomial mod p with n random coefficients.
+func randomPoly(t testing.TB, prng *rand.Rand, p *large.Int, n int) *Polynomial {
+	c := make([]*large.Int, n)
+	for i := range c {
+		c[i] = large.NewIntFromBigInt(new(big.Int).Rand(prng, p.BigInt()))
+	}
+	f, err := New(p, c)
+	if err != nil {
+		t.Fatalf("New() error: %+v", err)
+	}
+	return f
+}
+
+// ints converts int64s to large.Ints.
+func ints(xs ...int64) []*large.Int {
+	res := make([]*large.Int, len(xs))
+	for i, x := range xs {
+		res[i] = large.NewInt(x)
+	}
+	return res
+}
+
+// Tests that New reduces the coefficients and trims leading zeros.
+func TestNew(t *testing.T) {
+	f, err := New(large.NewInt(7), ints(-1, 9, 0, 14))
+	if err != nil {
+		t.Fatalf("New() error: %+v", err)
+	}
+	if f.Degree() != 1 {
+		t.Errorf("Degree() = %d, expected 1", f.Degree())
+	}
+	if f.Coefficient(0).Int64() != 6 || f.Coefficient(1).Int64() != 2 ||
+		f.Coefficient(5).Int64() != 0 {
+		t.Errorf("unexpected coefficients %v", f.Coefficients())
+	}
+	if f.String() != "2x + 6" {
+		t.Errorf("String() = %q, expected %q", f.String(), "2x + 6")
+	}
+
+	zero, err := Zero(large.NewInt(7))
+	if err != nil {
+		t.Fatalf("Zero() error: %+v", err)
+	}
+	if !zero.IsZero() || zero.Degree() != -1 || zero.String() != "0" {
+		t.Errorf("unexpected zero polynomial %s of degree %d", zero,
+			zero.Degree())
+	}
+}
+
+// Tests that New rejects moduli that are not prime.
+func TestNew_NotPrime(t *testing.T) {
+	for _, p := range []*large.Int{nil, large.NewInt(1), large.NewInt(91)} {
+		if _, err := New(p, ints(1, 2)); err == nil {
+			t.Errorf("New() accepted modulus %v", p)
+		}
+	}
+}
+
+// Tests Horner evaluation against a direct computation.
+func TestPolynomial_Evaluate(t *testing.T) {
+	f, _ := New(large.NewInt(101), ints(3, 0, 5, 1))
+	for x := int64(-3); x < 10; x++ {
+		expected := ((3+5*x*x+x*x*x)%101 + 101) % 101
+		if got := f.Evaluate(large.NewInt(x)); got.Int64() != expected {
+			t.Errorf("f(%d) = %s, expected %d", x, got.Text(10), expected)
+		}
+	}
+}
+
+// Tests that Random produces polynomials of exactly the requested degree and
+// RandomWithConstant keeps the constant term.
+func TestRandom(t *testing.T) {
+	rng := csprng.NewSystemRNG()
+	for _, degree := range []int{0, 1, 10} {
+		f, err := Random(p25519, degree, rng)
+		if err != nil {
+			t.Fatalf("Random() error: %+v", err)
+		}
+		if f.Degree() != degree {
+			t.Errorf("Degree() = %d, expected %d", f.Degree(), degree)
+		}
+
+		secret := large.NewInt(42)
+		g, err := RandomWithConstant(p25519, degree, secret, rng)
+		if err != nil {
+			t.Fatalf("RandomWithConstant() error: %+v", err)
+		}
+		if g.Degree() != degree {
+			t.Errorf("Degree() = %d, expected %d", g.Degree(), degree)
+		}
+		if g.Coefficient(0).Cmp(secret) != 0 {
+			t.Errorf("constant term is %s, expected %s",
+				g.Coefficient(0).Text(10), secret.Text(10))
+		}
+	}
+
+	if _, err := Random(p25519, -1, rng); err == nil {
+		t.Error("Random() accepted a negative degree")
+	}
+}
+
+// Tests that Random returns the error of a failing source.
+func TestRandom_SourceError(t *testing.T) {
+	rng := csprng.NewFailingSource(csprng.NewSystemRNG(), 2,
+		csprng.ErrInjectedFault)
+	_, err := Random(p25519, 5, rng)
+	if !errors.Is(err, csprng.ErrInjectedFault) {
+		t.Errorf("Random() returned %v, expected the injected fault", err)
+	}
+}
+
+// Tests addition, subtraction, negation and scaling.
+func TestPolynomial_AddSub(t *testing.T) {
+	p := large.NewInt(13)
+	f, _ := New(p, ints(1, 2, 3))
+	g, _ := New(p, ints(12, 11, 10))
+	h, _ := New(p, ints(5, 0, 10))
+
+	if sum := f.Add(g); !sum.IsZero() {
+		t.Errorf("f + g = %s, expected 0", sum)
+	}
+	if diff := f.Sub(h); diff.String() != "6x^2 + 2x + 9" {
+		t.Errorf("f - h = %s", diff)
+	}
+	if !f.Neg().Equal(g) {
+		t.Errorf("-f = %s, expected %s", f.Neg(), g)
+	}
+	if scaled := f.Scale(large.NewInt(2)); scaled.String() != "6x^2 + 4x + 2" {
+		t.Errorf("2f = %s", scaled)
+	}
+	if !f.Sub(f).IsZero() {
+		t.Error("f - f is not zero")
+	}
+}
+
+// Tests that combining polynomials over different fields panics.
+func TestPolynomial_DifferentFields(t *testing.T) {
+	f, _ := New(large.NewInt(13), ints(1, 2))
+	g, _ := New(large.NewInt(17), ints(1, 2))
+	defer func() {
+		if recover() == nil {
+			t.Error("Add() did not panic for different fields")
+		}
+	}()
+	f.Add(g)
+}
+
+// Tests that every multiplication strategy agrees with the schoolbook
+// product.
+func TestPolynomial_Mul(t *testing.T) {
+	prng := rand.New(rand.NewSource(42))
+	sizes := [][2]int{{1, 1}, {3, 7}, {20, 20}, {17, 100}, {64, 64},
+		{100, 300}}
+	for _, p := range []*large.Int{p25519, pBLS, p998, p63} {
+		for _, size := range sizes {
+			f := randomPoly(t, prng, p, size[0])
+			g := randomPoly(t, prng, p, size[1])
+			expected := newPoly(f.p, mulSchoolbook(f.coeffs, g.coeffs, f.p))
+			if got := f.Mul(g); !got.Equal(expected) {
+				t.Errorf("product of sizes %v mod %s is wrong", size,
+					p.Text(10))
+			}
+			if got := newPoly(f.p, mulKronecker(f.coeffs, g.coeffs,
+				f.p)); !got.Equal(expected) {
+				t.Errorf("Kronecker product of sizes %v mod %s is wrong",
+					size, p.Text(10))
+			}
+		}
+	}
+}
+
+// Tests that the quotient and remainder satisfy f = q·g + r with
+// deg r < deg g, for both long division and Newton division.
+func TestPolynomial_DivMod(t *testing.T) {
+	prng := rand.New(rand.NewSource(42))
+	sizes := [][2]int{{1, 1}, {5, 7}, {10, 3}, {50, 40}, {200, 50},
+		{300, 150}}
+	for _, p := range []*large.Int{p25519, p998} {
+		for _, size := range sizes {
+			f := randomPoly(t, prng, p, size[0])
+			g := randomPoly(t, prng, p, size[1])
+			q, r, err := f.DivMod(g)
+			if err != nil {
+				t.Fatalf("DivMod() error: %+v", err)
+			}
+			if r.Degree() >= g.Degree() && !r.IsZero() {
+				t.Errorf("remainder degree %d is not below %d", r.Degree(),
+					g.Degree())
+			}
+			if !q.Mul(g).Add(r).Equal(f) {
+				t.Errorf("q·g + r != f for sizes %v mod %s", size,
+					p.Text(10))
+			}
+		}
+	}
+}
+
+// Tests that dividing by zero returns an error.
+func TestPolynomial_DivMod_Zero(t *testing.T) {
+	f, _ := New(large.NewInt(13), ints(1, 2))
+	zero, _ := Zero(large.NewInt(13))
+	if _, _, err := f.DivMod(zero); err == nil {
+		t.Error("DivMod() accepted a zero divisor")
+	}
+}
+
+// Tests the formal derivative.
+func TestPolynomial_Derivative(t *testing.T) {
+	f, _ := New(large.NewInt(13), ints(4, 3, 2, 1))
+	if d := f.Derivative(); d.String() != "3x^2 + 4x + 3" {
+		t.Errorf("f' = %s", d)
+	}
+	c, _ := New(large.NewInt(13), ints(4))
+	if !c.Derivative().IsZero() {
+		t.Error("derivative of a constant is not zero")
+	}
+}
+
+func benchmarkMul(b *testing.B, p *large.Int, n int) {
+	prng := rand.New(rand.NewSource(42))
+	f, g := randomPoly(b, prng, p, n), randomPoly(b, prng, p, n)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f.Mul(g)
+	}
+}
+
+func BenchmarkMul_Kronecker1024(b *testing.B) { benchmarkMul(b, p25519, 1024) }
+func BenchmarkMul_NTT1024(b *testing.B)       { benchmarkMul(b, pBLS, 1024) }
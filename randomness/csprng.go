@@ -10,7 +10,12 @@ package randomness
 import (
 	"crypto/rand"
 	"hash"
+	"io"
 	"math/big"
+
+	"github.com/pkg/errors"
+	jww "github.com/spf13/jwalterweatherman"
+	"gitlab.com/xx_network/crypto/large"
 )
 
 // GenerateSecureRandom receives the number of desired randomness bytes and returns a slice of that size from Crypto.Rand
@@ -60,20 +65,97 @@ func CalculateModuloBias(maxValue *big.Int, requiredBytes int) *big.Int {
 	return randLimit
 }
 
+// IntervalVersion selects the algorithm used by RandInIntervalVersion to
+// derive a value from a seed. A seed only reproduces the same value under the
+// same version.
+type IntervalVersion uint8
+
+const (
+	// IntervalV1 is the original RandInInterval algorithm. Each attempt
+	// hashes the previous one with the caller's hash and needs three times
+	// the byte length of max, so max is limited to a third of the digest.
+	IntervalV1 IntervalVersion = 1
+
+	// IntervalV2 samples by rejection from a cSHAKE256 SeededReader and
+	// supports ranges of any size. The caller's hash is not used.
+	IntervalV2 IntervalVersion = 2
+)
+
+// intervalV2Domain is the SeededReader domain of IntervalV2.
+const intervalV2Domain = "xx_network/crypto randomness.RandInInterval v2"
+
 // RandInInterval receives a seed and returns a random value (using a PRF) between [0, max-1]
+//
+// This is IntervalV1; it panics if max is longer than a third of the digest
+// size of h. RandInIntervalVersion with IntervalV2 supports any range.
 func RandInInterval(max *big.Int, seed []byte, h hash.Hash) *big.Int {
+	r, err := RandInIntervalVersion(IntervalV1, max, seed, h)
+	if err != nil {
+		jww.FATAL.Panicf("RandInInterval failed: %+v", err)
+	}
+	return r
+}
+
+// RandInIntervalVersion deterministically derives a value in [0, max) from
+// the seed using the given algorithm version. A max of 0 returns 0. The hash
+// is only used by IntervalV1 and may be nil for IntervalV2.
+func RandInIntervalVersion(version IntervalVersion, max *big.Int, seed []byte,
+	h hash.Hash) (*big.Int, error) {
+	switch version {
+	case IntervalV1:
+		return randInIntervalV1(max, seed, h)
+	case IntervalV2:
+		if max.Sign() == 0 {
+			return big.NewInt(0), nil
+		}
+		rng, err := NewSeededReader(XOFShake256, intervalV2Domain, seed)
+		if err != nil {
+			return nil, err
+		}
+		return RandInIntervalFromReader(max, rng)
+	default:
+		return nil, errors.Errorf("unknown interval version %d", version)
+	}
+}
+
+// RandInIntervalFromReader returns a uniformly random value in [0, max) read
+// from rng, such as a SeededReader. A max of 0 returns 0.
+func RandInIntervalFromReader(max *big.Int, rng io.Reader) (*big.Int, error) {
+	if max.Sign() < 0 {
+		return nil, errors.Errorf("max must not be negative, received %s",
+			max.Text(10))
+	}
+	if max.Sign() == 0 {
+		return big.NewInt(0), nil
+	}
+	r, err := randomBelow(large.NewIntFromBigInt(max), rng)
+	if err != nil {
+		return nil, err
+	}
+	return r.BigInt(), nil
+}
+
+// randInIntervalV1 implements IntervalV1.
+func randInIntervalV1(max *big.Int, seed []byte, h hash.Hash) (*big.Int,
+	error) {
 
 	// BitLen returns an int with the length of the absolute value of max in bits
 	maxBitLength := max.BitLen()
 
 	// If max == 0, the BitLen function returns 0
 	if maxBitLength == 0 {
-		return big.NewInt(0)
+		return big.NewInt(0), nil
 	}
 
 	requiredBytes := (maxBitLength + 7) / 8 // Convert the max bit length to byte
 	totalSpace := 3 * requiredBytes         // Increase the random search space
 
+	if totalSpace > h.Size() {
+		return nil, errors.Errorf("max of %d bytes needs %d bytes per "+
+			"attempt but the hash outputs %d", requiredBytes, totalSpace,
+			h.Size())
+	}
+
 	randomByteValue := make([]byte, totalSpace) // Variable allocation for the random value (in byte)
 	randomIntValue := big.NewInt(0)             // Variable allocation for the random value (in big.Int)
 
@@ -92,7 +174,7 @@ func RandInInterval(max *big.Int, seed []byte, h hash.Hash) *big.Int {
 
 		// Check if obtained random value is smaller than randomLimit
 		if randomIntValue.Cmp(randomLimit) < 0 {
-			return randomIntValue.Mod(randomIntValue, max)
+			return randomIntValue.Mod(randomIntValue, max), nil
 		}
 	}
 }
@@ -0,0 +1,111 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package randomness
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+	"gitlab.com/xx_network/crypto/large"
+)
+
+// RandomInt returns a uniformly random *large.Int greater than or equal to
+// min and less than max. It draws just enough bits to cover the range and
+// rejects samples outside it, so there is no modulo bias. Any csprng.Source
+// can be passed as rng.
+func RandomInt(min, max *large.Int, rng io.Reader) (*large.Int, error) {
+	if max.Cmp(min) <= 0 {
+		return nil, errors.Errorf("invalid range [%s, %s)", min.Text(10),
+			max.Text(10))
+	}
+
+	size := large.NewInt(0).Sub(max, min)
+	res, err := randomBelow(size, rng)
+	if err != nil {
+		return nil, err
+	}
+	return res.Add(res, min), nil
+}
+
+// RandomPrime returns a uniformly random odd prime of exactly bits bits. Odd
+// candidates with the top bit set are drawn until one passes the primality
+// test; around bits/3 candidates are needed on average.
+func RandomPrime(bits int, rng io.Reader) (*large.Int, error) {
+	if bits < 2 {
+		return nil, errors.Errorf("prime must have at least 2 bits, "+
+			"received %d", bits)
+	}
+
+	buf := make([]byte, (bits+7)/8)
+	topBits := uint(bits-1)%8 + 1
+	candidate := large.NewInt(0)
+	for i := 0; i < maxRejectionAttempts; i++ {
+		if _, err := io.ReadFull(rng, buf); err != nil {
+			return nil, errors.Wrap(err, "failed to read prime candidate")
+		}
+
+		// Clear the excess bits, then set the top bit and make it odd
+		buf[0] &= byte(1<<topBits) - 1
+		buf[0] |= byte(1 << (topBits - 1))
+		buf[len(buf)-1] |= 1
+
+		candidate.SetBytes(buf)
+		if candidate.IsPrime() {
+			return candidate, nil
+		}
+	}
+	return nil, errRejectionLimit
+}
+
+// RandomUnit returns a uniformly random element of the multiplicative group
+// of integers modulo n, that is an integer in [1, n) coprime to n. The
+// modulus must be greater than 1.
+func RandomUnit(n *large.Int, rng io.Reader) (*large.Int, error) {
+	if n.Cmp(large.NewInt(1)) <= 0 {
+		return nil, errors.Errorf("modulus must be greater than 1, "+
+			"received %s", n.Text(10))
+	}
+
+	one := large.NewInt(1)
+	gcd := large.NewInt(0)
+	for i := 0; i < maxRejectionAttempts; i++ {
+		res, err := RandomInt(one, n, rng)
+		if err != nil {
+			return nil, err
+		}
+		if gcd.GCD(nil, nil, res, n).Cmp(one) == 0 {
+			return res, nil
+		}
+	}
+	return nil, errRejectionLimit
+}
+
+// randomBelow returns a uniformly random integer in [0, size) for size > 0.
+func randomBelow(size *large.Int, rng io.Reader) (*large.Int, error) {
+	// Sample from [0, 2^bits) where 2^(bits-1) < size <= 2^bits so that
+	// every attempt succeeds with probability greater than 1/2
+	bits := large.NewInt(0).Sub(size, large.NewInt(1)).BitLen()
+	if bits == 0 {
+		return large.NewInt(0), nil
+	}
+
+	buf := make([]byte, (bits+7)/8)
+	mask := byte(1<<(uint(bits-1)%8+1)) - 1
+	res := large.NewInt(0)
+	for i := 0; i < maxRejectionAttempts; i++ {
+		if _, err := io.ReadFull(rng, buf); err != nil {
+			return nil, errors.Wrap(err, "failed to read random integer")
+		}
+		buf[0] &= mask
+		res.SetBytes(buf)
+		if res.Cmp(size) < 0 {
+			return res, nil
+		}
+	}
+	return nil, errRejectionLimit
+}
@@ -0,0 +1,120 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package randomness
+
+import (
+	"math/rand"
+	"testing"
+
+	"gitlab.com/xx_network/crypto/csprng"
+	"gitlab.com/xx_network/crypto/large"
+)
+
+// TestRandomInt checks that samples stay in range and that every value of a
+// small range is reached roughly equally often
+func TestRandomInt(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	min, max := large.NewInt(-5), large.NewInt(7)
+
+	counts := make(map[int64]int)
+	const samples = 12000
+	for i := 0; i < samples; i++ {
+		n, err := RandomInt(min, max, rng)
+		if err != nil {
+			t.Fatalf("RandomInt returned an error: %+v", err)
+		}
+		if n.Cmp(min) < 0 || n.Cmp(max) >= 0 {
+			t.Fatalf("RandomInt out of range: %s", n.Text(10))
+		}
+		counts[n.Int64()]++
+	}
+
+	if len(counts) != 12 {
+		t.Errorf("RandomInt did not reach every value: %v", counts)
+	}
+	for v, c := range counts {
+		if c < 800 || c > 1200 {
+			t.Errorf("Value %d drawn %d times, expected about 1000", v, c)
+		}
+	}
+}
+
+// TestRandomInt_Large checks sampling a range wider than a machine word
+func TestRandomInt_Large(t *testing.T) {
+	max := large.NewIntFromString(
+		"FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD1", 16)
+	n, err := RandomInt(large.NewInt(0), max, csprng.NewSystemRNG())
+	if err != nil {
+		t.Fatalf("RandomInt returned an error: %+v", err)
+	}
+	if n.Cmp(max) >= 0 || n.Cmp(large.NewInt(0)) < 0 {
+		t.Errorf("RandomInt out of range: %s", n.Text(16))
+	}
+}
+
+// TestRandomInt_Errors checks invalid ranges and source failures
+func TestRandomInt_Errors(t *testing.T) {
+	rng := csprng.NewSystemRNG()
+	if _, err := RandomInt(large.NewInt(3), large.NewInt(3), rng); err == nil {
+		t.Errorf("RandomInt should reject an empty range")
+	}
+	_, err := RandomInt(large.NewInt(0), large.NewInt(1000),
+		csprng.NewFailingSource(rng, 0, nil))
+	if err == nil {
+		t.Errorf("RandomInt should fail when the source fails")
+	}
+	// Constant 0xff output is always above 1000 after masking
+	_, err = RandomInt(large.NewInt(0), large.NewInt(1000),
+		csprng.NewConstantSource(0xff))
+	if err == nil {
+		t.Errorf("RandomInt should give up on a stuck source")
+	}
+}
+
+// TestRandomPrime checks that the result is a prime of the right size
+func TestRandomPrime(t *testing.T) {
+	rng := csprng.NewSystemRNG()
+	for _, bits := range []int{2, 3, 17, 64, 256} {
+		p, err := RandomPrime(bits, rng)
+		if err != nil {
+			t.Fatalf("RandomPrime(%d) returned an error: %+v", bits, err)
+		}
+		if p.BitLen() != bits || !p.IsPrime() {
+			t.Errorf("RandomPrime(%d) returned %s", bits, p.Text(10))
+		}
+	}
+
+	if _, err := RandomPrime(1, rng); err == nil {
+		t.Errorf("RandomPrime should reject 1 bit primes")
+	}
+	if _, err := RandomPrime(64, csprng.NewZeroSource()); err == nil {
+		t.Errorf("RandomPrime should give up on a stuck source")
+	}
+}
+
+// TestRandomUnit checks that the result is coprime to the modulus
+func TestRandomUnit(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	// 2 * 3 * 5 * 7 has many non-units
+	n := large.NewInt(210)
+	one := large.NewInt(1)
+	for i := 0; i < 1000; i++ {
+		u, err := RandomUnit(n, rng)
+		if err != nil {
+			t.Fatalf("RandomUnit returned an error: %+v", err)
+		}
+		if u.Cmp(one) < 0 || u.Cmp(n) >= 0 ||
+			large.NewInt(0).GCD(nil, nil, u, n).Cmp(one) != 0 {
+			t.Fatalf("RandomUnit returned a non-unit: %s", u.Text(10))
+		}
+	}
+
+	if _, err := RandomUnit(one, rng); err == nil {
+		t.Errorf("RandomUnit should reject a modulus of 1")
+	}
+}
@@ -12,17 +12,27 @@ import (
 	"io"
 	"math"
 
+	"github.com/pkg/errors"
 	jww "github.com/spf13/jwalterweatherman"
 )
 
+// maxRejectionAttempts bounds the number of samples drawn by the rejection
+// samplers before concluding that the RNG is stuck. A working RNG never gets
+// close to it.
+const maxRejectionAttempts = 1 << 16
+
+// errRejectionLimit is returned when a rejection sampler gives up.
+var errRejectionLimit = errors.Errorf("no acceptable sample after %d "+
+	"attempts, the RNG may be stuck", maxRejectionAttempts)
+
 // ReadUint32 reads an integer from an io.Reader (which should be a CSPRNG).
+// It panics if the read fails; use Uint32 to handle the error instead.
 func ReadUint32(rng io.Reader) uint32 {
-	var rndBytes [4]byte
-	i, err := rng.Read(rndBytes[:])
-	if i != 4 || err != nil {
+	res, err := Uint32(rng)
+	if err != nil {
 		jww.FATAL.Panicf("cannot read from rng: %+v", err)
 	}
-	return binary.BigEndian.Uint32(rndBytes[:])
+	return res
 }
 
 // ReadRangeUint32 reduces a random integer from 0, MaxUint32 to
@@ -31,7 +41,41 @@ func ReadUint32(rng io.Reader) uint32 {
 // The reason start is inclusive and end is not is that this function
 // is meant to work with lists (i.e., get random element inside the list,
 // except for the first 3)
+//
+// It panics if the read fails; use RangeUint32 to handle the error instead.
 func ReadRangeUint32(start, end uint32, rng io.Reader) uint32 {
+	res, err := RangeUint32(start, end, rng)
+	if err != nil {
+		jww.FATAL.Panicf("cannot read from rng: %+v", err)
+	}
+	return res
+}
+
+// Uint32 reads a uniformly random uint32 from rng.
+func Uint32(rng io.Reader) (uint32, error) {
+	var rndBytes [4]byte
+	if _, err := io.ReadFull(rng, rndBytes[:]); err != nil {
+		return 0, errors.Wrap(err, "failed to read uint32 from rng")
+	}
+	return binary.BigEndian.Uint32(rndBytes[:]), nil
+}
+
+// Uint64 reads a uniformly random uint64 from rng.
+func Uint64(rng io.Reader) (uint64, error) {
+	var rndBytes [8]byte
+	if _, err := io.ReadFull(rng, rndBytes[:]); err != nil {
+		return 0, errors.Wrap(err, "failed to read uint64 from rng")
+	}
+	return binary.BigEndian.Uint64(rndBytes[:]), nil
+}
+
+// RangeUint32 returns a uniformly random integer greater than or equal to
+// start and less than end. Samples above the largest multiple of the range
+// size are rejected so there is no modulo bias.
+func RangeUint32(start, end uint32, rng io.Reader) (uint32, error) {
+	if end <= start {
+		return 0, errors.Errorf("invalid range [%d, %d)", start, end)
+	}
 	size := end - start
 	// Note that we could just do the part inside the () here, but
 	// then extra can == size which means a little range is
@@ -40,10 +84,49 @@ func ReadRangeUint32(start, end uint32, rng io.Reader) uint32 {
 	extra := (math.MaxUint32%size + 1) % size
 	limit := math.MaxUint32 - extra
 	// Loop until we read something inside the limit
-	for {
-		res := ReadUint32(rng)
+	for i := 0; i < maxRejectionAttempts; i++ {
+		res, err := Uint32(rng)
+		if err != nil {
+			return 0, err
+		}
+		if res <= limit {
+			return (res % size) + start, nil
+		}
+	}
+	return 0, errRejectionLimit
+}
+
+// RangeUint64 returns a uniformly random integer greater than or equal to
+// start and less than end, without modulo bias.
+func RangeUint64(start, end uint64, rng io.Reader) (uint64, error) {
+	if end <= start {
+		return 0, errors.Errorf("invalid range [%d, %d)", start, end)
+	}
+	size := end - start
+	extra := (math.MaxUint64%size + 1) % size
+	limit := uint64(math.MaxUint64) - extra
+	for i := 0; i < maxRejectionAttempts; i++ {
+		res, err := Uint64(rng)
+		if err != nil {
+			return 0, err
+		}
 		if res <= limit {
-			return (res % size) + start
+			return (res % size) + start, nil
 		}
 	}
+	return 0, errRejectionLimit
+}
+
+// RangeInt returns a uniformly random int greater than or equal to start and
+// less than end, without modulo bias. Negative bounds are supported.
+func RangeInt(start, end int, rng io.Reader) (int, error) {
+	if end <= start {
+		return 0, errors.Errorf("invalid range [%d, %d)", start, end)
+	}
+	// The difference always fits in a uint64, even when it overflows int
+	offset, err := RangeUint64(0, uint64(end)-uint64(start), rng)
+	if err != nil {
+		return 0, err
+	}
+	return int(uint64(start) + offset), nil
 }
@@ -8,9 +8,12 @@
 package randomness
 
 import (
+	"math"
 	"math/rand"
 	"strings"
 	"testing"
+
+	"gitlab.com/xx_network/crypto/csprng"
 )
 
 // TestReadRangeSmoke will check that the results of ReadRangeUint32 gets random
@@ -44,3 +47,71 @@ func TestReadRangeShort(t *testing.T) {
 
 	ReadRangeUint32(0, 10, rng)
 }
+
+// TestUint32_Error checks that read failures are returned instead of
+// panicking
+func TestUint32_Error(t *testing.T) {
+	if _, err := Uint32(strings.NewReader("ts")); err == nil {
+		t.Errorf("Uint32 should fail on a short read")
+	}
+	if _, err := Uint64(strings.NewReader("tsts")); err == nil {
+		t.Errorf("Uint64 should fail on a short read")
+	}
+	if _, err := RangeUint32(0, 10, strings.NewReader("ts")); err == nil {
+		t.Errorf("RangeUint32 should fail on a short read")
+	}
+	if _, err := RangeInt(0, 10, csprng.NewFailingSource(
+		csprng.NewSystemRNG(), 4, nil)); err == nil {
+		t.Errorf("RangeInt should fail when the source fails")
+	}
+}
+
+// TestRange_Invalid checks that empty ranges are rejected
+func TestRange_Invalid(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	if _, err := RangeUint32(5, 5, rng); err == nil {
+		t.Errorf("RangeUint32 should reject an empty range")
+	}
+	if _, err := RangeUint64(6, 5, rng); err == nil {
+		t.Errorf("RangeUint64 should reject an inverted range")
+	}
+	if _, err := RangeInt(0, -1, rng); err == nil {
+		t.Errorf("RangeInt should reject an inverted range")
+	}
+}
+
+// TestRange_Bounds checks that every sampler stays inside its range and
+// reaches both ends of a small range
+func TestRange_Bounds(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	seen := make(map[int]bool)
+	for i := 0; i < 1000; i++ {
+		u64, err := RangeUint64(math.MaxUint64-3, math.MaxUint64, rng)
+		if err != nil || u64 < math.MaxUint64-3 {
+			t.Fatalf("RangeUint64 out of range: %d, %v", u64, err)
+		}
+
+		n, err := RangeInt(-3, 2, rng)
+		if err != nil || n < -3 || n >= 2 {
+			t.Fatalf("RangeInt out of range: %d, %v", n, err)
+		}
+		seen[n] = true
+	}
+	if len(seen) != 5 {
+		t.Errorf("RangeInt did not reach every value: %v", seen)
+	}
+
+	// The full int range does not overflow
+	if _, err := RangeInt(math.MinInt64, math.MaxInt64, rng); err != nil {
+		t.Errorf("RangeInt failed on the full range: %+v", err)
+	}
+}
+
+// TestRangeUint32_Stuck checks that a source stuck above the rejection limit
+// causes an error instead of an endless loop
+func TestRangeUint32_Stuck(t *testing.T) {
+	_, err := RangeUint32(0, 3, csprng.NewConstantSource(0xff))
+	if err == nil {
+		t.Errorf("RangeUint32 should give up on a stuck source")
+	}
+}
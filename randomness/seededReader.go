@@ -0,0 +1,101 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package randomness
+
+import (
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/zeebo/blake3"
+	"golang.org/x/crypto/sha3"
+)
+
+// XOF selects the extendable output function behind a SeededReader.
+type XOF uint8
+
+const (
+	// XOFShake256 is cSHAKE256 with the domain as its customization string.
+	XOFShake256 XOF = iota
+	// XOFBlake3 is BLAKE3 in key derivation mode with the domain as its
+	// context string.
+	XOFBlake3
+)
+
+// String returns the name of the XOF.
+func (x XOF) String() string {
+	switch x {
+	case XOFShake256:
+		return "cSHAKE256"
+	case XOFBlake3:
+		return "BLAKE3"
+	default:
+		return "unknown XOF"
+	}
+}
+
+// SeededReader is a deterministic stream of unlimited length derived from a
+// seed. Streams with the same XOF, domain and seed are identical; streams
+// that differ in any of them are independent. The domain should name the
+// protocol and its version, so that a seed reused by two protocols does not
+// produce related values.
+//
+// SeededReader implements csprng.Source, so it can be passed to any sampler
+// that accepts one. It is only as unpredictable as its seed.
+type SeededReader struct {
+	xof    XOF
+	domain string
+	stream io.Reader
+	mux    sync.Mutex
+}
+
+// NewSeededReader returns the stream for the seed under the given XOF and
+// domain.
+func NewSeededReader(xof XOF, domain string, seed []byte) (*SeededReader,
+	error) {
+	if xof != XOFShake256 && xof != XOFBlake3 {
+		return nil, errors.Errorf("unknown XOF %d", xof)
+	}
+	if domain == "" {
+		return nil, errors.New("a SeededReader requires a domain")
+	}
+
+	s := &SeededReader{xof: xof, domain: domain}
+	s.reset(seed)
+	return s, nil
+}
+
+// Read fills b with the next bytes of the stream. It never fails.
+func (s *SeededReader) Read(b []byte) (int, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.stream.Read(b)
+}
+
+// SetSeed restarts the stream from the new seed, keeping the XOF and domain.
+func (s *SeededReader) SetSeed(seed []byte) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.reset(seed)
+	return nil
+}
+
+// reset starts the stream for seed. The caller must hold the lock unless the
+// reader has not been shared yet.
+func (s *SeededReader) reset(seed []byte) {
+	switch s.xof {
+	case XOFShake256:
+		h := sha3.NewCShake256(nil, []byte(s.domain))
+		_, _ = h.Write(seed)
+		s.stream = h
+	case XOFBlake3:
+		h := blake3.NewDeriveKey(s.domain)
+		_, _ = h.Write(seed)
+		s.stream = h.Digest()
+	}
+}
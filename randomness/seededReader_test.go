@@ -0,0 +1,156 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package randomness
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/zeebo/blake3"
+	"gitlab.com/xx_network/crypto/csprng"
+	"gitlab.com/xx_network/crypto/hasher"
+)
+
+// Ensure SeededReader can be used wherever a csprng.Source is expected
+var _ csprng.Source = &SeededReader{}
+
+// TestSeededReader_Consistency pins the start of both streams so that seeded
+// outputs stay reproducible across releases
+func TestSeededReader_Consistency(t *testing.T) {
+	expected := map[XOF]string{
+		XOFShake256: "733b8f317a43f436f8dee3a0705d1cac",
+		XOFBlake3:   "5999131d9962a947f18a940bdb1fd8c1",
+	}
+	for xof, e := range expected {
+		r, err := NewSeededReader(xof, "test domain", []byte("seed"))
+		if err != nil {
+			t.Fatalf("NewSeededReader(%s) returned an error: %+v", xof, err)
+		}
+		out := make([]byte, 16)
+		_, _ = r.Read(out)
+		if hex.EncodeToString(out) != e {
+			t.Errorf("%s stream changed.\nexpected: %s\nreceived: %x",
+				xof, e, out)
+		}
+	}
+}
+
+// TestSeededReader_Blake3 checks the BLAKE3 stream against the BLAKE3 key
+// derivation function
+func TestSeededReader_Blake3(t *testing.T) {
+	r, _ := NewSeededReader(XOFBlake3, "test domain", []byte("seed"))
+	out := make([]byte, 100)
+	_, _ = io.ReadFull(r, out)
+
+	expected := make([]byte, 100)
+	blake3.DeriveKey("test domain", []byte("seed"), expected)
+	if !bytes.Equal(out, expected) {
+		t.Errorf("BLAKE3 stream does not match DeriveKey.\nexpected: %x"+
+			"\nreceived: %x", expected, out)
+	}
+}
+
+// TestSeededReader_Separation checks that the stream depends on the seed and
+// the domain, and that SetSeed restarts it
+func TestSeededReader_Separation(t *testing.T) {
+	read := func(r *SeededReader) []byte {
+		out := make([]byte, 32)
+		_, _ = r.Read(out)
+		return out
+	}
+
+	base, _ := NewSeededReader(XOFShake256, "domain A", []byte("seed"))
+	first := read(base)
+	if bytes.Equal(first, read(base)) {
+		t.Errorf("Consecutive reads returned the same bytes")
+	}
+
+	other, _ := NewSeededReader(XOFShake256, "domain B", []byte("seed"))
+	if bytes.Equal(first, read(other)) {
+		t.Errorf("Different domains produced the same stream")
+	}
+	other, _ = NewSeededReader(XOFShake256, "domain A", []byte("seeds"))
+	if bytes.Equal(first, read(other)) {
+		t.Errorf("Different seeds produced the same stream")
+	}
+
+	if err := base.SetSeed([]byte("seed")); err != nil {
+		t.Fatalf("SetSeed returned an error: %+v", err)
+	}
+	if !bytes.Equal(first, read(base)) {
+		t.Errorf("SetSeed did not restart the stream")
+	}
+}
+
+// TestNewSeededReader_Invalid checks that unknown XOFs and empty domains are
+// rejected
+func TestNewSeededReader_Invalid(t *testing.T) {
+	if _, err := NewSeededReader(XOF(9), "domain", nil); err == nil {
+		t.Errorf("NewSeededReader accepted an unknown XOF")
+	}
+	if _, err := NewSeededReader(XOFShake256, "", nil); err == nil {
+		t.Errorf("NewSeededReader accepted an empty domain")
+	}
+}
+
+// TestRandInIntervalVersion_Large checks that IntervalV2 samples 4096 bit
+// ranges that IntervalV1 rejects, and that the result is pinned
+func TestRandInIntervalVersion_Large(t *testing.T) {
+	max, _ := new(big.Int).SetString("FFFFFFFFFFFFFFFFC90FDAA22168C234C4C66"+
+		"28B80DC1CD129024E088A67CC74020BBEA63B139B22514A08798E3404DD", 16)
+	h, _ := hasher.BLAKE3.New()
+
+	if _, err := RandInIntervalVersion(IntervalV1, max, []byte("pinned seed"),
+		h); err == nil {
+		t.Errorf("IntervalV1 should reject a max longer than a third of " +
+			"the digest")
+	}
+
+	r, err := RandInIntervalVersion(IntervalV2, max, []byte("pinned seed"), nil)
+	if err != nil {
+		t.Fatalf("IntervalV2 returned an error: %+v", err)
+	}
+	expected := "11c80d3847bbd1029ed095033e73a334f1b519daeac231d000af3a8" +
+		"002140ece26a69bbb955590ffb97bef8047e41fca"
+	if r.Text(16) != expected {
+		t.Errorf("IntervalV2 output changed.\nexpected: %s\nreceived: %s",
+			expected, r.Text(16))
+	}
+
+	p4096 := new(big.Int).Lsh(big.NewInt(1), 4096)
+	p4096.Sub(p4096, big.NewInt(159))
+	for i := 0; i < 10; i++ {
+		r, err = RandInIntervalVersion(IntervalV2, p4096, []byte{byte(i)}, nil)
+		if err != nil || r.Sign() < 0 || r.Cmp(p4096) >= 0 {
+			t.Errorf("IntervalV2 out of range for a 4096 bit max: %v", err)
+		}
+	}
+}
+
+// TestRandInIntervalVersion_V1 checks that IntervalV1 matches RandInInterval
+func TestRandInIntervalVersion_V1(t *testing.T) {
+	h, _ := hasher.BLAKE3.New()
+	max := big.NewInt(1000003)
+	r, err := RandInIntervalVersion(IntervalV1, max, []byte("seed"), h)
+	if err != nil {
+		t.Fatalf("IntervalV1 returned an error: %+v", err)
+	}
+	if r.Cmp(RandInInterval(max, []byte("seed"), h)) != 0 {
+		t.Errorf("IntervalV1 does not match RandInInterval")
+	}
+
+	if _, err = RandInIntervalVersion(IntervalVersion(7), max, nil, h); err == nil {
+		t.Errorf("RandInIntervalVersion accepted an unknown version")
+	}
+	if _, err = RandInIntervalFromReader(big.NewInt(-1), nil); err == nil {
+		t.Errorf("RandInIntervalFromReader accepted a negative max")
+	}
+}
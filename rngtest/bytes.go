@@ -0,0 +1,78 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package rngtest
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// minChiSquareBytes is the shortest input for which every byte value has an
+// expected count of at least 5.
+const minChiSquareBytes = 5 * 256
+
+// mcvZ is the upper 99.5 percentile of the standard normal distribution used
+// by the most common value estimate.
+const mcvZ = 2.576
+
+// ChiSquare runs a chi-square goodness of fit test of the byte values
+// against the uniform distribution with 255 degrees of freedom. The input
+// must be at least 1280 bytes.
+func ChiSquare(data []byte) (Result, error) {
+	if len(data) < minChiSquareBytes {
+		return Result{}, errors.Errorf("chi-square test requires at least "+
+			"%d bytes, received %d", minChiSquareBytes, len(data))
+	}
+
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+
+	expected := float64(len(data)) / 256
+	chi2 := 0.0
+	for _, c := range counts {
+		d := float64(c) - expected
+		chi2 += d * d / expected
+	}
+
+	return Result{
+		Name:      ChiSquareTest,
+		Statistic: chi2,
+		PValues:   []float64{igamc(255.0/2, chi2/2)},
+	}, nil
+}
+
+// MinEntropy estimates the min-entropy per byte with the most common value
+// estimate of SP 800-90B section 6.3.1. The estimate is returned as the
+// statistic; the result carries no p-value.
+func MinEntropy(data []byte) (Result, error) {
+	if len(data) < 2 {
+		return Result{}, errors.New("min-entropy estimate requires at " +
+			"least two bytes")
+	}
+
+	var counts [256]int
+	mode := 0
+	for _, b := range data {
+		counts[b]++
+		if counts[b] > mode {
+			mode = counts[b]
+		}
+	}
+
+	l := float64(len(data))
+	p := float64(mode) / l
+	pu := math.Min(1, p+mcvZ*math.Sqrt(p*(1-p)/(l-1)))
+
+	return Result{
+		Name:      MinEntropyTest,
+		Statistic: -math.Log2(pu),
+	}, nil
+}
@@ -0,0 +1,195 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+// Package rngtest runs statistical tests over the output of a random number
+// generator: a subset of the NIST SP 800-22 battery together with a byte
+// chi-square test and an SP 800-90B min-entropy estimate. It is meant for
+// qualifying new csprng.Source implementations; passing it shows only that
+// no obvious statistical defect was found, not that a generator is secure.
+package rngtest
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultBits is the default sequence length, 2^20 bits.
+	DefaultBits = 1 << 20
+
+	// DefaultAlpha is the default significance level recommended by
+	// SP 800-22.
+	DefaultAlpha = 0.01
+
+	// DefaultBlockFrequencySize is the default block frequency block size.
+	DefaultBlockFrequencySize = 128
+
+	// DefaultSerialLength is the default serial test pattern length.
+	DefaultSerialLength = 16
+
+	// DefaultApproximateEntropyLength is the default approximate entropy
+	// block length.
+	DefaultApproximateEntropyLength = 10
+
+	// DefaultMinEntropyThreshold is the default lowest acceptable
+	// min-entropy estimate, in bits per byte.
+	DefaultMinEntropyThreshold = 7.0
+
+	// minBits is the shortest sequence the full suite accepts; it is the
+	// smallest length the longest run test supports.
+	minBits = 128
+)
+
+// Config selects the sequence length and test parameters for Run.
+type Config struct {
+	// Bits is the number of bits read and tested. It must be a multiple of
+	// 8, and at least 1280 bytes are needed for the chi-square test.
+	Bits int
+
+	// Alpha is the significance level each p-value is compared against.
+	Alpha float64
+
+	// BlockFrequencySize is the block frequency block size M.
+	BlockFrequencySize int
+
+	// SerialLength is the serial test pattern length m.
+	SerialLength int
+
+	// ApproximateEntropyLength is the approximate entropy block length m.
+	ApproximateEntropyLength int
+
+	// MinEntropyThreshold is the lowest acceptable min-entropy estimate in
+	// bits per byte.
+	MinEntropyThreshold float64
+}
+
+// DefaultConfig returns the default configuration, which follows the
+// parameter recommendations of SP 800-22 for 2^20 bit sequences.
+func DefaultConfig() Config {
+	return Config{
+		Bits:                     DefaultBits,
+		Alpha:                    DefaultAlpha,
+		BlockFrequencySize:       DefaultBlockFrequencySize,
+		SerialLength:             DefaultSerialLength,
+		ApproximateEntropyLength: DefaultApproximateEntropyLength,
+		MinEntropyThreshold:      DefaultMinEntropyThreshold,
+	}
+}
+
+// Report holds the results of a Run.
+type Report struct {
+	// Bits is the number of bits tested.
+	Bits int
+	// Alpha is the significance level used.
+	Alpha float64
+	// Results holds one result per test in the order they were run.
+	Results []Result
+}
+
+// Passed returns true if every test passed.
+func (r *Report) Passed() bool {
+	for _, res := range r.Results {
+		if !res.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// Failed returns the results of the tests that failed.
+func (r *Report) Failed() []Result {
+	var failed []Result
+	for _, res := range r.Results {
+		if !res.Passed {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// String returns a table of the results.
+func (r *Report) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d bits, alpha %g\n", r.Bits, r.Alpha)
+	for _, res := range r.Results {
+		status := "PASS"
+		if !res.Passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(&sb, "%-20s %s  statistic %-12.6g", res.Name, status,
+			res.Statistic)
+		for _, p := range res.PValues {
+			fmt.Fprintf(&sb, " p=%.6f", p)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Run reads cfg.Bits bits from r and runs every test over them. Any
+// csprng.Source can be passed directly as the reader.
+func Run(r io.Reader, cfg Config) (*Report, error) {
+	if cfg.Bits%8 != 0 {
+		return nil, errors.Errorf("bit count must be a multiple of 8, "+
+			"received %d", cfg.Bits)
+	}
+	data := make([]byte, cfg.Bits/8)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, errors.Wrapf(err, "failed to read %d bytes", len(data))
+	}
+	return RunBytes(data, cfg)
+}
+
+// RunBytes runs every test over data. The Bits field of cfg is ignored.
+func RunBytes(data []byte, cfg Config) (*Report, error) {
+	if 8*len(data) < minBits || len(data) < minChiSquareBytes {
+		return nil, errors.Errorf("at least %d bytes are required, "+
+			"received %d", minChiSquareBytes, len(data))
+	}
+	if cfg.Alpha <= 0 || cfg.Alpha >= 1 {
+		return nil, errors.Errorf("alpha must be in (0, 1), received %g",
+			cfg.Alpha)
+	}
+
+	bits := BitsFromBytes(data)
+	tests := []func() (Result, error){
+		func() (Result, error) { return Frequency(bits) },
+		func() (Result, error) {
+			return BlockFrequency(bits, cfg.BlockFrequencySize)
+		},
+		func() (Result, error) { return Runs(bits) },
+		func() (Result, error) { return LongestRun(bits) },
+		func() (Result, error) { return Serial(bits, cfg.SerialLength) },
+		func() (Result, error) {
+			return ApproximateEntropy(bits, cfg.ApproximateEntropyLength)
+		},
+		func() (Result, error) { return CumulativeSums(bits) },
+		func() (Result, error) { return ChiSquare(data) },
+	}
+
+	report := &Report{Bits: len(bits), Alpha: cfg.Alpha}
+	for _, test := range tests {
+		res, err := test()
+		if err != nil {
+			return nil, err
+		}
+		res.Passed = res.pass(cfg.Alpha)
+		report.Results = append(report.Results, res)
+	}
+
+	minEntropy, err := MinEntropy(data)
+	if err != nil {
+		return nil, err
+	}
+	minEntropy.Passed = minEntropy.Statistic >= cfg.MinEntropyThreshold
+	report.Results = append(report.Results, minEntropy)
+
+	return report, nil
+}
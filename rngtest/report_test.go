@@ -0,0 +1,98 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package rngtest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gitlab.com/xx_network/crypto/csprng"
+)
+
+// newTestDRBG returns a deterministically seeded CTR_DRBG so that the
+// outcome of the suite is reproducible.
+func newTestDRBG(t *testing.T) csprng.Source {
+	entropy := bytes.Repeat([]byte{0x5a}, 32)
+	nonce := bytes.Repeat([]byte{0xa5}, 16)
+	drbg, err := csprng.NewCtrDRBGFromSeed(true, entropy, nonce,
+		[]byte("rngtest"))
+	if err != nil {
+		t.Fatalf("Failed to create DRBG: %+v", err)
+	}
+	return drbg
+}
+
+// Tests that a DRBG passes the full suite.
+func TestRun(t *testing.T) {
+	report, err := Run(newTestDRBG(t), DefaultConfig())
+	if err != nil {
+		t.Fatalf("Run returned an error: %+v", err)
+	}
+	if !report.Passed() {
+		t.Errorf("DRBG output failed the suite:\n%s", report)
+	}
+	if report.Bits != DefaultBits || len(report.Results) != 9 {
+		t.Errorf("Unexpected report shape: %d bits, %d results",
+			report.Bits, len(report.Results))
+	}
+	if !strings.Contains(report.String(), MinEntropyTest) {
+		t.Errorf("Report string is missing results:\n%s", report)
+	}
+}
+
+// Tests that biased and repeating output fails the suite.
+func TestRunBytes_Defective(t *testing.T) {
+	cfg := DefaultConfig()
+
+	// Every fourth byte is fixed, biasing the byte and bit distributions
+	biased := make([]byte, 1<<15)
+	_, _ = newTestDRBG(t).Read(biased)
+	for i := 0; i < len(biased); i += 4 {
+		biased[i] = 0xff
+	}
+
+	// A short block repeated over and over
+	block := make([]byte, 64)
+	_, _ = newTestDRBG(t).Read(block)
+	repeating := bytes.Repeat(block, 1<<9)
+
+	for name, data := range map[string][]byte{
+		"biased": biased, "repeating": repeating} {
+		report, err := RunBytes(data, cfg)
+		if err != nil {
+			t.Fatalf("RunBytes returned an error for %s data: %+v", name, err)
+		}
+		if report.Passed() || len(report.Failed()) == 0 {
+			t.Errorf("Defective %s data passed the suite:\n%s", name, report)
+		}
+	}
+}
+
+// Error path: tests that invalid configurations are rejected.
+func TestRun_Invalid(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Bits = 1001
+	if _, err := Run(newTestDRBG(t), cfg); err == nil {
+		t.Errorf("Run accepted a bit count that is not a multiple of 8.")
+	}
+
+	cfg = DefaultConfig()
+	cfg.Alpha = 0
+	if _, err := Run(newTestDRBG(t), cfg); err == nil {
+		t.Errorf("Run accepted an alpha of 0.")
+	}
+
+	if _, err := RunBytes(make([]byte, 100), DefaultConfig()); err == nil {
+		t.Errorf("RunBytes accepted too little data.")
+	}
+
+	if _, err := Run(bytes.NewReader(make([]byte, 16)), DefaultConfig()); err == nil {
+		t.Errorf("Run did not fail on a short reader.")
+	}
+}
@@ -0,0 +1,387 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package rngtest
+
+import (
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Names of the tests, as used in Result.Name.
+const (
+	FrequencyTest          = "Frequency"
+	BlockFrequencyTest     = "BlockFrequency"
+	RunsTest               = "Runs"
+	LongestRunTest         = "LongestRunOfOnes"
+	SerialTest             = "Serial"
+	ApproximateEntropyTest = "ApproximateEntropy"
+	CumulativeSumsTest     = "CumulativeSums"
+	ChiSquareTest          = "ChiSquare"
+	MinEntropyTest         = "MinEntropy"
+)
+
+// Bits is a bit sequence with one bit, 0 or 1, per element.
+type Bits []uint8
+
+// BitsFromBytes expands b into its bits, most significant bit first.
+func BitsFromBytes(b []byte) Bits {
+	bits := make(Bits, 8*len(b))
+	for i, v := range b {
+		for j := 0; j < 8; j++ {
+			bits[8*i+j] = (v >> uint(7-j)) & 1
+		}
+	}
+	return bits
+}
+
+// ParseBits parses a string of '0' and '1' characters. Whitespace is ignored
+// so that the examples in SP 800-22 can be copied directly.
+func ParseBits(s string) (Bits, error) {
+	bits := make(Bits, 0, len(s))
+	for _, c := range s {
+		switch {
+		case c == '0' || c == '1':
+			bits = append(bits, uint8(c-'0'))
+		case strings.ContainsRune(" \t\r\n", c):
+		default:
+			return nil, errors.Errorf("invalid bit character %q", c)
+		}
+	}
+	return bits, nil
+}
+
+// Result is the outcome of a single test.
+type Result struct {
+	// Name is the name of the test.
+	Name string
+	// Statistic is the test statistic, such as the chi-square value or, for
+	// the min-entropy estimate, the estimated bits of entropy per byte.
+	Statistic float64
+	// PValues holds the p-values of the test. Most tests have one; the
+	// serial and cumulative sums tests have two. The min-entropy estimate
+	// has none.
+	PValues []float64
+	// Passed is set by Run once the result has been checked.
+	Passed bool
+}
+
+// pass returns true if every p-value is at least alpha.
+func (r *Result) pass(alpha float64) bool {
+	for _, p := range r.PValues {
+		if math.IsNaN(p) || p < alpha {
+			return false
+		}
+	}
+	return true
+}
+
+// Frequency runs the frequency (monobit) test, SP 800-22 section 2.1.
+func Frequency(bits Bits) (Result, error) {
+	n := len(bits)
+	if n == 0 {
+		return Result{}, errors.New("frequency test requires at least one bit")
+	}
+
+	sum := 0
+	for _, b := range bits {
+		sum += 2*int(b) - 1
+	}
+	sObs := math.Abs(float64(sum)) / math.Sqrt(float64(n))
+	return Result{
+		Name:      FrequencyTest,
+		Statistic: sObs,
+		PValues:   []float64{math.Erfc(sObs / math.Sqrt2)},
+	}, nil
+}
+
+// BlockFrequency runs the frequency test within blocks of m bits, SP 800-22
+// section 2.2. Bits after the last full block are discarded.
+func BlockFrequency(bits Bits, m int) (Result, error) {
+	if m < 1 || m > len(bits) {
+		return Result{}, errors.Errorf("block size must be between 1 and "+
+			"%d, received %d", len(bits), m)
+	}
+
+	blocks := len(bits) / m
+	chi2 := 0.0
+	for i := 0; i < blocks; i++ {
+		ones := 0
+		for _, b := range bits[i*m : (i+1)*m] {
+			ones += int(b)
+		}
+		pi := float64(ones)/float64(m) - 0.5
+		chi2 += pi * pi
+	}
+	chi2 *= 4 * float64(m)
+
+	return Result{
+		Name:      BlockFrequencyTest,
+		Statistic: chi2,
+		PValues:   []float64{igamc(float64(blocks)/2, chi2/2)},
+	}, nil
+}
+
+// Runs runs the runs test, SP 800-22 section 2.3. If the sequence fails the
+// frequency prerequisite the p-value is 0.
+func Runs(bits Bits) (Result, error) {
+	n := len(bits)
+	if n < 2 {
+		return Result{}, errors.New("runs test requires at least two bits")
+	}
+
+	ones := 0
+	for _, b := range bits {
+		ones += int(b)
+	}
+	pi := float64(ones) / float64(n)
+	if math.Abs(pi-0.5) >= 2/math.Sqrt(float64(n)) {
+		return Result{Name: RunsTest, PValues: []float64{0}}, nil
+	}
+
+	runs := 1
+	for i := 1; i < n; i++ {
+		if bits[i] != bits[i-1] {
+			runs++
+		}
+	}
+
+	v := float64(runs)
+	num := math.Abs(v - 2*float64(n)*pi*(1-pi))
+	den := 2 * math.Sqrt(2*float64(n)) * pi * (1 - pi)
+	return Result{
+		Name:      RunsTest,
+		Statistic: v,
+		PValues:   []float64{math.Erfc(num / den)},
+	}, nil
+}
+
+// longestRunParams are the block size, run length classes and class
+// probabilities of the longest run test for one range of sequence lengths.
+type longestRunParams struct {
+	minBits  int
+	m        int
+	minClass int
+	pi       []float64
+}
+
+// longestRunTable holds the parameters from SP 800-22 section 2.4.4 for
+// sequences of at least 128, 6272 and 750,000 bits.
+var longestRunTable = []longestRunParams{
+	{750000, 10000, 10,
+		[]float64{0.0882, 0.2092, 0.2483, 0.1933, 0.1208, 0.0675, 0.0727}},
+	{6272, 128, 4,
+		[]float64{0.1174, 0.2430, 0.2493, 0.1752, 0.1027, 0.1124}},
+	{128, 8, 1,
+		[]float64{0.2148, 0.3672, 0.2305, 0.1875}},
+}
+
+// LongestRun runs the test for the longest run of ones in a block, SP 800-22
+// section 2.4. The block size is chosen from the sequence length, which must
+// be at least 128 bits.
+func LongestRun(bits Bits) (Result, error) {
+	n := len(bits)
+	var params *longestRunParams
+	for i := range longestRunTable {
+		if n >= longestRunTable[i].minBits {
+			params = &longestRunTable[i]
+			break
+		}
+	}
+	if params == nil {
+		return Result{}, errors.Errorf("longest run test requires at least "+
+			"128 bits, received %d", n)
+	}
+
+	k := len(params.pi) - 1
+	blocks := n / params.m
+	counts := make([]int, k+1)
+	for i := 0; i < blocks; i++ {
+		longest, run := 0, 0
+		for _, b := range bits[i*params.m : (i+1)*params.m] {
+			if b == 1 {
+				run++
+				if run > longest {
+					longest = run
+				}
+			} else {
+				run = 0
+			}
+		}
+
+		class := longest - params.minClass
+		if class < 0 {
+			class = 0
+		} else if class > k {
+			class = k
+		}
+		counts[class]++
+	}
+
+	chi2 := 0.0
+	for i, p := range params.pi {
+		expected := float64(blocks) * p
+		d := float64(counts[i]) - expected
+		chi2 += d * d / expected
+	}
+
+	return Result{
+		Name:      LongestRunTest,
+		Statistic: chi2,
+		PValues:   []float64{igamc(float64(k)/2, chi2/2)},
+	}, nil
+}
+
+// patternCounts counts every overlapping m-bit pattern in the sequence,
+// wrapping around at the end, SP 800-22 sections 2.11 and 2.12.
+func patternCounts(bits Bits, m int) []int {
+	n := len(bits)
+	counts := make([]int, 1<<uint(m))
+	for i := 0; i < n; i++ {
+		pattern := 0
+		for j := 0; j < m; j++ {
+			pattern = pattern<<1 | int(bits[(i+j)%n])
+		}
+		counts[pattern]++
+	}
+	return counts
+}
+
+// psiSquared returns the serial test statistic psi^2_m.
+func psiSquared(bits Bits, m int) float64 {
+	if m <= 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, c := range patternCounts(bits, m) {
+		sum += float64(c) * float64(c)
+	}
+	n := float64(len(bits))
+	return sum*float64(uint64(1)<<uint(m))/n - n
+}
+
+// Serial runs the serial test with pattern length m, SP 800-22 section 2.11.
+// It returns two p-values.
+func Serial(bits Bits, m int) (Result, error) {
+	if m < 2 || m > 24 || m >= len(bits) {
+		return Result{}, errors.Errorf("serial pattern length must be "+
+			"between 2 and min(24, %d), received %d", len(bits)-1, m)
+	}
+
+	psi0 := psiSquared(bits, m)
+	psi1 := psiSquared(bits, m-1)
+	psi2 := psiSquared(bits, m-2)
+	del1 := psi0 - psi1
+	del2 := psi0 - 2*psi1 + psi2
+
+	return Result{
+		Name:      SerialTest,
+		Statistic: del1,
+		PValues: []float64{
+			igamc(math.Exp2(float64(m-2)), del1/2),
+			igamc(math.Exp2(float64(m-3)), del2/2),
+		},
+	}, nil
+}
+
+// phi returns the approximate entropy statistic phi^(m).
+func phi(bits Bits, m int) float64 {
+	n := float64(len(bits))
+	sum := 0.0
+	for _, c := range patternCounts(bits, m) {
+		if c > 0 {
+			p := float64(c) / n
+			sum += p * math.Log(p)
+		}
+	}
+	return sum
+}
+
+// ApproximateEntropy runs the approximate entropy test with block length m,
+// SP 800-22 section 2.12.
+func ApproximateEntropy(bits Bits, m int) (Result, error) {
+	if m < 1 || m > 23 || m >= len(bits) {
+		return Result{}, errors.Errorf("approximate entropy block length "+
+			"must be between 1 and min(23, %d), received %d", len(bits)-1, m)
+	}
+
+	n := float64(len(bits))
+	apEn := phi(bits, m) - phi(bits, m+1)
+	chi2 := 2 * n * (math.Ln2 - apEn)
+
+	return Result{
+		Name:      ApproximateEntropyTest,
+		Statistic: chi2,
+		PValues:   []float64{igamc(math.Exp2(float64(m-1)), chi2/2)},
+	}, nil
+}
+
+// CumulativeSums runs the cumulative sums test in the forward and reverse
+// directions, SP 800-22 section 2.13. It returns the forward p-value
+// followed by the reverse p-value.
+func CumulativeSums(bits Bits) (Result, error) {
+	n := len(bits)
+	if n == 0 {
+		return Result{}, errors.New("cumulative sums test requires at " +
+			"least one bit")
+	}
+
+	maxExcursion := func(reverse bool) int {
+		sum, z := 0, 0
+		for i := 0; i < n; i++ {
+			b := bits[i]
+			if reverse {
+				b = bits[n-1-i]
+			}
+			sum += 2*int(b) - 1
+			if sum > z {
+				z = sum
+			} else if -sum > z {
+				z = -sum
+			}
+		}
+		return z
+	}
+
+	forward := maxExcursion(false)
+	return Result{
+		Name:      CumulativeSumsTest,
+		Statistic: float64(forward),
+		PValues: []float64{
+			cusumPValue(n, forward),
+			cusumPValue(n, maxExcursion(true)),
+		},
+	}, nil
+}
+
+// cusumPValue returns the cumulative sums p-value for maximum excursion z.
+func cusumPValue(n, z int) float64 {
+	if z == 0 {
+		return 0
+	}
+	nf, zf := float64(n), float64(z)
+	sqrtN := math.Sqrt(nf)
+
+	sum1 := 0.0
+	start := int(math.Floor((-nf/zf + 1) / 4))
+	end := int(math.Floor((nf/zf - 1) / 4))
+	for k := start; k <= end; k++ {
+		sum1 += normalCDF(float64(4*k+1)*zf/sqrtN) -
+			normalCDF(float64(4*k-1)*zf/sqrtN)
+	}
+
+	sum2 := 0.0
+	start = int(math.Floor((-nf/zf - 3) / 4))
+	for k := start; k <= end; k++ {
+		sum2 += normalCDF(float64(4*k+3)*zf/sqrtN) -
+			normalCDF(float64(4*k+1)*zf/sqrtN)
+	}
+
+	return 1 - sum1 + sum2
+}
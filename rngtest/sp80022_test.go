@@ -0,0 +1,136 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package rngtest
+
+import (
+	"math"
+	"testing"
+)
+
+// epsilon100 is the 100 bit example sequence used throughout SP 800-22
+// section 2 (the first 100 binary digits of e).
+const epsilon100 = "11001001000011111101101010100010001000010110100011" +
+	"00001000110100110001001100011001100010100010111000"
+
+// epsilon128 is the example sequence from SP 800-22 section 2.4.8.
+const epsilon128 = "11001100000101010110110001001100111000000000001001" +
+	"00110101010001000100111101011010000000110101111100" +
+	"1100111001101101100010110010"
+
+// mustParseBits parses the bit string or fails the test.
+func mustParseBits(t *testing.T, s string) Bits {
+	bits, err := ParseBits(s)
+	if err != nil {
+		t.Fatalf("Failed to parse bits: %+v", err)
+	}
+	return bits
+}
+
+// Tests every SP 800-22 test against the worked examples in section 2 of the
+// specification.
+func TestSP80022_Examples(t *testing.T) {
+	tests := []struct {
+		name     string
+		run      func(Bits) (Result, error)
+		bits     string
+		expected []float64
+	}{
+		{"Frequency", Frequency, "1011010101", []float64{0.527089}},
+		{"Frequency100", Frequency, epsilon100, []float64{0.109599}},
+		{"BlockFrequency",
+			func(b Bits) (Result, error) { return BlockFrequency(b, 3) },
+			"0110011010", []float64{0.801252}},
+		{"BlockFrequency100",
+			func(b Bits) (Result, error) { return BlockFrequency(b, 10) },
+			epsilon100, []float64{0.706438}},
+		{"Runs", Runs, "1001101011", []float64{0.147232}},
+		{"Runs100", Runs, epsilon100, []float64{0.500798}},
+		{"LongestRun", LongestRun, epsilon128, []float64{0.180598}},
+		{"Serial", func(b Bits) (Result, error) { return Serial(b, 3) },
+			"0011011101", []float64{0.808792, 0.670320}},
+		{"ApproximateEntropy",
+			func(b Bits) (Result, error) { return ApproximateEntropy(b, 3) },
+			"0100110101", []float64{0.261961}},
+		{"ApproximateEntropy100",
+			func(b Bits) (Result, error) { return ApproximateEntropy(b, 2) },
+			epsilon100, []float64{0.235301}},
+		// The specification prints 0.4116588, computed with a less precise
+		// normal distribution; the 100 bit example below agrees exactly
+		{"CumulativeSums", CumulativeSums, "1011010111",
+			[]float64{0.411585, 0.411585}},
+		{"CumulativeSums100", CumulativeSums, epsilon100,
+			[]float64{0.219194, 0.114866}},
+	}
+
+	for _, tt := range tests {
+		res, err := tt.run(mustParseBits(t, tt.bits))
+		if err != nil {
+			t.Errorf("%s returned an error: %+v", tt.name, err)
+			continue
+		}
+		if len(res.PValues) != len(tt.expected) {
+			t.Errorf("%s returned %d p-values, expected %d.", tt.name,
+				len(res.PValues), len(tt.expected))
+			continue
+		}
+		for i, p := range tt.expected {
+			if math.Abs(res.PValues[i]-p) > 1e-5 {
+				t.Errorf("%s p-value %d incorrect.\nexpected: %f\nreceived: %f",
+					tt.name, i, p, res.PValues[i])
+			}
+		}
+	}
+}
+
+// Tests that the runs test fails a sequence that fails its frequency
+// prerequisite.
+func TestRuns_Prerequisite(t *testing.T) {
+	res, err := Runs(mustParseBits(t, "1111111111111111111111111110"))
+	if err != nil {
+		t.Fatalf("Runs returned an error: %+v", err)
+	}
+	if res.PValues[0] != 0 {
+		t.Errorf("Runs should return a zero p-value when the prerequisite "+
+			"fails, received %f", res.PValues[0])
+	}
+}
+
+// Tests that BitsFromBytes expands bytes most significant bit first.
+func TestBitsFromBytes(t *testing.T) {
+	bits := BitsFromBytes([]byte{0xA5, 0x01})
+	expected := mustParseBits(t, "10100101 00000001")
+	for i := range expected {
+		if bits[i] != expected[i] {
+			t.Fatalf("Bits differ.\nexpected: %v\nreceived: %v", expected, bits)
+		}
+	}
+}
+
+// Error path: tests that invalid parameters are rejected.
+func TestSP80022_InvalidParameters(t *testing.T) {
+	bits := mustParseBits(t, epsilon100)
+
+	if _, err := ParseBits("0102"); err == nil {
+		t.Errorf("ParseBits accepted an invalid character.")
+	}
+	if _, err := Frequency(nil); err == nil {
+		t.Errorf("Frequency accepted an empty sequence.")
+	}
+	if _, err := BlockFrequency(bits, 101); err == nil {
+		t.Errorf("BlockFrequency accepted a block longer than the sequence.")
+	}
+	if _, err := LongestRun(bits); err == nil {
+		t.Errorf("LongestRun accepted fewer than 128 bits.")
+	}
+	if _, err := Serial(bits, 1); err == nil {
+		t.Errorf("Serial accepted a pattern length of 1.")
+	}
+	if _, err := ApproximateEntropy(bits, 0); err == nil {
+		t.Errorf("ApproximateEntropy accepted a block length of 0.")
+	}
+}
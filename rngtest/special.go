@@ -0,0 +1,84 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package rngtest
+
+import "math"
+
+const (
+	// gammaEpsilon is the relative precision of the incomplete gamma
+	// function evaluations.
+	gammaEpsilon = 1e-15
+
+	// gammaMaxIterations bounds the series and continued fraction loops.
+	gammaMaxIterations = 10000
+
+	// gammaTiny guards the continued fraction against division by zero.
+	gammaTiny = 1e-300
+)
+
+// igamc returns the regularised upper incomplete gamma function Q(a, x),
+// which SP 800-22 uses to turn chi-square statistics into p-values.
+func igamc(a, x float64) float64 {
+	if x <= 0 || a <= 0 {
+		return 1
+	}
+	if x < a+1 {
+		return 1 - igamSeries(a, x)
+	}
+	return igamcContinuedFraction(a, x)
+}
+
+// igamSeries evaluates the regularised lower incomplete gamma function
+// P(a, x) by its power series, which converges quickly for x < a+1.
+func igamSeries(a, x float64) float64 {
+	lg, _ := math.Lgamma(a)
+	sum := 1 / a
+	term := sum
+	for n := 1; n < gammaMaxIterations; n++ {
+		term *= x / (a + float64(n))
+		sum += term
+		if math.Abs(term) < math.Abs(sum)*gammaEpsilon {
+			break
+		}
+	}
+	return sum * math.Exp(-x+a*math.Log(x)-lg)
+}
+
+// igamcContinuedFraction evaluates Q(a, x) by its continued fraction using
+// the modified Lentz method, which converges quickly for x >= a+1.
+func igamcContinuedFraction(a, x float64) float64 {
+	lg, _ := math.Lgamma(a)
+	b := x + 1 - a
+	c := 1 / gammaTiny
+	d := 1 / b
+	h := d
+	for i := 1; i < gammaMaxIterations; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < gammaTiny {
+			d = gammaTiny
+		}
+		c = b + an/c
+		if math.Abs(c) < gammaTiny {
+			c = gammaTiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < gammaEpsilon {
+			break
+		}
+	}
+	return math.Exp(-x+a*math.Log(x)-lg) * h
+}
+
+// normalCDF returns the standard normal cumulative distribution function.
+func normalCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
@@ -8,11 +8,29 @@
 package shuffle
 
 import (
+	"github.com/pkg/errors"
 	"gitlab.com/xx_network/crypto/hasher"
 	"gitlab.com/xx_network/crypto/randomness"
 	"math/big"
 )
 
+// Version selects the algorithm used by SeededShuffleVersion. A seed only
+// reproduces the same permutation under the same version.
+type Version uint8
+
+const (
+	// V1 is the original SeededShuffle algorithm, which draws each index
+	// with randomness.RandInInterval over a BLAKE3 PRF chain.
+	V1 Version = 1
+
+	// V2 draws every index from a single cSHAKE256 randomness.SeededReader
+	// by rejection sampling. It is faster and has no limit on the list size.
+	V2 Version = 2
+)
+
+// shuffleV2Domain is the SeededReader domain of V2.
+const shuffleV2Domain = "xx_network/crypto shuffle.SeededShuffle v2"
+
 func CreateList(size int) []int {
 	list := make([]int, size)
 
@@ -24,17 +42,20 @@ func CreateList(size int) []int {
 }
 
 // SeededShuffle performs a deterministic Fisher-Yates Shuffle given a list size and a random seed
+//
+// This is V1; use SeededShuffleVersion to select another version.
 func SeededShuffle(size int, seed []byte) []int {
 
 	var (
 		j   int
 		max *big.Int
-		// Blake2 is used to hash the seed when shuffling each position
-		h = hasher.BLAKE2.New()
-		// Blake3 is used as a PRF to obtain verifiable random numbers
-		h2 = hasher.BLAKE3.New()
-		s  = seed
+		s   = seed
 	)
+	// Blake2 is used to hash the seed when shuffling each position. Built-in
+	// hash types are always registered
+	h, _ := hasher.BLAKE2.New()
+	// Blake3 is used as a PRF to obtain verifiable random numbers
+	h2, _ := hasher.BLAKE3.New()
 
 	// Create a new list
 	list := CreateList(size)
@@ -53,3 +74,36 @@ func SeededShuffle(size int, seed []byte) []int {
 	}
 	return list
 }
+
+// SeededShuffleVersion performs a deterministic Fisher-Yates shuffle of a list
+// of the given size using the given algorithm version.
+func SeededShuffleVersion(size int, seed []byte, version Version) ([]int,
+	error) {
+	switch version {
+	case V1:
+		return SeededShuffle(size, seed), nil
+	case V2:
+		return seededShuffleV2(size, seed)
+	default:
+		return nil, errors.Errorf("unknown shuffle version %d", version)
+	}
+}
+
+// seededShuffleV2 implements V2.
+func seededShuffleV2(size int, seed []byte) ([]int, error) {
+	rng, err := randomness.NewSeededReader(
+		randomness.XOFShake256, shuffleV2Domain, seed)
+	if err != nil {
+		return nil, err
+	}
+
+	list := CreateList(size)
+	for i := size - 1; i > 0; i-- {
+		j, err := randomness.RangeInt(0, i+1, rng)
+		if err != nil {
+			return nil, err
+		}
+		list[j], list[i] = list[i], list[j]
+	}
+	return list, nil
+}
@@ -14,7 +14,7 @@ import (
 )
 
 var (
-	h = hasher.SHA2_224.New()
+	h, _ = hasher.SHA2_224.New()
 	//s = []byte("321f485cffb6027f14b7764e8795d6feea5eeeccdc9c08b9487d7b90")
 )
 
@@ -57,3 +57,42 @@ func TestSeededShuffle_Lists(t *testing.T) {
 		t.Errorf("SeededShuffle(): Function output different lists and they should be equal.")
 	}
 }
+
+// Test that the output of each version is reproducible
+func TestSeededShuffleVersion_Consistency(t *testing.T) {
+	expected := map[Version][]int{
+		V1: {5, 3, 9, 1, 6, 4, 2, 0, 8, 7},
+		V2: {9, 5, 2, 8, 1, 7, 4, 6, 0, 3},
+	}
+	for version, e := range expected {
+		list, err := SeededShuffleVersion(10, []byte("pinned seed"), version)
+		if err != nil {
+			t.Fatalf("SeededShuffleVersion(%d) returned an error: %+v",
+				version, err)
+		}
+		if !Equal(list, e) {
+			t.Errorf("SeededShuffleVersion(%d) output changed.\nexpected: %v"+
+				"\nreceived: %v", version, e, list)
+		}
+	}
+}
+
+// Test that V2 produces a permutation of a large list
+func TestSeededShuffleVersion_V2(t *testing.T) {
+	size := 100000
+	list, err := SeededShuffleVersion(size, []byte("seed"), V2)
+	if err != nil {
+		t.Fatalf("SeededShuffleVersion returned an error: %+v", err)
+	}
+	seen := make([]bool, size)
+	for _, v := range list {
+		if seen[v] {
+			t.Fatalf("Value %d appears twice", v)
+		}
+		seen[v] = true
+	}
+
+	if _, err = SeededShuffleVersion(size, nil, Version(0)); err == nil {
+		t.Errorf("SeededShuffleVersion accepted an unknown version")
+	}
+}
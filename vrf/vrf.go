@@ -0,0 +1,251 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+// Package vrf implements the ECVRF-EDWARDS25519-SHA512-TAI verifiable random
+// function from RFC 9381 over the ed25519 keys in signature/ec.
+//
+// The holder of a private key can compute a pseudorandom output (beta) for
+// any input (alpha) together with a proof (pi). Anyone with the public key can
+// verify the proof and recover the same output, and nobody without the
+// private key can predict it. This makes VRF output suitable as a public,
+// unbiasable seed, for example for leader election or for
+// shuffle.SeededShuffle.
+package vrf
+
+import (
+	"bytes"
+	"crypto/sha512"
+
+	"filippo.io/edwards25519"
+	"github.com/pkg/errors"
+	jww "github.com/spf13/jwalterweatherman"
+	"gitlab.com/xx_network/crypto/signature/ec"
+)
+
+const (
+	// ProofSize is the size of a proof (pi) in bytes.
+	ProofSize = ptLen + cLen + qLen
+
+	// OutputSize is the size of the VRF output (beta) in bytes.
+	OutputSize = sha512.Size
+
+	// suiteString identifies ECVRF-EDWARDS25519-SHA512-TAI
+	suiteString = 0x03
+
+	// Lengths of an encoded point, challenge and scalar
+	ptLen = 32
+	cLen  = 16
+	qLen  = 32
+
+	// Domain separators from RFC 9381 section 5.4
+	encodeToCurveFront = 0x01
+	challengeFront     = 0x02
+	proofToHashFront   = 0x03
+	backByte           = 0x00
+)
+
+// ErrInvalidProof is returned when a proof does not verify.
+var ErrInvalidProof = errors.New("invalid VRF proof")
+
+// Prove computes the VRF output beta for the input alpha and the proof pi
+// that beta was correctly computed with the private key (RFC 9381 section
+// 5.1 and 5.2).
+func Prove(priv *ec.PrivateKey, alpha []byte) (beta, pi []byte) {
+	// The marshalled ed25519 private key is the seed followed by the public
+	// key
+	key := priv.Marshal()
+	seed, pkString := key[:32], key[32:]
+
+	hashedSK := sha512.Sum512(seed)
+	x, err := edwards25519.NewScalar().SetBytesWithClamping(hashedSK[:32])
+	if err != nil {
+		jww.FATAL.Panicf("Failed to derive VRF secret scalar: %+v", err)
+	}
+
+	h, hString, err := encodeToCurve(pkString, alpha)
+	if err != nil {
+		jww.FATAL.Panicf("Failed to encode VRF input to the curve: %+v", err)
+	}
+
+	gamma := new(edwards25519.Point).ScalarMult(x, h)
+	k := nonceGeneration(hashedSK[32:], hString)
+
+	kB := new(edwards25519.Point).ScalarBaseMult(k)
+	kH := new(edwards25519.Point).ScalarMult(k, h)
+	cString := challengeGeneration(pkString, hString, gamma.Bytes(),
+		kB.Bytes(), kH.Bytes())
+
+	c := scalarFromChallenge(cString)
+	s := edwards25519.NewScalar().MultiplyAdd(c, x, k)
+
+	pi = make([]byte, 0, ProofSize)
+	pi = append(pi, gamma.Bytes()...)
+	pi = append(pi, cString...)
+	pi = append(pi, s.Bytes()...)
+
+	return proofToHash(gamma), pi
+}
+
+// Verify checks that pi is a valid proof for the input alpha under the public
+// key and returns the VRF output beta (RFC 9381 section 5.3). It returns
+// ErrInvalidProof if the proof does not verify and another error if the
+// proof or key are malformed.
+func Verify(pub *ec.PublicKey, alpha, pi []byte) ([]byte, error) {
+	pkString := pub.Marshal()
+	y, err := stringToPoint(pkString)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid VRF public key")
+	}
+	// Reject low order keys (RFC 9381 section 5.4.5)
+	if new(edwards25519.Point).MultByCofactor(y).Equal(
+		edwards25519.NewIdentityPoint()) == 1 {
+		return nil, errors.New("invalid VRF public key: small order point")
+	}
+
+	gamma, cString, s, err := decodeProof(pi)
+	if err != nil {
+		return nil, err
+	}
+
+	h, hString, err := encodeToCurve(pkString, alpha)
+	if err != nil {
+		return nil, err
+	}
+
+	// U = s*B - c*Y, V = s*H - c*Gamma
+	c := scalarFromChallenge(cString)
+	negC := edwards25519.NewScalar().Negate(c)
+	u := new(edwards25519.Point).VarTimeDoubleScalarBaseMult(negC, y, s)
+	v := new(edwards25519.Point).VarTimeMultiScalarMult(
+		[]*edwards25519.Scalar{s, negC}, []*edwards25519.Point{h, gamma})
+
+	expected := challengeGeneration(pkString, hString, gamma.Bytes(),
+		u.Bytes(), v.Bytes())
+	if !bytes.Equal(cString, expected) {
+		return nil, ErrInvalidProof
+	}
+
+	return proofToHash(gamma), nil
+}
+
+// ProofToHash returns the VRF output beta for a proof without verifying it
+// (RFC 9381 section 5.2). Only use it on proofs that have already been
+// verified.
+func ProofToHash(pi []byte) ([]byte, error) {
+	gamma, _, _, err := decodeProof(pi)
+	if err != nil {
+		return nil, err
+	}
+	return proofToHash(gamma), nil
+}
+
+// proofToHash returns Hash(suite || 0x03 || cofactor*Gamma || 0x00).
+func proofToHash(gamma *edwards25519.Point) []byte {
+	h := sha512.New()
+	h.Write([]byte{suiteString, proofToHashFront})
+	h.Write(new(edwards25519.Point).MultByCofactor(gamma).Bytes())
+	h.Write([]byte{backByte})
+	return h.Sum(nil)
+}
+
+// decodeProof splits pi into Gamma, the challenge string and s (RFC 9381
+// section 5.4.4).
+func decodeProof(pi []byte) (*edwards25519.Point, []byte,
+	*edwards25519.Scalar, error) {
+	if len(pi) != ProofSize {
+		return nil, nil, nil, errors.Errorf("VRF proof must be %d bytes, "+
+			"received %d", ProofSize, len(pi))
+	}
+
+	gamma, err := stringToPoint(pi[:ptLen])
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "invalid VRF proof point")
+	}
+
+	s, err := edwards25519.NewScalar().SetCanonicalBytes(pi[ptLen+cLen:])
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "invalid VRF proof scalar")
+	}
+
+	cString := make([]byte, cLen)
+	copy(cString, pi[ptLen:ptLen+cLen])
+	return gamma, cString, s, nil
+}
+
+// stringToPoint decodes a point, rejecting the non-canonical encodings that
+// edwards25519.Point.SetBytes accepts (RFC 8032 section 5.1.3), so that every
+// point and therefore every proof has a single valid encoding.
+func stringToPoint(s []byte) (*edwards25519.Point, error) {
+	p, err := new(edwards25519.Point).SetBytes(s)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(p.Bytes(), s) {
+		return nil, errors.New("non-canonical point encoding")
+	}
+	return p, nil
+}
+
+// encodeToCurve hashes alpha to a point with try-and-increment, using the
+// public key as salt (RFC 9381 section 5.4.1.1). It returns the point and its
+// encoding.
+func encodeToCurve(pkString, alpha []byte) (*edwards25519.Point, []byte,
+	error) {
+	h := sha512.New()
+	for ctr := 0; ctr < 256; ctr++ {
+		h.Reset()
+		h.Write([]byte{suiteString, encodeToCurveFront})
+		h.Write(pkString)
+		h.Write(alpha)
+		h.Write([]byte{byte(ctr), backByte})
+		digest := h.Sum(nil)
+
+		p, err := stringToPoint(digest[:ptLen])
+		if err == nil {
+			p.MultByCofactor(p)
+			return p, p.Bytes(), nil
+		}
+	}
+	return nil, nil, errors.New("failed to encode VRF input to the curve")
+}
+
+// nonceGeneration derives the proof nonce deterministically as in RFC 8032
+// (RFC 9381 section 5.4.2.2).
+func nonceGeneration(truncatedHashedSK, hString []byte) *edwards25519.Scalar {
+	h := sha512.New()
+	h.Write(truncatedHashedSK)
+	h.Write(hString)
+	k, err := edwards25519.NewScalar().SetUniformBytes(h.Sum(nil))
+	if err != nil {
+		jww.FATAL.Panicf("Failed to derive VRF nonce: %+v", err)
+	}
+	return k
+}
+
+// challengeGeneration returns the truncated challenge string over the given
+// point encodings (RFC 9381 section 5.4.3).
+func challengeGeneration(points ...[]byte) []byte {
+	h := sha512.New()
+	h.Write([]byte{suiteString, challengeFront})
+	for _, p := range points {
+		h.Write(p)
+	}
+	h.Write([]byte{backByte})
+	return h.Sum(nil)[:cLen]
+}
+
+// scalarFromChallenge interprets the 16 byte little-endian challenge as a
+// scalar.
+func scalarFromChallenge(cString []byte) *edwards25519.Scalar {
+	var buf [qLen]byte
+	copy(buf[:], cString)
+	c, err := edwards25519.NewScalar().SetCanonicalBytes(buf[:])
+	if err != nil {
+		jww.FATAL.Panicf("Failed to decode VRF challenge: %+v", err)
+	}
+	return c
+}
@@ -0,0 +1,197 @@
+////////////////////////////////////////////////////////////////////////////////
+// Copyright © 2022 xx foundation                                             //
+//                                                                            //
+// Use of this source code is governed by a license that can be found in the  //
+// LICENSE file.                                                              //
+////////////////////////////////////////////////////////////////////////////////
+
+package vrf
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"testing"
+
+	"filippo.io/edwards25519"
+	"gitlab.com/xx_network/crypto/csprng"
+	"gitlab.com/xx_network/crypto/signature/ec"
+)
+
+// Test vectors for ECVRF-EDWARDS25519-SHA512-TAI, examples 16 to 18 of RFC
+// 9381 appendix B.3.
+var rfc9381Vectors = []struct {
+	sk, pk, alpha, pi, beta string
+}{
+	{
+		sk:    "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60",
+		pk:    "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a",
+		alpha: "",
+		pi: "8657106690b5526245a92b003bb079ccd1a92130477671f6fc01ad16f26f723f" +
+			"26f8a57ccaed74ee1b190bed1f479d9727d2d0f9b005a6e456a35d4fb0daab12" +
+			"68a1b0db10836d9826a528ca76567805",
+		beta: "90cf1df3b703cce59e2a35b925d411164068269d7b2d29f3301c03dd757876ff" +
+			"66b71dda49d2de59d03450451af026798e8f81cd2e333de5cdf4f3e140fdd8ae",
+	},
+	{
+		sk:    "4ccd089b28ff96da9db6c346ec114e0f5b8a319f35aba624da8cf6ed4fb8a6fb",
+		pk:    "3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c",
+		alpha: "72",
+		pi: "f3141cd382dc42909d19ec5110469e4feae18300e94f304590abdced48aed593" +
+			"3bf0864a62558b3ed7f2fea45c92a465301b3bbf5e3e54ddf2d935be3b67926d" +
+			"a3ef39226bbc355bdc9850112c8f4b02",
+		beta: "eb4440665d3891d668e7e0fcaf587f1b4bd7fbfe99d0eb2211ccec90496310eb" +
+			"5e33821bc613efb94db5e5b54c70a848a0bef4553a41befc57663b56373a5031",
+	},
+	{
+		sk:    "c5aa8df43f9f837bedb7442f31dcb7b166d38535076f094b85ce3a2e0b4458f7",
+		pk:    "fc51cd8e6218a1a38da47ed00230f0580816ed13ba3303ac5deb911548908025",
+		alpha: "af82",
+		pi: "9bc0f79119cc5604bf02d23b4caede71393cedfbb191434dd016d30177ccbf80" +
+			"96bb474e53895c362d8628ee9f9ea3c0e52c7a5c691b6c18c9979866568add7a" +
+			"2d41b00b05081ed0f58ee5e31b3a970e",
+		beta: "645427e5d00c62a23fb703732fa5d892940935942101e456ecca7bb217c61c45" +
+			"2118fec1219202a0edcf038bb6373241578be7217ba85a2687f7a0310b2df19f",
+	},
+}
+
+// decodeHex decodes a hex string or fails the test.
+func decodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("Failed to decode hex string %q: %+v", s, err)
+	}
+	return b
+}
+
+// keyFromSeed returns the ec key pair for an RFC 8032 secret key.
+func keyFromSeed(t *testing.T, seed []byte) *ec.PrivateKey {
+	priv := &ec.PrivateKey{}
+	if err := priv.Unmarshal(ed25519.NewKeyFromSeed(seed)); err != nil {
+		t.Fatalf("Failed to load private key: %+v", err)
+	}
+	return priv
+}
+
+// Tests Prove and Verify against the RFC 9381 test vectors.
+func TestProveVerify_RFC9381(t *testing.T) {
+	for i, v := range rfc9381Vectors {
+		priv := keyFromSeed(t, decodeHex(t, v.sk))
+		pub := priv.GetPublic()
+		if !bytes.Equal(pub.Marshal(), decodeHex(t, v.pk)) {
+			t.Fatalf("Vector %d: public key mismatch.\nexpected: %s"+
+				"\nreceived: %x", i, v.pk, pub.Marshal())
+		}
+		alpha := decodeHex(t, v.alpha)
+
+		beta, pi := Prove(priv, alpha)
+		if hex.EncodeToString(pi) != v.pi {
+			t.Errorf("Vector %d: proof mismatch.\nexpected: %s\nreceived: %x",
+				i, v.pi, pi)
+		}
+		if hex.EncodeToString(beta) != v.beta {
+			t.Errorf("Vector %d: output mismatch.\nexpected: %s\nreceived: %x",
+				i, v.beta, beta)
+		}
+
+		verified, err := Verify(pub, alpha, pi)
+		if err != nil {
+			t.Errorf("Vector %d: Verify returned an error: %+v", i, err)
+		}
+		if hex.EncodeToString(verified) != v.beta {
+			t.Errorf("Vector %d: verified output mismatch.\nexpected: %s"+
+				"\nreceived: %x", i, v.beta, verified)
+		}
+
+		fromProof, err := ProofToHash(pi)
+		if err != nil || !bytes.Equal(fromProof, beta) {
+			t.Errorf("Vector %d: ProofToHash mismatch: %v", i, err)
+		}
+	}
+}
+
+// Error path: tests that proofs fail for a different input, key or a
+// modified proof.
+func TestVerify_Invalid(t *testing.T) {
+	priv, err := ec.NewKeyPair(csprng.NewSystemRNG())
+	if err != nil {
+		t.Fatalf("Failed to generate key: %+v", err)
+	}
+	other, _ := ec.NewKeyPair(csprng.NewSystemRNG())
+	alpha := []byte("round 42")
+	_, pi := Prove(priv, alpha)
+
+	if _, err = Verify(priv.GetPublic(), []byte("round 43"), pi); err != ErrInvalidProof {
+		t.Errorf("Proof verified for a different input: %v", err)
+	}
+	if _, err = Verify(other.GetPublic(), alpha, pi); err != ErrInvalidProof {
+		t.Errorf("Proof verified for a different key: %v", err)
+	}
+
+	for i := range pi {
+		modified := append([]byte{}, pi...)
+		modified[i] ^= 0x01
+		if _, err = Verify(priv.GetPublic(), alpha, modified); err == nil {
+			t.Errorf("Proof with byte %d modified verified.", i)
+		}
+	}
+
+	if _, err = Verify(priv.GetPublic(), alpha, pi[:ProofSize-1]); err == nil {
+		t.Errorf("Truncated proof verified.")
+	}
+	if _, err = ProofToHash(pi[1:]); err == nil {
+		t.Errorf("ProofToHash accepted a truncated proof.")
+	}
+
+	// A small order public key is rejected, here the identity point
+	identity := &ec.PublicKey{}
+	_ = identity.Unmarshal(append([]byte{1}, make([]byte, 31)...))
+	if _, err = Verify(identity, alpha, pi); err == nil {
+		t.Errorf("Verify accepted a small order public key.")
+	}
+}
+
+// nonCanonicalPoint returns a non-canonical encoding, y + p for a small y, of
+// a point that is not of small order.
+func nonCanonicalPoint(t *testing.T) []byte {
+	for y := 0; y < 19; y++ {
+		// p = 2^255 - 19 in little-endian
+		enc := bytes.Repeat([]byte{0xff}, ptLen)
+		enc[0], enc[ptLen-1] = 0xed+byte(y), 0x7f
+		pt, err := new(edwards25519.Point).SetBytes(enc)
+		if err != nil || new(edwards25519.Point).MultByCofactor(pt).Equal(
+			edwards25519.NewIdentityPoint()) == 1 {
+			continue
+		}
+		return enc
+	}
+	t.Fatal("No non-canonical point encoding found")
+	return nil
+}
+
+// Error path: tests that a proof point or public key with a non-canonical
+// encoding is rejected as malformed (RFC 8032 section 5.1.3).
+func TestVerify_NonCanonical(t *testing.T) {
+	priv, err := ec.NewKeyPair(csprng.NewSystemRNG())
+	if err != nil {
+		t.Fatalf("Failed to generate key: %+v", err)
+	}
+	alpha := []byte("round 42")
+	_, pi := Prove(priv, alpha)
+	enc := nonCanonicalPoint(t)
+
+	modified := append(append([]byte{}, enc...), pi[ptLen:]...)
+	if _, err = Verify(priv.GetPublic(), alpha, modified); err == nil ||
+		err == ErrInvalidProof {
+		t.Errorf("Verify did not reject a non-canonical proof point: %v", err)
+	}
+	if _, err = ProofToHash(modified); err == nil {
+		t.Errorf("ProofToHash accepted a non-canonical proof point.")
+	}
+
+	pub := &ec.PublicKey{}
+	_ = pub.Unmarshal(enc)
+	if _, err = Verify(pub, alpha, pi); err == nil || err == ErrInvalidProof {
+		t.Errorf("Verify did not reject a non-canonical public key: %v", err)
+	}
+}